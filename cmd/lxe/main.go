@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/automaticserver/lxe/cli"
 	"github.com/automaticserver/lxe/cri"
+	"github.com/automaticserver/lxe/lxf"
 	"github.com/automaticserver/lxe/network"
 	"github.com/dionysius/errand"
 	"github.com/sirupsen/logrus"
@@ -29,8 +30,16 @@ func init() {
 	// application flags
 	pflags.StringP("socket", "s", "/run/lxe.sock", "Path of the socket where it should provide the runtime and image service to kubelet.")
 	pflags.StringP("lxd-socket", "l", "/var/lib/lxd/unix.socket", "Path of the socket where LXD provides it's API.")
+	pflags.StringP("lxd-remote-url", "", "", "HTTPS address of a remote LXD to connect to instead of the socket, like https://10.0.0.1:8443.")
+	pflags.StringP("lxd-client-cert", "", "", "Path of the client certificate which must be trusted by the remote LXD.")
+	pflags.StringP("lxd-client-key", "", "", "Path of the key of the client certificate.")
+	pflags.StringP("lxd-server-cert", "", "", "Path of the certificate of the remote LXD to trust. (system CAs by default)")
 	pflags.StringP("lxd-remote-config", "r", "", "Path to the LXD remote config. (guessed by default)")
+	pflags.StringP("lxd-project", "", "", "LXD project in which all containers, profiles, images and networks are managed. The project must exist already. (default project of LXD if empty)")
+	pflags.StringP("lxd-criu", "", lxf.CRIUAuto, "Whether CRIU is installed on the LXD host, which checkpoints and live migrations require. One of: auto, installed, missing. LXD doesn't report it, so 'auto' assumes it's installed until such an operation fails for lack of it, which is then remembered for a while.")
 	pflags.StringP("lxd-image-remote", "", "local", "Use this remote if ImageSpec doesn't provide an explicit remote.")
+	pflags.BoolP("lxd-image-auto-update", "", true, "Let LXD refresh pulled images from their source. New containers may then use a newer image than running ones of the same name.")
+	pflags.IntP("lxd-image-cache-expiry", "", 0, "Number of days after which LXD removes unused cached images. This is a server wide setting of LXD. (setting of LXD is kept if 0)")
 	pflags.StringSliceP("lxd-profiles", "p", []string{"default"}, "Set these additional profiles when creating containers.")
 	pflags.StringP("streaming-bindaddr", "", ":44124", "Listen address for the streaming service. Be careful from where this service can be accessed from as it allows to run exec commands on the containers! Format: [IP]:Port.")
 	pflags.StringP("streaming-baseurl", "", "", "Define which base address to use for constructing streaming URLs for a client to connect to. If this is set to empty, it will use the same host address and port from --streaming-bindaddr. If that has an empty host address, it will obtain the address of the interface to the default gateway. Format: [IP][:Port].")
@@ -39,8 +48,23 @@ func init() {
 	pflags.StringP("network-plugin", "n", "bridge", "The network plugin to use. 'bridge' manages the lxd bridge defined in --bridge-name. 'cni' uses kubernetes cni tools to attach interfaces using configuration defined in --cni-conf-dir.")
 	pflags.StringP("bridge-name", "", network.DefaultLXDBridge, "Which bridge to create and use when using --network-plugin 'bridge'.")
 	pflags.StringP("bridge-dhcp-range", "", "", "Which DHCP range to configure the lxd bridge when using --network-plugin 'bridge'. If empty, uses random range provided by lxd. Not needed, if kubernetes will publish the range using CRI UpdateRuntimeconfig.")
+	pflags.StringP("bridge-dhcp-pool", "", "", "Subrange of --bridge-dhcp-range from which DHCP hands out addresses and pods get their IP when using --network-plugin 'bridge', leaving the rest of the subnet for other hosts. Format: start-end, e.g. 10.0.0.100-10.0.0.200. If empty, the whole subnet is used.")
+	pflags.BoolP("bridge-disable-dhcp", "", false, "Disable DHCP on the lxd bridge when using --network-plugin 'bridge'. Pods must get their address by other means then, e.g. statically using cloud-init.")
+	pflags.BoolP("bridge-conflict-check", "", false, "Ping a found IP before assigning it to a pod and select another one if it answers, when using --network-plugin 'bridge'. Detects hosts on the same segment which the bridge has no lease of.")
+	pflags.BoolP("bridge-dns", "", false, "Keep the DNS server of the lxd bridge enabled when using --network-plugin 'bridge'. Kubernetes sets the DNS of pods itself, so it's disabled by default.")
+	pflags.StringP("bridge-dns-domain", "", "", "Domain of the lxd bridge, requires --bridge-dns.")
+	pflags.StringSliceP("bridge-dns-search", "", nil, "Search domains handed out by the lxd bridge, requires --bridge-dns.")
+	pflags.StringP("bridge-gateway", "", "", "IPv4 gateway handed out by DHCP of the lxd bridge instead of the bridge address when using --network-plugin 'bridge'. Must be within --bridge-dhcp-range.")
+	pflags.BoolP("bridge-gateway-off-subnet", "", false, "Allow --bridge-gateway outside of the subnet of the lxd bridge. The pods must be able to reach it by other means.")
+	pflags.StringP("bridge-lock-dir", "", "", "Dir in which a lock file per bridge is locked with flock while IPs are allocated, when using --network-plugin 'bridge'. Other processes allocating IPs of the bridge can lock <dir>/<bridge>.lock to not race with LXE. If empty, allocations are only serialized within LXE.")
+	pflags.StringP("bridge-freeze-file", "", "", "While this file exists, no IPs are allocated for new pods when using --network-plugin 'bridge'. Creating such pods fails with a temporary error kubelet retries, existing pods are kept. Create it for a maintenance window of the bridge and remove it afterwards.")
+	pflags.StringP("config-disk-dir", "", lxf.DefaultConfigDiskDir, "Dir in which the config disks of pods are assembled, a subdir per pod. It is removed with the pod.")
+	pflags.BoolP("force-delete-protected", "", false, "Remove pods and containers which have security.protection.delete set in LXD, by clearing the protection first. Without it, removing them fails until the protection is lifted.")
 	pflags.StringP("cni-conf-dir", "", network.DefaultCNIconfPath, "Dir in which to search for CNI configuration files when using --network-plugin 'cni'.")
 	pflags.StringP("cni-bin-dir", "", network.DefaultCNIbinPath, "Dir in which to search for CNI plugin binaries when using --network-plugin 'cni'.")
+	pflags.StringP("cni-cache-dir", "", network.DefaultCNIcachePath, "Dir in which libcni caches the attachments of pods when using --network-plugin 'cni'. Attachments of unknown pods are deleted on start, except those named like the ids of other runtimes.")
+	pflags.StringP("cni-netns-dir", "", network.DefaultCNInetnsPath, "Dir in which the network namespaces of pods are pinned when using --network-plugin 'cni', a file per pod. It must not be used by anything else, files of unknown pods are removed.")
+	pflags.StringSliceP("cni-netns-allowed-dirs", "", nil, "Dirs in which privileged pods may request an existing network namespace with the annotation lxe.io/netns-path when using --network-plugin 'cni'. The annotation is refused if empty.")
 	pflags.StringP("cni-output-target", "", "stderr", "Where to forward the cni command output, one of: stdout, stderr, file.")
 	pflags.StringP("cni-output-file-path", "", "stderr", "Path to output file. Only required if --cni-output-target is set to file.")
 
@@ -49,21 +73,44 @@ func init() {
 
 func rootCmdRunE(cmd *cobra.Command, args []string) error {
 	conf := &cri.Config{
-		UnixSocket:           venom.GetString("socket"),
-		LXDSocket:            venom.GetString("lxd-socket"),
-		LXDRemoteConfig:      venom.GetString("lxd-remote-config"),
-		LXDImageRemote:       venom.GetString("lxd-image-remote"),
-		LXDProfiles:          venom.GetStringSlice("lxd-profiles"),
-		LXEStreamingBindAddr: venom.GetString("streaming-bindaddr"),
-		LXEStreamingBaseURL:  venom.GetString("streaming-baseurl"),
-		LXEHostnetworkFile:   venom.GetString("hostnetwork-file"),
-		LXENetworkPlugin:     venom.GetString("network-plugin"),
-		LXEBridgeName:        venom.GetString("bridge-name"),
-		LXEBridgeDHCPRange:   venom.GetString("bridge-dhcp-range"),
-		CNIConfDir:           venom.GetString("cni-conf-dir"),
-		CNIBinDir:            venom.GetString("cni-bin-dir"),
-		CNIOutputTarget:      venom.GetString("cni-output-target"),
-		CNIOutputFile:        venom.GetString("cni-output-file-path"),
+		UnixSocket:                venom.GetString("socket"),
+		LXDSocket:                 venom.GetString("lxd-socket"),
+		LXDRemoteURL:              venom.GetString("lxd-remote-url"),
+		LXDClientCert:             venom.GetString("lxd-client-cert"),
+		LXDClientKey:              venom.GetString("lxd-client-key"),
+		LXDServerCert:             venom.GetString("lxd-server-cert"),
+		LXDRemoteConfig:           venom.GetString("lxd-remote-config"),
+		LXDProject:                venom.GetString("lxd-project"),
+		LXDCRIU:                   venom.GetString("lxd-criu"),
+		LXDImageRemote:            venom.GetString("lxd-image-remote"),
+		LXDImageAutoUpdate:        venom.GetBool("lxd-image-auto-update"),
+		LXDImageCacheExpiry:       venom.GetInt("lxd-image-cache-expiry"),
+		LXDProfiles:               venom.GetStringSlice("lxd-profiles"),
+		LXEStreamingBindAddr:      venom.GetString("streaming-bindaddr"),
+		LXEStreamingBaseURL:       venom.GetString("streaming-baseurl"),
+		LXEHostnetworkFile:        venom.GetString("hostnetwork-file"),
+		LXENetworkPlugin:          venom.GetString("network-plugin"),
+		LXEBridgeName:             venom.GetString("bridge-name"),
+		LXEBridgeDHCPRange:        venom.GetString("bridge-dhcp-range"),
+		LXEBridgeDHCPPool:         venom.GetString("bridge-dhcp-pool"),
+		LXEBridgeDisableDHCP:      venom.GetBool("bridge-disable-dhcp"),
+		LXEBridgeConflictCheck:    venom.GetBool("bridge-conflict-check"),
+		LXEBridgeDNS:              venom.GetBool("bridge-dns"),
+		LXEBridgeDNSDomain:        venom.GetString("bridge-dns-domain"),
+		LXEBridgeDNSSearch:        venom.GetStringSlice("bridge-dns-search"),
+		LXEBridgeGateway:          venom.GetString("bridge-gateway"),
+		LXEBridgeGatewayOffSubnet: venom.GetBool("bridge-gateway-off-subnet"),
+		LXEBridgeLockDir:          venom.GetString("bridge-lock-dir"),
+		LXEBridgeFreezeFile:       venom.GetString("bridge-freeze-file"),
+		LXEConfigDiskDir:          venom.GetString("config-disk-dir"),
+		LXEForceDeleteProtected:   venom.GetBool("force-delete-protected"),
+		CNIConfDir:                venom.GetString("cni-conf-dir"),
+		CNIBinDir:                 venom.GetString("cni-bin-dir"),
+		CNICacheDir:               venom.GetString("cni-cache-dir"),
+		CNINetnsDir:               venom.GetString("cni-netns-dir"),
+		CNINetnsAllowedDirs:       venom.GetStringSlice("cni-netns-allowed-dirs"),
+		CNIOutputTarget:           venom.GetString("cni-output-target"),
+		CNIOutputFile:             venom.GetString("cni-output-file-path"),
 	}
 
 	criServer := cri.NewServer(conf)
@@ -9,10 +9,25 @@ type Config struct {
 	UnixSocket string
 	// LXDSocket where LXD is reachable under
 	LXDSocket string
+	// LXDRemoteURL of a remote LXD to connect to via HTTPS instead of LXDSocket
+	LXDRemoteURL string
+	// LXDClientCert and LXDClientKey are the file paths of the certificate LXE authenticates with at LXDRemoteURL
+	LXDClientCert string
+	LXDClientKey  string
+	// LXDServerCert file path of the trusted certificate of LXDRemoteURL, the system CAs are used if empty
+	LXDServerCert string
 	// LXDRemoteConfig file path where lxd remote settings are stored
 	LXDRemoteConfig string
+	// LXDProject to manage all resources in, the default project if empty
+	LXDProject string
+	// LXDCRIU tells whether CRIU is installed on the LXD host, see lxf.Config.CRIU
+	LXDCRIU string
 	// LXDImageRemote to use by default when ImageSpec doesn't provide an explicit remote
 	LXDImageRemote string
+	// LXDImageAutoUpdate lets LXD refresh pulled images from their source
+	LXDImageAutoUpdate bool
+	// LXDImageCacheExpiry is the number of days LXD keeps unused cached images, the setting of LXD is kept if zero
+	LXDImageCacheExpiry int
 	// LXDProfiles which all cri containers inherit
 	LXDProfiles []string
 	// LXEStreamingBindAddr contains the listen address for the streaming server
@@ -27,10 +42,38 @@ type Config struct {
 	LXEBridgeName string
 	// LXEBridgeDHCPRange to configure for lxebr0 if NetworkPlugin is default
 	LXEBridgeDHCPRange string
+	// LXEBridgeDHCPPool is the subrange of LXEBridgeDHCPRange, in the form start-end, from which pods get their IP
+	LXEBridgeDHCPPool string
+	// LXEBridgeDisableDHCP turns off the DHCP server of the bridge
+	LXEBridgeDisableDHCP bool
+	// LXEBridgeConflictCheck enables probing found IPs on the bridge before using them
+	LXEBridgeConflictCheck bool
+	// LXEBridgeDNS keeps the DNS server of the bridge enabled, optionally with a domain and search domains
+	LXEBridgeDNS       bool
+	LXEBridgeDNSDomain string
+	LXEBridgeDNSSearch []string
+	// LXEBridgeGateway is handed out to the pods instead of the bridge address, LXEBridgeGatewayOffSubnet allows one
+	// outside of the bridge subnet
+	LXEBridgeGateway          string
+	LXEBridgeGatewayOffSubnet bool
+	// LXEBridgeLockDir holds the lock files other processes take to serialize IP allocations with the bridge
+	LXEBridgeLockDir string
+	// LXEBridgeFreezeFile freezes allocating IPs of the bridge while it exists
+	LXEBridgeFreezeFile string
+	// LXEConfigDiskDir is where the config disks of pods are assembled on the host
+	LXEConfigDiskDir string
+	// LXEForceDeleteProtected lets kubelet remove pods and containers even if they have the delete protection of LXD set
+	LXEForceDeleteProtected bool
 	// CNIConfDir is the path where the cni configuration files are
 	CNIConfDir string
 	// CNIBinDir is the path where the cni plugins are
 	CNIBinDir string
+	// CNICacheDir is the path where libcni caches the attachments of the pods
+	CNICacheDir string
+	// CNINetnsDir is the path where the network namespaces of pods are pinned
+	CNINetnsDir string
+	// CNINetnsAllowedDirs contain the existing network namespaces privileged pods may request
+	CNINetnsAllowedDirs []string
 	// CNIOutputWriter is the writer for CNI call outputs
 	CNIOutputTarget string
 	// CNIOutputFile is the path to a file
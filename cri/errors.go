@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Provide possibility to annotate errors for logging. The grpc CallTracer will try to match the returned error and log accordingly.
@@ -21,6 +23,16 @@ func (e AnnotatedError) String() string {
 	return fmt.Sprintf("%s: %v", e.Err, e.Log.Data)
 }
 
+// GRPCStatus returns the grpc status of Err if it has one, so e.g. temporary conditions reach the client as
+// codes.Unavailable. Other errors are returned as codes.Unknown like grpc does for plain errors
+func (e AnnotatedError) GRPCStatus() *status.Status {
+	if s, is := status.FromError(e.Err); is && s != nil {
+		return s
+	}
+
+	return status.New(codes.Unknown, e.Error())
+}
+
 func AnnErr(log *logrus.Entry, err error, msg string) error {
 	return AnnotatedError{log, err, msg}
 }
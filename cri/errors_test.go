@@ -0,0 +1,38 @@
+package cri
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAnnotatedError_GRPCStatus(t *testing.T) {
+	t.Parallel()
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"Plain", errors.New("failed"), codes.Unknown},
+		{"Unavailable", status.Error(codes.Unavailable, "ip allocation frozen"), codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, is := status.FromError(AnnErr(entry, tt.err, "msg"))
+			assert.True(t, is)
+			assert.Equal(t, tt.code, s.Code())
+		})
+	}
+}
@@ -1,3 +0,0 @@
-package cri // import "github.com/automaticserver/lxe/cri"
-
-//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 github.com/automaticserver/lxe/lxf.Client
@@ -78,7 +78,7 @@ func (s ImageServer) ListImages(ctx context.Context, req *rtApi.ListImagesReques
 func (s ImageServer) ImageStatus(ctx context.Context, req *rtApi.ImageStatusRequest) (*rtApi.ImageStatusResponse, error) {
 	log := log.WithContext(ctx).WithField("image", req.GetImage().GetImage())
 
-	img, err := s.lxf.GetImage(req.GetImage().GetImage())
+	img, err := s.lxf.ImageStatus(req.GetImage().GetImage())
 	if err != nil {
 		// If the image can't be found, return no error with empty result
 		if shared.IsErrNotFound(err) {
@@ -89,14 +89,21 @@ func (s ImageServer) ImageStatus(ctx context.Context, req *rtApi.ImageStatusRequ
 	}
 
 	response := &rtApi.ImageStatusResponse{Image: &rtApi.Image{
-		Id:    img.Hash,
+		Id:    img.Fingerprint,
 		Size_: uint64(img.Size),
 		RepoDigests: []string{
-			img.Hash,
+			img.Fingerprint,
 		},
 		RepoTags: img.Aliases,
 	}}
 
+	// kubelet checks runAsNonRoot against the uid, a user name can't be checked
+	if uid, is := img.UID(); is {
+		response.Image.Uid = &rtApi.Int64Value{Value: uid}
+	} else {
+		response.Image.Username = img.User
+	}
+
 	return response, nil
 }
 
@@ -108,13 +115,13 @@ func (s ImageServer) ImageStatus(ctx context.Context, req *rtApi.ImageStatusRequ
 func (s ImageServer) PullImage(ctx context.Context, req *rtApi.PullImageRequest) (*rtApi.PullImageResponse, error) {
 	log := log.WithContext(ctx).WithField("image", req.GetImage().GetImage())
 
-	hash, err := s.lxf.PullImage(req.GetImage().GetImage())
+	img, err := s.lxf.PullImage(req.GetImage().GetImage())
 	if err != nil {
 		return nil, AnnErr(log, err, "failed to pull image")
 	}
 
 	response := &rtApi.PullImageResponse{
-		ImageRef: hash,
+		ImageRef: img.Fingerprint,
 	}
 
 	return response, nil
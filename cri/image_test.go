@@ -4,7 +4,9 @@ import (
 	"context"
 	"testing"
 
-	"github.com/automaticserver/lxe/cri/crifakes"
+	"github.com/automaticserver/lxe/lxf"
+	"github.com/automaticserver/lxe/lxf/lxffakes"
+	"github.com/automaticserver/lxe/shared"
 	"github.com/stretchr/testify/assert"
 	rtApi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
@@ -13,8 +15,8 @@ var (
 	ctx = context.TODO()
 )
 
-func testImageServer() (*ImageServer, *crifakes.FakeClient) {
-	fake := &crifakes.FakeClient{}
+func testImageServer() (*ImageServer, *lxffakes.FakeClient) {
+	fake := &lxffakes.FakeClient{}
 
 	return &ImageServer{
 		lxf: fake,
@@ -24,7 +26,7 @@ func testImageServer() (*ImageServer, *crifakes.FakeClient) {
 func TestImageServer_PullImage(t *testing.T) {
 	s, fake := testImageServer()
 
-	fake.PullImageReturns("something", nil)
+	fake.PullImageReturns(&lxf.ImageInfo{Fingerprint: "something"}, nil)
 
 	resp, err := s.PullImage(ctx, &rtApi.PullImageRequest{
 		Image: &rtApi.ImageSpec{
@@ -37,3 +39,37 @@ func TestImageServer_PullImage(t *testing.T) {
 	assert.Equal(t, "an/image", fake.PullImageArgsForCall(0))
 	assert.Equal(t, "something", resp.ImageRef)
 }
+
+func TestImageServer_ImageStatus(t *testing.T) {
+	s, fake := testImageServer()
+
+	fake.ImageStatusReturns(&lxf.ImageInfo{Fingerprint: "abc", Size: 1024, Aliases: []string{"ubuntu:latest"}, User: "1000"}, nil)
+
+	resp, err := s.ImageStatus(ctx, &rtApi.ImageStatusRequest{Image: &rtApi.ImageSpec{Image: "ubuntu"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", resp.Image.Id)
+	assert.Equal(t, uint64(1024), resp.Image.Size_)
+	assert.Equal(t, int64(1000), resp.Image.Uid.GetValue())
+	assert.Empty(t, resp.Image.Username)
+}
+
+func TestImageServer_ImageStatus_Username(t *testing.T) {
+	s, fake := testImageServer()
+
+	fake.ImageStatusReturns(&lxf.ImageInfo{Fingerprint: "abc", User: "nginx"}, nil)
+
+	resp, err := s.ImageStatus(ctx, &rtApi.ImageStatusRequest{Image: &rtApi.ImageSpec{Image: "nginx"}})
+	assert.NoError(t, err)
+	assert.Nil(t, resp.Image.Uid)
+	assert.Equal(t, "nginx", resp.Image.Username)
+}
+
+func TestImageServer_ImageStatus_NotFound(t *testing.T) {
+	s, fake := testImageServer()
+
+	fake.ImageStatusReturns(nil, shared.NewErrNotFound())
+
+	resp, err := s.ImageStatus(ctx, &rtApi.ImageStatusRequest{Image: &rtApi.ImageSpec{Image: "missing"}})
+	assert.NoError(t, err)
+	assert.Nil(t, resp.Image)
+}
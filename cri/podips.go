@@ -0,0 +1,69 @@
+package cri // import "github.com/automaticserver/lxe/cri"
+
+import (
+	"sync"
+	"time"
+
+	"github.com/automaticserver/lxe/lxf"
+)
+
+// RecentPodIPTTL is how long the IP of a removed pod is kept for a recreated pod of the same name
+var RecentPodIPTTL = 10 * time.Minute
+
+// recentPodIPs remembers the IPs of removed pods, so a pod which is recreated quickly can ask the network plugin for
+// the same IP again. This keeps e.g. external firewall rules valid.
+type recentPodIPs struct {
+	sync.Mutex
+	ips map[string]recentPodIP
+}
+
+type recentPodIP struct {
+	ip      string
+	removed time.Time
+}
+
+// podKey identifies a pod across recreations
+func podKey(meta lxf.SandboxMetadata) string {
+	return meta.Namespace + "/" + meta.Name
+}
+
+// remember stores the ip of the removed pod and forgets expired ones
+func (r *recentPodIPs) remember(meta lxf.SandboxMetadata, ip string) {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+
+	if r.ips == nil {
+		r.ips = make(map[string]recentPodIP)
+	}
+
+	for key, e := range r.ips {
+		if now.Sub(e.removed) > RecentPodIPTTL {
+			delete(r.ips, key)
+		}
+	}
+
+	r.ips[podKey(meta)] = recentPodIP{ip: ip, removed: now}
+}
+
+// take returns and forgets the ip a removed pod had, empty if there is none
+func (r *recentPodIPs) take(meta lxf.SandboxMetadata) string {
+	r.Lock()
+	defer r.Unlock()
+
+	key := podKey(meta)
+
+	e, has := r.ips[key]
+	if !has {
+		return ""
+	}
+
+	delete(r.ips, key)
+
+	if time.Since(e.removed) > RecentPodIPTTL {
+		return ""
+	}
+
+	return e.ip
+}
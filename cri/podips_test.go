@@ -0,0 +1,78 @@
+package cri
+
+import (
+	"testing"
+	"time"
+
+	"github.com/automaticserver/lxe/lxf"
+	"github.com/automaticserver/lxe/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentPodIPs_Take(t *testing.T) {
+	t.Parallel()
+
+	r := &recentPodIPs{}
+	meta := lxf.SandboxMetadata{Namespace: "default", Name: "foo", UID: "1"}
+
+	assert.Equal(t, "", r.take(meta))
+
+	r.remember(meta, "10.0.0.5")
+
+	// a recreated pod has a different uid and attempt
+	assert.Equal(t, "", r.take(lxf.SandboxMetadata{Namespace: "other", Name: "foo"}))
+	assert.Equal(t, "10.0.0.5", r.take(lxf.SandboxMetadata{Namespace: "default", Name: "foo", UID: "2"}))
+	assert.Equal(t, "", r.take(meta))
+}
+
+func TestRecentPodIPs_Expired(t *testing.T) {
+	t.Parallel()
+
+	meta := lxf.SandboxMetadata{Namespace: "default", Name: "foo"}
+	r := &recentPodIPs{ips: map[string]recentPodIP{
+		podKey(meta):  {ip: "10.0.0.5", removed: time.Now().Add(-RecentPodIPTTL - time.Second)},
+		"default/bar": {ip: "10.0.0.6", removed: time.Now().Add(-RecentPodIPTTL - time.Second)},
+	}}
+
+	assert.Equal(t, "", r.take(meta))
+
+	r.remember(meta, "10.0.0.7")
+	assert.NotContains(t, r.ips, "default/bar")
+}
+
+func TestRuntimeServer_withPreferredIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{"recent ip", map[string]string{"foo": "bar"}, "10.0.0.5"},
+		{"own preference", map[string]string{network.AnnotationPreferredIP: "10.0.0.9"}, "10.0.0.9"},
+		{"requested ip", map[string]string{network.AnnotationIP: "10.0.0.9"}, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := testRuntimeServer()
+
+			sb := &lxf.Sandbox{}
+			sb.Metadata = lxf.SandboxMetadata{Namespace: "default", Name: "foo"}
+			sb.Annotations = map[string]string{}
+
+			for k, v := range tt.annotations {
+				sb.Annotations[k] = v
+			}
+
+			s.recentIPs.remember(sb.Metadata, "10.0.0.5")
+
+			annotations := s.withPreferredIP(sb)
+			assert.Equal(t, tt.want, annotations[network.AnnotationPreferredIP])
+			// the annotations of the pod are left untouched
+			assert.Equal(t, tt.annotations, sb.Annotations)
+		})
+	}
+}
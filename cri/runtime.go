@@ -2,8 +2,10 @@ package cri // import "github.com/automaticserver/lxe/cri"
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"net"
 	"path"
 	"strconv"
 	"strings"
@@ -24,11 +26,43 @@ import (
 
 const (
 	criVersion = "0.1.0"
+	// AnnotationEphemeral on a pod creates its containers as ephemeral LXD containers, which are deleted as soon as they
+	// stop. Only suitable for pods which are never restarted, as kubelet can't obtain the exit code of a deleted container
+	AnnotationEphemeral = "lxe.io/ephemeral"
+	// AnnotationShmSize on a pod sets the size of /dev/shm in its containers, like 64MB or 1GiB
+	AnnotationShmSize = "lxe.io/shm-size"
+	// AnnotationArchitecture on a pod creates its containers with this architecture, like x86_64 or arm64
+	AnnotationArchitecture = "lxe.io/architecture"
+	// AnnotationAppArmorUnconfined on a privileged pod runs its privileged containers without AppArmor profile
+	AnnotationAppArmorUnconfined = "lxe.io/apparmor-unconfined"
+	// AnnotationTimezone on a pod sets the timezone of all its containers, like Europe/Zurich
+	AnnotationTimezone = "lxe.io/timezone"
+	// AnnotationKeepImageTemplates on a pod leaves the image templates for /etc/hostname and /etc/hosts of its
+	// containers in place, which are otherwise disabled if the pod sets a hostname
+	AnnotationKeepImageTemplates = "lxe.io/keep-image-templates"
+	// AnnotationInstanceType on a pod runs its containers as LXD instances of this type, container or virtual-machine
+	AnnotationInstanceType = "lxe.io/instance-type"
+	// AnnotationClusterTarget on a pod creates its containers on this member of the LXD cluster, or within this
+	// cluster group if prefixed with @
+	AnnotationClusterTarget = "lxe.io/cluster-target"
+	// AnnotationProxyProtocol on a pod sends a PROXY protocol header with the client address to its tcp host ports, so
+	// the services see who connected instead of the proxy. They must expect the header
+	AnnotationProxyProtocol = "lxe.io/proxy-protocol"
+
+	// appArmorProfileUnconfined is the apparmor profile kubelet passes for containers annotated to be unconfined
+	appArmorProfileUnconfined = "unconfined"
 )
 
 var (
 	ErrNotImplemented       = errors.New("not implemented")
 	ErrUnknownNetworkPlugin = errors.New("unknown network plugin")
+	ErrNoHostNetworkFile    = errors.New("no hostnetwork file configured")
+	ErrUnknownPropagation   = errors.New("unknown mount propagation")
+	ErrArgsWithoutCommand   = errors.New("args require a command")
+	ErrUnprivilegedNetns    = errors.New("only privileged pods may request a network namespace")
+
+	// hostIP returns the address of the host, which pods with host networking share
+	hostIP = utilNet.ChooseHostInterface
 )
 
 // RuntimeServer is the PoC implementation of the CRI RuntimeServer
@@ -39,6 +73,7 @@ type RuntimeServer struct {
 	lxdConfig *config.Config
 	criConfig *Config
 	network   network.Plugin
+	recentIPs *recentPodIPs
 }
 
 // NewRuntimeServer returns a new RuntimeServer backed by LXD
@@ -48,6 +83,7 @@ func NewRuntimeServer(criConfig *Config, lxf lxf.Client, network network.Plugin)
 	runtime := RuntimeServer{
 		criConfig: criConfig,
 		network:   network,
+		recentIPs: &recentPodIPs{},
 	}
 
 	configPath, err := getLXDConfigPath(criConfig)
@@ -111,6 +147,13 @@ func (s RuntimeServer) RunPodSandbox(ctx context.Context, req *rtApi.RunPodSandb
 	}
 	sb.Labels = req.GetConfig().GetLabels()
 	sb.Annotations = req.GetConfig().GetAnnotations()
+	sb.ShmSize = sb.Annotations[AnnotationShmSize]
+	sb.Architecture = sb.Annotations[AnnotationArchitecture]
+	sb.AppArmorUnconfined = sb.Annotations[AnnotationAppArmorUnconfined] == "true"
+	sb.Timezone = sb.Annotations[AnnotationTimezone]
+	sb.InstanceType = lxf.InstanceType(sb.Annotations[AnnotationInstanceType])
+	// kubelet requests the pod mode for shareProcessNamespace
+	sb.SharedPIDNamespace = req.GetConfig().GetLinux().GetSecurityContext().GetNamespaceOptions().GetPid() == rtApi.NamespaceMode_POD
 
 	if req.GetConfig().GetDnsConfig() != nil {
 		sb.NetworkConfig.Nameservers = req.GetConfig().GetDnsConfig().GetServers()
@@ -119,10 +162,21 @@ func (s RuntimeServer) RunPodSandbox(ctx context.Context, req *rtApi.RunPodSandb
 
 	// Find out which network mode should be used
 	if strings.ToLower(req.GetConfig().GetLinux().GetSecurityContext().GetNamespaceOptions().GetNetwork().String()) == string(lxf.NetworkHost) {
-		// host network explicitly requested
+		// host network explicitly requested. The pod shares the network namespace of the host, so the network plugin isn't
+		// involved in any later step of this pod. Without the include file the container would end up with its own empty
+		// network namespace instead
+		if s.criConfig.LXEHostnetworkFile == "" {
+			return nil, AnnErr(log, ErrNoHostNetworkFile, "pod requests host network")
+		}
+
 		sb.NetworkConfig.Mode = lxf.NetworkHost
-		lxf.AppendIfSet(&sb.Config, "raw.lxc", "lxc.include = "+s.criConfig.LXEHostnetworkFile)
+		sb.RawLXC = append(sb.RawLXC, "lxc.include = "+s.criConfig.LXEHostnetworkFile)
 	} else {
+		// an existing namespace might be shared with the host or other pods, so only privileged pods may join one
+		if _, has := sb.Annotations[network.AnnotationNetnsPath]; has && !req.GetConfig().GetLinux().GetSecurityContext().GetPrivileged() {
+			return nil, AnnErr(log, ErrUnprivilegedNetns, "pod requests network namespace "+sb.Annotations[network.AnnotationNetnsPath])
+		}
+
 		// manage network according to selected network plugin
 		// TODO: we could omit these since we use network plugin, but we still need to remember if it is HostNetwork
 		switch s.criConfig.LXENetworkPlugin {
@@ -136,48 +190,28 @@ func (s RuntimeServer) RunPodSandbox(ctx context.Context, req *rtApi.RunPodSandb
 		}
 	}
 
-	// If HostPort is defined, set forwardings from that port to the container. In lxd, we can use proxy devices for that.
-	// This can be applied to all NetworkModes except HostNetwork.
-	if sb.NetworkConfig.Mode != lxf.NetworkHost {
-		for _, portMap := range req.Config.PortMappings {
-			// both HostPort and ContainerPort must be defined, otherwise invalid
-			if portMap.GetHostPort() == 0 || portMap.GetContainerPort() == 0 {
-				continue
-			}
-
-			hostPort := int(portMap.GetHostPort())
-			containerPort := int(portMap.GetContainerPort())
-
-			var protocol device.Protocol
-
-			switch portMap.GetProtocol() { // nolint: exhaustive
-			case rtApi.Protocol_UDP:
-				protocol = device.ProtocolUDP
-			case rtApi.Protocol_TCP:
-				fallthrough
-			default:
-				protocol = device.ProtocolTCP
-			}
+	// If HostPort is defined, set forwardings from that port to the container. If the network plugin can't publish them
+	// itself, we can use proxy devices of lxd for that. This can be applied to all NetworkModes except HostNetwork.
+	if mapper, is := s.network.(network.PortMapper); is && mapper.MapsPorts() && sb.NetworkConfig.Mode != lxf.NetworkHost {
+		err = setPortMappings(sb, toPortMappings(req.GetConfig().GetPortMappings()))
+		if err != nil {
+			return nil, AnnErr(log, err, "unable to record port mappings")
+		}
+	} else if sb.NetworkConfig.Mode != lxf.NetworkHost {
+		sourceIP := device.ProxySourceIPHidden
+		if sb.Annotations[AnnotationProxyProtocol] == "true" {
+			sourceIP = device.ProxySourceIPProxyProtocol
+		}
 
-			hostIP := portMap.GetHostIp()
-			if hostIP == "" {
-				hostIP = "0.0.0.0"
-			}
+		var proxies device.Devices
 
-			containerIP := "127.0.0.1"
+		proxies, err = portMappingProxies(toPortMappings(req.GetConfig().GetPortMappings()), sourceIP)
+		if err != nil {
+			return nil, AnnErr(log, err, "unable to publish host port")
+		}
 
-			sb.Devices.Upsert(&device.Proxy{
-				Listen: &device.ProxyEndpoint{
-					Protocol: protocol,
-					Address:  hostIP,
-					Port:     hostPort,
-				},
-				Destination: &device.ProxyEndpoint{
-					Protocol: protocol,
-					Address:  containerIP,
-					Port:     containerPort,
-				},
-			})
+		for _, proxy := range proxies {
+			sb.Devices.Upsert(proxy)
 		}
 	}
 
@@ -219,6 +253,7 @@ func (s RuntimeServer) RunPodSandbox(ctx context.Context, req *rtApi.RunPodSandb
 
 			lxf.SetIfSet(&sb.Config, "user.linux.security_context.seccomp_profile_path",
 				req.Config.Linux.SecurityContext.SeccompProfilePath)
+			sb.SeccompProfile = req.Config.Linux.SecurityContext.SeccompProfilePath
 
 			if req.Config.Linux.SecurityContext.SelinuxOptions != nil {
 				sci := "user.linux.security_context.namespace_options"
@@ -239,36 +274,10 @@ func (s RuntimeServer) RunPodSandbox(ctx context.Context, req *rtApi.RunPodSandb
 	log = log.WithField("podid", sb.ID)
 
 	// create network
-	if sb.NetworkConfig.Mode != lxf.NetworkHost { // nolint: nestif
-		podNet, err := s.network.PodNetwork(sb.ID, sb.Annotations)
-		if err != nil {
-			return nil, AnnErr(log, err, "can't enter pod network context")
-		}
-
-		res, err := podNet.WhenCreated(ctx, &network.Properties{})
-		if err != nil {
-			return nil, AnnErr(log, err, "can't create pod network")
-		}
-
-		err = s.handleNetworkResult(sb, res)
-		if err != nil {
-			return nil, AnnErr(log, err, "unable to save pod network result")
-		}
-
-		// Since a PodSandbox is created "started", also fire started network
-		res, err = podNet.WhenStarted(ctx, &network.PropertiesRunning{
-			Properties: network.Properties{
-				Data: sb.NetworkConfig.ModeData,
-			},
-			Pid: 0, // if we had real 1:n pod:container we would add here the pid of the pod process
-		})
-		if err != nil {
-			return nil, AnnErr(log, err, "can't start pod network")
-		}
-
-		err = s.handleNetworkResult(sb, res)
+	if sb.NetworkConfig.Mode != lxf.NetworkHost {
+		err = s.runPodNetwork(ctx, log, sb)
 		if err != nil {
-			return nil, AnnErr(log, err, "unable to save start pod network result")
+			return nil, err
 		}
 	}
 
@@ -358,6 +367,26 @@ func (s RuntimeServer) RemovePodSandbox(ctx context.Context, req *rtApi.RemovePo
 		}
 	}
 
+	// The dynamic lease of the pod lingers in the bridge until it expires, shrinking the pool of free IPs
+	if sb.NetworkConfig.Mode == lxf.NetworkBridged {
+		if ip := net.ParseIP(sb.NetworkConfig.ModeData["interface-address"]); ip != nil {
+			// a recreated pod of the same name gets the ip back if it's still free
+			s.recentIPs.remember(sb.Metadata, ip.String())
+
+			err = s.lxf.ReclaimLease(sb, s.criConfig.LXEBridgeName, ip)
+			if err != nil {
+				logReclaimLeaseErr(log.WithField("ip", ip.String()), err)
+			}
+		}
+
+		for _, a := range network.AdditionalBridgeAddresses(sb.NetworkConfig.ModeData) {
+			err = s.lxf.ReclaimLease(sb, a.Bridge, a.IP)
+			if err != nil {
+				logReclaimLeaseErr(log.WithField("bridge", a.Bridge).WithField("ip", a.IP.String()), err)
+			}
+		}
+	}
+
 	log.Info("remove pod successful")
 
 	return &rtApi.RemovePodSandboxResponse{}, nil
@@ -416,6 +445,13 @@ func (s RuntimeServer) PodSandboxStatus(ctx context.Context, req *rtApi.PodSandb
 		response.Status.Network.Ip = ip
 	}
 
+	// the result of the connectivity check is only of interest when diagnosing
+	if req.GetVerbose() {
+		if check := s.podConnectivity(ctx, sb); check != nil {
+			response.Info = map[string]string{"connectivity": connectivityInfo(check)}
+		}
+	}
+
 	return response, nil
 }
 
@@ -425,7 +461,8 @@ func (s RuntimeServer) getInetAddress(ctx context.Context, sb *lxf.Sandbox) stri
 
 	switch sb.NetworkConfig.Mode {
 	case lxf.NetworkHost:
-		ip, err := utilNet.ChooseHostInterface()
+		// the pod shares the network of the host, whose address is chosen by the default route like kubelet does
+		ip, err := hostIP()
 		if err != nil {
 			log.WithError(err).Error("Couldn't choose host interface")
 			return ""
@@ -469,7 +506,7 @@ func (s RuntimeServer) getInetAddress(ctx context.Context, sb *lxf.Sandbox) stri
 		}
 
 		// get the ipv4 address of eth0
-		ip := c.GetInetAddress([]string{network.DefaultInterface})
+		ip := c.GetInetAddress([]string{shared.DefaultInterface})
 		if ip != "" {
 			return ip
 		}
@@ -560,11 +597,17 @@ func (s RuntimeServer) CreateContainer(ctx context.Context, req *rtApi.CreateCon
 			containerPath = path.Join("/mnt", strings.TrimPrefix(containerPath, "/run"))
 		}
 
+		propagation, err := toDiskPropagation(mnt.GetPropagation())
+		if err != nil {
+			return nil, AnnErr(log, err, "unable to mount "+containerPath)
+		}
+
 		c.Devices.Upsert(&device.Disk{
-			Path:     containerPath,
-			Source:   hostPath,
-			Readonly: mnt.GetReadonly(),
-			Optional: false,
+			Path:        containerPath,
+			Source:      hostPath,
+			Readonly:    mnt.GetReadonly(),
+			Optional:    false,
+			Propagation: propagation,
 		})
 	}
 
@@ -576,6 +619,32 @@ func (s RuntimeServer) CreateContainer(ctx context.Context, req *rtApi.CreateCon
 	}
 
 	c.Privileged = req.GetConfig().GetLinux().GetSecurityContext().GetPrivileged()
+	c.Ephemeral = req.GetSandboxConfig().GetAnnotations()[AnnotationEphemeral] == "true"
+	c.KeepImageTemplates = req.GetSandboxConfig().GetAnnotations()[AnnotationKeepImageTemplates] == "true"
+	c.Target = req.GetSandboxConfig().GetAnnotations()[AnnotationClusterTarget]
+	c.SeccompProfile = req.GetConfig().GetLinux().GetSecurityContext().GetSeccompProfilePath()
+	c.AppArmorUnconfined = req.GetConfig().GetLinux().GetSecurityContext().GetApparmorProfile() == appArmorProfileUnconfined
+
+	// LXD images have no entrypoint the args could be passed to, so they're only used along with a command. Without
+	// command the container boots the init of the image
+	if cmd := req.GetConfig().GetCommand(); len(cmd) > 0 {
+		c.InitCommand = append(append([]string{}, cmd...), req.GetConfig().GetArgs()...)
+	} else if len(req.GetConfig().GetArgs()) > 0 {
+		return nil, AnnErr(log, ErrArgsWithoutCommand, "images have no entrypoint to pass args to")
+	}
+
+	if user := req.GetConfig().GetLinux().GetSecurityContext().GetRunAsUser(); user != nil {
+		uid := user.GetValue()
+		c.RunAsUser = &uid
+	}
+
+	if group := req.GetConfig().GetLinux().GetSecurityContext().GetRunAsGroup(); group != nil {
+		gid := group.GetValue()
+		c.RunAsGroup = &gid
+	}
+
+	// CRI has no fsGroup, kubelet passes it as one of the supplemental groups and takes care of the volumes itself
+	c.SupplementalGroups = req.GetConfig().GetLinux().GetSecurityContext().GetSupplementalGroups()
 
 	// get metadata & cloud-init if defined
 	for _, env := range req.GetConfig().GetEnvs() {
@@ -608,6 +677,8 @@ func (s RuntimeServer) CreateContainer(ctx context.Context, req *rtApi.CreateCon
 		period := uint64(resrc.CpuPeriod)
 		c.Resources.CPU.Period = &period
 		c.Resources.Memory.Limit = &resrc.MemoryLimitInBytes
+		c.CPUPriority = lxf.CPUPriorityFromShares(shares)
+		c.CPUAllowance = lxf.CPUAllowanceFromQuota(resrc.CpuQuota, period)
 	}
 
 	err = c.Apply()
@@ -632,7 +703,7 @@ func (s RuntimeServer) CreateContainer(ctx context.Context, req *rtApi.CreateCon
 			return nil, AnnErr(log, err, "can't enter container network context")
 		}
 
-		res, err := contNet.WhenCreated(ctx, &network.Properties{})
+		res, err := contNet.WhenCreated(ctx, &network.Properties{Data: sb.NetworkConfig.ModeData})
 		if err != nil {
 			return nil, AnnErr(log, err, "can't create container network")
 		}
@@ -641,6 +712,16 @@ func (s RuntimeServer) CreateContainer(ctx context.Context, req *rtApi.CreateCon
 		if err != nil {
 			return nil, AnnErr(log, err, "unable to save create container network result")
 		}
+
+		// containers of a pod share the network namespace the pod network is attached to
+		if res != nil && res.JoinNetns != "" {
+			c.RawLXC = append(c.RawLXC, "lxc.namespace.share.net = "+res.JoinNetns)
+
+			err = c.Apply()
+			if err != nil {
+				return nil, AnnErr(log, err, "unable to join pod network namespace")
+			}
+		}
 	}
 
 	log.Info("create container successful")
@@ -767,6 +848,21 @@ func (s RuntimeServer) ContainerStatus(ctx context.Context, req *rtApi.Container
 
 	response := toCriStatusResponse(ct)
 
+	// CRI has no network stats, so they are at least part of the verbose info
+	if req.GetVerbose() && ct.StateName == lxf.ContainerStateRunning {
+		st, err := ct.State()
+		if err != nil {
+			return nil, AnnErr(log, err, "unable to get container state")
+		}
+
+		stats, err := json.Marshal(st.Stats.Network)
+		if err != nil {
+			return nil, AnnErr(log, err, "unable to encode network stats")
+		}
+
+		response.Info["network"] = string(stats)
+	}
+
 	return response, nil
 }
 
@@ -828,7 +924,14 @@ func (s RuntimeServer) Exec(ctx context.Context, req *rtApi.ExecRequest) (*rtApi
 
 // Attach prepares a streaming endpoint to attach to a running container.
 func (s RuntimeServer) Attach(ctx context.Context, req *rtApi.AttachRequest) (*rtApi.AttachResponse, error) {
-	return nil, SilErr(log, ErrNotImplemented, "")
+	log := log.WithContext(ctx).WithField("containerid", req.GetContainerId())
+
+	resp, err := s.stream.streamServer.GetAttach(req)
+	if err != nil {
+		return nil, AnnErr(log, err, "unable to get attach stream")
+	}
+
+	return resp, nil
 }
 
 // PortForward prepares a streaming endpoint to forward ports from a PodSandbox.
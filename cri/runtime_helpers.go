@@ -1,10 +1,13 @@
 package cri // import "github.com/automaticserver/lxe/cri"
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,7 +16,10 @@ import (
 	"github.com/automaticserver/lxe/network"
 	"github.com/automaticserver/lxe/shared"
 	sharedLXD "github.com/lxc/lxd/shared"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	rtApi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
@@ -51,7 +57,7 @@ func toCriStatusResponse(c *lxf.Container) *rtApi.ContainerStatusResponse {
 				HostPath:       d.Source,
 				Readonly:       d.Readonly,
 				SelinuxRelabel: false, // though don't know what this means
-				Propagation:    rtApi.MountPropagation_PROPAGATION_PRIVATE,
+				Propagation:    diskPropagationAsCri(d.Propagation),
 			})
 		}
 	}
@@ -133,6 +139,32 @@ func stateSandboxAsCri(s lxf.SandboxState) rtApi.PodSandboxState {
 		rtApi.PodSandboxState_value["SANDBOX_"+strings.ToUpper(s.String())])
 }
 
+// toDiskPropagation translates the mount propagation of cri to the one of lxd disks. Private is the default of lxd
+func toDiskPropagation(p rtApi.MountPropagation) (string, error) {
+	switch p {
+	case rtApi.MountPropagation_PROPAGATION_PRIVATE:
+		return "", nil
+	case rtApi.MountPropagation_PROPAGATION_HOST_TO_CONTAINER:
+		return device.DiskPropagationRSlave, nil
+	case rtApi.MountPropagation_PROPAGATION_BIDIRECTIONAL:
+		return device.DiskPropagationRShared, nil
+	}
+
+	return "", fmt.Errorf("%w: %v", ErrUnknownPropagation, p)
+}
+
+// diskPropagationAsCri is the reverse of toDiskPropagation
+func diskPropagationAsCri(p string) rtApi.MountPropagation {
+	switch p {
+	case device.DiskPropagationSlave, device.DiskPropagationRSlave:
+		return rtApi.MountPropagation_PROPAGATION_HOST_TO_CONTAINER
+	case device.DiskPropagationShared, device.DiskPropagationRShared:
+		return rtApi.MountPropagation_PROPAGATION_BIDIRECTIONAL
+	}
+
+	return rtApi.MountPropagation_PROPAGATION_PRIVATE
+}
+
 func nameSpaceOptionToString(no rtApi.NamespaceMode) string {
 	return strings.ToLower(no.String())
 }
@@ -190,7 +222,21 @@ func (s RuntimeServer) stopContainers(sb *lxf.Sandbox) error {
 		return err
 	}
 
+	// the holder of a shared PID namespace is stopped last, so the processes of the others are reaped till the end
+	ordered := make([]*lxf.Container, 0, len(cl))
 	for _, c := range cl {
+		if c.PIDNamespaceHolder != "" {
+			ordered = append(ordered, c)
+		}
+	}
+
+	for _, c := range cl {
+		if c.PIDNamespaceHolder == "" {
+			ordered = append(ordered, c)
+		}
+	}
+
+	for _, c := range ordered {
 		err := s.stopContainer(c, 30)
 		if err != nil {
 			return err
@@ -235,7 +281,7 @@ func (s RuntimeServer) deleteContainers(ctx context.Context, sb *lxf.Sandbox) er
 }
 
 func (s RuntimeServer) deleteContainer(ctx context.Context, c *lxf.Container) error {
-	err := c.Delete()
+	err := c.DeleteContext(ctx)
 	if err != nil {
 		if shared.IsErrNotFound(err) {
 			return nil
@@ -265,6 +311,43 @@ func (s RuntimeServer) deleteContainer(ctx context.Context, c *lxf.Container) er
 
 var NetworkSetupTimeout = 30 * time.Second
 
+// withPreferredIP returns the annotations of the sandbox for the network plugin. If a pod of the same name was removed
+// recently, its IP is preferred, unless the pod asks for a specific IP itself
+func (s RuntimeServer) withPreferredIP(sb *lxf.Sandbox) map[string]string {
+	ip := s.recentIPs.take(sb.Metadata)
+	if ip == "" {
+		return sb.Annotations
+	}
+
+	if _, has := sb.Annotations[network.AnnotationPreferredIP]; has {
+		return sb.Annotations
+	}
+
+	if _, has := sb.Annotations[network.AnnotationIP]; has {
+		return sb.Annotations
+	}
+
+	annotations := make(map[string]string, len(sb.Annotations)+1)
+	for k, v := range sb.Annotations {
+		annotations[k] = v
+	}
+
+	annotations[network.AnnotationPreferredIP] = ip
+
+	return annotations
+}
+
+// logReclaimLeaseErr logs why the lease of a removed pod couldn't be reclaimed. A remote LXD never supports it, which is
+// no reason to warn about every removed pod
+func logReclaimLeaseErr(log *logrus.Entry, err error) {
+	if errors.Is(err, lxf.ErrLeaseReclaimUnsupported) {
+		log.WithError(err).Debug("unable to reclaim lease of pod")
+		return
+	}
+
+	log.WithError(err).Warn("unable to reclaim lease of pod")
+}
+
 // ContainerStarted implements lxf.EventHandler interface
 func (s RuntimeServer) ContainerStarted(c *lxf.Container) error {
 	sb, err := c.Sandbox()
@@ -273,7 +356,11 @@ func (s RuntimeServer) ContainerStarted(c *lxf.Container) error {
 	}
 
 	if sb.NetworkConfig.Mode != lxf.NetworkHost { // nolint: nestif
-		st, err := c.State()
+		ctx, cancel := context.WithTimeout(context.Background(), NetworkSetupTimeout)
+		defer cancel()
+
+		// the network can only be attached once the init process exists
+		pid, err := s.lxf.WaitRunning(ctx, c.ID)
 		if err != nil {
 			return err
 		}
@@ -288,13 +375,18 @@ func (s RuntimeServer) ContainerStarted(c *lxf.Container) error {
 			return fmt.Errorf("can't enter container network context: %w", err)
 		}
 
-		ctx, _ := context.WithTimeout(context.Background(), NetworkSetupTimeout)
+		// the container might hold the pod network, which publishes the ports of the pod
+		mappings, err := podPortMappings(sb)
+		if err != nil {
+			return fmt.Errorf("unable to read port mappings: %w", err)
+		}
 
 		res, err := contNet.WhenStarted(ctx, &network.PropertiesRunning{
 			Properties: network.Properties{
-				Data: sb.NetworkConfig.ModeData,
+				Data:         sb.NetworkConfig.ModeData,
+				PortMappings: mappings,
 			},
-			Pid: st.Pid,
+			Pid: pid,
 		})
 		if err != nil {
 			return fmt.Errorf("can't start container network: %w", err)
@@ -349,3 +441,200 @@ func (s *RuntimeServer) handleNetworkResult(sb *lxf.Sandbox, res *network.Result
 
 	return nil
 }
+
+// runPodNetwork creates and starts the network of a new pod and saves the results in the sandbox. If it fails after the
+// network got created, the network is deleted again
+func (s *RuntimeServer) runPodNetwork(ctx context.Context, log *logrus.Entry, sb *lxf.Sandbox) error {
+	podNet, err := s.network.PodNetwork(sb.ID, s.withPreferredIP(sb))
+	if err != nil {
+		return AnnErr(log, err, "can't enter pod network context")
+	}
+
+	res, err := podNet.WhenCreated(ctx, &network.Properties{})
+	if errors.Is(err, network.ErrAllocationFrozen) {
+		return AnnErr(log, status.Error(codes.Unavailable, err.Error()), "ip allocation of pod network is frozen")
+	} else if err != nil {
+		return AnnErr(log, err, "can't create pod network")
+	}
+
+	// the pod doesn't run if any later step fails, so the IPs reserved for it can't wait for its removal
+	defer func() {
+		if err != nil {
+			_ = podNet.WhenDeleted(ctx, &network.Properties{Data: sb.NetworkConfig.ModeData})
+		}
+	}()
+
+	err = s.handleNetworkResult(sb, res)
+	if err != nil {
+		return AnnErr(log, err, "unable to save pod network result")
+	}
+
+	mappings, err := podPortMappings(sb)
+	if err != nil {
+		return AnnErr(log, err, "unable to read port mappings")
+	}
+
+	privileged, _ := strconv.ParseBool(sb.Config["security.privileged"])
+
+	// Since a PodSandbox is created "started", also fire started network. The pod has no process, plugins attach the
+	// network to a namespace of their own the containers join, or to the first started container
+	res, err = podNet.WhenStarted(ctx, &network.PropertiesRunning{
+		Properties: network.Properties{
+			Data:         sb.NetworkConfig.ModeData,
+			PortMappings: mappings,
+		},
+		Pid:        0,
+		Privileged: privileged,
+	})
+	if err != nil {
+		return AnnErr(log, err, "can't start pod network")
+	}
+
+	err = s.handleNetworkResult(sb, res)
+	if err != nil {
+		return AnnErr(log, err, "unable to save start pod network result")
+	}
+
+	return nil
+}
+
+// cfgPortMappings records the port mappings of a pod, if the network plugin publishes them
+const cfgPortMappings = "user.port_mappings"
+
+// toPortMappings converts the port mappings of cri to the ones of the network plugins. Mappings without host or
+// container port are skipped
+func toPortMappings(pms []*rtApi.PortMapping) []network.PortMapping {
+	var mappings []network.PortMapping
+
+	for _, pm := range pms {
+		if pm.GetHostPort() == 0 || pm.GetContainerPort() == 0 {
+			continue
+		}
+
+		mappings = append(mappings, network.PortMapping{
+			HostPort:      pm.GetHostPort(),
+			ContainerPort: pm.GetContainerPort(),
+			Protocol:      strings.ToLower(pm.GetProtocol().String()),
+			HostIP:        pm.GetHostIp(),
+		})
+	}
+
+	return mappings
+}
+
+// setPortMappings records the port mappings on the sandbox, so they are at hand when its network is set up
+func setPortMappings(sb *lxf.Sandbox, mappings []network.PortMapping) error {
+	if len(mappings) == 0 {
+		delete(sb.Config, cfgPortMappings)
+		return nil
+	}
+
+	raw, err := json.Marshal(mappings)
+	if err != nil {
+		return err
+	}
+
+	sb.Config[cfgPortMappings] = string(raw)
+
+	return nil
+}
+
+// podPortMappings returns the port mappings recorded on the sandbox
+func podPortMappings(sb *lxf.Sandbox) ([]network.PortMapping, error) {
+	raw := sb.Config[cfgPortMappings]
+	if raw == "" {
+		return nil, nil
+	}
+
+	var mappings []network.PortMapping
+
+	err := json.Unmarshal([]byte(raw), &mappings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v of pod %v: %w", cfgPortMappings, sb.ID, err)
+	}
+
+	return mappings, nil
+}
+
+const (
+	// proxyWildcard is listened on for mappings without host ip
+	proxyWildcard = "0.0.0.0"
+	// proxyLoopback is connected to inside the instance, as the proxy reaches it from within its network namespace
+	proxyLoopback = "127.0.0.1"
+)
+
+// portMappingProxies returns the proxy devices publishing the mappings on the host. Mappings without host ip listen on
+// all addresses. The tcp proxies preserve the source ip as selected by sourceIP, NAT isn't possible as the proxies
+// connect to the loopback address of the instance. Only tcp and udp can be proxied
+func portMappingProxies(mappings []network.PortMapping, sourceIP device.ProxySourceIP) (device.Devices, error) {
+	proxies := device.Devices{}
+
+	for _, m := range mappings {
+		var protocol device.Protocol
+
+		switch m.Protocol {
+		case network.ProtocolTCP:
+			protocol = device.ProtocolTCP
+		case network.ProtocolUDP:
+			protocol = device.ProtocolUDP
+		default:
+			return nil, fmt.Errorf("%w: %v host port %v requires a network plugin publishing the ports itself",
+				network.ErrPortMappingUnsupported, m.Protocol, m.HostPort)
+		}
+
+		hostIP := m.HostIP
+		if hostIP == "" {
+			hostIP = proxyWildcard
+		}
+
+		proxy := &device.Proxy{
+			Listen: &device.ProxyEndpoint{
+				Protocol: protocol,
+				Address:  hostIP,
+				Port:     int(m.HostPort),
+			},
+			Destination: &device.ProxyEndpoint{
+				Protocol: protocol,
+				Address:  proxyLoopback,
+				Port:     int(m.ContainerPort),
+			},
+		}
+
+		if protocol == device.ProtocolTCP {
+			proxy.SourceIP = sourceIP
+		}
+
+		proxies.Upsert(proxy)
+	}
+
+	return proxies, nil
+}
+
+// podConnectivity returns the outcome of the connectivity check of the pod network, nil if none ran
+func (s RuntimeServer) podConnectivity(ctx context.Context, sb *lxf.Sandbox) *network.ConnectivityCheck {
+	if sb.NetworkConfig.Mode != lxf.NetworkCNI && sb.NetworkConfig.Mode != lxf.NetworkBridged {
+		return nil
+	}
+
+	podNet, err := s.network.PodNetwork(sb.ID, sb.Annotations)
+	if err != nil {
+		return nil
+	}
+
+	status, err := podNet.Status(ctx, &network.PropertiesRunning{Properties: network.Properties{Data: sb.NetworkConfig.ModeData}})
+	if err != nil {
+		return nil
+	}
+
+	return status.Connectivity
+}
+
+// connectivityInfo describes the outcome of the connectivity check for the verbose info
+func connectivityInfo(check *network.ConnectivityCheck) string {
+	switch {
+	case check.Err != nil:
+		return fmt.Sprintf("failed: %v", check.Err)
+	default:
+		return fmt.Sprintf("gateway %v reachable", check.Gateway)
+	}
+}
@@ -0,0 +1,313 @@
+package cri
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf"
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxffakes"
+	"github.com/automaticserver/lxe/network"
+	"github.com/automaticserver/lxe/network/networkfakes"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/stretchr/testify/assert"
+	rtApi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func testRuntimeServer() (*RuntimeServer, *lxffakes.FakeClient, *networkfakes.FakePlugin) {
+	fake := &lxffakes.FakeClient{}
+	fakeNet := &networkfakes.FakePlugin{}
+
+	return &RuntimeServer{
+		criConfig: &Config{},
+		lxf:       fake,
+		network:   fakeNet,
+		recentIPs: &recentPodIPs{},
+	}, fake, fakeNet
+}
+
+func TestRuntimeServer_RunPodSandbox_HostNetworkWithoutFile(t *testing.T) {
+	t.Parallel()
+
+	s, fake, fakeNet := testRuntimeServer()
+
+	fake.NewSandboxReturns(&lxf.Sandbox{})
+
+	_, err := s.RunPodSandbox(ctx, &rtApi.RunPodSandboxRequest{
+		Config: &rtApi.PodSandboxConfig{
+			Metadata: &rtApi.PodSandboxMetadata{Name: "foo"},
+			Linux: &rtApi.LinuxPodSandboxConfig{
+				SecurityContext: &rtApi.LinuxSandboxSecurityContext{
+					NamespaceOptions: &rtApi.NamespaceOption{Network: rtApi.NamespaceMode_NODE},
+				},
+			},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, ErrNoHostNetworkFile, err.(AnnotatedError).Err)
+	assert.Equal(t, 0, fakeNet.PodNetworkCallCount())
+}
+
+func TestRuntimeServer_runPodNetwork_StartFails(t *testing.T) {
+	t.Parallel()
+
+	s, _, fakeNet := testRuntimeServer()
+	fakePodNet := &networkfakes.FakePodNetwork{}
+	fakeNet.PodNetworkReturns(fakePodNet, nil)
+	fakePodNet.WhenStartedReturns(nil, errors.New("start fails"))
+
+	sb := &lxf.Sandbox{}
+	sb.ID = "foo"
+	sb.NetworkConfig.ModeData = map[string]string{"interface-address": "10.0.0.5"}
+
+	err := s.runPodNetwork(ctx, log, sb)
+	assert.Error(t, err)
+	// the ip reserved on creation is released again
+	assert.Equal(t, 1, fakePodNet.WhenDeletedCallCount())
+
+	_, argProp := fakePodNet.WhenDeletedArgsForCall(0)
+	assert.Equal(t, "10.0.0.5", argProp.Data["interface-address"])
+}
+
+func TestRuntimeServer_runPodNetwork_CreateFails(t *testing.T) {
+	t.Parallel()
+
+	s, _, fakeNet := testRuntimeServer()
+	fakePodNet := &networkfakes.FakePodNetwork{}
+	fakeNet.PodNetworkReturns(fakePodNet, nil)
+	fakePodNet.WhenCreatedReturns(nil, errors.New("create fails"))
+
+	err := s.runPodNetwork(ctx, log, &lxf.Sandbox{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, fakePodNet.WhenStartedCallCount())
+	assert.Equal(t, 0, fakePodNet.WhenDeletedCallCount())
+}
+
+func TestRuntimeServer_runPodNetwork_Ok(t *testing.T) {
+	t.Parallel()
+
+	s, _, fakeNet := testRuntimeServer()
+	fakePodNet := &networkfakes.FakePodNetwork{}
+	fakeNet.PodNetworkReturns(fakePodNet, nil)
+
+	err := s.runPodNetwork(ctx, log, &lxf.Sandbox{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fakePodNet.WhenStartedCallCount())
+	assert.Equal(t, 0, fakePodNet.WhenDeletedCallCount())
+}
+
+func TestRuntimeServer_runPodNetwork_Privileged(t *testing.T) {
+	t.Parallel()
+
+	s, _, fakeNet := testRuntimeServer()
+	fakePodNet := &networkfakes.FakePodNetwork{}
+	fakeNet.PodNetworkReturns(fakePodNet, nil)
+
+	sb := &lxf.Sandbox{}
+	sb.Config = map[string]string{"security.privileged": "true"}
+
+	err := s.runPodNetwork(ctx, log, sb)
+	assert.NoError(t, err)
+
+	_, argProp := fakePodNet.WhenStartedArgsForCall(0)
+	assert.True(t, argProp.Privileged)
+}
+
+// fakeHostIP replaces hostIP to return ip and err
+func fakeHostIP(t *testing.T, ip net.IP, err error) {
+	oldHostIP := hostIP
+	hostIP = func() (net.IP, error) {
+		return ip, err
+	}
+
+	t.Cleanup(func() { hostIP = oldHostIP })
+}
+
+func TestRuntimeServer_PodSandboxStatus_HostNetwork(t *testing.T) {
+	s, fake, fakeNet := testRuntimeServer()
+	fakeHostIP(t, net.ParseIP("10.0.0.5"), nil)
+
+	fake.GetSandboxReturns(&lxf.Sandbox{
+		NetworkConfig: lxf.NetworkConfig{Mode: lxf.NetworkHost},
+	}, nil)
+
+	resp, err := s.PodSandboxStatus(ctx, &rtApi.PodSandboxStatusRequest{PodSandboxId: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", resp.GetStatus().GetNetwork().GetIp())
+	assert.Equal(t, 0, fakeNet.PodNetworkCallCount())
+}
+
+func TestRuntimeServer_PodSandboxStatus_HostNetworkNoInterface(t *testing.T) {
+	s, fake, _ := testRuntimeServer()
+	fakeHostIP(t, nil, errors.New("no default route"))
+
+	fake.GetSandboxReturns(&lxf.Sandbox{
+		NetworkConfig: lxf.NetworkConfig{Mode: lxf.NetworkHost},
+	}, nil)
+
+	resp, err := s.PodSandboxStatus(ctx, &rtApi.PodSandboxStatusRequest{PodSandboxId: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", resp.GetStatus().GetNetwork().GetIp())
+}
+
+func TestRuntimeServer_RunPodSandbox_UnprivilegedNetns(t *testing.T) {
+	t.Parallel()
+
+	s, fake, fakeNet := testRuntimeServer()
+
+	fake.NewSandboxReturns(&lxf.Sandbox{})
+
+	_, err := s.RunPodSandbox(ctx, &rtApi.RunPodSandboxRequest{
+		Config: &rtApi.PodSandboxConfig{
+			Metadata:    &rtApi.PodSandboxMetadata{Name: "foo"},
+			Annotations: map[string]string{network.AnnotationNetnsPath: "/run/netns/foo"},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, ErrUnprivilegedNetns, err.(AnnotatedError).Err)
+	assert.Equal(t, 0, fakeNet.PodNetworkCallCount())
+}
+
+func TestRuntimeServer_CreateContainer_ArgsWithoutCommand(t *testing.T) {
+	t.Parallel()
+
+	s, fake, _ := testRuntimeServer()
+
+	fake.NewContainerReturns(&lxf.Container{})
+
+	_, err := s.CreateContainer(ctx, &rtApi.CreateContainerRequest{
+		PodSandboxId: "foo",
+		Config: &rtApi.ContainerConfig{
+			Metadata: &rtApi.ContainerMetadata{Name: "bar"},
+			Args:     []string{"--verbose"},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, ErrArgsWithoutCommand, err.(AnnotatedError).Err)
+}
+
+func TestRuntimeServer_StopContainer_AlreadyDeleted(t *testing.T) {
+	t.Parallel()
+
+	s, fake, _ := testRuntimeServer()
+
+	fake.GetContainerReturns(nil, fmt.Errorf("container %w: foo", shared.NewErrNotFound()))
+
+	_, err := s.StopContainer(ctx, &rtApi.StopContainerRequest{ContainerId: "foo"})
+	assert.NoError(t, err)
+}
+
+func TestRuntimeServer_RemoveContainer_AlreadyDeleted(t *testing.T) {
+	t.Parallel()
+
+	s, fake, fakeNet := testRuntimeServer()
+
+	fake.GetContainerReturns(nil, fmt.Errorf("container %w: foo", shared.NewErrNotFound()))
+
+	_, err := s.RemoveContainer(ctx, &rtApi.RemoveContainerRequest{ContainerId: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fakeNet.PodNetworkCallCount())
+}
+
+func Test_toDiskPropagation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   rtApi.MountPropagation
+		want    string
+		wantErr bool
+	}{
+		{"private", rtApi.MountPropagation_PROPAGATION_PRIVATE, "", false},
+		{"host to container", rtApi.MountPropagation_PROPAGATION_HOST_TO_CONTAINER, device.DiskPropagationRSlave, false},
+		{"bidirectional", rtApi.MountPropagation_PROPAGATION_BIDIRECTIONAL, device.DiskPropagationRShared, false},
+		{"unknown", rtApi.MountPropagation(42), "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toDiskPropagation(tt.input)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrUnknownPropagation))
+
+			if !tt.wantErr {
+				assert.Equal(t, tt.input, diskPropagationAsCri(got))
+			}
+		})
+	}
+}
+
+func Test_toPortMappings(t *testing.T) {
+	t.Parallel()
+
+	mappings := toPortMappings([]*rtApi.PortMapping{
+		{Protocol: rtApi.Protocol_TCP, ContainerPort: 80, HostPort: 8080},
+		{Protocol: rtApi.Protocol_UDP, ContainerPort: 53, HostPort: 5353, HostIp: "10.0.0.1"},
+		{Protocol: rtApi.Protocol_SCTP, ContainerPort: 3868, HostPort: 3868},
+		{Protocol: rtApi.Protocol_TCP, ContainerPort: 443},
+	})
+
+	assert.Equal(t, []network.PortMapping{
+		{HostPort: 8080, ContainerPort: 80, Protocol: network.ProtocolTCP},
+		{HostPort: 5353, ContainerPort: 53, Protocol: network.ProtocolUDP, HostIP: "10.0.0.1"},
+		{HostPort: 3868, ContainerPort: 3868, Protocol: network.ProtocolSCTP},
+	}, mappings)
+}
+
+func Test_setPortMappings(t *testing.T) {
+	t.Parallel()
+
+	sb := &lxf.Sandbox{}
+	sb.Config = map[string]string{}
+
+	err := setPortMappings(sb, []network.PortMapping{{HostPort: 5353, ContainerPort: 53, Protocol: network.ProtocolUDP}})
+	assert.NoError(t, err)
+
+	mappings, err := podPortMappings(sb)
+	assert.NoError(t, err)
+	assert.Equal(t, []network.PortMapping{{HostPort: 5353, ContainerPort: 53, Protocol: network.ProtocolUDP}}, mappings)
+
+	err = setPortMappings(sb, nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, sb.Config, cfgPortMappings)
+}
+
+func testPortProxy(listen, connect string) *device.Proxy {
+	l, _ := device.NewProxyEndpoint(listen)
+	c, _ := device.NewProxyEndpoint(connect)
+
+	return &device.Proxy{Listen: l, Destination: c}
+}
+
+func Test_portMappingProxies(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := portMappingProxies([]network.PortMapping{
+		{HostPort: 8080, ContainerPort: 80, Protocol: network.ProtocolTCP},
+		{HostPort: 5353, ContainerPort: 53, Protocol: network.ProtocolUDP, HostIP: "10.0.0.1"},
+	}, device.ProxySourceIPProxyProtocol)
+	assert.NoError(t, err)
+
+	tcp := testPortProxy("tcp:0.0.0.0:8080", "tcp:127.0.0.1:80")
+	tcp.SourceIP = device.ProxySourceIPProxyProtocol
+
+	assert.Equal(t, device.Devices{tcp, testPortProxy("udp:10.0.0.1:5353", "udp:127.0.0.1:53")}, proxies)
+}
+
+func Test_portMappingProxies_SCTP(t *testing.T) {
+	t.Parallel()
+
+	_, err := portMappingProxies([]network.PortMapping{{HostPort: 3868, ContainerPort: 3868, Protocol: network.ProtocolSCTP}}, "")
+	assert.True(t, errors.Is(err, network.ErrPortMappingUnsupported))
+}
+
+func Test_connectivityInfo(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "gateway 10.22.0.1 reachable", connectivityInfo(&network.ConnectivityCheck{Gateway: net.ParseIP("10.22.0.1")}))
+	assert.Equal(t, "failed: no gateway", connectivityInfo(&network.ConnectivityCheck{Err: network.ErrNoGateway}))
+}
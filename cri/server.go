@@ -11,6 +11,7 @@ import (
 
 	"github.com/automaticserver/lxe/lxf"
 	"github.com/automaticserver/lxe/network"
+	"github.com/automaticserver/lxe/shared"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	rtApi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -45,12 +46,32 @@ func NewServer(criConfig *Config) *Server {
 		log.WithError(err).Fatal("Unable to find lxc config")
 	}
 
-	client, err := lxf.NewClient(criConfig.LXDSocket, configPath)
+	client, err := lxf.NewClient(lxf.Config{
+		Socket:        criConfig.LXDSocket,
+		RemoteURL:     criConfig.LXDRemoteURL,
+		ClientCert:    criConfig.LXDClientCert,
+		ClientKey:     criConfig.LXDClientKey,
+		ServerCert:    criConfig.LXDServerCert,
+		ConfigPath:    configPath,
+		Project:       criConfig.LXDProject,
+		ConfigDiskDir: criConfig.LXEConfigDiskDir,
+		CRIU:          criConfig.LXDCRIU,
+		// kubelet retries to remove a protected pod forever, unless the protection is deliberately overridden
+		ForceDeleteProtected: criConfig.LXEForceDeleteProtected,
+	})
 	if err != nil {
 		log.WithError(err).Fatal("Unable to initialize lxe facade")
 	}
 
-	log.WithField("lxdsocket", criConfig.LXDSocket).Info("Connected to LXD")
+	log.WithField("lxdsocket", criConfig.LXDSocket).WithField("lxdremote", criConfig.LXDRemoteURL).WithField("lxdproject", criConfig.LXDProject).Info("Connected to LXD")
+
+	err = client.SetImagePolicy(lxf.ImagePolicy{
+		AutoUpdate:        criConfig.LXDImageAutoUpdate,
+		RemoteCacheExpiry: criConfig.LXDImageCacheExpiry,
+	})
+	if err != nil {
+		log.WithError(err).Fatal("Unable to set image policy")
+	}
 
 	// Ensure profile and container schema migration
 	migration := lxf.NewMigrationWorkspace(client)
@@ -86,16 +107,49 @@ func NewServer(criConfig *Config) *Server {
 		}
 
 		netPlugin, err = network.InitPluginCNI(network.ConfCNI{
-			BinPath:      criConfig.CNIBinDir,
-			ConfPath:     criConfig.CNIConfDir,
-			OutputWriter: writer,
+			BinPath:          criConfig.CNIBinDir,
+			ConfPath:         criConfig.CNIConfDir,
+			CacheDir:         criConfig.CNICacheDir,
+			NetnsPath:        criConfig.CNINetnsDir,
+			NetnsAllowedDirs: criConfig.CNINetnsAllowedDirs,
+			OutputWriter:     writer,
 		})
 	case NetworkPluginBridge:
+		var gateway net.IP
+
+		if criConfig.LXEBridgeGateway != "" {
+			gateway = net.ParseIP(criConfig.LXEBridgeGateway)
+			if gateway == nil {
+				log.WithField("gateway", criConfig.LXEBridgeGateway).Fatal("Invalid bridge gateway")
+			}
+		}
+
+		var pool shared.IPRange
+
+		if criConfig.LXEBridgeDHCPPool != "" {
+			ranges, err := shared.ParseIPRanges(criConfig.LXEBridgeDHCPPool)
+			if err != nil || len(ranges) != 1 {
+				log.WithError(err).WithField("pool", criConfig.LXEBridgeDHCPPool).Fatal("Invalid bridge dhcp pool")
+			}
+
+			pool = ranges[0]
+		}
+
 		netPlugin, err = network.InitPluginLXDBridge(client.GetServer(), network.ConfLXDBridge{
-			LXDBridge:  criConfig.LXEBridgeName,
-			Cidr:       criConfig.LXEBridgeDHCPRange,
-			Nat:        true,
-			CreateOnly: true,
+			LXDBridge:        criConfig.LXEBridgeName,
+			Cidr:             criConfig.LXEBridgeDHCPRange,
+			Nat:              true,
+			CreateOnly:       true,
+			DisableDHCP:      criConfig.LXEBridgeDisableDHCP,
+			ConflictCheck:    criConfig.LXEBridgeConflictCheck,
+			DNS:              criConfig.LXEBridgeDNS,
+			DNSDomain:        criConfig.LXEBridgeDNSDomain,
+			DNSSearch:        criConfig.LXEBridgeDNSSearch,
+			Gateway:          gateway,
+			GatewayOffSubnet: criConfig.LXEBridgeGatewayOffSubnet,
+			LockDir:          criConfig.LXEBridgeLockDir,
+			DHCPPool:         pool,
+			FreezeFile:       criConfig.LXEBridgeFreezeFile,
 		})
 	default:
 		err = fmt.Errorf("%w: %s", ErrUnknownNetworkPlugin, criConfig.LXENetworkPlugin)
@@ -105,6 +159,11 @@ func NewServer(criConfig *Config) *Server {
 		log.WithError(err).Fatal("Unable to initialize network plugin")
 	}
 
+	// Clean up networks of pods which were removed while a teardown didn't complete
+	if gc, is := netPlugin.(network.GarbageCollector); is {
+		collectNetworkGarbage(client, gc)
+	}
+
 	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(callTracing))
 
 	// for now we bind the http on every interface
@@ -135,6 +194,29 @@ func NewServer(criConfig *Config) *Server {
 	}
 }
 
+// collectNetworkGarbage lets the network plugin remove the networks of all pods which don't exist anymore. Errors are
+// only logged, as the remaining pods can be served anyway
+func collectNetworkGarbage(client lxf.Client, gc network.GarbageCollector) {
+	sbs, err := client.ListSandboxes()
+	if err != nil {
+		log.WithError(err).Error("Unable to list pods for network garbage collection")
+		return
+	}
+
+	ids := make([]string, 0, len(sbs))
+	for _, sb := range sbs {
+		ids = append(ids, sb.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), NetworkSetupTimeout)
+	defer cancel()
+
+	err = gc.GC(ctx, ids)
+	if err != nil {
+		log.WithError(err).Warn("Network garbage collection failed partially")
+	}
+}
+
 // Serve creates the cri socket and wraps for grpc.Serve
 func (c *Server) Serve() error {
 	var err error
@@ -128,6 +128,37 @@ func (ss streamService) Exec(containerID string, cmd []string, stdinR io.Reader,
 	return nil
 }
 
+// Attach connects the streams to the console of the container. A console is a terminal with a single output, even if
+// tty wasn't requested. That output goes to stdout, or to stderr if only that one was requested.
+func (ss streamService) Attach(containerID string, stdin io.Reader, stdout, stderr io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize) error {
+	log := log.WithField("container", containerID).WithField("tty", tty)
+
+	var out io.Writer
+
+	switch {
+	case stdout != nil:
+		out = stdout
+
+		if stderr != nil {
+			log.Debug("console has no separate stderr, it is merged into stdout")
+		}
+	case stderr != nil:
+		out = stderr
+	}
+
+	// without tty there is no terminal size of the caller to apply
+	if !tty {
+		resize = nil
+	}
+
+	err := ss.runtimeServer.lxf.AttachConsole(containerID, stdin, out, resize)
+	if err != nil {
+		return AnnErr(log, err, "error attaching to console")
+	}
+
+	return nil
+}
+
 func (ss streamService) PortForward(podSandboxID string, port int32, stream io.ReadWriteCloser) error {
 	log := log.WithField("podsandbox", podSandboxID).WithField("port", port)
 
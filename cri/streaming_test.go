@@ -0,0 +1,54 @@
+package cri
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func Test_streamService_Attach(t *testing.T) {
+	t.Parallel()
+
+	s, fake, _ := testRuntimeServer()
+	ss := streamService{runtimeServer: s}
+
+	stdout := nopWriteCloser{&bytes.Buffer{}}
+	stderr := nopWriteCloser{&bytes.Buffer{}}
+	resize := make(chan remotecommand.TerminalSize)
+
+	err := ss.Attach("foo", nil, stdout, stderr, true, resize)
+	assert.NoError(t, err)
+
+	id, stdin, out, gotResize := fake.AttachConsoleArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Nil(t, stdin)
+	assert.Equal(t, stdout, out)
+	assert.Equal(t, (<-chan remotecommand.TerminalSize)(resize), gotResize)
+}
+
+func Test_streamService_Attach_StderrOnly(t *testing.T) {
+	t.Parallel()
+
+	s, fake, _ := testRuntimeServer()
+	ss := streamService{runtimeServer: s}
+
+	stderr := nopWriteCloser{&bytes.Buffer{}}
+
+	err := ss.Attach("foo", nil, nil, stderr, false, make(chan remotecommand.TerminalSize))
+	assert.NoError(t, err)
+
+	_, _, out, resize := fake.AttachConsoleArgsForCall(0)
+	assert.Equal(t, stderr, out)
+	assert.Nil(t, resize)
+}
@@ -1,7 +0,0 @@
-// +build tools
-
-package cri // import "github.com/automaticserver/lxe/cri"
-
-import (
-	_ "github.com/maxbrunsfeld/counterfeiter/v6"
-)
@@ -0,0 +1,54 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// cfgAppArmorUnconfined marks the lxc.apparmor.profile line as generated from AppArmorUnconfined, so a line the user
+	// set in RawLXC stays there
+	cfgAppArmorUnconfined = "user.apparmor_unconfined"
+	lxcAppArmorProfile    = "lxc.apparmor.profile"
+	appArmorUnconfined    = "unconfined"
+)
+
+// validateAppArmor refuses to run the object unconfined unless it is privileged, as AppArmor is what keeps an
+// unprivileged container from reaching the host through e.g. /proc and /sys
+func (o *LXDObject) validateAppArmor(privileged bool) error {
+	if o.AppArmorUnconfined && !privileged {
+		return fmt.Errorf("%w: running unconfined by AppArmor requires privileged", ErrUsage)
+	}
+
+	return nil
+}
+
+// makeAppArmorConfig sets the AppArmor profile of liblxc to unconfined if requested. Otherwise the profile LXD
+// generates applies
+func (o *LXDObject) makeAppArmorConfig(config map[string]string) {
+	if !o.AppArmorUnconfined {
+		delete(config, cfgAppArmorUnconfined)
+		return
+	}
+
+	config[cfgAppArmorUnconfined] = strconv.FormatBool(true)
+	setRawLXCOption(config, lxcAppArmorProfile, appArmorUnconfined)
+}
+
+// parseAppArmorConfig returns whether the object runs unconfined and the raw.lxc lines without the one generated for it
+func parseAppArmorConfig(config map[string]string, rawLXC []string) (bool, []string) {
+	if unconfined, _ := strconv.ParseBool(config[cfgAppArmorUnconfined]); !unconfined {
+		return false, rawLXC
+	}
+
+	generated := lxcAppArmorProfile + " = " + appArmorUnconfined
+	lines := make([]string, 0, len(rawLXC))
+
+	for _, l := range rawLXC {
+		if l != generated {
+			lines = append(lines, l)
+		}
+	}
+
+	return true, lines
+}
@@ -0,0 +1,50 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXDObject_validateAppArmor(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, (&LXDObject{}).validateAppArmor(false))
+	assert.NoError(t, (&LXDObject{AppArmorUnconfined: true}).validateAppArmor(true))
+
+	err := (&LXDObject{AppArmorUnconfined: true}).validateAppArmor(false)
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestLXDObject_makeAppArmorConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{RawLXC: []string{"lxc.include = /foo"}, AppArmorUnconfined: true}
+	config := map[string]string{}
+
+	makeRawLXC(config, o.RawLXC)
+	o.makeAppArmorConfig(config)
+	assert.Equal(t, "lxc.include = /foo\nlxc.apparmor.profile = unconfined", config[cfgRawLXC])
+	assert.Equal(t, "true", config[cfgAppArmorUnconfined])
+
+	unconfined, lines := parseAppArmorConfig(config, rawLXCLines(config))
+	assert.True(t, unconfined)
+	assert.Equal(t, o.RawLXC, lines)
+}
+
+func TestLXDObject_makeAppArmorConfig_UserLine(t *testing.T) {
+	t.Parallel()
+
+	// a profile set by the user in raw.lxc isn't taken for the field
+	o := &LXDObject{RawLXC: []string{"lxc.apparmor.profile = unconfined"}}
+	config := map[string]string{cfgAppArmorUnconfined: "true"}
+
+	makeRawLXC(config, o.RawLXC)
+	o.makeAppArmorConfig(config)
+	assert.NotContains(t, config, cfgAppArmorUnconfined)
+
+	unconfined, lines := parseAppArmorConfig(config, rawLXCLines(config))
+	assert.False(t, unconfined)
+	assert.Equal(t, o.RawLXC, lines)
+}
@@ -0,0 +1,43 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// cfgArchitecture records the architecture of a sandbox, as profiles have none
+const cfgArchitecture = "user.architecture"
+
+// normalizeArchitecture returns the name LXD uses for arch, so aliases like amd64 or arm64 can be used as well
+func normalizeArchitecture(arch string) (string, error) {
+	id, err := osarch.ArchitectureId(arch)
+	if err != nil {
+		return "", fmt.Errorf("%w: unknown architecture %s", ErrUsage, arch)
+	}
+
+	return osarch.ArchitectureName(id)
+}
+
+// validateArchitecture returns the normalized name of arch, or an error if the node can't run instances of it
+func (l *client) validateArchitecture(arch string) (string, error) {
+	name, err := normalizeArchitecture(arch)
+	if err != nil {
+		return "", err
+	}
+
+	server, _, err := l.GetServer().GetServer()
+	if err != nil {
+		return "", err
+	}
+
+	for _, a := range server.Environment.Architectures {
+		if a == name {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: architecture %s can't run on this node, supported are: %s", ErrUsage, name,
+		strings.Join(server.Environment.Architectures, ", "))
+}
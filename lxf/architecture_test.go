@@ -0,0 +1,82 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testArchitectureServer(archs ...string) *api.Server {
+	return &api.Server{
+		Environment: api.ServerEnvironment{
+			Architectures: archs,
+		},
+	}
+}
+
+func Test_normalizeArchitecture(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		arch    string
+		want    string
+		wantErr bool
+	}{
+		{"lxd name", "x86_64", "x86_64", false},
+		{"alias", "amd64", "x86_64", false},
+		{"arm alias", "arm64", "aarch64", false},
+		{"unknown", "z80", "", true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := normalizeArchitecture(tt.arch)
+			assert.False(t, (err != nil) != tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_validateArchitecture_Supported(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(testArchitectureServer("x86_64", "i686"), "", nil)
+
+	arch, err := client.validateArchitecture("i386")
+	assert.NoError(t, err)
+	assert.Equal(t, "i686", arch)
+}
+
+func TestClient_validateArchitecture_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(testArchitectureServer("x86_64", "i686"), "", nil)
+
+	_, err := client.validateArchitecture("arm64")
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Contains(t, err.Error(), "x86_64, i686")
+}
+
+func TestContainer_validateWithSandbox_UnknownArchitecture(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	c := &Container{}
+	c.ID = "foo"
+	c.client = client
+	c.Architecture = "pdp11"
+
+	err := c.validateWithSandbox(&Sandbox{})
+	assert.True(t, errors.Is(err, ErrUsage))
+	// whether the node supports it is only checked on create
+	assert.Equal(t, 0, fake.GetServerCallCount())
+}
@@ -0,0 +1,75 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	cfgBootAutostart         = "boot.autostart"
+	cfgBootAutostartPriority = "boot.autostart.priority"
+	cfgBootAutostartDelay    = "boot.autostart.delay"
+)
+
+// validateBoot checks priority and delay of the autostart are usable by LXD
+func (o *LXDObject) validateBoot() error {
+	if o.AutostartPriority < 0 {
+		return fmt.Errorf("%w: autostart priority must not be negative: %d", ErrUsage, o.AutostartPriority)
+	}
+
+	if o.AutostartDelay < 0 {
+		return fmt.Errorf("%w: autostart delay must not be negative: %d", ErrUsage, o.AutostartDelay)
+	}
+
+	return nil
+}
+
+// makeBootConfig writes the autostart fields to config. Unset fields are left out, so LXD or the profile of the
+// sandbox decide then
+func (o *LXDObject) makeBootConfig(config map[string]string) {
+	if o.Autostart != nil {
+		config[cfgBootAutostart] = strconv.FormatBool(*o.Autostart)
+	}
+
+	if o.AutostartPriority > 0 {
+		config[cfgBootAutostartPriority] = strconv.Itoa(o.AutostartPriority)
+	}
+
+	if o.AutostartDelay > 0 {
+		config[cfgBootAutostartDelay] = strconv.Itoa(o.AutostartDelay)
+	}
+}
+
+// parseBootConfig reads the autostart fields from config
+func (o *LXDObject) parseBootConfig(config map[string]string) error {
+	o.Autostart = nil
+	o.AutostartPriority = 0
+	o.AutostartDelay = 0
+
+	if v, has := config[cfgBootAutostart]; has {
+		autostart, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrParse, cfgBootAutostart, err)
+		}
+
+		o.Autostart = &autostart
+	}
+
+	var err error
+
+	if v, has := config[cfgBootAutostartPriority]; has {
+		o.AutostartPriority, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrParse, cfgBootAutostartPriority, err)
+		}
+	}
+
+	if v, has := config[cfgBootAutostartDelay]; has {
+		o.AutostartDelay, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrParse, cfgBootAutostartDelay, err)
+		}
+	}
+
+	return nil
+}
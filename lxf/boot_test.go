@@ -0,0 +1,71 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXDObject_validateBoot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		obj     LXDObject
+		wantErr bool
+	}{
+		{"unset", LXDObject{}, false},
+		{"valid", LXDObject{AutostartPriority: 10, AutostartDelay: 5}, false},
+		{"negative priority", LXDObject{AutostartPriority: -1}, true},
+		{"negative delay", LXDObject{AutostartDelay: -1}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.obj.validateBoot()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrUsage))
+		})
+	}
+}
+
+func TestLXDObject_makeBootConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	autostart := true
+	o := &LXDObject{Autostart: &autostart, AutostartPriority: 10, AutostartDelay: 5}
+	config := map[string]string{}
+
+	o.makeBootConfig(config)
+	assert.Equal(t, map[string]string{cfgBootAutostart: "true", cfgBootAutostartPriority: "10", cfgBootAutostartDelay: "5"}, config)
+
+	r := &LXDObject{}
+	err := r.parseBootConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeBootConfig_Unset(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	(&LXDObject{}).makeBootConfig(config)
+	assert.Empty(t, config)
+
+	r := &LXDObject{}
+	err := r.parseBootConfig(config)
+	assert.NoError(t, err)
+	assert.Nil(t, r.Autostart)
+}
+
+func TestLXDObject_parseBootConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	err := (&LXDObject{}).parseBootConfig(map[string]string{cfgBootAutostartPriority: "high"})
+	assert.True(t, errors.Is(err, ErrParse))
+}
@@ -0,0 +1,237 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automaticserver/lxe/shared"
+	"github.com/dionysius/errand"
+	"github.com/ghodss/yaml"
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// checkpointPrefix is the name prefix of the snapshots and backups a checkpoint is made of
+const checkpointPrefix = "lxe-checkpoint-"
+
+// isCRIUError returns true if err tells CRIU isn't available or failed, which LXD uses to save the runtime state
+func isCRIUError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "criu")
+}
+
+// Checkpoint saves the running container including its runtime state to a tarball at exportPath. It takes a stateful
+// snapshot and exports it as a backup of LXD, which carries the config, devices and profile names of the container.
+// The snapshot and backup are removed again after the export. CRIU is required to save the state, if it isn't
+// available ErrStatefulUnsupported is returned, which also matches ErrCheckpoint.
+func (l *client) Checkpoint(id, exportPath string) error {
+	err := l.checkStateful(ErrCheckpoint, id)
+	if err != nil {
+		return err
+	}
+
+	ct, _, err := l.GetServer().GetContainer(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return err
+	}
+
+	if ct.StatusCode != api.Running {
+		return fmt.Errorf("%w: container %v must be running to be checkpointed, but is %v", ErrUsage, id, ct.Status)
+	}
+
+	name := checkpointPrefix + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	err = l.opWait().CreateContainerSnapshot(id, name, true)
+	if err != nil {
+		if isCRIUError(err) {
+			return l.statefulError(ErrCheckpoint, id, err)
+		}
+
+		return err
+	}
+
+	defer func() {
+		err := l.opWait().DeleteContainerSnapshot(id, name)
+		if err != nil {
+			log.WithError(err).WithField("containerid", id).WithField("snapshot", name).Warn("unable to remove checkpoint snapshot")
+		}
+	}()
+
+	err = l.opWait().CreateContainerBackup(id, name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := l.opWait().DeleteContainerBackup(id, name)
+		if err != nil {
+			log.WithError(err).WithField("containerid", id).WithField("backup", name).Warn("unable to remove checkpoint backup")
+		}
+	}()
+
+	f, err := os.Create(exportPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.GetServer().GetContainerBackupFile(id, name, &lxd.BackupFileRequest{BackupFile: f})
+	if err != nil {
+		return errand.Append(err, f.Close(), os.Remove(exportPath))
+	}
+
+	return f.Close()
+}
+
+// RestoreCheckpoint imports the tarball at importPath written by Checkpoint and restores the runtime state of the
+// container, so it continues running where it was checkpointed. LXD creates the container with the name stored in the
+// tarball, which must be id. The tarball can be restored on another node, as long as the profiles of the container
+// (including its sandbox) exist there and CRIU is of a compatible version. The container must not exist yet and is
+// removed again if the restore fails after the import. Like Checkpoint, ErrStatefulUnsupported is returned if CRIU is
+// missing.
+func (l *client) RestoreCheckpoint(id, importPath string) error {
+	err := l.checkStateful(ErrCheckpoint, id)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(importPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name, err := backupName(f)
+	if err != nil {
+		return fmt.Errorf("%w: %v is no checkpoint: %v", ErrUsage, importPath, err)
+	}
+
+	if name != id {
+		return fmt.Errorf("%w: %v is a checkpoint of container %v, not %v", ErrUsage, importPath, name, id)
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	err = l.opWait().CreateContainerFromBackup(lxd.ContainerBackupArgs{BackupFile: f})
+	if err != nil {
+		return err
+	}
+
+	err = l.restoreImported(id, importPath)
+	if err != nil {
+		return errand.Append(err, l.opWait().DeleteContainer(id))
+	}
+
+	return nil
+}
+
+// restoreImported restores the latest checkpoint snapshot of the imported container id and removes the snapshot
+func (l *client) restoreImported(id, importPath string) error {
+	snapshots, err := l.GetServer().GetContainerSnapshotNames(id)
+	if err != nil {
+		return err
+	}
+
+	var checkpoints []string
+
+	for _, s := range snapshots {
+		// depending on the LXD version the names may be given as their urls
+		s = s[strings.LastIndex(s, "/")+1:]
+		if strings.HasPrefix(s, checkpointPrefix) {
+			checkpoints = append(checkpoints, s)
+		}
+	}
+
+	if len(checkpoints) == 0 {
+		return fmt.Errorf("%w: %v contains no checkpoint of container %v", ErrUsage, importPath, id)
+	}
+
+	// the names only differ by time and have the same length, so the last one is the latest
+	sort.Strings(checkpoints)
+	name := checkpoints[len(checkpoints)-1]
+
+	err = l.opWait().UpdateContainer(id, api.ContainerPut{Restore: name, Stateful: true}, "")
+	if err != nil {
+		if isCRIUError(err) {
+			return l.statefulError(ErrCheckpoint, id, err)
+		}
+
+		return err
+	}
+
+	return l.opWait().DeleteContainerSnapshot(id, name)
+}
+
+// backupIndex is the part of the index of an LXD backup tarball that is needed to restore it
+type backupIndex struct {
+	Name string `json:"name"`
+}
+
+// backupName returns the container name stored in the index of the backup tarball r, which is compressed with gzip,
+// the default of LXD, or not at all
+func backupName(r io.Reader) (string, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err != nil {
+		return "", err
+	}
+
+	var tr *tar.Reader
+
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return "", err
+		}
+		defer gr.Close()
+
+		tr = tar.NewReader(gr)
+	} else {
+		tr = tar.NewReader(br)
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("%w: backup/index.yaml is missing", ErrParse)
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if path.Clean(hdr.Name) != "backup/index.yaml" {
+			continue
+		}
+
+		raw, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return "", err
+		}
+
+		index := &backupIndex{}
+
+		err = yaml.Unmarshal(raw, index)
+		if err != nil {
+			return "", err
+		}
+
+		return index.Name, nil
+	}
+}
@@ -0,0 +1,218 @@
+package lxf
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCheckpointDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "lxe-checkpoint")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+// testCheckpointTarball writes a backup tarball of container name like LXD exports it and returns its path
+func testCheckpointTarball(t *testing.T, name string) string {
+	importPath := filepath.Join(testCheckpointDir(t), name+".tar.gz")
+
+	f, err := os.Create(importPath)
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	index := []byte("name: " + name + "\nbackend: dir\npool: default\n")
+
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "backup/index.yaml", Mode: 0644, Size: int64(len(index))}))
+	_, err = tw.Write(index)
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+
+	return importPath
+}
+
+func testCheckpointClient(status api.StatusCode) (*client, *lxdfakes.FakeContainerServer, *lxdfakes.FakeOperation) {
+	client, fake := testClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	ct := basicContainer("foo", "default")
+	ct.StatusCode = status
+
+	fake.GetContainerReturns(ct, "", nil)
+	fake.CreateContainerSnapshotReturns(fakeOp, nil)
+	fake.DeleteContainerSnapshotReturns(fakeOp, nil)
+	fake.CreateContainerBackupReturns(fakeOp, nil)
+	fake.DeleteContainerBackupReturns(fakeOp, nil)
+	fake.CreateContainerFromBackupReturns(fakeOp, nil)
+	fake.UpdateContainerReturns(fakeOp, nil)
+	fake.DeleteContainerReturns(fakeOp, nil)
+	fake.GetContainerBackupFileStub = func(_, _ string, req *lxd.BackupFileRequest) (*lxd.BackupFileResponse, error) {
+		_, err := req.BackupFile.Write([]byte("tarball"))
+		return &lxd.BackupFileResponse{Size: 7}, err
+	}
+
+	return client, fake, fakeOp
+}
+
+func TestClient_Checkpoint(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Running)
+	exportPath := filepath.Join(testCheckpointDir(t), "foo.tar.gz")
+
+	err := client.Checkpoint("foo", exportPath)
+	assert.NoError(t, err)
+
+	_, post := fake.CreateContainerSnapshotArgsForCall(0)
+	assert.True(t, post.Stateful)
+	assert.Contains(t, post.Name, checkpointPrefix)
+
+	_, backup := fake.CreateContainerBackupArgsForCall(0)
+	assert.Equal(t, post.Name, backup.Name)
+	assert.False(t, backup.ContainerOnly)
+
+	content, err := ioutil.ReadFile(exportPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "tarball", string(content))
+
+	// nothing is left behind
+	assert.Equal(t, 1, fake.DeleteContainerBackupCallCount())
+	assert.Equal(t, 1, fake.DeleteContainerSnapshotCallCount())
+}
+
+func TestClient_Checkpoint_Stopped(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+
+	err := client.Checkpoint("foo", filepath.Join(testCheckpointDir(t), "foo.tar.gz"))
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerSnapshotCallCount())
+}
+
+func TestClient_Checkpoint_WithoutCRIU(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeOp := testCheckpointClient(api.Running)
+	fakeOp.WaitReturns(errors.New("Unable to create a stateful snapshot. CRIU isn't installed"))
+
+	err := client.Checkpoint("foo", filepath.Join(testCheckpointDir(t), "foo.tar.gz"))
+	assert.True(t, errors.Is(err, ErrCheckpoint))
+	assert.Equal(t, 0, fake.CreateContainerBackupCallCount())
+}
+
+func TestClient_Checkpoint_ExportFailed(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Running)
+	fake.GetContainerBackupFileStub = nil
+	fake.GetContainerBackupFileReturns(nil, errors.New("connection lost"))
+
+	exportPath := filepath.Join(testCheckpointDir(t), "foo.tar.gz")
+
+	err := client.Checkpoint("foo", exportPath)
+	assert.Error(t, err)
+
+	_, err = os.Stat(exportPath)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, 1, fake.DeleteContainerBackupCallCount())
+	assert.Equal(t, 1, fake.DeleteContainerSnapshotCallCount())
+}
+
+func TestClient_RestoreCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+	fake.GetContainerSnapshotNamesReturns([]string{
+		"/1.0/containers/foo/snapshots/snap0",
+		"/1.0/containers/foo/snapshots/" + checkpointPrefix + "1600000000000000000",
+		"/1.0/containers/foo/snapshots/" + checkpointPrefix + "1600000000000000001",
+	}, nil)
+
+	err := client.RestoreCheckpoint("foo", testCheckpointTarball(t, "foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.CreateContainerFromBackupCallCount())
+
+	id, put, _ := fake.UpdateContainerArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, checkpointPrefix+"1600000000000000001", put.Restore)
+	assert.True(t, put.Stateful)
+
+	_, name := fake.DeleteContainerSnapshotArgsForCall(0)
+	assert.Equal(t, put.Restore, name)
+}
+
+func TestClient_RestoreCheckpoint_NoCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+	fake.GetContainerSnapshotNamesReturns([]string{"/1.0/containers/foo/snapshots/snap0"}, nil)
+
+	err := client.RestoreCheckpoint("foo", testCheckpointTarball(t, "foo"))
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+
+	// the imported container is removed again
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+	assert.Equal(t, "foo", fake.DeleteContainerArgsForCall(0))
+}
+
+func TestClient_RestoreCheckpoint_RestoreFailed(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+	fake.GetContainerSnapshotNamesReturns([]string{"/1.0/containers/foo/snapshots/" + checkpointPrefix + "1600000000000000000"}, nil)
+	fake.UpdateContainerReturns(nil, errors.New("connection lost"))
+
+	err := client.RestoreCheckpoint("foo", testCheckpointTarball(t, "foo"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+}
+
+func TestClient_RestoreCheckpoint_OtherContainer(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+
+	// LXD would create the container bar instead
+	err := client.RestoreCheckpoint("foo", testCheckpointTarball(t, "bar"))
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerFromBackupCallCount())
+}
+
+func TestClient_RestoreCheckpoint_NoBackup(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+
+	importPath := filepath.Join(testCheckpointDir(t), "foo.tar.gz")
+	assert.NoError(t, ioutil.WriteFile(importPath, []byte("tarball"), 0600))
+
+	err := client.RestoreCheckpoint("foo", importPath)
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerFromBackupCallCount())
+}
+
+func TestClient_RestoreCheckpoint_Missing(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _ := testCheckpointClient(api.Stopped)
+
+	err := client.RestoreCheckpoint("foo", filepath.Join(testCheckpointDir(t), "missing.tar.gz"))
+	assert.Error(t, err)
+	assert.Equal(t, 0, fake.CreateContainerFromBackupCallCount())
+}
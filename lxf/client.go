@@ -5,13 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"path"
+	"sync"
 	"time"
 
+	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/lxf/lxo"
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/fsnotify.v1"
 	"k8s.io/client-go/tools/remotecommand"
@@ -19,9 +23,19 @@ import (
 
 var (
 	ErrMissingETag = errors.New("missing ETag")
-	ErrConvert     = errors.New("convert error")
-	ErrParse       = errors.New("parse error")
-	ErrUsage       = errors.New("usage error")
+	// ErrETagConflict is returned when saving an object whose ETag is outdated, it must be fetched again
+	ErrETagConflict = errors.New("ETag conflict")
+	ErrConvert      = errors.New("convert error")
+	ErrParse        = errors.New("parse error")
+	ErrUsage        = errors.New("usage error")
+	ErrExists       = errors.New("already exists")
+	// ErrLiveMigration is returned when a running container can't be migrated, usually as CRIU isn't available
+	ErrLiveMigration = errors.New("live migration failed")
+	// ErrCheckpoint is returned when the runtime state of a container can't be saved or restored, usually as CRIU
+	// isn't available
+	ErrCheckpoint = errors.New("checkpoint failed")
+	// ErrNotHotpluggable is returned when a device can't be added to or removed from a running container
+	ErrNotHotpluggable = errors.New("not hotpluggable")
 )
 
 // Client is a facade to thin the interface to map the cri logic to lxd.
@@ -34,15 +48,25 @@ type Client interface {
 	GetRuntimeInfo() (*RuntimeInfo, error)
 	// SetEventHandler for container's starting and stopping events
 	SetEventHandler(eh EventHandler)
+	// SetIDGenerator replaces the generator of ids for new sandboxes and containers
+	SetIDGenerator(gen IDGenerator)
+	// SupportsStatefulOps tells whether LXD can save and restore the runtime state of containers
+	SupportsStatefulOps() (bool, error)
 
-	// PullImage copies the given image from the remote server
-	PullImage(name string) (string, error)
+	// PullImage copies the given image from the remote server and returns the info of the local copy
+	PullImage(name string) (*ImageInfo, error)
 	// RemoveImage will remove the given image
 	RemoveImage(name string) error
 	// ListImages will list all local images from the lxd server
 	ListImages(filter string) ([]Image, error)
 	// GetImage will fetch information about the already downloaded image identified by name
 	GetImage(name string) (*Image, error)
+	// ImageStatus returns the info of the already downloaded image identified by ref, a not found if it isn't present
+	ImageStatus(ref string) (*ImageInfo, error)
+	// SetImagePolicy changes whether pulled images are auto updated and how long LXD caches them
+	SetImagePolicy(policy ImagePolicy) error
+	// PublishAsImage creates an image from the container, points alias to it and returns its fingerprint
+	PublishAsImage(id, alias string, stop bool) (string, error)
 	// GetFSPoolUsage returns a list of usage information about the used storage pools
 	GetFSPoolUsage() ([]FSPoolUsage, error)
 
@@ -59,10 +83,47 @@ type Client interface {
 	GetContainer(id string) (*Container, error)
 	// ListContainers returns a list of all available containers
 	ListContainers() ([]*Container, error)
+	// RenameContainer renames the stopped container oldID to newName and returns the new id
+	RenameContainer(oldID, newName string) (string, error)
+	// MoveContainer moves the container to another member of the LXD cluster
+	MoveContainer(id, targetMember string, live bool) error
+	// WaitRunning blocks until the container is running and returns the pid of its init process
+	WaitRunning(ctx context.Context, id string) (int64, error)
+	// GetDevices returns the devices the container currently has grouped by type
+	GetDevices(id string) (*device.Collection, error)
+	// HotplugDevice adds a single device to the container without restarting it
+	HotplugDevice(id, name string, dev map[string]string) error
+	// AttachConfigDisk writes the files of disk to the host and attaches them to the container, or updates them in place
+	AttachConfigDisk(id, sandboxID string, disk *ConfigDisk) error
+	// HotunplugDevice removes a single device from the container without restarting it
+	HotunplugDevice(id, name string) error
+	// ReconcileProxies makes the proxy devices of the sandbox match desired, adding, replacing and removing them
+	ReconcileProxies(sandboxID string, desired device.Devices) error
+	// Reconcile makes the devices and config of the live container match desired
+	Reconcile(desired *LXDObject) error
+	// Checkpoint saves the running container including its runtime state to a tarball at exportPath
+	Checkpoint(id, exportPath string) error
+	// RestoreCheckpoint imports the container id from a tarball written by Checkpoint and restores its runtime state
+	RestoreCheckpoint(id, importPath string) error
+
+	// ReclaimLease removes the lease of ip in bridge, if sb was assigned ip and no instance is associated with it anymore
+	ReclaimLease(sb *Sandbox, bridge string, ip net.IP) error
+	// BridgeUtilization returns how many IPv4 addresses bridge can hand out and how many of them are leased
+	BridgeUtilization(bridge string) (total, used int, err error)
+	// ContainersOnBridge lists the nics of the instances in bridge with their addresses
+	ContainersOnBridge(bridge string) ([]BridgeMember, error)
 
 	// Exec will start a command on the server and attach the provided streams. It will block till the command terminated
 	// AND all data was written to stdout/stdin. The caller is responsible to provide a sink which doesn't block.
 	Exec(cid string, cmd []string, stdin io.ReadCloser, stdout, stderr io.WriteCloser, interactive, tty bool, timeout int64, resize <-chan remotecommand.TerminalSize) (int32, error)
+	// RunOnce creates an instance from image with the devices and config of obj, runs cmd in it to completion and
+	// removes the instance again. It returns the output and exit code of cmd
+	RunOnce(obj *LXDObject, image string, cmd []string) (stdout, stderr []byte, exitCode int, err error)
+	// AttachConsole attaches the provided streams to the console of the container. It blocks till stdin, if given, is
+	// closed or the console is closed by LXD.
+	AttachConsole(id string, stdin io.Reader, stdout io.Writer, resize <-chan remotecommand.TerminalSize) error
+	// GetConsoleLog returns the last maxBytes of the console log of the container
+	GetConsoleLog(id string, maxBytes int) ([]byte, error)
 }
 
 var (
@@ -71,23 +132,52 @@ var (
 )
 
 type client struct {
+	// mu guards the connection, which is server, opwait, listener and eventsDone. Use GetServer and opWait to access it
+	mu           sync.RWMutex
 	server       lxd.ContainerServer
 	config       *config.Config
 	opwait       *lxo.LXO
 	eventHandler EventHandler
-	socket       string
+	conn         Config
+	idGenerator  IDGenerator
+	// project all requests are scoped to, the default project of LXD if empty
+	project string
+	// imagePolicy applied when pulling images
+	imagePolicy ImagePolicy
+	// dial replaces how the connection to LXD is opened, only used in tests
+	dial func(httpClient *http.Client) (lxd.ContainerServer, error)
+	// listen replaces how the event listener of a connection is opened, only used in tests
+	listen func(server lxd.ContainerServer) (eventListener, error)
+	// listener receives the lifecycle events of the current connection
+	listener eventListener
+	// reconnecting serializes replacing the connection
+	reconnecting sync.Mutex
+	// eventsDone is closed once the event listener of the current connection got disconnected
+	eventsDone chan struct{}
+	// criuMissingAt is when a stateful operation last failed as CRIU is missing, see SupportsStatefulOps
+	statefulMu    sync.Mutex
+	criuMissingAt time.Time
 }
 
-// NewClient will set up a connection and return the client
-func NewClient(socket string, configPath string) (Client, error) {
-	config, err := config.LoadConfig(configPath)
+// NewClient validates cfg, sets up a connection and returns the client. All instances, profiles, images and networks
+// are managed within the project of cfg.
+func NewClient(cfg Config) (Client, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := config.LoadConfig(cfg.ConfigPath)
 	if err != nil {
 		return nil, err
 	}
 
 	cl := &client{
-		config: config,
-		socket: socket,
+		config:  config,
+		conn:    cfg,
+		project: cfg.Project,
+		// LXE always let LXD update pulled images until the policy was configurable
+		imagePolicy: ImagePolicy{AutoUpdate: true},
 	}
 
 	err = cl.connect()
@@ -95,7 +185,17 @@ func NewClient(socket string, configPath string) (Client, error) {
 		return nil, err
 	}
 
-	go cl.detectNeedReconnect()
+	// only a known socket file can be watched, neither a remote nor a socket the LXD client finds by itself
+	if !cfg.IsRemote() && cfg.Socket != "" {
+		watcher, err := watchSocket(cfg.Socket)
+		if err != nil {
+			return nil, err
+		}
+
+		go cl.detectNeedReconnect(watcher)
+	}
+
+	go cl.keepAlive(KeepAliveInterval)
 
 	return cl, nil
 }
@@ -104,9 +204,20 @@ func NewClient(socket string, configPath string) (Client, error) {
 // network plugin) either return it here, or extract creation of the connection outside and pass server into
 // NewClient(), but that makes the initialisation NewClient() pretty unnecessary
 func (l *client) GetServer() lxd.ContainerServer {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	return l.server
 }
 
+// opWait returns the client of the current connection which waits for operations
+func (l *client) opWait() *lxo.LXO {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.opwait
+}
+
 // SetEventHandler for container's starting and stopping events
 func (l *client) SetEventHandler(eh EventHandler) {
 	l.eventHandler = eh
@@ -119,7 +230,14 @@ type RuntimeInfo struct {
 
 // GetRuntimeInfo returns informations about the runtime
 func (l *client) GetRuntimeInfo() (*RuntimeInfo, error) {
-	server, _, err := l.server.GetServer()
+	var server *api.Server
+
+	err := l.withReconnect(func(s lxd.ContainerServer) error {
+		var err error
+		server, _, err = s.GetServer()
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -131,77 +249,53 @@ func (l *client) GetRuntimeInfo() (*RuntimeInfo, error) {
 }
 
 func (l *client) connect() error {
-	args := lxd.ConnectionArgs{
-		HTTPClient: &http.Client{
-			// this was a byproduct of a bughunt, but i figured using TCP connections with TLS instead of unix sockets
-			// might by useful some time in the future so i will leave this here commented out
-			// to setup this up in LXD see: https://help.ubuntu.com/lts/serverguide/lxd.html.en#lxd-server-config
-
-			// tlsServerCert, err := ioutil.ReadFile("/root/.config/lxc/servercerts/r1.crt")
-			// if err != nil {
-			// 	panic(err)
-			// }
-			// tlsClientCert, err := ioutil.ReadFile("/root/.config/lxc/client.crt")
-			// if err != nil {
-			// 	panic(err)
-			// }
-			// tlsClientKey, err := ioutil.ReadFile("/root/.config/lxc/client.key")
-			// if err != nil {
-			// 	panic(err)
-			// }
-
-			// server, err := lxd.ConnectLXD("https://127.0.0.1:8443", &lxd.ConnectionArgs{
-			// 	TLSServerCert:      string(tlsServerCert),
-			// 	TLSClientCert:      string(tlsClientCert),
-			// 	TLSClientKey:       string(tlsClientKey),
-			// 	InsecureSkipVerify: true,
-			// })
-			// -------------------------------------------
-			// it was discovered when using a container with "hostnetwork: true" LXE
-			// would leak filehandles indefinitely until the process hits the system limit and
-			// LXE would stop working since no new connections could be opened.
-			// This happens for unix sockets as well as for tcp/tls connections.
-
-			// this issue could be observed by
-			// a) lsof -n -p $(pidof lxe)     yielding more and more connections
-			// b) pkill -SIGABRT lxe          seeing many many goroutines like this:
-			// net/http.(*persistConn).readLoop(0xc000273b00)
-			// 	/home/dj/src/go/src/net/http/transport.go:1761 +0x6b9
-			// and
-			// net/http.(*persistConn).writeLoop(0xc0002c25a0)
-			// 	/home/dj/src/go/src/net/http/transport.go:1885 +0x113
-			// without any stacktrace/callstack.
-
-			// online search will lead to some golang issues at github which most of are marked as fixed
-			// as well as the solution to "defer resp.Body.Close()" which is done by the LXD client api.
-			// other measures like "_, err = io.Copy(ioutil.Discard, resp.Body)" were tried as well.
-			// (see https://hackernoon.com/avoiding-memory-leak-in-golang-api-1843ef45fca8 e.g.)
-
-			// the chain to track this is:
-			//    call lxd.ConnectLXDUnix (lxf/client.go)
-			// -> unixHttpClient (lxd/client/connection.go)
-			//    >> here we force the httpClient to have a Timeout <<
-			// -> unixHttpClient (lxd/client/util.go) setups a DialUnix inside of a Transport inside of the HttpClient
-
-			// the HttpClient tries to reuse already opened connections (this is done by golangs core library
-			// and is rather transparent for the caller) which does not seem to happen in this special case.
-			// this commit forces a timeout on the httpClient used by the LXE (via the LXD client API) to talk to LXD.
-
-			// this does not fix the real problem, which can be either in LXE, LXD client API or the LXD server
-			// and still needs more investigation.
-			// since sharing the networknamespace between host and container via "lxc.raw = lxc.net.0.type=none"
-			// is neither officially supported nor encouraged, filing a bugreport against LXD is rather pointless.
-			Timeout: lxdHTTPTimeout,
-		},
+	httpClient := &http.Client{
+		// it was discovered when using a container with "hostnetwork: true" LXE
+		// would leak filehandles indefinitely until the process hits the system limit and
+		// LXE would stop working since no new connections could be opened.
+		// This happens for unix sockets as well as for tcp/tls connections.
+
+		// this issue could be observed by
+		// a) lsof -n -p $(pidof lxe)     yielding more and more connections
+		// b) pkill -SIGABRT lxe          seeing many many goroutines like this:
+		// net/http.(*persistConn).readLoop(0xc000273b00)
+		// 	/home/dj/src/go/src/net/http/transport.go:1761 +0x6b9
+		// and
+		// net/http.(*persistConn).writeLoop(0xc0002c25a0)
+		// 	/home/dj/src/go/src/net/http/transport.go:1885 +0x113
+		// without any stacktrace/callstack.
+
+		// online search will lead to some golang issues at github which most of are marked as fixed
+		// as well as the solution to "defer resp.Body.Close()" which is done by the LXD client api.
+		// other measures like "_, err = io.Copy(ioutil.Discard, resp.Body)" were tried as well.
+		// (see https://hackernoon.com/avoiding-memory-leak-in-golang-api-1843ef45fca8 e.g.)
+
+		// the chain to track this is:
+		//    call lxd.ConnectLXDUnix (lxf/client.go)
+		// -> unixHttpClient (lxd/client/connection.go)
+		//    >> here we force the httpClient to have a Timeout <<
+		// -> unixHttpClient (lxd/client/util.go) setups a DialUnix inside of a Transport inside of the HttpClient
+
+		// the HttpClient tries to reuse already opened connections (this is done by golangs core library
+		// and is rather transparent for the caller) which does not seem to happen in this special case.
+		// this commit forces a timeout on the httpClient used by the LXE (via the LXD client API) to talk to LXD.
+
+		// this does not fix the real problem, which can be either in LXE, LXD client API or the LXD server
+		// and still needs more investigation.
+		// since sharing the networknamespace between host and container via "lxc.raw = lxc.net.0.type=none"
+		// is neither officially supported nor encouraged, filing a bugreport against LXD is rather pointless.
+		Timeout: lxdHTTPTimeout,
 	}
 
-	server, err := lxd.ConnectLXDUnix(l.socket, &args)
+	unscoped, err := l.dialServer(httpClient)
 	if err != nil {
 		return err
 	}
 
+	server := l.scope(unscoped)
+
 	// register LXD eventhandler
-	listener, err := server.GetEvents()
+	listener, err := l.getEvents(server)
 	if err != nil {
 		return err
 	}
@@ -211,27 +305,60 @@ func (l *client) connect() error {
 		return err
 	}
 
+	eventsDone := make(chan struct{})
+	go watchEvents(listener, eventsDone)
+
+	l.mu.Lock()
+	stale := l.listener
+	l.listener = listener
+	l.eventsDone = eventsDone
 	l.server = server
 	l.opwait = lxo.NewClient(server)
+	l.mu.Unlock()
+
+	// the listener of the replaced connection might still be connected, e.g. if only an api call failed or the socket
+	// got recreated, and would handle every event a second time
+	if stale != nil {
+		disconnectEvents(stale)
+	}
 
 	return nil
 }
 
-// detect if server needs to be connected again to. Seems to be needed if we get a lxd.RemoteOperation (e.g. in CopyImage), the op.Wait() never succeeds unless we have connected to the lxd socket again. All other lxd.Operations seem to work fine and wouldn't be needed for them.
-func (l *client) detectNeedReconnect() { // nolint: gocognit
-	// currently I know no way to find out when a socket is gone as all is encapsulated in lxd.ContainerServer. We can set an fsnotify to the socket file so we get an event when it was created. If we got such event, we try to connect again until it is successful.
+// scope returns the server which operates within the project of the client. This includes the events, so only
+// lifecycle events of the own project are handled.
+func (l *client) scope(server lxd.ContainerServer) lxd.ContainerServer {
+	if l.project == "" {
+		return server
+	}
+
+	return server.UseProject(l.project)
+}
+
+// watchSocket returns a watcher for the directory of the socket. Currently I know no way to find out when a socket is
+// gone as all is encapsulated in lxd.ContainerServer. We can set an fsnotify to the socket file so we get an event when
+// it was created.
+func watchSocket(socket string) (*fsnotify.Watcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Fatal(err.Error())
+		return nil, err
 	}
-	defer watcher.Close()
 
-	err = watcher.Add(path.Dir(l.socket))
+	err = watcher.Add(path.Dir(socket))
 	if err != nil {
-		log.Fatal(err.Error())
+		watcher.Close()
+		return nil, err
 	}
 
-	log := log.WithField("lxdsocket", l.socket)
+	return watcher, nil
+}
+
+// detect if server needs to be connected again to. Seems to be needed if we get a lxd.RemoteOperation (e.g. in CopyImage), the op.Wait() never succeeds unless we have connected to the lxd socket again. All other lxd.Operations seem to work fine and wouldn't be needed for them.
+func (l *client) detectNeedReconnect(watcher *fsnotify.Watcher) { // nolint: gocognit
+	// if we got an event of the socket file being created, we try to connect again until it is successful.
+	defer watcher.Close()
+
+	log := log.WithField("lxdsocket", l.conn.Socket)
 
 	for {
 		select {
@@ -240,12 +367,14 @@ func (l *client) detectNeedReconnect() { // nolint: gocognit
 				return
 			}
 
-			if event.Op&fsnotify.Create == fsnotify.Create && event.Name == l.socket {
+			if event.Op&fsnotify.Create == fsnotify.Create && event.Name == l.conn.Socket {
 				log.Info("lxd socket got created, trying to reconnect")
 
+				stale := l.GetServer()
+
 				go func() {
 					for {
-						err := l.connect()
+						err := l.reconnect(stale)
 						if err != nil {
 							// print error and try again
 							log.WithError(err).Error("failed reconnecting to lxd socket")
@@ -258,7 +387,7 @@ func (l *client) detectNeedReconnect() { // nolint: gocognit
 				}()
 			}
 
-			if event.Op&fsnotify.Remove == fsnotify.Remove && event.Name == l.socket {
+			if event.Op&fsnotify.Remove == fsnotify.Remove && event.Name == l.conn.Socket {
 				log.Warn("lxd socket got deleted, will try to reconnect once it's created again")
 			}
 		case err, ok := <-watcher.Errors:
@@ -266,7 +395,8 @@ func (l *client) detectNeedReconnect() { // nolint: gocognit
 				return
 			}
 
-			log.WithError(err).Fatal("watcher error")
+			// the socket isn't watched reliably anymore, but the connection itself might still be fine
+			log.WithError(err).Error("watcher error")
 		}
 	}
 }
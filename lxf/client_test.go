@@ -2,6 +2,9 @@ package lxf
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/automaticserver/lxe/lxf/lxdfakes"
@@ -44,6 +47,51 @@ func TestClient_GetRuntimeInfo_Error(t *testing.T) {
 	assert.Equal(t, 1, fake.GetServerCallCount())
 }
 
+func TestClient_scope_DefaultProject(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	assert.Same(t, fake, client.scope(fake))
+	assert.Equal(t, 0, fake.UseProjectCallCount())
+}
+
+func TestClient_scope_Project(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	client.project = "k8s"
+
+	scoped := &lxdfakes.FakeContainerServer{}
+	fake.UseProjectReturns(scoped)
+
+	assert.Same(t, scoped, client.scope(fake))
+	assert.Equal(t, "k8s", fake.UseProjectArgsForCall(0))
+}
+
+func TestWatchSocket_Ok(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "lxe-socket")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	watcher, err := watchSocket(filepath.Join(dir, "unix.socket"))
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Close())
+}
+
+func TestWatchSocket_MissingDir(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "lxe-socket")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	_, err = watchSocket(filepath.Join(dir, "missing", "unix.socket"))
+	assert.Error(t, err)
+}
+
 // func TestConnection(t *testing.T) {
 // 	_, err := lxf.NewClient("", os.Getenv("HOME")+"/.config/lxc/config.yml")
 // 	if err != nil {
@@ -0,0 +1,256 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/automaticserver/lxe/lxf/device"
+)
+
+const (
+	// DefaultConfigDiskDir is where config disks are assembled if the client got no ConfigDiskDir
+	DefaultConfigDiskDir = "/var/lib/lxe/configdisks"
+	// configDiskPrefix names the disk devices of config disks
+	configDiskPrefix = "configdisk-"
+	// configDiskDirMode is the mode of the directories of a config disk, they only hold what the files allow to read
+	configDiskDirMode = 0755
+	// configFileMode and secretFileMode are the modes of files which have none set
+	configFileMode = 0644
+	secretFileMode = 0640
+	// worldAccess are the permission bits of others, which secret files never get
+	worldAccess = 0007
+)
+
+// ConfigFile is a file of a config disk
+type ConfigFile struct {
+	Content []byte
+	// Mode are the permissions of the file, 0644 if zero or 0640 for a secret. Only permission bits are used
+	Mode os.FileMode
+	// Secret files are never accessible by others, whatever their Mode says
+	Secret bool
+}
+
+// ConfigDisk is a set of files LXE writes to a directory of the host and attaches to a container as read only disk,
+// like a projected, configMap or secret volume of kubernetes
+type ConfigDisk struct {
+	// Name identifies the disk within its sandbox
+	Name string
+	// Path the disk is mounted at in the container
+	Path string
+	// Files maps the path of each file relative to the disk to its content
+	Files map[string]ConfigFile
+}
+
+// validate checks the disk has a name usable as directory and each file stays within the disk
+func (d *ConfigDisk) validate() error {
+	if d.Name == "" || strings.ContainsAny(d.Name, "/\x00") || d.Name == "." || d.Name == ".." {
+		return fmt.Errorf("%w: config disk name must be a single path element: %q", ErrUsage, d.Name)
+	}
+
+	if !path.IsAbs(d.Path) {
+		return fmt.Errorf("%w: config disk %v must be mounted at an absolute path: %q", ErrUsage, d.Name, d.Path)
+	}
+
+	for name := range d.Files {
+		clean := path.Clean(name)
+		if path.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%w: file %q of config disk %v is outside of the disk", ErrUsage, name, d.Name)
+		}
+	}
+
+	return nil
+}
+
+// mode returns the permissions f is written with
+func (f ConfigFile) mode() os.FileMode {
+	mode := f.Mode.Perm()
+
+	switch {
+	case mode == 0 && f.Secret:
+		return secretFileMode
+	case mode == 0:
+		return configFileMode
+	case f.Secret:
+		return mode &^ worldAccess
+	}
+
+	return mode
+}
+
+// configDiskDir returns the directory of the config disks of sandbox sandboxID
+func (l *client) configDiskDir(sandboxID string) string {
+	dir := l.conn.ConfigDiskDir
+	if dir == "" {
+		dir = DefaultConfigDiskDir
+	}
+
+	return filepath.Join(dir, sandboxID)
+}
+
+// AttachConfigDisk writes the files of disk to a directory of the sandbox sandboxID and attaches it to the container
+// id, if not attached yet. Calling it again with changed files updates the disk in place: each file is replaced
+// atomically and files not listed anymore are removed, so the container sees the update without being restarted. The
+// directory is removed with the sandbox. As the files are written on the host, a local LXD is required
+func (l *client) AttachConfigDisk(id, sandboxID string, disk *ConfigDisk) error {
+	if l.conn.IsRemote() {
+		return fmt.Errorf("%w: config disks require a local LXD", ErrUsage)
+	}
+
+	err := disk.validate()
+	if err != nil {
+		return err
+	}
+
+	uid, gid, err := l.configDiskOwner(id)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(l.configDiskDir(sandboxID), disk.Name)
+
+	err = writeConfigDisk(dir, disk.Files, uid, gid)
+	if err != nil {
+		return fmt.Errorf("write config disk %v: %w", disk.Name, err)
+	}
+
+	name, options := (&device.Disk{
+		KeyName:  configDiskPrefix + disk.Name,
+		Path:     disk.Path,
+		Source:   dir,
+		Readonly: true,
+	}).ToMap()
+
+	return l.HotplugDevice(id, name, options)
+}
+
+// configDiskOwner returns the host uid and gid the files of a config disk of container id are owned by. The disk isn't
+// shifted, so the files are owned by the root of the container as mapped to the host and by its FSGroup if set.
+// Otherwise an unprivileged container couldn't read files which aren't accessible by others, like secrets
+func (l *client) configDiskOwner(id string) (int, int, error) {
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	base, err := c.idmapBase()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	gid := base
+	if c.FSGroup != nil {
+		gid += *c.FSGroup
+	}
+
+	return int(base), int(gid), nil
+}
+
+// writeConfigDisk makes dir contain exactly files owned by uid and gid. Each file is written to a temporary file next
+// to it first and then renamed, so a reader never sees a partially written one
+func writeConfigDisk(dir string, files map[string]ConfigFile, uid, gid int) error {
+	err := os.MkdirAll(dir, configDiskDirMode)
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]bool, len(files))
+
+	for name, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(path.Clean(name)))
+		want[target] = true
+
+		err = os.MkdirAll(filepath.Dir(target), configDiskDirMode)
+		if err != nil {
+			return err
+		}
+
+		err = writeFileAtomic(target, f.Content, f.mode(), uid, gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return pruneConfigDisk(dir, want)
+}
+
+// writeFileAtomic replaces target with a file of content and mode owned by uid and gid
+func writeFileAtomic(target string, content []byte, mode os.FileMode, uid, gid int) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(target), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// a privileged container without FSGroup uses the ids of the host, LXE created the file as root already
+	if uid != 0 || gid != 0 {
+		err = chownPath(tmp.Name(), uid, gid)
+	}
+
+	// the temp file is created with 0600, restrict it before any content is written
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+
+	if err == nil {
+		_, err = tmp.Write(content)
+	}
+
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
+
+// pruneConfigDisk removes the files within dir which aren't wanted anymore and the directories left empty by that
+func pruneConfigDisk(dir string, want map[string]bool) error {
+	var dirs []string
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case p == dir:
+		case info.IsDir():
+			dirs = append(dirs, p)
+		case !want[p]:
+			return os.Remove(p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// walked in lexical order, so nested directories come after their parents
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if entries, err := ioutil.ReadDir(dirs[i]); err == nil && len(entries) == 0 {
+			err = os.Remove(dirs[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeConfigDisks removes the config disks of sandbox sandboxID from the host
+func (l *client) removeConfigDisks(sandboxID string) error {
+	if l.conn.IsRemote() {
+		return nil
+	}
+
+	return os.RemoveAll(l.configDiskDir(sandboxID))
+}
@@ -0,0 +1,257 @@
+package lxf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfigDiskClient(t *testing.T) (*client, *lxdfakes.FakeContainerServer, string) {
+	client, fake := testClient()
+
+	dir, err := ioutil.TempDir("", "configdisk")
+	assert.NoError(t, err)
+
+	client.conn.ConfigDiskDir = dir
+
+	// a privileged container uses the ids of the host, so the files aren't chowned
+	ct := basicContainer("foo", "sandbox")
+	ct.Config[cfgSecurityPrivileged] = "true"
+
+	fake.GetContainerReturns(ct, "etag", nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	return client, fake, dir
+}
+
+func readConfigFile(t *testing.T, name string) (string, os.FileMode) {
+	content, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+
+	return string(content), info.Mode().Perm()
+}
+
+func TestClient_AttachConfigDisk(t *testing.T) {
+	t.Parallel()
+
+	client, fake, dir := testConfigDiskClient(t)
+	defer os.RemoveAll(dir)
+
+	err := client.AttachConfigDisk("foo", "sandbox", &ConfigDisk{
+		Name: "config",
+		Path: "/etc/app",
+		Files: map[string]ConfigFile{
+			"app.conf":     {Content: []byte("debug=false")},
+			"tls/key.pem":  {Content: []byte("key"), Mode: 0644, Secret: true},
+			"tls/cert.pem": {Content: []byte("cert"), Mode: 0600},
+		},
+	})
+	assert.NoError(t, err)
+
+	source := filepath.Join(dir, "sandbox", "config")
+
+	content, mode := readConfigFile(t, filepath.Join(source, "app.conf"))
+	assert.Equal(t, "debug=false", content)
+	assert.Equal(t, os.FileMode(0644), mode)
+
+	_, mode = readConfigFile(t, filepath.Join(source, "tls", "key.pem"))
+	assert.Equal(t, os.FileMode(0640), mode)
+
+	_, mode = readConfigFile(t, filepath.Join(source, "tls", "cert.pem"))
+	assert.Equal(t, os.FileMode(0600), mode)
+
+	assert.Equal(t, 1, fake.UpdateContainerCallCount())
+
+	_, put, _ := fake.UpdateContainerArgsForCall(0)
+	assert.Equal(t, map[string]string{
+		"type":     "disk",
+		"path":     "/etc/app",
+		"source":   source,
+		"pool":     "",
+		"size":     "",
+		"readonly": "true",
+		"optional": "false",
+	}, put.Devices["configdisk-config"])
+}
+
+func TestClient_AttachConfigDisk_UpdateInPlace(t *testing.T) {
+	t.Parallel()
+
+	client, fake, dir := testConfigDiskClient(t)
+	defer os.RemoveAll(dir)
+
+	disk := &ConfigDisk{
+		Name: "config",
+		Path: "/etc/app",
+		Files: map[string]ConfigFile{
+			"app.conf":  {Content: []byte("debug=false")},
+			"old/a.txt": {Content: []byte("a")},
+		},
+	}
+
+	err := client.AttachConfigDisk("foo", "sandbox", disk)
+	assert.NoError(t, err)
+
+	_, put, _ := fake.UpdateContainerArgsForCall(0)
+	attached := basicContainer("foo", "sandbox")
+	attached.Config[cfgSecurityPrivileged] = "true"
+	attached.Devices = put.Devices
+	fake.GetContainerReturns(attached, "etag2", nil)
+
+	source := filepath.Join(dir, "sandbox", "config")
+
+	// a reader holding the directory open, like the bind mount, keeps seeing the same one
+	before, err := os.Stat(source)
+	assert.NoError(t, err)
+
+	disk.Files = map[string]ConfigFile{
+		"app.conf": {Content: []byte("debug=true")},
+		"new.txt":  {Content: []byte("new")},
+	}
+
+	err = client.AttachConfigDisk("foo", "sandbox", disk)
+	assert.NoError(t, err)
+
+	// the device is attached already, so only the files changed
+	assert.Equal(t, 1, fake.UpdateContainerCallCount())
+
+	after, err := os.Stat(source)
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+
+	content, _ := readConfigFile(t, filepath.Join(source, "app.conf"))
+	assert.Equal(t, "debug=true", content)
+
+	content, _ = readConfigFile(t, filepath.Join(source, "new.txt"))
+	assert.Equal(t, "new", content)
+
+	entries, err := ioutil.ReadDir(source)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestClient_AttachConfigDisk_Unprivileged(t *testing.T) {
+	owners := make(map[string][2]int)
+
+	oldChown := chownPath
+	chownPath = func(path string, uid, gid int) error {
+		owners[filepath.Base(path)] = [2]int{uid, gid}
+
+		return nil
+	}
+
+	t.Cleanup(func() { chownPath = oldChown })
+
+	client, fake, dir := testConfigDiskClient(t)
+	defer os.RemoveAll(dir)
+
+	ct := basicContainer("foo", "sandbox")
+	ct.Config[cfgVolatileIdmapBase] = "1000000"
+	ct.Config[cfgSecurityFSGroup] = "2000"
+	fake.GetContainerReturns(ct, "etag", nil)
+
+	err := client.AttachConfigDisk("foo", "sandbox", &ConfigDisk{
+		Name:  "secret",
+		Path:  "/etc/secret",
+		Files: map[string]ConfigFile{"token": {Content: []byte("s3cr3t"), Secret: true}},
+	})
+	assert.NoError(t, err)
+
+	// the root of the container owns the secret and its fs group may read it, although others may not
+	assert.Len(t, owners, 1)
+
+	for _, owner := range owners {
+		assert.Equal(t, [2]int{1000000, 1002000}, owner)
+	}
+
+	_, mode := readConfigFile(t, filepath.Join(dir, "sandbox", "secret", "token"))
+	assert.Equal(t, os.FileMode(0640), mode)
+}
+
+func TestClient_AttachConfigDisk_NoIdmap(t *testing.T) {
+	t.Parallel()
+
+	client, fake, dir := testConfigDiskClient(t)
+	defer os.RemoveAll(dir)
+
+	fake.GetContainerReturns(basicContainer("foo", "sandbox"), "etag", nil)
+
+	err := client.AttachConfigDisk("foo", "sandbox", &ConfigDisk{
+		Name:  "secret",
+		Path:  "/etc/secret",
+		Files: map[string]ConfigFile{"token": {Content: []byte("s3cr3t"), Secret: true}},
+	})
+	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(dir, "sandbox", "secret"))
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestClient_AttachConfigDisk_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		disk *ConfigDisk
+	}{
+		{"NoName", &ConfigDisk{Path: "/etc/app"}},
+		{"NameWithSlash", &ConfigDisk{Name: "a/b", Path: "/etc/app"}},
+		{"RelativePath", &ConfigDisk{Name: "config", Path: "etc/app"}},
+		{"FileOutside", &ConfigDisk{Name: "config", Path: "/etc/app", Files: map[string]ConfigFile{"../passwd": {}}}},
+		{"AbsoluteFile", &ConfigDisk{Name: "config", Path: "/etc/app", Files: map[string]ConfigFile{"/etc/passwd": {}}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, fake := testClient()
+
+			err := client.AttachConfigDisk("foo", "sandbox", tt.disk)
+			assert.True(t, errors.Is(err, ErrUsage))
+			assert.Equal(t, 0, fake.UpdateContainerCallCount())
+		})
+	}
+}
+
+func TestClient_AttachConfigDisk_Remote(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	client.conn.RemoteURL = "https://10.0.0.1:8443"
+
+	err := client.AttachConfigDisk("foo", "sandbox", &ConfigDisk{Name: "config", Path: "/etc/app"})
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+}
+
+func TestSandbox_Delete_RemovesConfigDisks(t *testing.T) {
+	t.Parallel()
+
+	client, fake, dir := testConfigDiskClient(t)
+	defer os.RemoveAll(dir)
+
+	err := client.AttachConfigDisk("foo", "sandbox", &ConfigDisk{
+		Name:  "config",
+		Path:  "/etc/app",
+		Files: map[string]ConfigFile{"app.conf": {Content: []byte("debug=false")}},
+	})
+	assert.NoError(t, err)
+
+	s := &Sandbox{LXDObject: LXDObject{ID: "sandbox", client: client}}
+
+	err = s.Delete()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DeleteProfileCallCount())
+
+	_, err = os.Stat(filepath.Join(dir, "sandbox"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,140 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	lxd "github.com/lxc/lxd/client"
+)
+
+// ErrNotTrusted is returned when a remote LXD doesn't trust the client certificate
+var ErrNotTrusted = errors.New("client certificate not trusted")
+
+// Config defines how the client connects to LXD. Either a local LXD is reached by its unix socket, or a remote LXD by
+// its HTTPS address where the client authenticates with a certificate.
+type Config struct {
+	// Socket is the path of the unix socket of a local LXD. Only used if RemoteURL is empty, where an empty Socket
+	// lets the LXD client find the socket by itself
+	Socket string
+	// RemoteURL is the address of a remote LXD, like https://10.0.0.1:8443
+	RemoteURL string
+	// ClientCert and ClientKey are the paths to the PEM encoded certificate and key which must be trusted by the remote
+	ClientCert string
+	ClientKey  string
+	// ServerCert is the path to the PEM encoded certificate of the remote. If empty the remote certificate is verified
+	// against the CAs of the system
+	ServerCert string
+	// ConfigPath is the path of the lxc config file with the image remotes
+	ConfigPath string
+	// Project all instances, profiles, images and networks are managed within, the default project of LXD if empty
+	Project string
+	// ConfigDiskDir is where config disks are assembled on the host, DefaultConfigDiskDir if empty
+	ConfigDiskDir string
+	// CRIU tells whether CRIU is installed on the LXD host, one of CRIUAuto, CRIUInstalled or CRIUMissing. LXD doesn't
+	// report it, see SupportsStatefulOps. CRIUAuto if empty
+	CRIU string
+	// ForceDeleteProtected deletes every sandbox and container as if ForceDelete was set on it
+	ForceDeleteProtected bool
+}
+
+// IsRemote returns true if the client connects to LXD via HTTPS
+func (c Config) IsRemote() bool {
+	return c.RemoteURL != ""
+}
+
+// Validate returns an error if the config can't be used to connect to LXD
+func (c Config) Validate() error {
+	switch c.CRIU {
+	case "", CRIUAuto, CRIUInstalled, CRIUMissing:
+	default:
+		return fmt.Errorf("%w: unknown criu setting: %s", ErrUsage, c.CRIU)
+	}
+
+	if !c.IsRemote() {
+		if c.ClientCert != "" || c.ClientKey != "" || c.ServerCert != "" {
+			return fmt.Errorf("%w: certificates can only be used with a remote url", ErrUsage)
+		}
+
+		return nil
+	}
+
+	u, err := url.Parse(c.RemoteURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid remote url: %v", ErrUsage, err)
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: remote url must be an https address: %s", ErrUsage, c.RemoteURL)
+	}
+
+	if c.ClientCert == "" || c.ClientKey == "" {
+		return fmt.Errorf("%w: a remote url requires a client certificate and key", ErrUsage)
+	}
+
+	return nil
+}
+
+// connectionArgs returns the arguments for the LXD client with the certificates loaded from their files
+func (c Config) connectionArgs(httpClient *http.Client) (*lxd.ConnectionArgs, error) {
+	args := &lxd.ConnectionArgs{
+		HTTPClient: httpClient,
+	}
+
+	if !c.IsRemote() {
+		return args, nil
+	}
+
+	for _, f := range []struct {
+		path string
+		dest *string
+	}{
+		{c.ClientCert, &args.TLSClientCert},
+		{c.ClientKey, &args.TLSClientKey},
+		{c.ServerCert, &args.TLSServerCert},
+	} {
+		if f.path == "" {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+
+		*f.dest = string(content)
+	}
+
+	return args, nil
+}
+
+// connectServer opens the connection to LXD and ensures a remote trusts the client
+func (c Config) connectServer(httpClient *http.Client) (lxd.ContainerServer, error) {
+	args, err := c.connectionArgs(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.IsRemote() {
+		return lxd.ConnectLXDUnix(c.Socket, args)
+	}
+
+	server, err := lxd.ConnectLXD(c.RemoteURL, args)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", c.RemoteURL, err)
+	}
+
+	// an untrusted client can connect but only sees the public part of the api
+	info, _, err := server.GetServer()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", c.RemoteURL, err)
+	}
+
+	if info.Auth != "trusted" {
+		return nil, fmt.Errorf("%w by %s: add it with `lxc config trust add`", ErrNotTrusted, c.RemoteURL)
+	}
+
+	return server, nil
+}
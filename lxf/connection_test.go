@@ -0,0 +1,87 @@
+package lxf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default socket", Config{}, false},
+		{"socket", Config{Socket: "/var/lib/lxd/unix.socket"}, false},
+		{"remote", Config{RemoteURL: "https://10.0.0.1:8443", ClientCert: "client.crt", ClientKey: "client.key"}, false},
+		{"remote with server cert", Config{RemoteURL: "https://lxd:8443", ClientCert: "client.crt", ClientKey: "client.key", ServerCert: "server.crt"}, false},
+		{"remote without key", Config{RemoteURL: "https://10.0.0.1:8443", ClientCert: "client.crt"}, true},
+		{"remote without cert", Config{RemoteURL: "https://10.0.0.1:8443"}, true},
+		{"remote http", Config{RemoteURL: "http://10.0.0.1:8443", ClientCert: "client.crt", ClientKey: "client.key"}, true},
+		{"remote without host", Config{RemoteURL: "https://", ClientCert: "client.crt", ClientKey: "client.key"}, true},
+		{"socket with cert", Config{Socket: "/var/lib/lxd/unix.socket", ClientCert: "client.crt"}, true},
+		{"criu installed", Config{CRIU: CRIUInstalled}, false},
+		{"criu unknown", Config{CRIU: "maybe"}, true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.cfg.Validate()
+			assert.False(t, (err != nil) != tt.wantErr)
+
+			if err != nil {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func TestConfig_connectionArgs_Socket(t *testing.T) {
+	t.Parallel()
+
+	args, err := Config{Socket: "/var/lib/lxd/unix.socket"}.connectionArgs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, args.TLSClientCert)
+	assert.Empty(t, args.TLSClientKey)
+}
+
+func TestConfig_connectionArgs_Remote(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "lxe-connection")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range map[string]string{"client.crt": "CERT", "client.key": "KEY", "server.crt": "SERVER"} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		assert.NoError(t, err)
+	}
+
+	cfg := Config{
+		RemoteURL:  "https://10.0.0.1:8443",
+		ClientCert: filepath.Join(dir, "client.crt"),
+		ClientKey:  filepath.Join(dir, "client.key"),
+		ServerCert: filepath.Join(dir, "server.crt"),
+	}
+
+	args, err := cfg.connectionArgs(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "CERT", args.TLSClientCert)
+	assert.Equal(t, "KEY", args.TLSClientKey)
+	assert.Equal(t, "SERVER", args.TLSServerCert)
+
+	cfg.ClientKey = filepath.Join(dir, "missing.key")
+
+	_, err = cfg.connectionArgs(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/automaticserver/lxe/shared"
+	lxd "github.com/lxc/lxd/client"
+	lxdApi "github.com/lxc/lxd/shared/api"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ErrConsoleLogUnavailable is returned if LXD keeps no console log for a container, e.g. as it's a virtual machine or
+// the console log file is disabled in its raw.lxc
+var ErrConsoleLogUnavailable = errors.New("console log unavailable")
+
+// GetConsoleLog returns the recent output of the console of the container id, which LXD keeps in a ring buffer while the
+// container runs and in the console log file once it stopped. Only the last maxBytes are returned, all of it if maxBytes
+// isn't positive. If LXD keeps no console log for the container, ErrConsoleLogUnavailable is returned instead of an
+// empty log, so the caller can tell this apart from a container which didn't write anything yet
+func (l *client) GetConsoleLog(id string, maxBytes int) ([]byte, error) {
+	rc, err := l.GetServer().GetContainerConsoleLog(id, &lxd.ContainerConsoleLogArgs{})
+	if err != nil {
+		return nil, l.consoleLogError(id, err)
+	}
+	defer rc.Close()
+
+	buf, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxBytes > 0 && len(buf) > maxBytes {
+		buf = buf[len(buf)-maxBytes:]
+	}
+
+	return buf, nil
+}
+
+// consoleLogError explains why LXD returned no console log. LXD answers with not found for a missing container as well
+// as for a missing console log file, so the container is looked up to tell the two apart
+func (l *client) consoleLogError(id string, err error) error {
+	if strings.Contains(strings.ToLower(err.Error()), "not supported") {
+		return fmt.Errorf("%w: container %v: %v", ErrConsoleLogUnavailable, id, err)
+	}
+
+	if !shared.IsErrNotFound(err) {
+		return err
+	}
+
+	_, _, cErr := l.GetServer().GetContainer(id)
+	if cErr != nil {
+		if shared.IsErrNotFound(cErr) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return cErr
+	}
+
+	return fmt.Errorf("%w: container %v has no console log, it may be disabled by lxc.console.logfile in raw.lxc",
+		ErrConsoleLogUnavailable, id)
+}
+
+// AttachConsole attaches the provided streams to the console of the container, which is the tty of PID 1 and not a
+// new process like Exec. It blocks till stdin is closed by the caller or the console is closed by LXD. Without stdin
+// only the output is attached, which ends once the console is closed by LXD.
+func (l *client) AttachConsole(id string, stdin io.Reader, stdout io.Writer, resize <-chan remotecommand.TerminalSize) error {
+	ses := &session{
+		resize:      resize,
+		closeResize: make(chan struct{}),
+	}
+	defer close(ses.closeResize)
+
+	term := &consoleTerminal{
+		Reader:   stdin,
+		Writer:   stdout,
+		eof:      make(chan struct{}),
+		detached: make(chan struct{}),
+	}
+	defer close(term.detached)
+
+	req := lxdApi.ContainerConsolePost{
+		Width:  WindowWidthDefault,
+		Height: WindowHeightDefault,
+	}
+	args := &lxd.ContainerConsoleArgs{
+		Terminal: term,
+		Control:  ses.controlHandler,
+		// buffered as LXD stops listening to it once the console is gone
+		ConsoleDisconnect: make(chan bool, 1),
+	}
+
+	op, err := l.GetServer().ConsoleContainer(id, req, args)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- op.Wait()
+	}()
+
+	select {
+	// The caller detached, so disconnect the console but keep the container running
+	case <-term.eof:
+		args.ConsoleDisconnect <- true
+		return nil
+	// The console was closed by LXD, e.g. because the container stopped
+	case err := <-done:
+		return err
+	}
+}
+
+// consoleTerminal combines the attached streams into the terminal LXD expects and signals when stdin reached its end
+type consoleTerminal struct {
+	io.Reader
+	io.Writer
+	eof  chan struct{}
+	once sync.Once
+	// detached is closed once the attach returned, till then reading without stdin blocks
+	detached chan struct{}
+}
+
+func (t *consoleTerminal) Read(p []byte) (int, error) {
+	// without stdin there is nothing to detach on, the output stays attached till the console is closed
+	if t.Reader == nil {
+		<-t.detached
+		return 0, io.EOF
+	}
+
+	n, err := t.Reader.Read(p)
+	if err == io.EOF {
+		t.signalEOF()
+	}
+
+	return n, err
+}
+
+func (t *consoleTerminal) Write(p []byte) (int, error) {
+	if t.Writer == nil {
+		return len(p), nil
+	}
+
+	return t.Writer.Write(p)
+}
+
+// Close is a noop, the attached streams are owned by the caller
+func (t *consoleTerminal) Close() error {
+	return nil
+}
+
+func (t *consoleTerminal) signalEOF() {
+	t.once.Do(func() {
+		close(t.eof)
+	})
+}
@@ -0,0 +1,177 @@
+package lxf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	lxd "github.com/lxc/lxd/client"
+	lxdApi "github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_AttachConsole_Detach(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+	block := make(chan struct{})
+	defer close(block)
+
+	var args *lxd.ContainerConsoleArgs
+
+	fake.ConsoleContainerCalls(func(arg1 string, arg2 lxdApi.ContainerConsolePost, arg3 *lxd.ContainerConsoleArgs) (lxd.Operation, error) {
+		args = arg3
+
+		_, err := arg3.Terminal.Write([]byte("login: "))
+		assert.NoError(t, err)
+
+		go func() {
+			_, _ = io.Copy(ioutil.Discard, arg3.Terminal)
+		}()
+
+		return fakeOp, nil
+	})
+	fakeOp.WaitCalls(func() error {
+		<-block
+		return nil
+	})
+
+	stdout := &bytes.Buffer{}
+
+	err := client.AttachConsole("foo", strings.NewReader("root\n"), stdout, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "login: ", stdout.String())
+	assert.Len(t, args.ConsoleDisconnect, 1)
+}
+
+func TestClient_AttachConsole_OutputOnly(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+	read := make(chan struct{})
+	release := make(chan struct{})
+
+	var args *lxd.ContainerConsoleArgs
+
+	fake.ConsoleContainerCalls(func(arg1 string, arg2 lxdApi.ContainerConsolePost, arg3 *lxd.ContainerConsoleArgs) (lxd.Operation, error) {
+		args = arg3
+
+		go func() {
+			_, _ = io.Copy(ioutil.Discard, arg3.Terminal)
+			close(read)
+		}()
+
+		return fakeOp, nil
+	})
+	fakeOp.WaitCalls(func() error {
+		<-release
+
+		_, err := args.Terminal.Write([]byte("kernel panic"))
+		assert.NoError(t, err)
+
+		return nil
+	})
+
+	stdout := &bytes.Buffer{}
+	done := make(chan error)
+
+	go func() {
+		done <- client.AttachConsole("foo", nil, stdout, nil)
+	}()
+
+	// without stdin the attach must not end by itself
+	select {
+	case <-done:
+		t.Fatal("attach ended before the console was closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, "kernel panic", stdout.String())
+	assert.Len(t, args.ConsoleDisconnect, 0)
+
+	// reading the terminal ends once detached
+	<-read
+}
+
+func TestClient_AttachConsole_Closed(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.ConsoleContainerReturns(fakeOp, nil)
+	fakeOp.WaitReturns(errors.New("container stopped"))
+
+	err := client.AttachConsole("foo", nil, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestClient_GetConsoleLog(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerConsoleLogReturns(ioutil.NopCloser(strings.NewReader("booting\nlogin: ")), nil)
+
+	out, err := client.GetConsoleLog("foo", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "login: ", string(out))
+
+	name, _ := fake.GetContainerConsoleLogArgsForCall(0)
+	assert.Equal(t, "foo", name)
+}
+
+func TestClient_GetConsoleLog_All(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerConsoleLogReturns(ioutil.NopCloser(strings.NewReader("booting\n")), nil)
+
+	out, err := client.GetConsoleLog("foo", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "booting\n", string(out))
+}
+
+func TestClient_GetConsoleLog_Disabled(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerConsoleLogReturns(nil, shared.NewErrNotFound())
+	fake.GetContainerReturns(basicContainer("foo", "sandbox"), "etag", nil)
+
+	out, err := client.GetConsoleLog("foo", 0)
+	assert.True(t, errors.Is(err, ErrConsoleLogUnavailable))
+	assert.Nil(t, out)
+}
+
+func TestClient_GetConsoleLog_NotSupported(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerConsoleLogReturns(nil, errors.New("Querying the console buffer is not supported for VMs"))
+
+	_, err := client.GetConsoleLog("foo", 0)
+	assert.True(t, errors.Is(err, ErrConsoleLogUnavailable))
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+}
+
+func TestClient_GetConsoleLog_ContainerNotFound(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerConsoleLogReturns(nil, shared.NewErrNotFound())
+	fake.GetContainerReturns(nil, "", shared.NewErrNotFound())
+
+	_, err := client.GetConsoleLog("foo", 0)
+	assert.True(t, shared.IsErrNotFound(err))
+	assert.False(t, errors.Is(err, ErrConsoleLogUnavailable))
+}
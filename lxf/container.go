@@ -1,9 +1,8 @@
 package lxf // import "github.com/automaticserver/lxe/lxf"
 
 import (
-	"crypto/md5" // nolint: gosec
+	"context"
 	"fmt"
-	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -11,15 +10,19 @@ import (
 	"github.com/automaticserver/lxe/shared"
 	"github.com/lxc/lxd/shared/api"
 	opencontainers "github.com/opencontainers/runtime-spec/specs-go"
-	"k8s.io/apimachinery/pkg/util/uuid"
 )
 
 const (
 	cfgLogPath              = "user.log_path"
 	cfgSecurityPrivileged   = "security.privileged"
+	cfgSecurityRunAsUser    = "user.security.run_as_user"
+	cfgSecurityRunAsGroup   = "user.security.run_as_group"
+	cfgSecurityGroups       = "user.security.supplemental_groups"
+	cfgSecurityFSGroup      = "user.security.fs_group"
 	cfgVolatileBaseImage    = cfgVolatile + ".base_image"
 	cfgStartedAt            = "user.started_at"
 	cfgFinishedAt           = "user.finished_at"
+	cfgRestartCount         = "user.restartcount"
 	cfgCloudInitUserData    = "user.user-data"
 	cfgCloudInitMetaData    = "user.meta-data"
 	cfgEnvironmentPrefix    = "environment"
@@ -29,7 +32,6 @@ const (
 	cfgResourcesCPUQuota    = cfgResourcesCPUPrefix + ".quota"
 	cfgResourcesCPUPeriod   = cfgResourcesCPUPrefix + ".period"
 	cfgResourcesMemoryLimit = cfgResourcesPrefix + ".memory.limit"
-	cfgLimitCPUAllowance    = "limits.cpu.allowance"
 	cfgLimitMemory          = "limits.memory"
 )
 
@@ -38,8 +40,36 @@ var (
 		append([]string{
 			cfgLogPath,
 			cfgSecurityPrivileged,
+			cfgSecurityRunAsUser,
+			cfgSecurityRunAsGroup,
+			cfgSecurityGroups,
+			cfgSecurityFSGroup,
+			cfgTmpfs,
+			cfgInitCommand,
+			cfgRawLXC,
+			cfgConfigHash,
+			cfgSeccompProfile,
+			cfgRawSeccomp,
+			cfgSyscallsBlacklistDefault,
+			cfgShmSize,
+			cfgSandboxRawLXC,
+			cfgBootAutostart,
+			cfgBootAutostartPriority,
+			cfgBootAutostartDelay,
+			cfgLimitProcesses,
+			cfgLimitMemorySwap,
+			cfgLimitMemorySwapPriority,
+			cfgLimitCPUPriority,
+			cfgLimitCPUAllowance,
+			cfgSecurityProtectionDelete,
+			cfgTimezone,
+			cfgKeepImageTemplates,
+			cfgInstanceType,
+			cfgAppArmorUnconfined,
+			cfgPIDNamespaceHolder,
 			cfgStartedAt,
 			cfgFinishedAt,
+			cfgRestartCount,
 			cfgCloudInitUserData,
 			cfgCloudInitMetaData,
 			cfgCloudInitNetworkConfig,
@@ -50,6 +80,7 @@ var (
 		append([]string{
 			cfgEnvironmentPrefix,
 			cfgResourcesPrefix,
+			cfgLimitHugepagesPrefix,
 		}, reservedConfigPrefixesCRI...,
 		)...,
 	)
@@ -66,8 +97,38 @@ type Container struct {
 	Image string
 	// Privileged defines if the container is run privileged
 	Privileged bool
+	// Ephemeral containers are deleted by LXD as soon as they stop, e.g. for one-shot jobs. Along with the container its
+	// exit state is gone, so it can't be inspected after it stopped
+	Ephemeral bool
+	// RunAsUser and RunAsGroup set the uid and gid the init process of the container is started with. If unset, the
+	// init process runs as root. The ids are those inside the container; for unprivileged containers they are shifted
+	// by the idmap of the container, so they must be within the range the idmap maps (usually 0-65535)
+	RunAsUser  *int64
+	RunAsGroup *int64
+	// SupplementalGroups are additional gids the init process of the container is a member of
+	SupplementalGroups []int64
+	// FSGroup is added to the supplemental groups and becomes the group owner of the host sources of writable emptyDir
+	// volumes and of the files of config disks, see applyFSGroup. Like RunAsGroup it is a gid inside the container
+	FSGroup *int64
+	// Tmpfs are memory backed mounts, e.g. for scratch space which must not hit the disk
+	Tmpfs []TmpfsMount
+	// InitCommand is run as PID 1 instead of the init of the image, e.g. for app-style containers running a single
+	// process. If empty, the container boots the init of the image like systemd. It's passed as lxc.init.cmd, so it
+	// replaces such a line of RawLXC. RunAsUser, RunAsGroup and the Environment apply to it like to the init
+	InitCommand []string
 	// Environment specifies to the container exported environment variables
 	Environment map[string]string
+	// KeepImageTemplates leaves the metadata templates of the image as they are. Otherwise the templates for
+	// /etc/hostname and /etc/hosts are removed on creation if the sandbox sets a hostname, as cloud-init writes these
+	// files then, see Templates
+	KeepImageTemplates bool
+	// Target is where in the LXD cluster the container is created, either a member or a cluster group prefixed with @
+	// like @gpu. It's only used on creation and not read back. If empty, LXD selects the member
+	Target string
+	// PIDNamespaceHolder is the id of the container whose PID namespace this container joined, if its sandbox shares
+	// the PID namespace. It's empty if the container holds the namespace itself. It's selected on every start and is
+	// read-only
+	PIDNamespaceHolder string
 
 	// CRIObject inherits common CRI fields
 	CRIObject
@@ -77,6 +138,10 @@ type Container struct {
 	StartedAt time.Time
 	// FinishedAt is when the container was exited
 	FinishedAt time.Time
+	// RestartCount is how often this container was started again after its first start. It counts the starts of this
+	// LXD container only: a recreated container begins at zero again, like kubelet begins a new attempt (see
+	// ContainerMetadata.Attempt) when it recreates a container
+	RestartCount uint32
 	// StateName of the current container
 	StateName ContainerStateName
 	// LogPath TODO, to be implemented?
@@ -130,6 +195,23 @@ type ContainerStats struct {
 	MemoryUsage     uint64
 	CPUUsage        uint64
 	FilesystemUsage uint64
+	// Network contains the counters of the interfaces keyed by their name inside the container, except the loopback
+	// interface. It's empty if the container isn't running
+	Network map[string]InterfaceStats
+}
+
+// InterfaceStats are the traffic counters of a network interface since it was created
+type InterfaceStats struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+}
+
+// PrimaryNetwork returns the counters of the interface the pod is reachable on. They are zero if the container isn't
+// running or has no such interface, e.g. in the host network
+func (s ContainerStats) PrimaryNetwork() InterfaceStats {
+	return s.Network[shared.DefaultInterface]
 }
 
 // ContainerMetadata has the metadata neede by a container
@@ -189,7 +271,7 @@ func (c *Container) State() (*ContainerState, error) {
 func (c *Container) getState() (*ContainerState, error) {
 	cs := &ContainerState{}
 
-	state, _, err := c.client.server.GetContainerState(c.ID)
+	state, _, err := c.client.GetServer().GetContainerState(c.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -200,6 +282,20 @@ func (c *Container) getState() (*ContainerState, error) {
 		CPUUsage:        uint64(state.CPU.Usage),
 		MemoryUsage:     uint64(state.Memory.Usage),
 		FilesystemUsage: uint64(state.Disk[lxdInitDefaultDiskName].Usage),
+		Network:         make(map[string]InterfaceStats, len(state.Network)),
+	}
+
+	for name, n := range state.Network {
+		if n.Type == "loopback" {
+			continue
+		}
+
+		cs.Stats.Network[name] = InterfaceStats{
+			RxBytes:   uint64(n.Counters.BytesReceived),
+			TxBytes:   uint64(n.Counters.BytesSent),
+			RxPackets: uint64(n.Counters.PacketsReceived),
+			TxPackets: uint64(n.Counters.PacketsSent),
+		}
 	}
 
 	return cs, nil
@@ -225,17 +321,88 @@ func (c *Container) Apply() error {
 		return err
 	}
 
-	err = c.apply()
+	s, err := c.Sandbox()
+	if err != nil {
+		return err
+	}
+
+	// the settings of the sandbox are only merged into a copy, so they're never mistaken for the ones of the container
+	// and later changes of the sandbox are still picked up
+	eff := c.withSandbox(s)
+
+	err = eff.validateWithSandbox(s)
+	if err != nil {
+		return err
+	}
+
+	create := c.ID == ""
+
+	if create {
+		err = c.client.validateTarget(c.Target)
+		if err != nil {
+			return err
+		}
+
+		// the architecture of an existing container can't change anymore
+		if eff.Architecture != "" {
+			eff.Architecture, err = c.client.validateArchitecture(eff.Architecture)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = c.client.validateNicSubnets(changedNics(c.Devices, c.appliedNics))
+	if err != nil {
+		return err
+	}
+
+	err = eff.validateTmpfsMemory()
 	if err != nil {
 		return err
 	}
 
-	return c.refresh()
+	if eff.AppArmorUnconfined {
+		log.WithField("containerid", c.ID).WithField("containername", c.Metadata.Name).Warn("container runs unconfined by AppArmor")
+	}
+
+	err = eff.apply(s, c)
+	c.ID, c.CreatedAt, c.AppliedConfigHash = eff.ID, eff.CreatedAt, eff.AppliedConfigHash
+
+	if err != nil {
+		return err
+	}
+
+	c.appliedNics = nicOptions(c.Devices)
+
+	err = c.refresh()
+	if err != nil {
+		return err
+	}
+
+	if create {
+		err = c.disableManagedTemplates()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the volume ownership is only set once, the container may change it afterwards
+	if create && c.FSGroup != nil {
+		return c.applyFSGroup()
+	}
+
+	return nil
 }
 
 // Start the container
 func (c *Container) Start() error {
-	err := c.client.opwait.StartContainer(c.ID)
+	err := c.joinPIDNamespace()
+	if err != nil {
+		return err
+	}
+
+	err = c.client.opWait().StartContainer(c.ID)
 	if err != nil {
 		if shared.IsErrNotFound(err) {
 			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), c.ID)
@@ -250,6 +417,11 @@ func (c *Container) Start() error {
 		return err
 	}
 
+	// a container which isn't marked as created anymore was started before
+	if _, created := c.Config[cfgState]; !created {
+		c.RestartCount++
+	}
+
 	// delete created mark if exists, so next stopping state can be exited
 	delete(c.Config, cfgState)
 	c.StartedAt = time.Now()
@@ -258,9 +430,15 @@ func (c *Container) Start() error {
 }
 
 // Stop will try to stop the container, returns nil when container is already stopped or
-// got stopped in the meantime, otherwise it will return an error.
+// got stopped in the meantime, otherwise it will return an error. A container holding the
+// shared PID namespace of its sandbox is only stopped after the ones which joined it.
 func (c *Container) Stop(timeout int) error {
-	err := c.client.opwait.StopContainer(c.ID, timeout, 1)
+	err := c.stopPIDNamespace()
+	if err != nil {
+		return err
+	}
+
+	err = c.client.opWait().StopContainer(c.ID, timeout, 1)
 	if err != nil {
 		if shared.IsErrNotFound(err) {
 			return nil
@@ -269,6 +447,11 @@ func (c *Container) Stop(timeout int) error {
 		return err
 	}
 
+	// LXD deleted the container on stop, there is nothing left to save
+	if c.Ephemeral {
+		return nil
+	}
+
 	// when changing state of container, need to refresh ETag
 	err = c.refresh()
 	if err != nil {
@@ -281,23 +464,204 @@ func (c *Container) Stop(timeout int) error {
 }
 
 // Delete the container, returns nil when container is already deleted or
-// got deleted in the meantime, otherwise it will return an error.
+// got deleted in the meantime, otherwise it will return an error. Operations
+// in flight are settled for at most DeleteBusyTimeout, see DeleteContext.
+// A delete protected container is only deleted if ForceDelete or
+// Config.ForceDeleteProtected of the client is set.
 func (c *Container) Delete() error {
-	err := c.client.opwait.DeleteContainer(c.ID)
-	if err != nil {
-		if shared.IsErrNotFound(err) {
+	ctx, cancel := context.WithTimeout(context.Background(), DeleteBusyTimeout)
+	defer cancel()
+
+	return c.DeleteContext(ctx)
+}
+
+// DeleteContext deletes the container like Delete. If LXD refuses as an operation of the container is still in flight,
+// e.g. a start, the operation is cancelled or waited for and the delete retried until ctx is done
+func (c *Container) DeleteContext(ctx context.Context) error {
+	if c.forceDelete() {
+		err := c.client.clearDeleteProtection(c.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		err := c.client.opWait().DeleteContainer(c.ID)
+		if err == nil || shared.IsErrNotFound(err) {
 			return nil
 		}
 
-		return err
+		if isDeleteProtectedError(err) {
+			return fmt.Errorf("%w: container %v, it can only be deleted with force", ErrDeleteProtected, c.ID)
+		}
+
+		if !isBusyError(err) {
+			return err
+		}
+
+		log.WithField("containerid", c.ID).WithError(err).Info("container busy, settling its operations to delete it")
+
+		err = c.client.settleOperations(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: container %v: %v", ErrBusy, c.ID, ctx.Err())
+		case <-time.After(deleteBusyInterval):
+		}
 	}
+}
 
-	return nil
+// withSandbox returns a copy of the container where the settings it leaves unset are the ones of its sandbox s. The
+// copy is only used to validate and render the config, the container itself is left unchanged
+func (c *Container) withSandbox(s *Sandbox) *Container {
+	eff := *c
+
+	if eff.ShmSize == "" {
+		eff.ShmSize = s.ShmSize
+	}
+
+	if len(eff.DeviceCgroupRules) == 0 {
+		eff.DeviceCgroupRules = s.DeviceCgroupRules
+	}
+
+	if eff.Architecture == "" {
+		eff.Architecture = s.Architecture
+	}
+
+	// the pod was already checked to be privileged, the container must be too
+	if s.AppArmorUnconfined && eff.Privileged {
+		eff.AppArmorUnconfined = true
+	}
+
+	// the instance type of an existing container can't change anymore
+	if eff.ID == "" && eff.InstanceType == "" {
+		eff.InstanceType = s.InstanceType
+	}
+
+	return &eff
 }
 
-// validate checks for misconfigurations
+// validate checks for misconfigurations of the settings which don't depend on the sandbox
 func (c *Container) validate() error {
-	s, err := c.Sandbox()
+	if c.ID == "" {
+		err := c.validateCreate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.Devices.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.RunAsUser != nil && *c.RunAsUser < 0 {
+		return fmt.Errorf("%w: run as user must not be negative: %d", ErrUsage, *c.RunAsUser)
+	}
+
+	if c.RunAsGroup != nil && *c.RunAsGroup < 0 {
+		return fmt.Errorf("%w: run as group must not be negative: %d", ErrUsage, *c.RunAsGroup)
+	}
+
+	for _, g := range c.SupplementalGroups {
+		if g < 0 {
+			return fmt.Errorf("%w: supplemental group must not be negative: %d", ErrUsage, g)
+		}
+	}
+
+	if c.FSGroup != nil && *c.FSGroup < 0 {
+		return fmt.Errorf("%w: fs group must not be negative: %d", ErrUsage, *c.FSGroup)
+	}
+
+	err = validateRawLXC(c.RawLXC)
+	if err != nil {
+		return err
+	}
+
+	err = validateInitCommand(c.InitCommand)
+	if err != nil {
+		return err
+	}
+
+	err = c.validateBoot()
+	if err != nil {
+		return err
+	}
+
+	err = c.validatePidsLimit()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateMemorySwap()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateCPU()
+	if err != nil {
+		return err
+	}
+
+	err = c.validateHugepages()
+	if err != nil {
+		return err
+	}
+
+	err = validateTimezone(c.Timezone)
+	if err != nil {
+		return err
+	}
+
+	if tz, has := c.Environment["TZ"]; has && c.Timezone != "" && tz != c.Timezone {
+		return fmt.Errorf("%w: timezone %v conflicts with environment variable TZ=%v", ErrUsage, c.Timezone, tz)
+	}
+
+	return validateSeccompProfile(c.SeccompProfile)
+}
+
+// validateWithSandbox checks for misconfigurations of the container in its sandbox s, where c is the copy returned by
+// withSandbox. Neither is changed
+func (c *Container) validateWithSandbox(s *Sandbox) error {
+	err := c.validateInstanceType()
+	if err != nil {
+		return err
+	}
+
+	if c.InstanceType.IsVM() {
+		err = c.validateVM()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.validateAppArmor(c.Privileged)
+	if err != nil {
+		return err
+	}
+
+	if c.Architecture != "" {
+		_, err = normalizeArchitecture(c.Architecture)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateDeviceCgroupRules(c.DeviceCgroupRules)
+	if err != nil {
+		return err
+	}
+
+	mounts, err := c.tmpfsMounts()
+	if err != nil {
+		return err
+	}
+
+	// the sizes are checked against the memory of the node by validateTmpfsMemory
+	err = validateTmpfs(mounts, 0)
 	if err != nil {
 		return err
 	}
@@ -312,9 +676,24 @@ func (c *Container) validate() error {
 	return nil
 }
 
-// apply saves the changes to LXD
+// validateCreate checks the fields required to create a new container are set
+func (c *Container) validateCreate() error {
+	switch {
+	case c.Metadata.Name == "":
+		return fmt.Errorf("%w: create container requires a metadata name", ErrUsage)
+	case c.Image == "":
+		return fmt.Errorf("%w: create container requires an image", ErrUsage)
+	case len(c.Profiles) == 0:
+		return fmt.Errorf("%w: create container requires at least the sandbox profile", ErrUsage)
+	}
+
+	return nil
+}
+
+// apply saves the changes to LXD, where c is the copy returned by withSandbox and own the container it was made of.
+// Only the settings of own are recorded, so the ones taken from the sandbox s aren't read back as the container's.
 // Will not obtain the new ETag!
-func (c *Container) apply() error {
+func (c *Container) apply(s *Sandbox, own *Container) error {
 	// TODO: can't this be done easier?
 	imageID, err := c.client.parseImage(c.Image)
 	if err != nil {
@@ -332,11 +711,16 @@ func (c *Container) apply() error {
 
 	config := makeContainerConfig(c)
 
+	err = c.shareDisks()
+	if err != nil {
+		return err
+	}
+
 	devices := make(map[string]map[string]string)
 
 	for _, d := range c.Devices {
 		name, options := d.ToMap()
-		devices[name] = options
+		devices[name] = c.InstanceType.deviceOptions(options)
 	}
 
 	for key, val := range c.Config {
@@ -347,47 +731,86 @@ func (c *Container) apply() error {
 		}
 	}
 
+	err = c.makeRawLXCConfig(config, s)
+	if err != nil {
+		return err
+	}
+
+	err = makeSeccompConfig(config, c.SeccompProfile)
+	if err != nil {
+		return err
+	}
+
+	// the shm size of the sandbox only applies as long as the container has none
+	if own.ShmSize == "" {
+		delete(config, cfgShmSize)
+	}
+
+	configHash := own.ConfigHash()
+	config[cfgConfigHash] = configHash
 	config[cfgSchema] = SchemaVersionContainer
 	contPut := api.ContainerPut{
-		Profiles: c.Profiles,
-		Config:   config,
-		Devices:  devices,
+		Architecture: c.Architecture,
+		Profiles:     c.Profiles,
+		Config:       config,
+		Devices:      devices,
+		Ephemeral:    c.Ephemeral,
 	}
 
 	if c.ID == "" {
 		// container has to be created
-		c.ID = c.CreateID()
+		id, err := c.CreateID()
+		if err != nil {
+			return err
+		}
+
+		c.ID = id
 
-		return c.client.opwait.CreateContainer(api.ContainersPost{
-			Name:         c.ID,
-			ContainerPut: contPut,
-			Source: api.ContainerSource{
-				Fingerprint: hash,
-				Type:        "image",
-			},
-		})
+		if c.InstanceType.IsVM() {
+			err = c.createVM(contPut, hash)
+		} else {
+			err = c.targetOpwait().CreateContainer(api.ContainersPost{
+				Name:         c.ID,
+				ContainerPut: contPut,
+				Source: api.ContainerSource{
+					Fingerprint: hash,
+					Type:        "image",
+				},
+			})
+		}
+
+		if err != nil {
+			return err
+		}
+
+		c.AppliedConfigHash = configHash
+
+		return nil
 	}
 	// else container has to be updated
 	if c.ETag == "" {
 		return fmt.Errorf("update container not allowed: %w", ErrMissingETag)
 	}
 
-	err = c.client.opwait.UpdateContainer(c.ID, contPut, c.ETag)
+	err = c.client.opWait().UpdateContainer(c.ID, contPut, c.ETag)
 	if err != nil {
 		if shared.IsErrNotFound(err) {
 			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), c.ID)
+		} else if shared.IsErrETagMismatch(err) {
+			return fmt.Errorf("update container %v: %w", c.ID, ErrETagConflict)
 		}
 
 		return err
 	}
 
+	c.AppliedConfigHash = configHash
+
 	return nil
 }
 
-// CreateID creates a unique container id
-func (c *Container) CreateID() string {
-	bin := md5.Sum([]byte(uuid.NewUUID())) // nolint: gosec
-	return string(c.Metadata.Name[0]) + b32lowerEncoder.EncodeToString(bin[:])[:15]
+// CreateID creates a unique container id using the id generator of the client
+func (c *Container) CreateID() (string, error) {
+	return c.client.newID(c.Metadata.Name)
 }
 
 // GetInetAddress returns the IPv4 address of the first matching interface in the parameter list
@@ -411,6 +834,47 @@ func (c *Container) GetInetAddress(ifs []string) string {
 	return ""
 }
 
+// makeRawLXCConfig merges the lines of the sandbox and the ones LXE generates into the raw.lxc lines of the user
+func (c *Container) makeRawLXCConfig(config map[string]string, sandbox *Sandbox) error {
+	makeSandboxRawLXCConfig(config, sandbox, c.rawLXCWithDeviceCgroupRules())
+
+	// the init user and group are passed to liblxc directly
+	if c.RunAsUser != nil {
+		setRawLXCOption(config, "lxc.init.uid", strconv.FormatInt(*c.RunAsUser, 10))
+	}
+
+	if c.RunAsGroup != nil {
+		setRawLXCOption(config, "lxc.init.gid", strconv.FormatInt(*c.RunAsGroup, 10))
+	}
+
+	if groups := c.initGroups(); len(groups) > 0 {
+		setRawLXCOption(config, "lxc.init.groups", formatIDs(groups))
+	}
+
+	c.makeAppArmorConfig(config)
+	c.makePIDNamespaceConfig(config)
+
+	err := makeInitCommandConfig(config, c.InitCommand)
+	if err != nil {
+		return err
+	}
+
+	err = makeTmpfsConfig(config, c.Tmpfs)
+	if err != nil {
+		return err
+	}
+
+	shm, err := shmMount(c.ShmSize)
+	if err != nil || shm == nil {
+		return err
+	}
+
+	config[cfgShmSize] = c.ShmSize
+	appendRawLXC(config, lxcMountEntry+" = "+shm.mountEntry())
+
+	return nil
+}
+
 func makeContainerConfig(c *Container) map[string]string { // nolint: gocognit
 	// default values for new containers
 	if c.ID == "" {
@@ -432,7 +896,36 @@ func makeContainerConfig(c *Container) map[string]string { // nolint: gocognit
 	config[cfgCreatedAt] = strconv.FormatInt(c.CreatedAt.UnixNano(), 10)
 	config[cfgStartedAt] = strconv.FormatInt(c.StartedAt.UnixNano(), 10)
 	config[cfgFinishedAt] = strconv.FormatInt(c.FinishedAt.UnixNano(), 10)
-	config[cfgSecurityPrivileged] = strconv.FormatBool(c.Privileged)
+	config[cfgRestartCount] = strconv.FormatUint(uint64(c.RestartCount), 10)
+	// virtual machines are never privileged, LXD refuses the key for them
+	if !c.InstanceType.IsVM() {
+		config[cfgSecurityPrivileged] = strconv.FormatBool(c.Privileged)
+	}
+
+	SetIfSet(&config, cfgInstanceType, string(c.InstanceType))
+	c.makeBootConfig(config)
+	c.makePidsLimitConfig(config)
+	c.makeMemorySwapConfig(config)
+	c.makeCPUConfig(config)
+	c.makeDeleteProtectionConfig(config)
+	c.makeHugepagesConfig(config)
+
+	if c.RunAsUser != nil {
+		config[cfgSecurityRunAsUser] = strconv.FormatInt(*c.RunAsUser, 10)
+	}
+
+	if c.RunAsGroup != nil {
+		config[cfgSecurityRunAsGroup] = strconv.FormatInt(*c.RunAsGroup, 10)
+	}
+
+	if len(c.SupplementalGroups) > 0 {
+		config[cfgSecurityGroups] = formatIDs(c.SupplementalGroups)
+	}
+
+	if c.FSGroup != nil {
+		config[cfgSecurityFSGroup] = strconv.FormatInt(*c.FSGroup, 10)
+	}
+
 	config[cfgLogPath] = c.LogPath
 	config[cfgIsCRI] = strconv.FormatBool(true)
 	config[cfgMetaName] = c.Metadata.Name
@@ -443,6 +936,9 @@ func makeContainerConfig(c *Container) map[string]string { // nolint: gocognit
 		config[cfgEnvironmentPrefix+"."+k] = v
 	}
 
+	c.makeTimezoneConfig(config)
+	c.makeKeepImageTemplatesConfig(config)
+
 	// and meta-data & cloud-init
 	// fields should not exist when there's nothing
 	if c.CloudInitMetaData != "" {
@@ -470,14 +966,6 @@ func makeContainerConfig(c *Container) map[string]string { // nolint: gocognit
 			if c.Resources.CPU.Period != nil {
 				config[cfgResourcesCPUPeriod] = strconv.FormatUint(*c.Resources.CPU.Period, 10)
 			}
-
-			if c.Resources.CPU.Quota != nil && *c.Resources.CPU.Quota > 0 && c.Resources.CPU.Period != nil && *c.Resources.CPU.Period > 0 {
-				// nolint:gomnd
-				config[cfgLimitCPUAllowance] = fmt.Sprintf("%dms/%dms",
-					int(math.Ceil(float64(*c.Resources.CPU.Quota)/1000)),
-					int(math.Ceil(float64(*c.Resources.CPU.Period)/1000)),
-				)
-			}
 		}
 
 		if c.Resources.Memory != nil {
@@ -0,0 +1,340 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainer_Apply_CreateRequiredFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		setup func(c *Container)
+	}{
+		{"missing name", func(c *Container) { c.Image = "busybox" }},
+		{"missing image", func(c *Container) { c.Metadata.Name = "foo" }},
+		{"missing profiles", func(c *Container) { c.Metadata.Name = "foo"; c.Image = "busybox"; c.Profiles = nil }},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := testClient()
+			c := client.NewContainer("sandboxID")
+			tt.setup(c)
+
+			err := c.Apply()
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, ErrUsage))
+			assert.Empty(t, c.ID)
+			assert.Equal(t, 0, fake.GetProfileCallCount())
+			assert.Equal(t, 0, fake.CreateContainerCallCount())
+		})
+	}
+}
+
+func TestContainer_Apply_DeviceNameCollision(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.Devices = device.Devices{&device.Disk{KeyName: "data"}, &device.Block{KeyName: "data"}}
+
+	err := c.Apply()
+	assert.Error(t, err)
+	assert.Equal(t, 0, fake.GetProfileCallCount())
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
+
+func TestContainer_Apply_NegativeIDs(t *testing.T) {
+	t.Parallel()
+
+	negative := int64(-1)
+
+	tests := []struct {
+		name  string
+		setup func(c *Container)
+	}{
+		{"user", func(c *Container) { c.RunAsUser = &negative }},
+		{"group", func(c *Container) { c.RunAsGroup = &negative }},
+		{"supplemental group", func(c *Container) { c.SupplementalGroups = []int64{10, negative} }},
+		{"fs group", func(c *Container) { c.FSGroup = &negative }},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := testClient()
+			c := client.NewContainer("sandboxID")
+			c.Metadata.Name = "foo"
+			c.Image = "busybox"
+			tt.setup(c)
+
+			err := c.Apply()
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, ErrUsage))
+			assert.Equal(t, 0, fake.GetProfileCallCount())
+			assert.Equal(t, 0, fake.CreateContainerCallCount())
+		})
+	}
+}
+
+func TestContainer_makeRawLXCConfig_Merged(t *testing.T) {
+	t.Parallel()
+
+	uid := int64(1000)
+	c := &Container{RunAsUser: &uid, Tmpfs: []TmpfsMount{{Path: "/cache"}}}
+	c.RawLXC = []string{"lxc.hook.pre-start = /bin/true", "lxc.init.uid = 0", "lxc.hook.pre-start = /bin/true"}
+
+	config := map[string]string{}
+	err := c.makeRawLXCConfig(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"lxc.hook.pre-start = /bin/true",
+		"lxc.init.uid = 1000",
+		"lxc.mount.entry = tmpfs cache tmpfs rw,nosuid,nodev,create=dir 0 0",
+	}, rawLXCLines(config))
+
+	// reading the lines back and applying again keeps them stable
+	c.RawLXC = rawLXCLines(config)
+
+	err = c.makeRawLXCConfig(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, c.RawLXC, rawLXCLines(config))
+}
+
+func TestContainer_makeRawLXCConfig_Sandbox(t *testing.T) {
+	t.Parallel()
+
+	sb := &Sandbox{}
+	sb.RawLXC = []string{"lxc.hook.pre-start = /bin/sandbox"}
+	sb.DeviceCgroupRules = []DeviceCgroupRule{testGPURule}
+	sb.AppArmorUnconfined = true
+
+	uid := int64(1000)
+	c := &Container{RunAsUser: &uid}
+	c.RawLXC = []string{"lxc.include = /foo"}
+
+	config := map[string]string{}
+	err := c.makeRawLXCConfig(config, sb)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"lxc.hook.pre-start = /bin/sandbox",
+		testGPURule.rawLXC(),
+		"lxc.include = /foo",
+		"lxc.init.uid = 1000",
+	}, rawLXCLines(config))
+
+	// the unprivileged container isn't unconfined like the sandbox
+	assert.NotContains(t, config[cfgRawLXC], lxcAppArmorProfile)
+
+	// reading back only returns the lines of the container
+	rules, lines := splitDeviceCgroupRules(parseSandboxRawLXCConfig(config, rawLXCLines(config)))
+	assert.Empty(t, rules)
+	assert.Equal(t, []string{"lxc.include = /foo", "lxc.init.uid = 1000"}, lines)
+}
+
+func TestContainer_makeRawLXCConfig_Shm(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{Tmpfs: []TmpfsMount{{Path: "/cache"}}}
+	c.ShmSize = "1MiB"
+
+	config := map[string]string{}
+	err := c.makeRawLXCConfig(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1MiB", config[cfgShmSize])
+	assert.Equal(t, []string{
+		"lxc.mount.entry = tmpfs cache tmpfs rw,nosuid,nodev,create=dir 0 0",
+		"lxc.mount.entry = tmpfs dev/shm tmpfs rw,nosuid,nodev,create=dir,size=1048576 0 0",
+	}, rawLXCLines(config))
+
+	// the shm mount is no user defined tmpfs
+	tmpfs, err := parseTmpfsConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, c.Tmpfs, tmpfs)
+}
+
+func TestContainer_withSandbox(t *testing.T) {
+	t.Parallel()
+
+	s := &Sandbox{}
+	s.ShmSize = "64MB"
+	s.DeviceCgroupRules = []DeviceCgroupRule{testGPURule}
+	s.Architecture = "arm64"
+	s.AppArmorUnconfined = true
+	s.InstanceType = InstanceTypeVM
+
+	c := &Container{}
+	eff := c.withSandbox(s)
+	assert.Equal(t, "64MB", eff.ShmSize)
+	assert.Equal(t, []DeviceCgroupRule{testGPURule}, eff.DeviceCgroupRules)
+	assert.Equal(t, "arm64", eff.Architecture)
+	assert.Equal(t, InstanceTypeVM, eff.InstanceType)
+	// unprivileged containers of an unconfined pod keep their profile
+	assert.False(t, eff.AppArmorUnconfined)
+	// the container itself is left unchanged
+	assert.Equal(t, &Container{}, c)
+
+	c = &Container{}
+	c.ID = "foo"
+	c.Privileged = true
+	c.ShmSize = "128MB"
+	eff = c.withSandbox(s)
+	assert.Equal(t, "128MB", eff.ShmSize)
+	assert.True(t, eff.AppArmorUnconfined)
+	assert.False(t, c.AppArmorUnconfined)
+	// the instance type of an existing container is kept
+	assert.Equal(t, InstanceType(""), eff.InstanceType)
+}
+
+func TestContainer_Apply_SandboxSettingsNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetImageAliasReturns(&api.ImageAliasesEntry{ImageAliasesEntryPut: api.ImageAliasesEntryPut{Target: "abc"}}, "", nil)
+	fake.GetServerResourcesReturns(&api.Resources{Memory: api.ResourcesMemory{Total: 1 << 30}}, nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.GetContainerReturns(basicContainer("foo", "sandbox"), "etag2", nil)
+
+	c := client.NewContainer("sandbox")
+	c.ID = "foo"
+	c.ETag = "etag"
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.sandbox = &Sandbox{}
+	c.sandbox.ShmSize = "64MB"
+
+	hash := c.ConfigHash()
+
+	err := c.Apply()
+	assert.NoError(t, err)
+	assert.Empty(t, c.ShmSize)
+	assert.Equal(t, hash, c.AppliedConfigHash)
+
+	// the shm of the sandbox is mounted, but not recorded as the one of the container
+	_, put, _ := fake.UpdateContainerArgsForCall(0)
+	assert.Contains(t, put.Config[cfgRawLXC], "tmpfs dev/shm")
+	assert.NotContains(t, put.Config, cfgShmSize)
+	assert.Equal(t, hash, put.Config[cfgConfigHash])
+}
+
+func TestContainer_validateWithSandbox_Unchanged(t *testing.T) {
+	t.Parallel()
+
+	s := &Sandbox{}
+	s.ShmSize = "64MB"
+	s.Architecture = "arm64"
+	s.AppArmorUnconfined = true
+
+	c := &Container{}
+	c.ID = "foo"
+	c.Privileged = true
+	c.Architecture = "arm64"
+
+	err := c.validateWithSandbox(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "", c.ShmSize)
+	assert.Equal(t, "arm64", c.Architecture)
+	assert.False(t, c.AppArmorUnconfined)
+}
+
+func TestContainer_validateWithSandbox_AppArmorUnprivileged(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{}
+	c.ID = "foo"
+	c.AppArmorUnconfined = true
+
+	err := c.validateWithSandbox(&Sandbox{})
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestContainer_validateWithSandbox_InvalidShmSize(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{}
+	c.ID = "foo"
+	c.ShmSize = "lots"
+
+	err := c.validateWithSandbox(&Sandbox{})
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func Test_makeContainerConfig_RestartCount(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{RestartCount: 2}
+	c.ID = "foo"
+
+	config := makeContainerConfig(c)
+	assert.Equal(t, "2", config[cfgRestartCount])
+}
+
+func TestContainer_Stop_Ephemeral(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.UpdateContainerStateReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.GetProfileReturns(basicProfile("sandboxID"), "", nil)
+
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+	c.Ephemeral = true
+
+	err := c.Stop(30)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.UpdateContainerStateCallCount())
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestContainer_State_NetworkStats(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerStateReturns(&api.ContainerState{
+		StatusCode: api.Running,
+		Network: map[string]api.ContainerStateNetwork{
+			"eth0": {Type: "broadcast", Counters: api.ContainerStateNetworkCounters{BytesReceived: 100, BytesSent: 200, PacketsReceived: 3, PacketsSent: 4}},
+			"net1": {Type: "broadcast", Counters: api.ContainerStateNetworkCounters{BytesReceived: 10, PacketsReceived: 1}},
+			"lo":   {Type: "loopback", Counters: api.ContainerStateNetworkCounters{BytesReceived: 5, BytesSent: 5}},
+		},
+	}, "", nil)
+
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+
+	st, err := c.State()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]InterfaceStats{
+		"eth0": {RxBytes: 100, TxBytes: 200, RxPackets: 3, TxPackets: 4},
+		"net1": {RxBytes: 10, RxPackets: 1},
+	}, st.Stats.Network)
+	assert.Equal(t, InterfaceStats{RxBytes: 100, TxBytes: 200, RxPackets: 3, TxPackets: 4}, st.Stats.PrimaryNetwork())
+}
+
+func TestContainer_State_NetworkStatsStopped(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerStateReturns(&api.ContainerState{StatusCode: api.Stopped}, "", nil)
+
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+
+	st, err := c.State()
+	assert.NoError(t, err)
+	assert.Empty(t, st.Stats.Network)
+	assert.Equal(t, InterfaceStats{}, st.Stats.PrimaryNetwork())
+}
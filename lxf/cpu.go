@@ -0,0 +1,126 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+const (
+	cfgLimitCPUAllowance = "limits.cpu.allowance"
+	cfgLimitCPUPriority  = "limits.cpu.priority"
+)
+
+const (
+	// maxCPUPriority is the highest cpu priority of LXD, which is also its default
+	maxCPUPriority = 10
+	// sharesPerCPU are the cpu shares kubernetes assigns to a request of one cpu
+	sharesPerCPU = 1024
+	// maxCPUAllowancePercent is the highest soft limit, which leaves the instance the cpu time it can get
+	maxCPUAllowancePercent = 100
+)
+
+var (
+	// cpuAllowancePercent is a soft limit like 50%, which only applies while the cpus are busy
+	cpuAllowancePercent = regexp.MustCompile(`^(\d+)%$`)
+	// cpuAllowanceTime is a hard limit like 25ms/100ms, the cpu time the instance gets within each period
+	cpuAllowanceTime = regexp.MustCompile(`^(\d+)ms/(\d+)ms$`)
+)
+
+// CPUPriorityFromShares returns the cpu priority of LXD for cpu shares of kubernetes. The shares of one cpu get the
+// highest priority, smaller requests get a priority of one per tenth of a cpu, rounded up. Zero shares return zero,
+// which leaves the priority to LXD
+func CPUPriorityFromShares(shares uint64) int {
+	if shares == 0 {
+		return 0
+	}
+
+	priority := int(math.Ceil(float64(shares) * maxCPUPriority / sharesPerCPU))
+	if priority > maxCPUPriority {
+		return maxCPUPriority
+	}
+
+	return priority
+}
+
+// CPUAllowanceFromQuota returns the cpu allowance of LXD for a cpu quota and period in microseconds, e.g. 25ms/100ms.
+// LXD only takes milliseconds, so both are rounded up. The allowance is empty if either isn't set
+func CPUAllowanceFromQuota(quota int64, period uint64) string {
+	if quota <= 0 || period == 0 {
+		return ""
+	}
+
+	// nolint:gomnd
+	return fmt.Sprintf("%dms/%dms", int(math.Ceil(float64(quota)/1000)), int(math.Ceil(float64(period)/1000)))
+}
+
+// validateCPU checks the cpu priority is within the range of LXD and the allowance is either a percentage or a time
+// within a period
+func (o *LXDObject) validateCPU() error {
+	if o.CPUPriority < 0 || o.CPUPriority > maxCPUPriority {
+		return fmt.Errorf("%w: cpu priority must be between 0 and %d: %d", ErrUsage, maxCPUPriority, o.CPUPriority)
+	}
+
+	if o.CPUAllowance == "" {
+		return nil
+	}
+
+	if m := cpuAllowancePercent.FindStringSubmatch(o.CPUAllowance); m != nil {
+		percent, err := strconv.Atoi(m[1])
+		if err != nil || percent < 1 || percent > maxCPUAllowancePercent {
+			return fmt.Errorf("%w: cpu allowance must be between 1%% and %d%%: %v", ErrUsage, maxCPUAllowancePercent,
+				o.CPUAllowance)
+		}
+
+		return nil
+	}
+
+	if m := cpuAllowanceTime.FindStringSubmatch(o.CPUAllowance); m != nil {
+		quota, qErr := strconv.Atoi(m[1])
+		period, pErr := strconv.Atoi(m[2])
+
+		if qErr != nil || pErr != nil || quota < 1 || period < 1 {
+			return fmt.Errorf("%w: cpu allowance must have a time and period of at least 1ms: %v", ErrUsage, o.CPUAllowance)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: cpu allowance must be a percentage like 50%% or a time within a period like 25ms/100ms: %v",
+		ErrUsage, o.CPUAllowance)
+}
+
+// makeCPUConfig writes the cpu priority and allowance to config, the unset ones are left to LXD. Virtual machines get
+// neither, LXD limits them by their number of cpus only
+func (o *LXDObject) makeCPUConfig(config map[string]string) {
+	if o.InstanceType.IsVM() {
+		return
+	}
+
+	if o.CPUPriority > 0 {
+		config[cfgLimitCPUPriority] = strconv.Itoa(o.CPUPriority)
+	}
+
+	SetIfSet(&config, cfgLimitCPUAllowance, o.CPUAllowance)
+}
+
+// parseCPUConfig reads the cpu priority and allowance from config
+func (o *LXDObject) parseCPUConfig(config map[string]string) error {
+	o.CPUAllowance = config[cfgLimitCPUAllowance]
+	o.CPUPriority = 0
+
+	v, has := config[cfgLimitCPUPriority]
+	if !has {
+		return nil
+	}
+
+	priority, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrParse, cfgLimitCPUPriority, err)
+	}
+
+	o.CPUPriority = priority
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPUPriorityFromShares(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, CPUPriorityFromShares(0))
+	assert.Equal(t, 1, CPUPriorityFromShares(2))
+	assert.Equal(t, 1, CPUPriorityFromShares(102))
+	assert.Equal(t, 5, CPUPriorityFromShares(512))
+	assert.Equal(t, 10, CPUPriorityFromShares(1024))
+	assert.Equal(t, 10, CPUPriorityFromShares(4096))
+}
+
+func TestCPUAllowanceFromQuota(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", CPUAllowanceFromQuota(0, 100000))
+	assert.Equal(t, "", CPUAllowanceFromQuota(-1, 100000))
+	assert.Equal(t, "", CPUAllowanceFromQuota(50000, 0))
+	assert.Equal(t, "50ms/100ms", CPUAllowanceFromQuota(50000, 100000))
+	assert.Equal(t, "2ms/100ms", CPUAllowanceFromQuota(1500, 100000))
+}
+
+func TestLXDObject_validateCPU(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		priority  int
+		allowance string
+		valid     bool
+	}{
+		{"Unset", 0, "", true},
+		{"Priority", 10, "", true},
+		{"NegativePriority", -1, "", false},
+		{"PriorityTooHigh", 11, "", false},
+		{"Percent", 0, "50%", true},
+		{"ZeroPercent", 0, "0%", false},
+		{"PercentTooHigh", 0, "101%", false},
+		{"Time", 0, "25ms/100ms", true},
+		{"MoreThanPeriod", 0, "200ms/100ms", true},
+		{"ZeroPeriod", 0, "25ms/0ms", false},
+		{"Seconds", 0, "1s/2s", false},
+		{"Garbage", 0, "half", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := (&LXDObject{CPUPriority: tt.priority, CPUAllowance: tt.allowance}).validateCPU()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func TestLXDObject_makeCPUConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{CPUPriority: 5, CPUAllowance: "25ms/100ms"}
+	config := map[string]string{}
+
+	o.makeCPUConfig(config)
+	assert.Equal(t, map[string]string{cfgLimitCPUPriority: "5", cfgLimitCPUAllowance: "25ms/100ms"}, config)
+
+	r := &LXDObject{}
+	err := r.parseCPUConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeCPUConfig_VM(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	(&LXDObject{CPUPriority: 5, CPUAllowance: "50%", InstanceType: InstanceTypeVM}).makeCPUConfig(config)
+	assert.Empty(t, config)
+}
+
+func TestLXDObject_parseCPUConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	err := (&LXDObject{}).parseCPUConfig(map[string]string{cfgLimitCPUPriority: "high"})
+	assert.True(t, errors.Is(err, ErrParse))
+}
+
+func TestContainer_Apply_InvalidCPUAllowance(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.CPUAllowance = "half"
+
+	err := c.Apply()
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
@@ -0,0 +1,84 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/automaticserver/lxe/shared"
+)
+
+const cfgSecurityProtectionDelete = "security.protection.delete"
+
+// ErrDeleteProtected is returned if an instance or sandbox can't be deleted, as it has the delete protection set
+var ErrDeleteProtected = errors.New("delete protected")
+
+// isDeleteProtectedError detects if LXD refused to delete an instance for its protection, which it only reports in the
+// message, e.g. "Instance is protected"
+func isDeleteProtectedError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "protected")
+}
+
+// isTrue reads a boolean config value like LXD does, anything it can't parse is false
+func isTrue(v string) bool {
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+// makeDeleteProtectionConfig writes the delete protection to config, if it's set
+func (o *LXDObject) makeDeleteProtectionConfig(config map[string]string) {
+	if o.DeleteProtected {
+		config[cfgSecurityProtectionDelete] = strconv.FormatBool(true)
+	}
+}
+
+// parseDeleteProtectionConfig reads the delete protection from config
+func (o *LXDObject) parseDeleteProtectionConfig(config map[string]string) {
+	o.DeleteProtected = isTrue(config[cfgSecurityProtectionDelete])
+}
+
+// forceDelete returns true if the delete protection is to be cleared when deleting the object, either as it's set on
+// the object or on the client for all
+func (o *LXDObject) forceDelete() bool {
+	return o.ForceDelete || (o.client != nil && o.client.conn.ForceDeleteProtected)
+}
+
+// clearDeleteProtection lifts the delete protection of container id. A protection inherited from its sandbox is
+// overridden in the config of the container, as the profile is shared with the other containers of the sandbox
+func (l *client) clearDeleteProtection(id string) error {
+	ct, etag, err := l.GetServer().GetContainer(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if !isTrue(ct.ExpandedConfig[cfgSecurityProtectionDelete]) {
+		return nil
+	}
+
+	put := ct.Writable()
+	if put.Config == nil {
+		put.Config = make(map[string]string)
+	}
+
+	put.Config[cfgSecurityProtectionDelete] = strconv.FormatBool(false)
+
+	err = l.opWait().UpdateContainer(id, put, etag)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return nil
+		} else if shared.IsErrETagMismatch(err) {
+			return fmt.Errorf("clear delete protection of container %v: %w", id, ErrETagConflict)
+		}
+
+		return err
+	}
+
+	log.WithField("containerid", id).Info("cleared delete protection to delete container")
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package lxf
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXDObject_makeDeleteProtectionConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{DeleteProtected: true}
+	config := map[string]string{}
+
+	o.makeDeleteProtectionConfig(config)
+	assert.Equal(t, map[string]string{cfgSecurityProtectionDelete: "true"}, config)
+
+	r := &LXDObject{}
+	r.parseDeleteProtectionConfig(config)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeDeleteProtectionConfig_Unset(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	(&LXDObject{}).makeDeleteProtectionConfig(config)
+	assert.Empty(t, config)
+}
+
+func TestContainer_DeleteContext_Protected(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.DeleteContainerReturns(nil, errors.New("Instance is protected"))
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client}}
+
+	err := c.DeleteContext(context.Background())
+	assert.True(t, errors.Is(err, ErrDeleteProtected))
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestContainer_DeleteContext_ForceInherited(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "sandbox")
+	ct.ExpandedConfig = map[string]string{cfgSecurityProtectionDelete: "true"}
+
+	fake.GetContainerReturns(ct, "etag", nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.DeleteContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client, ForceDelete: true}}
+
+	err := c.DeleteContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.UpdateContainerCallCount())
+
+	_, put, etag := fake.UpdateContainerArgsForCall(0)
+	assert.Equal(t, "etag", etag)
+	assert.Equal(t, "false", put.Config[cfgSecurityProtectionDelete])
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+}
+
+func TestContainer_DeleteContext_ForceUnprotected(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerReturns(basicContainer("foo", "sandbox"), "etag", nil)
+	fake.DeleteContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client, ForceDelete: true}}
+
+	err := c.DeleteContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+}
+
+func TestSandbox_Delete_Protected(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	s := &Sandbox{LXDObject: LXDObject{ID: "foo", client: client, DeleteProtected: true}}
+
+	err := s.Delete()
+	assert.True(t, errors.Is(err, ErrDeleteProtected))
+	assert.Equal(t, 0, fake.DeleteProfileCallCount())
+
+	s.ForceDelete = true
+
+	err = s.Delete()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DeleteProfileCallCount())
+}
+
+func TestSandbox_Delete_ProtectedForcedByClient(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	client.conn.ForceDeleteProtected = true
+
+	ct := basicContainer("bar", "foo")
+	ct.ExpandedConfig = map[string]string{cfgSecurityProtectionDelete: "true"}
+
+	fake.GetContainerReturns(ct, "etag", nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.DeleteContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	// removing a protected pod deletes its containers first, then the sandbox
+	c := &Container{LXDObject: LXDObject{ID: "bar", client: client}}
+
+	err := c.DeleteContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.UpdateContainerCallCount())
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+
+	s := &Sandbox{LXDObject: LXDObject{ID: "foo", client: client, DeleteProtected: true}}
+
+	err = s.Delete()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DeleteProfileCallCount())
+}
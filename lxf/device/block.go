@@ -1,7 +1,11 @@
 // nolint: dupl
 package device // import "github.com/automaticserver/lxe/lxf/device"
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/juju/errors"
+)
 
 const (
 	BlockType = "unix-block"
@@ -12,6 +16,11 @@ type Block struct {
 	KeyName string
 	Path    string
 	Source  string
+	// Major and Minor device number, if the device isn't looked up by Source
+	Major string
+	Minor string
+	// Mode is the octal access mode of the device in the container
+	Mode string
 }
 
 func (d *Block) getName() string {
@@ -35,6 +44,9 @@ func (d *Block) ToMap() (string, map[string]string) {
 		"type":   BlockType,
 		"source": d.Source,
 		"path":   d.Path,
+		"major":  d.Major,
+		"minor":  d.Minor,
+		"mode":   d.Mode,
 	}
 }
 
@@ -43,6 +55,22 @@ func (d *Block) FromMap(name string, options map[string]string) error {
 	d.KeyName = name
 	d.Path = options["path"]
 	d.Source = options["source"]
+	d.Major = options["major"]
+	d.Minor = options["minor"]
+	d.Mode = options["mode"]
+
+	return nil
+}
+
+// validate checks the device can be found on the host, either by source path or by major and minor number
+func (d *Block) validate() error {
+	if d.Source == "" && (d.Major == "" || d.Minor == "") {
+		return errors.NotValidf("%v device %v without source or major and minor", BlockType, d.getName())
+	}
+
+	if d.Source == "" && d.Path == "" {
+		return errors.NotValidf("%v device %v without source or path", BlockType, d.getName())
+	}
 
 	return nil
 }
@@ -45,7 +45,7 @@ func TestBlock_ToMap(t *testing.T) {
 	t.Parallel()
 
 	d := &Block{KeyName: "foo", Path: "bar", Source: "baz"}
-	exp := map[string]string{"type": BlockType, "path": "bar", "source": "baz"}
+	exp := map[string]string{"type": BlockType, "path": "bar", "source": "baz", "major": "", "minor": "", "mode": ""}
 	n, m := d.ToMap()
 	assert.Equal(t, "foo", n)
 	assert.Equal(t, exp, m)
@@ -54,10 +54,44 @@ func TestBlock_ToMap(t *testing.T) {
 func TestBlock_FromMap(t *testing.T) {
 	t.Parallel()
 
-	raw := map[string]string{"type": BlockType, "path": "bar", "source": "baz"}
-	exp := &Block{KeyName: "foo", Path: "bar", Source: "baz"}
+	raw := map[string]string{"type": BlockType, "path": "bar", "source": "baz", "major": "8", "minor": "16", "mode": "0660"}
+	exp := &Block{KeyName: "foo", Path: "bar", Source: "baz", Major: "8", Minor: "16", Mode: "0660"}
 	d := &Block{}
 	err := d.FromMap("foo", raw)
 	assert.NoError(t, err)
 	assert.Exactly(t, exp, d)
 }
+
+func TestBlock_validate_Passthrough(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   *Block
+		wantErr bool
+	}{
+		{"source", &Block{Source: "/dev/sdb", Path: "/dev/sdb"}, false},
+		{"major minor", &Block{Path: "/dev/sdb", Major: "8", Minor: "16", Mode: "0660"}, false},
+		{"major only", &Block{Path: "/dev/sdb", Major: "8"}, true},
+		{"major minor without path", &Block{Major: "8", Minor: "16"}, true},
+		{"nothing", &Block{Path: "/dev/sdb"}, true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.validate()
+			assert.False(t, (err != nil) != tt.wantErr)
+		})
+	}
+}
+
+func TestBlock_ToMap_Passthrough(t *testing.T) {
+	t.Parallel()
+
+	d := &Block{Path: "/dev/sdb", Major: "8", Minor: "16", Mode: "0660"}
+	exp := map[string]string{"type": BlockType, "path": "/dev/sdb", "source": "", "major": "8", "minor": "16", "mode": "0660"}
+	n, m := d.ToMap()
+	assert.Equal(t, BlockType+"-/dev/sdb", n)
+	assert.Equal(t, exp, m)
+}
@@ -1,7 +1,10 @@
 // nolint: dupl
 package device // import "github.com/automaticserver/lxe/lxf/device"
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 const (
 	CharType = "unix-char"
@@ -12,6 +15,11 @@ type Char struct {
 	KeyName string
 	Path    string
 	Source  string
+	// Required fails the container start if the device is missing on the host. It's left to LXD if nil, which
+	// requires it
+	Required *bool
+	// Mode is the octal access mode of the device in the container, left to LXD if empty
+	Mode string
 }
 
 func (d *Char) getName() string {
@@ -31,11 +39,21 @@ func (d *Char) getName() string {
 
 // ToMap returns assigned name or if unset the type specific unique name and serializes the options into a lxd device map
 func (d *Char) ToMap() (string, map[string]string) {
-	return d.getName(), map[string]string{
+	options := map[string]string{
 		"type":   CharType,
 		"source": d.Source,
 		"path":   d.Path,
 	}
+
+	if d.Required != nil {
+		options["required"] = strconv.FormatBool(*d.Required)
+	}
+
+	if d.Mode != "" {
+		options["mode"] = d.Mode
+	}
+
+	return d.getName(), options
 }
 
 // FromMap loads assigned name (can be empty) and options
@@ -43,6 +61,13 @@ func (d *Char) FromMap(name string, options map[string]string) error {
 	d.KeyName = name
 	d.Path = options["path"]
 	d.Source = options["source"]
+	d.Required = nil
+	d.Mode = options["mode"]
+
+	if v, has := options["required"]; has {
+		required := v != "false"
+		d.Required = &required
+	}
 
 	return nil
 }
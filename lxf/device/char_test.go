@@ -61,3 +61,25 @@ func TestChar_FromMap(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Exactly(t, exp, d)
 }
+
+func TestChar_FromMap_Optional(t *testing.T) {
+	t.Parallel()
+
+	required := false
+	raw := map[string]string{"type": CharType, "path": "/dev/fuse", "required": "false", "mode": "0666"}
+	exp := &Char{KeyName: "foo", Path: "/dev/fuse", Required: &required, Mode: "0666"}
+	d := &Char{}
+	err := d.FromMap("foo", raw)
+	assert.NoError(t, err)
+	assert.Exactly(t, exp, d)
+}
+
+func TestChar_ToMap_Optional(t *testing.T) {
+	t.Parallel()
+
+	required := false
+	d := &Char{KeyName: "foo", Path: "/dev/fuse", Required: &required, Mode: "0666"}
+	exp := map[string]string{"type": CharType, "path": "/dev/fuse", "source": "", "required": "false", "mode": "0666"}
+	_, m := d.ToMap()
+	assert.Equal(t, exp, m)
+}
@@ -1,6 +1,8 @@
 package device // import "github.com/automaticserver/lxe/lxf/device"
 
 import (
+	"strings"
+
 	"github.com/juju/errors"
 )
 
@@ -25,6 +27,11 @@ type Device interface {
 	new() Device
 }
 
+// validator is implemented by devices which can detect misconfigurations before they are sent to lxd
+type validator interface {
+	validate() error
+}
+
 // Detects and loads device by type
 func Detect(name string, options map[string]string) (Device, error) {
 	t, is := schema[options["type"]]
@@ -42,6 +49,11 @@ func Detect(name string, options map[string]string) (Device, error) {
 	return d, nil
 }
 
+// IsUnknownType reports if err was returned by Detect because the device type is not supported
+func IsUnknownType(err error) bool {
+	return errors.IsNotSupported(err)
+}
+
 // Devices allows having a list of devices unique by name
 type Devices []Device
 
@@ -59,3 +71,144 @@ func (d *Devices) Upsert(a Device) {
 
 	*d = append(*d, a)
 }
+
+// Mask replaces the device with given name by a None device, which also hides a device of that name inherited from
+// a profile
+func (d *Devices) Mask(name string) {
+	d.Upsert(&None{KeyName: name})
+}
+
+// Validate checks that no device name is used more than once, as they would override each other in the lxd device map,
+// and every device is configured properly
+func (d Devices) Validate() error {
+	names := make(map[string]bool, len(d))
+
+	for _, e := range d {
+		name, _ := e.ToMap()
+		if names[name] {
+			return errors.NotValidf("duplicate device name %q", name)
+		}
+
+		names[name] = true
+
+		if v, is := e.(validator); is {
+			err := v.validate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return d.validateBootPriorities()
+}
+
+// validateBootPriorities checks that the disk with the highest boot priority is the only one with it, otherwise it's
+// ambiguous which one boots
+func (d Devices) validateBootPriorities() error {
+	var first []string
+
+	highest := 0
+
+	for _, e := range d {
+		disk, is := e.(*Disk)
+		if !is || disk.BootPriority == 0 {
+			continue
+		}
+
+		switch {
+		case disk.BootPriority > highest:
+			highest = disk.BootPriority
+			first = []string{disk.getName()}
+		case disk.BootPriority == highest:
+			first = append(first, disk.getName())
+		}
+	}
+
+	if len(first) > 1 {
+		return errors.NotValidf("boot priority %d of %v devices %v, only one can boot first", highest, DiskType, strings.Join(first, ", "))
+	}
+
+	return nil
+}
+
+// Diff compares the devices by name with current. It returns the devices which are missing in current or whose
+// options differ from the device of the same name in current, and the devices of current which are missing in d
+func (d Devices) Diff(current Devices) (upsert, remove Devices) {
+	have := make(map[string]map[string]string, len(current))
+
+	for _, e := range current {
+		name, options := e.ToMap()
+		have[name] = options
+	}
+
+	want := make(map[string]bool, len(d))
+
+	for _, e := range d {
+		name, options := e.ToMap()
+		want[name] = true
+
+		if !equalOptions(options, have[name]) {
+			upsert = append(upsert, e)
+		}
+	}
+
+	for _, e := range current {
+		name, _ := e.ToMap()
+		if !want[name] {
+			remove = append(remove, e)
+		}
+	}
+
+	return upsert, remove
+}
+
+// Collection holds devices grouped by their type. Devices of a type LXE doesn't know are kept in Unknowns
+type Collection struct {
+	Blocks   []*Block
+	Chars    []*Char
+	Disks    []*Disk
+	Nics     []*Nic
+	Nones    []*None
+	Proxies  []*Proxy
+	Unknowns []*Unknown
+}
+
+// Collect groups the devices by their type, keeping their order
+func (d Devices) Collect() *Collection {
+	c := &Collection{}
+
+	for _, e := range d {
+		switch e := e.(type) {
+		case *Block:
+			c.Blocks = append(c.Blocks, e)
+		case *Char:
+			c.Chars = append(c.Chars, e)
+		case *Disk:
+			c.Disks = append(c.Disks, e)
+		case *Nic:
+			c.Nics = append(c.Nics, e)
+		case *None:
+			c.Nones = append(c.Nones, e)
+		case *Proxy:
+			c.Proxies = append(c.Proxies, e)
+		case *Unknown:
+			c.Unknowns = append(c.Unknowns, e)
+		}
+	}
+
+	return c
+}
+
+func equalOptions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if w, has := b[k]; !has || v != w {
+			return false
+		}
+	}
+
+	return true
+}
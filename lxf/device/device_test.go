@@ -13,6 +13,7 @@ func TestDetect_UnknownType(t *testing.T) {
 	_, err := Detect("foo", map[string]string{"type": "foo"})
 	assert.Error(t, err)
 	assert.Equal(t, true, errors.IsNotSupported(err))
+	assert.True(t, IsUnknownType(err))
 }
 
 func TestDetect_KnownType(t *testing.T) {
@@ -59,3 +60,105 @@ func TestDevices_Upsert_Override(t *testing.T) {
 	assert.Len(t, d, 1)
 	assert.Exactly(t, disk, d[0])
 }
+
+func TestDevices_Validate_Unique(t *testing.T) {
+	t.Parallel()
+
+	d := Devices{&None{KeyName: "foo"}, &Disk{KeyName: "bar"}}
+
+	assert.NoError(t, d.Validate())
+}
+
+func TestDevices_Validate_Collision(t *testing.T) {
+	t.Parallel()
+
+	d := Devices{&None{KeyName: "foo"}, &Disk{Path: "/mnt"}, &Nic{KeyName: "foo"}}
+
+	err := d.Validate()
+	assert.Error(t, err)
+	assert.True(t, errors.IsNotValid(err))
+	assert.Contains(t, err.Error(), `"foo"`)
+}
+
+func TestDevices_Validate_BootPriorities(t *testing.T) {
+	t.Parallel()
+
+	d := Devices{
+		&Disk{KeyName: "root", Path: "/", Pool: "default", BootPriority: 10},
+		&Disk{KeyName: "data", Path: "/data", Pool: "default", BootPriority: 5},
+		&Disk{KeyName: "scratch", Path: "/scratch", Pool: "default", BootPriority: 5},
+		&Disk{KeyName: "other", Path: "/other", Pool: "default"},
+	}
+	assert.NoError(t, d.Validate())
+
+	d.Upsert(&Disk{KeyName: "iso", Source: "/srv/boot.iso", BootPriority: 10})
+
+	err := d.Validate()
+	assert.True(t, errors.IsNotValid(err))
+	assert.Contains(t, err.Error(), "root, iso")
+}
+
+func TestDevices_Mask(t *testing.T) {
+	t.Parallel()
+
+	d := Devices{&Nic{KeyName: "eth0", NicType: "bridged"}, &Disk{KeyName: "root"}}
+	d.Mask("eth0")
+	d.Mask("eth1")
+
+	assert.Len(t, d, 3)
+	assert.Exactly(t, &None{KeyName: "eth0"}, d[0])
+	assert.Exactly(t, &None{KeyName: "eth1"}, d[2])
+}
+
+func TestDevices_Diff(t *testing.T) {
+	t.Parallel()
+
+	desired := Devices{
+		&None{KeyName: "same"},
+		&Disk{KeyName: "changed", Path: "/data", Source: "/srv/new"},
+		&Disk{KeyName: "added", Path: "/cache", Source: "/srv/cache"},
+	}
+	current := Devices{
+		&None{KeyName: "same"},
+		&Disk{KeyName: "changed", Path: "/data", Source: "/srv/old"},
+		&None{KeyName: "extra"},
+	}
+
+	upsert, remove := desired.Diff(current)
+	assert.Equal(t, Devices{desired[1], desired[2]}, upsert)
+	assert.Equal(t, Devices{current[2]}, remove)
+}
+
+func TestDevices_Diff_Equal(t *testing.T) {
+	t.Parallel()
+
+	d := Devices{&None{KeyName: "foo"}, &Disk{KeyName: "bar", Path: "/data"}}
+
+	upsert, remove := d.Diff(Devices{&Disk{KeyName: "bar", Path: "/data"}, &None{KeyName: "foo"}})
+	assert.Empty(t, upsert)
+	assert.Empty(t, remove)
+}
+
+func TestDevices_Collect(t *testing.T) {
+	t.Parallel()
+
+	d := Devices{
+		&Disk{KeyName: "b", Path: "/b"},
+		&Nic{KeyName: "eth0"},
+		&Unknown{KeyName: "gpu"},
+		&Disk{KeyName: "a", Path: "/a"},
+		&Proxy{KeyName: "p"},
+		&None{KeyName: "n"},
+		&Block{KeyName: "blk"},
+		&Char{KeyName: "chr"},
+	}
+
+	c := d.Collect()
+	assert.Equal(t, []*Disk{d[0].(*Disk), d[3].(*Disk)}, c.Disks)
+	assert.Equal(t, []*Nic{d[1].(*Nic)}, c.Nics)
+	assert.Equal(t, []*Unknown{d[2].(*Unknown)}, c.Unknowns)
+	assert.Equal(t, []*Proxy{d[4].(*Proxy)}, c.Proxies)
+	assert.Equal(t, []*None{d[5].(*None)}, c.Nones)
+	assert.Equal(t, []*Block{d[6].(*Block)}, c.Blocks)
+	assert.Equal(t, []*Char{d[7].(*Char)}, c.Chars)
+}
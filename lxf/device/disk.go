@@ -2,13 +2,39 @@ package device // import "github.com/automaticserver/lxe/lxf/device"
 
 import (
 	"fmt"
+	"path"
 	"strconv"
+
+	"github.com/juju/errors"
 )
 
 const (
 	DiskType = "disk"
+	// DiskOptionBootPriority is the option of the boot priority, which only virtual machines know
+	DiskOptionBootPriority = "boot.priority"
+
+	// Mount propagation modes of bind mounted disks. Unset is the same as private
+	DiskPropagationPrivate     = "private"
+	DiskPropagationShared      = "shared"
+	DiskPropagationSlave       = "slave"
+	DiskPropagationUnbindable  = "unbindable"
+	DiskPropagationRPrivate    = "rprivate"
+	DiskPropagationRShared     = "rshared"
+	DiskPropagationRSlave      = "rslave"
+	DiskPropagationRUnbindable = "runbindable"
 )
 
+var diskPropagations = map[string]bool{
+	DiskPropagationPrivate:     true,
+	DiskPropagationShared:      true,
+	DiskPropagationSlave:       true,
+	DiskPropagationUnbindable:  true,
+	DiskPropagationRPrivate:    true,
+	DiskPropagationRShared:     true,
+	DiskPropagationRSlave:      true,
+	DiskPropagationRUnbindable: true,
+}
+
 // Disk device representation https://lxd.readthedocs.io/en/latest/containers/#type-disk
 type Disk struct {
 	KeyName  string
@@ -18,6 +44,10 @@ type Disk struct {
 	Size     string
 	Readonly bool
 	Optional bool
+	// Propagation is the mount propagation of a bind mounted host path, one of the DiskPropagation modes
+	Propagation string
+	// BootPriority orders the disks a virtual machine boots from, the highest first. Zero leaves the order to LXD
+	BootPriority int
 }
 
 func (d *Disk) getName() string {
@@ -37,7 +67,7 @@ func (d *Disk) getName() string {
 
 // ToMap returns assigned name or if unset the type specific unique name and serializes the options into a lxd device map
 func (d *Disk) ToMap() (string, map[string]string) {
-	return d.getName(), map[string]string{
+	options := map[string]string{
 		"type":     DiskType,
 		"path":     d.Path,
 		"source":   d.Source,
@@ -46,6 +76,17 @@ func (d *Disk) ToMap() (string, map[string]string) {
 		"readonly": strconv.FormatBool(d.Readonly),
 		"optional": strconv.FormatBool(d.Optional),
 	}
+
+	// only set if requested, older lxd versions don't know the option
+	if d.Propagation != "" {
+		options["propagation"] = d.Propagation
+	}
+
+	if d.BootPriority != 0 {
+		options[DiskOptionBootPriority] = strconv.Itoa(d.BootPriority)
+	}
+
+	return d.getName(), options
 }
 
 // FromMap loads assigned name (can be empty) and options
@@ -57,6 +98,39 @@ func (d *Disk) FromMap(name string, options map[string]string) error {
 	d.Size = options["size"]
 	d.Readonly = options["readonly"] == "true"
 	d.Optional = options["optional"] == "true"
+	d.Propagation = options["propagation"]
+	d.BootPriority = 0
+
+	if v, has := options[DiskOptionBootPriority]; has {
+		priority, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.NotValidf("%v device %v with boot priority %q", DiskType, name, v)
+		}
+
+		d.BootPriority = priority
+	}
+
+	return nil
+}
+
+// validate checks the propagation is a mode lxd supports and is only requested for bind mounts, since lxd would ignore
+// it otherwise. The boot priority must not be negative
+func (d *Disk) validate() error {
+	if d.BootPriority < 0 {
+		return errors.NotValidf("%v device %v with negative boot priority %d", DiskType, d.getName(), d.BootPriority)
+	}
+
+	if d.Propagation == "" {
+		return nil
+	}
+
+	if !diskPropagations[d.Propagation] {
+		return errors.NotValidf("%v device %v with propagation %q", DiskType, d.getName(), d.Propagation)
+	}
+
+	if d.Pool != "" || !path.IsAbs(d.Source) {
+		return errors.NotValidf("%v device %v with propagation %q on a source which is not a host path", DiskType, d.getName(), d.Propagation)
+	}
 
 	return nil
 }
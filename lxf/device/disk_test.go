@@ -3,6 +3,7 @@ package device
 import (
 	"testing"
 
+	"github.com/juju/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -61,3 +62,66 @@ func TestDisk_FromMap(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Exactly(t, exp, d)
 }
+
+func TestDisk_ToMap_Propagation(t *testing.T) {
+	t.Parallel()
+
+	d := &Disk{KeyName: "foo", Path: "/bar", Source: "/baz", Propagation: DiskPropagationRShared}
+	n, m := d.ToMap()
+	assert.Equal(t, DiskPropagationRShared, m["propagation"])
+
+	r := &Disk{}
+	err := r.FromMap(n, m)
+	assert.NoError(t, err)
+	assert.Exactly(t, d, r)
+}
+
+func TestDisk_ToMap_BootPriority(t *testing.T) {
+	t.Parallel()
+
+	d := &Disk{KeyName: "root", Path: "/", Pool: "default", BootPriority: 10}
+	n, m := d.ToMap()
+	assert.Equal(t, "10", m[DiskOptionBootPriority])
+
+	r := &Disk{}
+	err := r.FromMap(n, m)
+	assert.NoError(t, err)
+	assert.Exactly(t, d, r)
+
+	_, m = (&Disk{Path: "/data"}).ToMap()
+	assert.NotContains(t, m, DiskOptionBootPriority)
+}
+
+func TestDisk_FromMap_InvalidBootPriority(t *testing.T) {
+	t.Parallel()
+
+	err := (&Disk{}).FromMap("root", map[string]string{"type": DiskType, DiskOptionBootPriority: "first"})
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestDisk_validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   *Disk
+		wantErr bool
+	}{
+		{"no propagation", &Disk{Path: "/", Pool: "default"}, false},
+		{"private", &Disk{Path: "/bar", Source: "/baz", Propagation: DiskPropagationPrivate}, false},
+		{"rslave", &Disk{Path: "/bar", Source: "/baz", Propagation: DiskPropagationRSlave}, false},
+		{"unknown", &Disk{Path: "/bar", Source: "/baz", Propagation: "bidirectional"}, true},
+		{"pool volume", &Disk{Path: "/bar", Source: "vol", Pool: "default", Propagation: DiskPropagationShared}, true},
+		{"relative source", &Disk{Path: "/bar", Source: "baz", Propagation: DiskPropagationShared}, true},
+		{"boot priority", &Disk{Path: "/", Pool: "default", BootPriority: 1}, false},
+		{"negative boot priority", &Disk{Path: "/", Pool: "default", BootPriority: -1}, true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.validate()
+			assert.False(t, (err != nil) != tt.wantErr)
+		})
+	}
+}
@@ -2,10 +2,16 @@ package device // import "github.com/automaticserver/lxe/lxf/device"
 
 import (
 	"fmt"
+	"net"
+	"strings"
+
+	"github.com/juju/errors"
 )
 
 const (
 	NicType = "nic"
+	// NicTypeRouted attaches the nic without a bridge, the addresses are routed to the container by the host
+	NicTypeRouted = "routed"
 )
 
 // Nic device representation https://lxd.readthedocs.io/en/latest/containers/#type-nic
@@ -15,6 +21,16 @@ type Nic struct {
 	NicType     string
 	Parent      string
 	IPv4Address string
+	IPv6Address string
+	// IPv4Gateway and IPv6Gateway of a routed nic, either "auto" to add a default route to the host or "none"
+	IPv4Gateway string
+	IPv6Gateway string
+	// IPv4HostAddress and IPv6HostAddress are the addresses of the host side of a routed nic, the gateway if "auto".
+	// Setting them per pod gives each its own point-to-point gateway instead of LXD's shared link-local default
+	IPv4HostAddress string
+	IPv6HostAddress string
+	// HostName of the host side interface of a routed nic
+	HostName string
 }
 
 func (d *Nic) getName() string {
@@ -33,11 +49,17 @@ func (d *Nic) getName() string {
 // ToMap returns assigned name or if unset the type specific unique name and serializes the options into a lxd device map
 func (d *Nic) ToMap() (string, map[string]string) {
 	return d.getName(), map[string]string{
-		"type":         NicType,
-		"name":         d.Name,
-		"nictype":      d.NicType,
-		"parent":       d.Parent,
-		"ipv4.address": d.IPv4Address,
+		"type":              NicType,
+		"name":              d.Name,
+		"nictype":           d.NicType,
+		"parent":            d.Parent,
+		"ipv4.address":      d.IPv4Address,
+		"ipv6.address":      d.IPv6Address,
+		"ipv4.gateway":      d.IPv4Gateway,
+		"ipv6.gateway":      d.IPv6Gateway,
+		"ipv4.host_address": d.IPv4HostAddress,
+		"ipv6.host_address": d.IPv6HostAddress,
+		"host_name":         d.HostName,
 	}
 }
 
@@ -48,6 +70,80 @@ func (d *Nic) FromMap(name string, options map[string]string) error {
 	d.NicType = options["nictype"]
 	d.Parent = options["parent"]
 	d.IPv4Address = options["ipv4.address"]
+	d.IPv6Address = options["ipv6.address"]
+	d.IPv4Gateway = options["ipv4.gateway"]
+	d.IPv6Gateway = options["ipv6.gateway"]
+	d.IPv4HostAddress = options["ipv4.host_address"]
+	d.IPv6HostAddress = options["ipv6.host_address"]
+	d.HostName = options["host_name"]
+
+	return nil
+}
+
+// validate checks a routed nic has at least one valid address to route and the gateways are understood by lxd. The
+// gateway and host address of a family need an address of the same family to be reachable from, and the host address
+// can't be one of the addresses of the nic itself
+func (d *Nic) validate() error {
+	if d.NicType != NicTypeRouted {
+		return nil
+	}
+
+	if d.IPv4Address == "" && d.IPv6Address == "" {
+		return errors.NotValidf("%v %v device %v without address", NicTypeRouted, NicType, d.getName())
+	}
+
+	families := []struct {
+		name        string
+		v4          bool
+		addresses   string
+		gateway     string
+		hostAddress string
+	}{
+		{"ipv4", true, d.IPv4Address, d.IPv4Gateway, d.IPv4HostAddress},
+		{"ipv6", false, d.IPv6Address, d.IPv6Gateway, d.IPv6HostAddress},
+	}
+
+	for _, f := range families {
+		var ips []net.IP
+
+		if f.addresses != "" {
+			for _, raw := range strings.Split(f.addresses, ",") {
+				ip := net.ParseIP(strings.TrimSpace(raw))
+				if ip == nil || (ip.To4() != nil) != f.v4 {
+					return errors.NotValidf("%v %v device %v with %v address %q", NicTypeRouted, NicType, d.getName(), f.name, raw)
+				}
+
+				ips = append(ips, ip)
+			}
+		}
+
+		if f.gateway != "" && f.gateway != "auto" && f.gateway != "none" {
+			return errors.NotValidf("%v %v device %v with gateway %q", NicTypeRouted, NicType, d.getName(), f.gateway)
+		}
+
+		if f.gateway == "auto" && len(ips) == 0 {
+			return errors.NotValidf("%v %v device %v with %v gateway but without %v address", NicTypeRouted, NicType, d.getName(), f.name, f.name)
+		}
+
+		if f.hostAddress == "" {
+			continue
+		}
+
+		host := net.ParseIP(f.hostAddress)
+		if host == nil || (host.To4() != nil) != f.v4 {
+			return errors.NotValidf("%v %v device %v with %v host address %q", NicTypeRouted, NicType, d.getName(), f.name, f.hostAddress)
+		}
+
+		if len(ips) == 0 {
+			return errors.NotValidf("%v %v device %v with %v host address but without %v address", NicTypeRouted, NicType, d.getName(), f.name, f.name)
+		}
+
+		for _, ip := range ips {
+			if ip.Equal(host) {
+				return errors.NotValidf("%v %v device %v with host address %v which is also its own", NicTypeRouted, NicType, d.getName(), host)
+			}
+		}
+	}
 
 	return nil
 }
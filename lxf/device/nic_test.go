@@ -30,8 +30,9 @@ func TestNic_getName_KeyNamePriority(t *testing.T) {
 func TestNic_ToMap(t *testing.T) {
 	t.Parallel()
 
-	d := &Nic{KeyName: "foo", Name: "ethX", NicType: "bridge", Parent: "brX", IPv4Address: "1.2.3.4"}
-	exp := map[string]string{"type": NicType, "name": "ethX", "nictype": "bridge", "parent": "brX", "ipv4.address": "1.2.3.4"}
+	d := &Nic{KeyName: "foo", Name: "ethX", NicType: "bridge", Parent: "brX", IPv4Address: "1.2.3.4", IPv6Address: "fd00::4"}
+	exp := map[string]string{"type": NicType, "name": "ethX", "nictype": "bridge", "parent": "brX", "ipv4.address": "1.2.3.4", "ipv6.address": "fd00::4",
+		"ipv4.gateway": "", "ipv6.gateway": "", "ipv4.host_address": "", "ipv6.host_address": "", "host_name": ""}
 	n, m := d.ToMap()
 	assert.Equal(t, "foo", n)
 	assert.Equal(t, exp, m)
@@ -40,10 +41,78 @@ func TestNic_ToMap(t *testing.T) {
 func TestNic_FromMap(t *testing.T) {
 	t.Parallel()
 
-	raw := map[string]string{"type": NicType, "name": "ethX", "nictype": "bridge", "parent": "brX", "ipv4.address": "1.2.3.4"}
-	exp := &Nic{KeyName: "foo", Name: "ethX", NicType: "bridge", Parent: "brX", IPv4Address: "1.2.3.4"}
+	raw := map[string]string{"type": NicType, "name": "ethX", "nictype": "bridge", "parent": "brX", "ipv4.address": "1.2.3.4", "ipv6.address": "fd00::4"}
+	exp := &Nic{KeyName: "foo", Name: "ethX", NicType: "bridge", Parent: "brX", IPv4Address: "1.2.3.4", IPv6Address: "fd00::4"}
 	d := &Nic{}
 	err := d.FromMap("foo", raw)
 	assert.NoError(t, err)
 	assert.Exactly(t, exp, d)
 }
+
+func TestNic_ToMap_Routed(t *testing.T) {
+	t.Parallel()
+
+	d := &Nic{KeyName: "eth0", Name: "eth0", NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4Gateway: "auto", IPv4HostAddress: "169.254.0.1", HostName: "veth-pod"}
+	exp := map[string]string{"type": NicType, "name": "eth0", "nictype": NicTypeRouted, "parent": "", "ipv4.address": "10.0.0.5", "ipv6.address": "",
+		"ipv4.gateway": "auto", "ipv6.gateway": "", "ipv4.host_address": "169.254.0.1", "ipv6.host_address": "", "host_name": "veth-pod"}
+	n, m := d.ToMap()
+	assert.Equal(t, "eth0", n)
+	assert.Equal(t, exp, m)
+
+	r := &Nic{}
+	err := r.FromMap(n, m)
+	assert.NoError(t, err)
+	assert.Exactly(t, d, r)
+}
+
+func TestNic_ToMap_RoutedOwnGateway(t *testing.T) {
+	t.Parallel()
+
+	d := &Nic{Name: "eth0", NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4Gateway: "auto", IPv4HostAddress: "10.0.0.1",
+		IPv6Address: "fd00::5", IPv6Gateway: "auto", IPv6HostAddress: "fd00::1"}
+	assert.NoError(t, d.validate())
+
+	n, m := d.ToMap()
+	assert.Equal(t, "10.0.0.1", m["ipv4.host_address"])
+	assert.Equal(t, "fd00::1", m["ipv6.host_address"])
+
+	r := &Nic{}
+	err := r.FromMap(n, m)
+	assert.NoError(t, err)
+
+	d.KeyName = n
+	assert.Exactly(t, d, r)
+}
+
+func TestNic_validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   *Nic
+		wantErr bool
+	}{
+		{"bridged", &Nic{NicType: "bridged", Parent: "brX"}, false},
+		{"routed ipv4", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4Gateway: "auto"}, false},
+		{"routed multiple", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5,10.0.0.6", IPv6Address: "fd00::5"}, false},
+		{"routed without address", &Nic{NicType: NicTypeRouted}, true},
+		{"routed invalid address", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.0/24"}, true},
+		{"routed invalid host address", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4HostAddress: "foo"}, true},
+		{"routed invalid gateway", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4Gateway: "10.0.0.1"}, true},
+		{"routed own gateway", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4Gateway: "auto", IPv4HostAddress: "10.0.0.1"}, false},
+		{"routed gateway without address of family", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv6Gateway: "auto"}, true},
+		{"routed host address without address of family", &Nic{NicType: NicTypeRouted, IPv6Address: "fd00::5", IPv4HostAddress: "10.0.0.1"}, true},
+		{"routed host address of other family", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4HostAddress: "fd00::1"}, true},
+		{"routed host address is own", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5,10.0.0.6", IPv4HostAddress: "10.0.0.6"}, true},
+		{"routed multiple host addresses", &Nic{NicType: NicTypeRouted, IPv4Address: "10.0.0.5", IPv4HostAddress: "10.0.0.1,10.0.0.2"}, true},
+		{"routed address of other family", &Nic{NicType: NicTypeRouted, IPv4Address: "fd00::5"}, true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.validate()
+			assert.False(t, (err != nil) != tt.wantErr)
+		})
+	}
+}
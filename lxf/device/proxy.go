@@ -2,6 +2,7 @@ package device // import "github.com/automaticserver/lxe/lxf/device"
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -12,11 +13,27 @@ const (
 	ProxyType = "proxy"
 )
 
+// ProxySourceIP selects how a proxy device preserves the address of the client
+type ProxySourceIP string
+
+const (
+	// ProxySourceIPHidden lets LXD connect by itself, so the instance sees the proxy as client. This is the default
+	ProxySourceIPHidden = ProxySourceIP("")
+	// ProxySourceIPNAT forwards the packets using NAT rules instead of proxying, so the client address is kept. LXD
+	// requires the connect address to be the static address of a bridged nic of the instance
+	ProxySourceIPNAT = ProxySourceIP("nat")
+	// ProxySourceIPProxyProtocol sends a HAProxy PROXY protocol header with the client address on each connection, which
+	// the service in the instance must understand
+	ProxySourceIPProxyProtocol = ProxySourceIP("proxy-protocol")
+)
+
 // Proxy device representation https://lxd.readthedocs.io/en/latest/containers/#type-proxy
 type Proxy struct {
 	KeyName     string
 	Listen      *ProxyEndpoint
 	Destination *ProxyEndpoint
+	// SourceIP selects whether and how the address of the client is preserved, by default it's hidden
+	SourceIP ProxySourceIP
 }
 
 func (d *Proxy) getName() string {
@@ -34,11 +51,73 @@ func (d *Proxy) getName() string {
 
 // ToMap returns assigned name or if unset the type specific unique name and serializes the options into a lxd device map
 func (d *Proxy) ToMap() (string, map[string]string) {
-	return d.getName(), map[string]string{
+	options := map[string]string{
 		"type":    ProxyType,
 		"listen":  d.Listen.String(),
 		"connect": d.Destination.String(),
 	}
+
+	switch d.SourceIP {
+	case ProxySourceIPNAT:
+		options["nat"] = strconv.FormatBool(true)
+	case ProxySourceIPProxyProtocol:
+		options["proxy_protocol"] = strconv.FormatBool(true)
+	}
+
+	return d.getName(), options
+}
+
+// validate checks both endpoints are complete, so a malformed address is reported with the field it's in instead of
+// failing when LXD sets up the proxy
+func (d *Proxy) validate() error {
+	if d.Listen == nil {
+		return errors.NotValidf("%v device %v without listen address", ProxyType, d.KeyName)
+	}
+
+	if d.Destination == nil {
+		return errors.NotValidf("%v device %v without connect address", ProxyType, d.getName())
+	}
+
+	for field, e := range map[string]*ProxyEndpoint{"listen": d.Listen, "connect": d.Destination} {
+		err := e.validate()
+		if err != nil {
+			return errors.NotValidf("%v device %v with %v address %q: %v", ProxyType, d.getName(), field, e.String(), err)
+		}
+	}
+
+	err := d.validateSourceIP()
+	if err != nil {
+		return errors.NotValidf("%v device %v preserving source ip by %v: %v", ProxyType, d.getName(), d.SourceIP, err)
+	}
+
+	return nil
+}
+
+// validateSourceIP returns why LXD wouldn't accept the mode of SourceIP for the endpoints, if so
+func (d *Proxy) validateSourceIP() error {
+	switch d.SourceIP {
+	case ProxySourceIPHidden:
+		return nil
+	case ProxySourceIPNAT:
+		// the packets are only rewritten, so they can't change the protocol nor the address family
+		if d.Listen.Protocol != d.Destination.Protocol {
+			return fmt.Errorf("protocol %v can't be forwarded to %v", d.Listen.Protocol, d.Destination.Protocol)
+		}
+
+		if (net.ParseIP(d.Listen.Address).To4() == nil) != (net.ParseIP(d.Destination.Address).To4() == nil) {
+			return fmt.Errorf("listen and connect address must be of the same ip family")
+		}
+
+		return nil
+	case ProxySourceIPProxyProtocol:
+		if d.Destination.Protocol != ProtocolTCP {
+			return fmt.Errorf("the header can only be sent to %v", ProtocolTCP)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown mode")
+	}
 }
 
 // New creates a new empty device
@@ -62,6 +141,15 @@ func (d *Proxy) FromMap(name string, options map[string]string) error {
 		return err
 	}
 
+	switch {
+	case options["nat"] == "true":
+		d.SourceIP = ProxySourceIPNAT
+	case options["proxy_protocol"] == "true":
+		d.SourceIP = ProxySourceIPProxyProtocol
+	default:
+		d.SourceIP = ProxySourceIPHidden
+	}
+
 	return nil
 }
 
@@ -112,32 +200,58 @@ type ProxyEndpoint struct {
 
 // NewProxyEndpoint parses a string of the form protocol:address:port
 // protocol: tcp|udp
-// address: ip or empty
+// address: ip or empty, an IPv6 address is enclosed in brackets like [::1]
 // port: uiint16
-// TODO verify and document allowed format values
+// The address and port are only parsed, see validate for what LXD accepts
 func NewProxyEndpoint(str string) (*ProxyEndpoint, error) {
-	parts := strings.Split(str, ":")
-	if len(parts) != 3 { // nolint: gomnd
+	i := strings.Index(str, ":")
+	if i < 0 {
 		return nil, errors.NotValidf("proxy endpoint must be delimited by two colons (::), we were given: `%v`", str)
 	}
 
-	prot, err := newProtocol(parts[0])
+	prot, err := newProtocol(str[:i])
 	if err != nil {
 		return nil, err
 	}
 
-	port, err := strconv.Atoi(parts[2])
+	address, portS, err := net.SplitHostPort(str[i+1:])
+	if err != nil {
+		return nil, errors.NotValidf("proxy endpoint must be delimited by two colons (::), we were given: `%v`", str)
+	}
+
+	port, err := strconv.Atoi(portS)
 	if err != nil {
-		return nil, errors.NotValidf("port must be an int not %v", parts[2])
+		return nil, errors.NotValidf("port must be an int not %v", portS)
 	}
 
 	return &ProxyEndpoint{
 		Protocol: prot,
-		Address:  parts[1],
+		Address:  address,
 		Port:     port,
 	}, nil
 }
 
 func (p *ProxyEndpoint) String() string {
-	return p.Protocol.String() + ":" + p.Address + ":" + strconv.Itoa(p.Port)
+	return p.Protocol.String() + ":" + net.JoinHostPort(p.Address, strconv.Itoa(p.Port))
+}
+
+// validate returns why LXD wouldn't accept the endpoint, if so
+func (p *ProxyEndpoint) validate() error {
+	if p.Protocol == ProtocolUndefined || p.Protocol.String() == "" {
+		return fmt.Errorf("unknown protocol %d", p.Protocol)
+	}
+
+	if p.Address == "" {
+		return fmt.Errorf("missing ip")
+	}
+
+	if net.ParseIP(p.Address) == nil {
+		return fmt.Errorf("%q is not an ip", p.Address)
+	}
+
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("port %d is out of range 1-65535", p.Port)
+	}
+
+	return nil
 }
@@ -48,6 +48,35 @@ func TestProxy_FromMap(t *testing.T) {
 	assert.Exactly(t, exp, d)
 }
 
+func TestProxy_ToMap_SourceIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sourceIP ProxySourceIP
+		key      string
+	}{
+		{ProxySourceIPNAT, "nat"},
+		{ProxySourceIPProxyProtocol, "proxy_protocol"},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(string(tt.sourceIP), func(t *testing.T) {
+			t.Parallel()
+
+			d := &Proxy{KeyName: "foo", Listen: &ProxyEndpoint{Protocol: ProtocolTCP, Address: "10.0.0.1", Port: 80}, Destination: &ProxyEndpoint{Protocol: ProtocolTCP, Address: "10.0.0.2", Port: 8080}, SourceIP: tt.sourceIP}
+			exp := map[string]string{"type": ProxyType, "listen": "tcp:10.0.0.1:80", "connect": "tcp:10.0.0.2:8080", tt.key: "true"}
+			n, m := d.ToMap()
+			assert.Equal(t, exp, m)
+
+			r := &Proxy{}
+			err := r.FromMap(n, m)
+			assert.NoError(t, err)
+			assert.Exactly(t, d, r)
+		})
+	}
+}
+
 func Test_newProtocol(t *testing.T) {
 	t.Parallel()
 
@@ -90,6 +119,8 @@ func TestNewProxyEndpoint(t *testing.T) {
 		{"udp:baz:35", &ProxyEndpoint{Protocol: ProtocolUDP, Address: "baz", Port: 35}, false},
 		{":baz:35", nil, true},
 		{"udp:baz:foo", nil, true},
+		{"tcp:[fd00::1]:80", &ProxyEndpoint{Protocol: ProtocolTCP, Address: "fd00::1", Port: 80}, false},
+		{"tcp:fd00::1:80", nil, true},
 	}
 	for _, tt := range tests {
 		tt := tt // pin!
@@ -101,3 +132,55 @@ func TestNewProxyEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestProxyEndpoint_String_IPv6(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "udp:[fd00::1]:53", (&ProxyEndpoint{Protocol: ProtocolUDP, Address: "fd00::1", Port: 53}).String())
+}
+
+func TestProxy_validate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *ProxyEndpoint { return &ProxyEndpoint{Protocol: ProtocolTCP, Address: "127.0.0.1", Port: 80} }
+
+	tests := []struct {
+		name    string
+		modify  func(d *Proxy)
+		wantErr string
+	}{
+		{"valid", func(d *Proxy) {}, ""},
+		{"ipv6", func(d *Proxy) { d.Listen.Address = "::" }, ""},
+		{"no listen", func(d *Proxy) { d.Listen = nil }, "without listen address"},
+		{"no connect", func(d *Proxy) { d.Destination = nil }, "without connect address"},
+		{"undefined protocol", func(d *Proxy) { d.Listen.Protocol = ProtocolUndefined }, "listen address"},
+		{"missing ip", func(d *Proxy) { d.Destination.Address = "" }, "connect address \"tcp::80\": missing ip"},
+		{"hostname", func(d *Proxy) { d.Destination.Address = "localhost" }, "is not an ip"},
+		{"port zero", func(d *Proxy) { d.Listen.Port = 0 }, "out of range"},
+		{"port too high", func(d *Proxy) { d.Destination.Port = 65536 }, "out of range"},
+		{"nat", func(d *Proxy) { d.SourceIP = ProxySourceIPNAT }, ""},
+		{"nat other protocol", func(d *Proxy) { d.SourceIP = ProxySourceIPNAT; d.Destination.Protocol = ProtocolUDP }, "can't be forwarded"},
+		{"nat other family", func(d *Proxy) { d.SourceIP = ProxySourceIPNAT; d.Destination.Address = "fd00::2" }, "same ip family"},
+		{"proxy protocol", func(d *Proxy) { d.SourceIP = ProxySourceIPProxyProtocol; d.Listen.Protocol = ProtocolUDP }, ""},
+		{"proxy protocol to udp", func(d *Proxy) { d.SourceIP = ProxySourceIPProxyProtocol; d.Destination.Protocol = ProtocolUDP }, "only be sent to tcp"},
+		{"unknown source ip mode", func(d *Proxy) { d.SourceIP = "foo" }, "unknown mode"},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &Proxy{KeyName: "foo", Listen: valid(), Destination: valid()}
+			tt.modify(d)
+
+			err := d.validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
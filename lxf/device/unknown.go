@@ -0,0 +1,44 @@
+package device // import "github.com/automaticserver/lxe/lxf/device"
+
+// Unknown device holds a device of a type which is not represented by its own type. The options are kept verbatim so
+// the device survives loading and saving it again
+type Unknown struct {
+	KeyName string
+	Options map[string]string
+}
+
+func (d *Unknown) getName() string {
+	return d.KeyName
+}
+
+// Type returns the LXD device type of the unknown device
+func (d *Unknown) Type() string {
+	return d.Options["type"]
+}
+
+// ToMap returns assigned name or if unset the type specific unique name and serializes the options into a lxd device map
+func (d *Unknown) ToMap() (string, map[string]string) {
+	options := make(map[string]string, len(d.Options))
+	for k, v := range d.Options {
+		options[k] = v
+	}
+
+	return d.getName(), options
+}
+
+// FromMap loads assigned name (can be empty) and options
+func (d *Unknown) FromMap(name string, options map[string]string) error {
+	d.KeyName = name
+	d.Options = make(map[string]string, len(options))
+
+	for k, v := range options {
+		d.Options[k] = v
+	}
+
+	return nil
+}
+
+// New creates a new empty device
+func (d *Unknown) new() Device {
+	return &Unknown{}
+}
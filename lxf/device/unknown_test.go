@@ -0,0 +1,29 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknown_ToMap(t *testing.T) {
+	t.Parallel()
+
+	d := &Unknown{KeyName: "foo", Options: map[string]string{"type": "infiniband", "nictype": "physical"}}
+	exp := map[string]string{"type": "infiniband", "nictype": "physical"}
+	n, m := d.ToMap()
+	assert.Equal(t, "foo", n)
+	assert.Equal(t, exp, m)
+	assert.Equal(t, "infiniband", d.Type())
+}
+
+func TestUnknown_FromMap(t *testing.T) {
+	t.Parallel()
+
+	raw := map[string]string{"type": "pci", "address": "0000:01:00.0"}
+	exp := &Unknown{KeyName: "foo", Options: map[string]string{"type": "pci", "address": "0000:01:00.0"}}
+	d := &Unknown{}
+	err := d.FromMap("foo", raw)
+	assert.NoError(t, err)
+	assert.Exactly(t, exp, d)
+}
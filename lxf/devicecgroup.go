@@ -0,0 +1,134 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	lxcCgroupDevicesAllow = "lxc.cgroup.devices.allow"
+	lxcCgroupDevicesDeny  = "lxc.cgroup.devices.deny"
+)
+
+// DeviceCgroupRule allows or denies access to device nodes in the devices cgroup of the container. Passing a device
+// node doesn't need a rule, LXD allows the devices it adds. The rules are passed to liblxc in raw.lxc and apply to the
+// devices cgroup of cgroup v1 only.
+type DeviceCgroupRule struct {
+	// Deny the access instead of allowing it
+	Deny bool
+	// Type of the device: a for all, b for block or c for char devices
+	Type string
+	// Major and Minor number of the device, or * for any
+	Major string
+	Minor string
+	// Access is a combination of r (read), w (write) and m (mknod)
+	Access string
+}
+
+// String returns the rule in the format of the devices cgroup, e.g. c 195:* rwm
+func (r DeviceCgroupRule) String() string {
+	return fmt.Sprintf("%s %s:%s %s", r.Type, r.Major, r.Minor, r.Access)
+}
+
+// rawLXC returns the raw.lxc line of the rule
+func (r DeviceCgroupRule) rawLXC() string {
+	key := lxcCgroupDevicesAllow
+	if r.Deny {
+		key = lxcCgroupDevicesDeny
+	}
+
+	return key + " = " + r.String()
+}
+
+func (r DeviceCgroupRule) validate() error {
+	switch r.Type {
+	case "a", "b", "c":
+	default:
+		return fmt.Errorf("%w: device cgroup rule %q: type must be one of a, b or c", ErrUsage, r)
+	}
+
+	for _, n := range []string{r.Major, r.Minor} {
+		if n == "*" {
+			continue
+		}
+
+		if _, err := strconv.ParseUint(n, 10, 32); err != nil {
+			return fmt.Errorf("%w: device cgroup rule %q: major and minor must be a number or *", ErrUsage, r)
+		}
+	}
+
+	if r.Access == "" || strings.Trim(r.Access, "rwm") != "" {
+		return fmt.Errorf("%w: device cgroup rule %q: access must be a combination of r, w and m", ErrUsage, r)
+	}
+
+	return nil
+}
+
+// validateDeviceCgroupRules checks the syntax of all rules
+func validateDeviceCgroupRules(rules []DeviceCgroupRule) error {
+	for _, r := range rules {
+		err := r.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseDeviceCgroupRule parses the value of a raw.lxc devices cgroup option. False is returned if it's no valid rule
+func parseDeviceCgroupRule(key, value string) (DeviceCgroupRule, bool) {
+	r := DeviceCgroupRule{Deny: key == lxcCgroupDevicesDeny}
+
+	fields := strings.Fields(value)
+	if len(fields) != 3 { // nolint: gomnd
+		return r, false
+	}
+
+	numbers := strings.SplitN(fields[1], ":", 2) // nolint: gomnd
+	if len(numbers) != 2 {                       // nolint: gomnd
+		return r, false
+	}
+
+	r.Type, r.Major, r.Minor, r.Access = fields[0], numbers[0], numbers[1], fields[2]
+
+	return r, r.validate() == nil
+}
+
+// splitDeviceCgroupRules takes the devices cgroup options out of the raw.lxc lines and returns them as rules along with
+// the remaining lines. Options which aren't valid rules are left in the lines.
+func splitDeviceCgroupRules(lines []string) ([]DeviceCgroupRule, []string) {
+	var (
+		rules []DeviceCgroupRule
+		rest  []string
+	)
+
+	for _, l := range lines {
+		if i := strings.Index(l, "="); i >= 0 {
+			key := strings.TrimSpace(l[:i])
+			if key == lxcCgroupDevicesAllow || key == lxcCgroupDevicesDeny {
+				if r, ok := parseDeviceCgroupRule(key, l[i+1:]); ok {
+					rules = append(rules, r)
+					continue
+				}
+			}
+		}
+
+		rest = append(rest, l)
+	}
+
+	return rules, rest
+}
+
+// rawLXCWithDeviceCgroupRules returns the raw.lxc lines of the object followed by the lines of its device cgroup rules
+func (o *LXDObject) rawLXCWithDeviceCgroupRules() []string {
+	lines := make([]string, 0, len(o.RawLXC)+len(o.DeviceCgroupRules))
+	lines = append(lines, o.RawLXC...)
+
+	for _, r := range o.DeviceCgroupRules {
+		lines = append(lines, r.rawLXC())
+	}
+
+	return lines
+}
@@ -0,0 +1,107 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testGPURule allows access to all nvidia char devices
+var testGPURule = DeviceCgroupRule{Type: "c", Major: "195", Minor: "*", Access: "rwm"}
+
+func Test_validateDeviceCgroupRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		rule    DeviceCgroupRule
+		wantErr bool
+	}{
+		{"gpu", testGPURule, false},
+		{"deny block", DeviceCgroupRule{Deny: true, Type: "b", Major: "8", Minor: "0", Access: "w"}, false},
+		{"all", DeviceCgroupRule{Type: "a", Major: "*", Minor: "*", Access: "rwm"}, false},
+		{"unknown type", DeviceCgroupRule{Type: "x", Major: "1", Minor: "3", Access: "r"}, true},
+		{"missing major", DeviceCgroupRule{Type: "c", Minor: "3", Access: "r"}, true},
+		{"invalid minor", DeviceCgroupRule{Type: "c", Major: "1", Minor: "-3", Access: "r"}, true},
+		{"missing access", DeviceCgroupRule{Type: "c", Major: "1", Minor: "3"}, true},
+		{"invalid access", DeviceCgroupRule{Type: "c", Major: "1", Minor: "3", Access: "rx"}, true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateDeviceCgroupRules([]DeviceCgroupRule{tt.rule})
+			assert.False(t, (err != nil) != tt.wantErr)
+
+			if err != nil {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func TestDeviceCgroupRule_rawLXC(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "lxc.cgroup.devices.allow = c 195:* rwm", testGPURule.rawLXC())
+
+	deny := DeviceCgroupRule{Deny: true, Type: "b", Major: "8", Minor: "0", Access: "w"}
+	assert.Equal(t, "lxc.cgroup.devices.deny = b 8:0 w", deny.rawLXC())
+}
+
+func Test_splitDeviceCgroupRules(t *testing.T) {
+	t.Parallel()
+
+	rules, rest := splitDeviceCgroupRules([]string{
+		"lxc.include = /foo",
+		"lxc.cgroup.devices.allow = c 195:* rwm",
+		"lxc.cgroup.devices.deny=b 8:0 w",
+		"lxc.cgroup.devices.allow = a",
+	})
+	assert.Equal(t, []DeviceCgroupRule{
+		testGPURule,
+		{Deny: true, Type: "b", Major: "8", Minor: "0", Access: "w"},
+	}, rules)
+	assert.Equal(t, []string{"lxc.include = /foo", "lxc.cgroup.devices.allow = a"}, rest)
+}
+
+func TestContainer_makeRawLXCConfig_DeviceCgroupRules(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{}
+	c.RawLXC = []string{"lxc.include = /foo"}
+	c.DeviceCgroupRules = []DeviceCgroupRule{testGPURule}
+
+	config := map[string]string{}
+	err := c.makeRawLXCConfig(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lxc.include = /foo", "lxc.cgroup.devices.allow = c 195:* rwm"}, rawLXCLines(config))
+
+	// reading back moves the rule out of the raw.lxc lines
+	rules, rest := splitDeviceCgroupRules(rawLXCLines(config))
+	assert.Equal(t, c.DeviceCgroupRules, rules)
+	assert.Equal(t, c.RawLXC, rest)
+}
+
+func TestContainer_validateWithSandbox_DeviceCgroupRulesOfSandbox(t *testing.T) {
+	t.Parallel()
+
+	s := &Sandbox{}
+	s.DeviceCgroupRules = []DeviceCgroupRule{testGPURule}
+
+	c := &Container{}
+	c.ID = "foo"
+	eff := c.withSandbox(s)
+
+	err := eff.validateWithSandbox(s)
+	assert.NoError(t, err)
+	assert.Equal(t, []DeviceCgroupRule{testGPURule}, eff.DeviceCgroupRules)
+
+	c.DeviceCgroupRules = []DeviceCgroupRule{{Type: "c", Major: "1", Minor: "3", Access: "x"}}
+
+	err = c.withSandbox(s).validateWithSandbox(s)
+	assert.True(t, errors.Is(err, ErrUsage))
+}
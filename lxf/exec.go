@@ -56,7 +56,7 @@ func (l *client) Exec(cid string, cmd []string, stdin io.ReadCloser, stdout, std
 		DataDone: make(chan bool),
 	}
 
-	op, err := l.server.ExecContainer(cid, req, args)
+	op, err := l.GetServer().ExecContainer(cid, req, args)
 	if err != nil {
 		return CodeExecError, err
 	}
@@ -0,0 +1,126 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/automaticserver/lxe/lxf/device"
+)
+
+const (
+	cfgVolatileIdmapBase = cfgVolatile + ".idmap.base"
+	// emptyDirPluginDir is the dir in which kubelet creates the sources of emptyDir volumes of a pod
+	emptyDirPluginDir = "kubernetes.io~empty-dir"
+)
+
+var (
+	// chownPath and chmodPath are replaceable for tests, as changing ownership requires root
+	chownPath = os.Lchown
+	chmodPath = os.Chmod
+)
+
+// initGroups returns the supplemental groups of the init process including the FSGroup, like kubelet does for other
+// runtimes
+func (c *Container) initGroups() []int64 {
+	groups := make([]int64, 0, len(c.SupplementalGroups)+1)
+	seen := make(map[int64]bool)
+
+	for _, g := range c.SupplementalGroups {
+		if !seen[g] {
+			seen[g] = true
+			groups = append(groups, g)
+		}
+	}
+
+	if c.FSGroup != nil && !seen[*c.FSGroup] {
+		groups = append(groups, *c.FSGroup)
+	}
+
+	return groups
+}
+
+// idmapBase returns the host uid and gid the root of the created container is mapped to. LXD only sets it in the
+// config of the instance, so it is loaded again. Privileged containers have no idmap.
+func (c *Container) idmapBase() (int64, error) {
+	if c.Privileged {
+		return 0, nil
+	}
+
+	created, err := c.client.GetContainer(c.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	baseS, has := created.Config[cfgVolatileIdmapBase]
+	if !has {
+		return 0, fmt.Errorf("container %v has no %v", c.ID, cfgVolatileIdmapBase)
+	}
+
+	base, err := strconv.ParseInt(baseS, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v: %w", cfgVolatileIdmapBase, err)
+	}
+
+	return base, nil
+}
+
+// isEmptyDirSource returns whether source is an emptyDir volume kubelet created for the pod. Only those are owned by
+// the pod, other sources like hostPath volumes belong to the host and are left as they are.
+func isEmptyDirSource(source string) bool {
+	plugin := filepath.Dir(filepath.Clean(source))
+
+	return filepath.Base(plugin) == emptyDirPluginDir && filepath.Base(filepath.Dir(plugin)) == "volumes"
+}
+
+// applyFSGroup makes the FSGroup the group owner of the host sources of writable emptyDir volumes. LXD mounts these
+// sources without shifting, so the group is set on the host with the gid shifted by the idmap of the container. Files
+// and directories become group read- and writable and directories get the setgid bit, so files created later inherit
+// the group. Read-only disks, pool volumes and other sources like hostPath volumes are left as they are.
+func (c *Container) applyFSGroup() error {
+	base, err := c.idmapBase()
+	if err != nil {
+		return err
+	}
+
+	gid := base + *c.FSGroup
+
+	for _, d := range c.Devices {
+		disk, is := d.(*device.Disk)
+		if !is || disk.Readonly || disk.Pool != "" || !isEmptyDirSource(disk.Source) {
+			continue
+		}
+
+		if _, err := os.Stat(disk.Source); os.IsNotExist(err) && disk.Optional {
+			continue
+		}
+
+		err = filepath.Walk(disk.Source, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			err = chownPath(path, -1, int(gid))
+			if err != nil {
+				return err
+			}
+
+			if info.Mode()&os.ModeSymlink != 0 {
+				return nil
+			}
+
+			mode := info.Mode()&os.ModePerm | 0060 // nolint: gomnd
+			if info.IsDir() {
+				mode |= 0010 | os.ModeSetgid // nolint: gomnd
+			}
+
+			return chmodPath(path, mode)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to apply fs group to %v: %w", disk.Source, err)
+		}
+	}
+
+	return nil
+}
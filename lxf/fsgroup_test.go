@@ -0,0 +1,194 @@
+package lxf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOwnership replaces chownPath and chmodPath and records the calls
+func fakeOwnership(t *testing.T) (map[string]int, map[string]os.FileMode) {
+	gids := make(map[string]int)
+	modes := make(map[string]os.FileMode)
+
+	oldChown, oldChmod := chownPath, chmodPath
+	chownPath = func(path string, uid, gid int) error {
+		assert.Equal(t, -1, uid)
+		gids[path] = gid
+
+		return nil
+	}
+	chmodPath = func(path string, mode os.FileMode) error {
+		modes[path] = mode
+
+		return nil
+	}
+
+	t.Cleanup(func() { chownPath, chmodPath = oldChown, oldChmod })
+
+	return gids, modes
+}
+
+func TestContainer_initGroups(t *testing.T) {
+	t.Parallel()
+
+	fsGroup := int64(2000)
+
+	tests := []struct {
+		name string
+		c    *Container
+		want []int64
+	}{
+		{"none", &Container{}, []int64{}},
+		{"supplemental", &Container{SupplementalGroups: []int64{10, 20, 10}}, []int64{10, 20}},
+		{"fs group", &Container{SupplementalGroups: []int64{10}, FSGroup: &fsGroup}, []int64{10, 2000}},
+		{"fs group duplicate", &Container{SupplementalGroups: []int64{2000}, FSGroup: &fsGroup}, []int64{2000}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.c.initGroups())
+		})
+	}
+}
+
+// testEmptyDir creates a dir like kubelet does for emptyDir volumes of a pod
+func testEmptyDir(t *testing.T) string {
+	pod, err := ioutil.TempDir("", "lxe-pod")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(pod) })
+
+	emptyDir := filepath.Join(pod, "volumes", emptyDirPluginDir, "cache")
+	assert.NoError(t, os.MkdirAll(emptyDir, 0750))
+
+	return emptyDir
+}
+
+// testFSGroupContainer returns a created container whose root is mapped to host id 1000000
+func testFSGroupContainer(privileged bool) (*Container, *lxdfakes.FakeContainerServer) {
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.Config[cfgVolatileIdmapBase] = "1000000"
+	fake.GetContainerReturns(ct, "", nil)
+
+	fsGroup := int64(2000)
+	c := &Container{FSGroup: &fsGroup, Privileged: privileged}
+	c.client = client
+	c.ID = "foo"
+
+	return c, fake
+}
+
+func Test_isEmptyDirSource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"/var/lib/kubelet/pods/1234/volumes/kubernetes.io~empty-dir/cache", true},
+		{"/var/lib/kubelet/pods/1234/volumes/kubernetes.io~empty-dir/cache/", true},
+		{"/var/lib/kubelet/pods/1234/volumes/kubernetes.io~empty-dir", false},
+		{"/var/lib/kubelet/pods/1234/volumes/kubernetes.io~configmap/config", false},
+		{"/srv/data", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.source, func(t *testing.T) {
+			assert.Equal(t, tt.want, isEmptyDirSource(tt.source))
+		})
+	}
+}
+
+func TestContainer_applyFSGroup_EmptyDir(t *testing.T) {
+	gids, modes := fakeOwnership(t)
+
+	emptyDir := testEmptyDir(t)
+	file := filepath.Join(emptyDir, "data")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("foo"), 0600))
+
+	c, fake := testFSGroupContainer(false)
+	c.Devices = device.Devices{
+		&device.Disk{Path: "/cache", Source: emptyDir},
+		&device.Disk{Path: "/config", Source: "/does/not/matter", Readonly: true},
+		&device.Disk{Path: "/", Pool: "default"},
+	}
+
+	err := c.applyFSGroup()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", fake.GetContainerArgsForCall(0))
+	assert.Equal(t, map[string]int{emptyDir: 1002000, file: 1002000}, gids)
+	assert.Equal(t, os.FileMode(0770)|os.ModeSetgid, modes[emptyDir]&(os.ModePerm|os.ModeSetgid))
+	assert.Equal(t, os.FileMode(0660), modes[file])
+}
+
+func TestContainer_applyFSGroup_HostPath(t *testing.T) {
+	gids, modes := fakeOwnership(t)
+
+	hostPath, err := ioutil.TempDir("", "lxe-hostpath")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(hostPath)
+
+	c, _ := testFSGroupContainer(false)
+	c.Devices = device.Devices{&device.Disk{Path: "/data", Source: hostPath}}
+
+	err = c.applyFSGroup()
+	assert.NoError(t, err)
+	assert.Empty(t, gids)
+	assert.Empty(t, modes)
+}
+
+func TestContainer_applyFSGroup_Privileged(t *testing.T) {
+	gids, _ := fakeOwnership(t)
+
+	emptyDir := testEmptyDir(t)
+
+	c, fake := testFSGroupContainer(true)
+	c.Devices = device.Devices{&device.Disk{Path: "/cache", Source: emptyDir}}
+
+	err := c.applyFSGroup()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+	assert.Equal(t, map[string]int{emptyDir: 2000}, gids)
+}
+
+func TestContainer_applyFSGroup_NoIdmap(t *testing.T) {
+	gids, _ := fakeOwnership(t)
+
+	c, fake := testFSGroupContainer(false)
+	fake.GetContainerReturns(basicContainer("foo", "default"), "", nil)
+	c.Devices = device.Devices{&device.Disk{Path: "/cache", Source: testEmptyDir(t)}}
+
+	err := c.applyFSGroup()
+	assert.Error(t, err)
+	assert.Empty(t, gids)
+}
+
+func TestContainer_applyFSGroup_MissingSource(t *testing.T) {
+	fakeOwnership(t)
+
+	missing := filepath.Join(testEmptyDir(t), "..", "missing")
+
+	c, _ := testFSGroupContainer(false)
+	c.Devices = device.Devices{&device.Disk{Path: "/cache", Source: missing}}
+
+	err := c.applyFSGroup()
+	assert.Error(t, err)
+
+	c.Devices = device.Devices{&device.Disk{Path: "/cache", Source: missing, Optional: true}}
+
+	err = c.applyFSGroup()
+	assert.NoError(t, err)
+}
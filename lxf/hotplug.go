@@ -0,0 +1,183 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/shared"
+)
+
+// hotpluggable lists the device types LXD can add to and remove from a running container
+var hotpluggable = map[string]bool{
+	device.DiskType:  true,
+	device.NicType:   true,
+	device.BlockType: true,
+	device.CharType:  true,
+	device.ProxyType: true,
+	"usb":            true,
+	"gpu":            true,
+	"infiniband":     true,
+	"unix-hotplug":   true,
+}
+
+// checkHotpluggable returns ErrNotHotpluggable if LXD can't change the device dev at runtime. The root disk is
+// required by a running container, so it can't be changed either.
+func checkHotpluggable(name string, dev map[string]string) error {
+	if !hotpluggable[dev["type"]] {
+		return fmt.Errorf("%w: device %v of type '%v'", ErrNotHotpluggable, name, dev["type"])
+	}
+
+	if dev["type"] == device.DiskType && dev["path"] == "/" {
+		return fmt.Errorf("%w: root disk %v", ErrNotHotpluggable, name)
+	}
+
+	return nil
+}
+
+// HotplugDevice adds the device dev named name to the container id without restarting it. Adding a device that
+// already exists with the same options does nothing, other options return ErrExists as LXD can't change most devices
+// in place.
+func (l *client) HotplugDevice(id, name string, dev map[string]string) error {
+	err := checkHotpluggable(name, dev)
+	if err != nil {
+		return err
+	}
+
+	return l.updateDevices(id, func(devices map[string]map[string]string) (bool, error) {
+		if existing, has := devices[name]; has {
+			if reflect.DeepEqual(existing, dev) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("device %v of container %v %w", name, id, ErrExists)
+		}
+
+		devices[name] = dev
+
+		return true, nil
+	})
+}
+
+// HotunplugDevice removes the device name from the container id without restarting it. Devices inherited from profiles
+// can't be removed this way.
+func (l *client) HotunplugDevice(id, name string) error {
+	return l.updateDevices(id, func(devices map[string]map[string]string) (bool, error) {
+		dev, has := devices[name]
+		if !has {
+			return false, fmt.Errorf("device %w: %s", shared.NewErrNotFound(), name)
+		}
+
+		err := checkHotpluggable(name, dev)
+		if err != nil {
+			return false, err
+		}
+
+		delete(devices, name)
+
+		return true, nil
+	})
+}
+
+// GetDevices returns the devices the container id currently has grouped by type. These are its expanded devices, so
+// the ones inherited from profiles are included. Devices of unknown type are returned as device.Unknown.
+func (l *client) GetDevices(id string) (*device.Collection, error) {
+	ct, _, err := l.GetServer().GetContainer(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return nil, fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return nil, err
+	}
+
+	devices, err := detectDevices(ct.ExpandedDevices)
+	if err != nil {
+		return nil, err
+	}
+
+	// the devices come from a map, order them for stable output
+	sort.Slice(devices, func(i, j int) bool {
+		a, _ := devices[i].ToMap()
+		b, _ := devices[j].ToMap()
+
+		return a < b
+	})
+
+	return devices.Collect(), nil
+}
+
+// updateProfileDevices lets change modify the devices of the sandbox profile id and saves them if it reports a change
+func (l *client) updateProfileDevices(id string, change func(devices map[string]map[string]string) (bool, error)) error {
+	p, etag, err := l.GetServer().GetProfile(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("sandbox %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return err
+	}
+
+	if !IsCRI(p) {
+		return fmt.Errorf("sandbox %w: %s", shared.NewErrNotFound(), id)
+	}
+
+	put := p.Writable()
+	if put.Devices == nil {
+		put.Devices = make(map[string]map[string]string)
+	}
+
+	changed, err := change(put.Devices)
+	if err != nil || !changed {
+		return err
+	}
+
+	err = l.GetServer().UpdateProfile(id, put, etag)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("sandbox %w: %s", shared.NewErrNotFound(), id)
+		} else if shared.IsErrETagMismatch(err) {
+			return fmt.Errorf("update devices of sandbox %v: %w", id, ErrETagConflict)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// updateDevices lets change modify the local devices of container id and saves them if it reports a change
+func (l *client) updateDevices(id string, change func(devices map[string]map[string]string) (bool, error)) error {
+	ct, etag, err := l.GetServer().GetContainer(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return err
+	}
+
+	put := ct.Writable()
+	if put.Devices == nil {
+		put.Devices = make(map[string]map[string]string)
+	}
+
+	changed, err := change(put.Devices)
+	if err != nil || !changed {
+		return err
+	}
+
+	err = l.opWait().UpdateContainer(id, put, etag)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		} else if shared.IsErrETagMismatch(err) {
+			return fmt.Errorf("update devices of container %v: %w", id, ErrETagConflict)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func testHotplugClient() (*client, *lxdfakes.FakeContainerServer) {
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.Devices = map[string]map[string]string{
+		"root": {"type": "disk", "path": "/", "pool": "default"},
+		"data": {"type": "disk", "path": "/data", "source": "/srv/data"},
+		"tpm":  {"type": "tpm"},
+	}
+
+	fake.GetContainerReturns(ct, "etag", nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	return client, fake
+}
+
+func TestClient_HotplugDevice(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testHotplugClient()
+
+	dev := map[string]string{"type": "disk", "path": "/cache", "source": "/srv/cache"}
+
+	err := client.HotplugDevice("foo", "cache", dev)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.UpdateContainerCallCount())
+
+	id, put, etag := fake.UpdateContainerArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, "etag", etag)
+	assert.Equal(t, dev, put.Devices["cache"])
+	assert.Contains(t, put.Devices, "data")
+}
+
+func TestClient_HotplugDevice_Existing(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testHotplugClient()
+
+	err := client.HotplugDevice("foo", "data", map[string]string{"type": "disk", "path": "/data", "source": "/srv/data"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+
+	err = client.HotplugDevice("foo", "data", map[string]string{"type": "disk", "path": "/data", "source": "/srv/other"})
+	assert.True(t, errors.Is(err, ErrExists))
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestClient_HotplugDevice_NotHotpluggable(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testHotplugClient()
+
+	err := client.HotplugDevice("foo", "vtpm", map[string]string{"type": "tpm"})
+	assert.True(t, errors.Is(err, ErrNotHotpluggable))
+
+	err = client.HotplugDevice("foo", "rootfs", map[string]string{"type": "disk", "path": "/", "pool": "other"})
+	assert.True(t, errors.Is(err, ErrNotHotpluggable))
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+}
+
+func TestClient_HotplugDevice_ETagConflict(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testHotplugClient()
+	fake.UpdateContainerReturns(nil, errors.New("ETag doesn't match"))
+
+	err := client.HotplugDevice("foo", "cache", map[string]string{"type": "nic", "nictype": "bridged", "parent": "br1"})
+	assert.True(t, errors.Is(err, ErrETagConflict))
+}
+
+func TestClient_HotunplugDevice(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testHotplugClient()
+
+	err := client.HotunplugDevice("foo", "data")
+	assert.NoError(t, err)
+
+	_, put, _ := fake.UpdateContainerArgsForCall(0)
+	assert.NotContains(t, put.Devices, "data")
+	assert.Contains(t, put.Devices, "root")
+}
+
+func TestClient_HotunplugDevice_Refused(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testHotplugClient()
+
+	err := client.HotunplugDevice("foo", "root")
+	assert.True(t, errors.Is(err, ErrNotHotpluggable))
+
+	err = client.HotunplugDevice("foo", "tpm")
+	assert.True(t, errors.Is(err, ErrNotHotpluggable))
+
+	err = client.HotunplugDevice("foo", "missing")
+	assert.True(t, shared.IsErrNotFound(err))
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestClient_GetDevices(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.ExpandedDevices = map[string]map[string]string{
+		"root":  {"type": "disk", "path": "/", "pool": "default"},
+		"data":  {"type": "disk", "path": "/data", "source": "/srv/data"},
+		"eth0":  {"type": "nic", "name": "eth0", "nictype": "bridged", "parent": "lxebr0"},
+		"gpu":   {"type": "gpu", "id": "0"},
+		"proxy": {"type": "proxy", "listen": "tcp:0.0.0.0:80", "connect": "tcp:127.0.0.1:80"},
+	}
+	fake.GetContainerReturns(ct, "", nil)
+
+	devices, err := client.GetDevices("foo")
+	assert.NoError(t, err)
+
+	assert.Len(t, devices.Disks, 2)
+	assert.Equal(t, "data", devices.Disks[0].KeyName)
+	assert.Equal(t, "root", devices.Disks[1].KeyName)
+	assert.Len(t, devices.Nics, 1)
+	assert.Len(t, devices.Proxies, 1)
+	assert.Len(t, devices.Unknowns, 1)
+	assert.Equal(t, "gpu", devices.Unknowns[0].KeyName)
+	assert.Empty(t, devices.Blocks)
+}
+
+func TestClient_GetDevices_NotFound(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerReturns(nil, "", shared.NewErrNotFound())
+
+	_, err := client.GetDevices("foo")
+	assert.True(t, shared.IsErrNotFound(err))
+}
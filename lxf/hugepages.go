@@ -0,0 +1,145 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const cfgLimitHugepagesPrefix = "limits.hugepages"
+
+// ErrHugepageSize is returned if a hugepage limit is requested for a page size LXD or the node doesn't support
+var ErrHugepageSize = errors.New("unsupported hugepage size")
+
+var (
+	// hugepageSizes are the page sizes LXD can limit, with their size in kB as the kernel names them
+	hugepageSizes = map[string]int{
+		"64KB": 64,
+		"1MB":  1024,
+		"2MB":  2048,
+		"1GB":  1024 * 1024,
+	}
+	// hugepagesPath lists a directory hugepages-<size>kB for each page size the kernel supports
+	hugepagesPath = "/sys/kernel/mm/hugepages"
+)
+
+// readHugepageSizes returns the page sizes LXD can limit which the kernel at path supports
+func readHugepageSizes(path string) (map[string]bool, error) {
+	sizes := make(map[string]bool)
+
+	entries, err := ioutil.ReadDir(path)
+	if os.IsNotExist(err) {
+		// a kernel without hugepage support
+		return sizes, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		kB, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(e.Name(), "hugepages-"), "kB"))
+		if err != nil {
+			continue
+		}
+
+		for size, sizeKB := range hugepageSizes {
+			if sizeKB == kB {
+				sizes[size] = true
+			}
+		}
+	}
+
+	return sizes, nil
+}
+
+// nodeHugepageSizes returns the page sizes the node supports. The node of a remote LXD can't be inspected, so nil is
+// returned there and only LXD checks the sizes
+func (l *client) nodeHugepageSizes() (map[string]bool, error) {
+	if l == nil || l.conn.IsRemote() {
+		return nil, nil
+	}
+
+	return readHugepageSizes(hugepagesPath)
+}
+
+// validateHugepages checks the limits are not negative and their page sizes are supported by LXD and by the node, if
+// the sizes of the node are known
+func (o *LXDObject) validateHugepages() error {
+	if len(o.HugepageLimits) == 0 {
+		return nil
+	}
+
+	node, err := o.client.nodeHugepageSizes()
+	if err != nil {
+		return err
+	}
+
+	return validateHugepageLimits(o.HugepageLimits, node)
+}
+
+// validateHugepageLimits checks limits against the page sizes of LXD and, unless nil, the ones of the node
+func validateHugepageLimits(limits map[string]int64, node map[string]bool) error {
+	for size, limit := range limits {
+		if _, has := hugepageSizes[size]; !has {
+			return fmt.Errorf("%w: %v, LXD only supports %v", ErrHugepageSize, size, strings.Join(supportedHugepageSizes(), ", "))
+		}
+
+		if node != nil && !node[size] {
+			return fmt.Errorf("%w: %v is not supported by the node", ErrHugepageSize, size)
+		}
+
+		if limit < 0 {
+			return fmt.Errorf("%w: hugepage limit of %v must not be negative: %d", ErrUsage, size, limit)
+		}
+	}
+
+	return nil
+}
+
+// supportedHugepageSizes returns the page sizes LXD can limit, from the smallest to the largest
+func supportedHugepageSizes() []string {
+	sizes := make([]string, 0, len(hugepageSizes))
+	for size := range hugepageSizes {
+		sizes = append(sizes, size)
+	}
+
+	sort.Slice(sizes, func(i, j int) bool {
+		return hugepageSizes[sizes[i]] < hugepageSizes[sizes[j]]
+	})
+
+	return sizes
+}
+
+// makeHugepagesConfig writes a limit for each requested page size to config
+func (o *LXDObject) makeHugepagesConfig(config map[string]string) {
+	for size, limit := range o.HugepageLimits {
+		config[cfgLimitHugepagesPrefix+"."+size] = strconv.FormatInt(limit, 10)
+	}
+}
+
+// parseHugepagesConfig reads the hugepage limits from config
+func (o *LXDObject) parseHugepagesConfig(config map[string]string) error {
+	o.HugepageLimits = nil
+
+	for k, v := range config {
+		if !strings.HasPrefix(k, cfgLimitHugepagesPrefix+".") {
+			continue
+		}
+
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrParse, k, err)
+		}
+
+		if o.HugepageLimits == nil {
+			o.HugepageLimits = make(map[string]int64)
+		}
+
+		o.HugepageLimits[strings.TrimPrefix(k, cfgLimitHugepagesPrefix+".")] = limit
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package lxf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readHugepageSizes(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "hugepages")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{"hugepages-2048kB", "hugepages-1048576kB", "hugepages-32768kB", "other"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, d), 0700))
+	}
+
+	sizes, err := readHugepageSizes(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"2MB": true, "1GB": true}, sizes)
+
+	sizes, err = readHugepageSizes(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, sizes)
+	assert.NotNil(t, sizes)
+}
+
+func Test_validateHugepageLimits(t *testing.T) {
+	t.Parallel()
+
+	node := map[string]bool{"2MB": true}
+
+	tests := []struct {
+		name   string
+		limits map[string]int64
+		node   map[string]bool
+		want   error
+	}{
+		{"none", nil, node, nil},
+		{"supported", map[string]int64{"2MB": 1 << 30}, node, nil},
+		{"unknown node", map[string]int64{"1GB": 1 << 30}, nil, nil},
+		{"unsupported by lxd", map[string]int64{"16MB": 1 << 30}, nil, ErrHugepageSize},
+		{"unsupported by node", map[string]int64{"1GB": 1 << 30}, node, ErrHugepageSize},
+		{"negative", map[string]int64{"2MB": -1}, node, ErrUsage},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateHugepageLimits(tt.limits, tt.node)
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.want))
+			}
+		})
+	}
+}
+
+func Test_supportedHugepageSizes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"64KB", "1MB", "2MB", "1GB"}, supportedHugepageSizes())
+}
+
+func TestLXDObject_makeHugepagesConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{HugepageLimits: map[string]int64{"2MB": 1 << 30, "1GB": 0}}
+	config := map[string]string{}
+
+	o.makeHugepagesConfig(config)
+	assert.Equal(t, map[string]string{"limits.hugepages.2MB": "1073741824", "limits.hugepages.1GB": "0"}, config)
+
+	r := &LXDObject{}
+	err := r.parseHugepagesConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeHugepagesConfig_Unset(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	(&LXDObject{}).makeHugepagesConfig(config)
+	assert.Empty(t, config)
+}
+
+func TestLXDObject_parseHugepagesConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	err := (&LXDObject{}).parseHugepagesConfig(map[string]string{"limits.hugepages.2MB": "1GB"})
+	assert.True(t, errors.Is(err, ErrParse))
+}
+
+func TestLXDObject_validateHugepages_Remote(t *testing.T) {
+	t.Parallel()
+
+	client, _ := testClient()
+	client.conn.RemoteURL = "https://10.0.0.1:8443"
+
+	o := &LXDObject{client: client, HugepageLimits: map[string]int64{"1GB": 1 << 30}}
+	assert.NoError(t, o.validateHugepages())
+}
@@ -0,0 +1,57 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"crypto/md5" // nolint: gosec
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// IDGenerator creates the ID of a new sandbox or container from its metadata name. The ID is used as the name of the
+// lxd profile or container, so it must be unique among all sandboxes or containers and satisfy the naming rules of lxd:
+// 1 to 63 letters, digits and hyphens, not starting with a digit or hyphen and not ending with a hyphen. The UID of the
+// pod is always kept in the config of the sandbox, so generators don't need to encode it.
+type IDGenerator func(name string) string
+
+// idPattern matches a name lxd accepts for containers, which is a valid hostname label
+var idPattern = regexp.MustCompile(`^[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// DefaultIDGenerator returns the first character of name followed by 15 random characters. As lxd names must start with
+// a letter, "x" is used instead if name starts with anything else.
+func DefaultIDGenerator(name string) string {
+	prefix := "x"
+	if name != "" && (name[0] >= 'a' && name[0] <= 'z') {
+		prefix = name[:1]
+	}
+
+	bin := md5.Sum([]byte(uuid.NewUUID())) // nolint: gosec
+
+	return prefix + b32lowerEncoder.EncodeToString(bin[:])[:15]
+}
+
+// ValidateID checks the id satisfies the naming rules of lxd
+func ValidateID(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q is not a valid lxd name", ErrUsage, id)
+	}
+
+	return nil
+}
+
+// newID generates an id using the configured generator and validates it
+func (l *client) newID(name string) (string, error) {
+	gen := l.idGenerator
+	if gen == nil {
+		gen = DefaultIDGenerator
+	}
+
+	id := gen(name)
+
+	return id, ValidateID(id)
+}
+
+// SetIDGenerator replaces the generator of ids for new sandboxes and containers
+func (l *client) SetIDGenerator(gen IDGenerator) {
+	l.idGenerator = gen
+}
@@ -0,0 +1,94 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultIDGenerator_ValidNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"nginx", "n"},
+		{"0pod", "x"},
+		{"-pod", "x"},
+		{"Pod", "x"},
+		{"", "x"},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			id := DefaultIDGenerator(tt.name)
+			assert.NoError(t, ValidateID(id))
+			assert.Len(t, id, 16)
+			assert.Equal(t, tt.prefix, id[:1])
+		})
+	}
+}
+
+func TestDefaultIDGenerator_Unique(t *testing.T) {
+	t.Parallel()
+
+	ids := map[string]bool{}
+
+	for i := 0; i < 1000; i++ {
+		id := DefaultIDGenerator("foo")
+		assert.False(t, ids[id], id)
+		ids[id] = true
+	}
+}
+
+func TestValidateID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"a", false},
+		{"abc-123", false},
+		{"Abc", false},
+		{"", true},
+		{"1abc", true},
+		{"-abc", true},
+		{"abc-", true},
+		{"ab_c", true},
+		{"ab.c", true},
+		{"a234567890123456789012345678901234567890123456789012345678901234", true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.id, func(t *testing.T) {
+			err := ValidateID(tt.id)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestClient_SetIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	client, _ := testClient()
+	client.SetIDGenerator(func(name string) string { return "custom-" + name })
+
+	id, err := client.newID("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-foo", id)
+}
+
+func TestClient_SetIDGenerator_Invalid(t *testing.T) {
+	t.Parallel()
+
+	client, _ := testClient()
+	client.SetIDGenerator(func(name string) string { return name + "_" })
+
+	_, err := client.newID("foo")
+	assert.True(t, errors.Is(err, ErrUsage))
+}
@@ -1,16 +1,42 @@
 package lxf // import "github.com/automaticserver/lxe/lxf"
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/automaticserver/lxe/shared"
+	"github.com/dionysius/errand"
 	lxd "github.com/lxc/lxd/client"
 	lxdApi "github.com/lxc/lxd/shared/api"
 )
 
+const (
+	cfgImagesRemoteCacheExpiry = "images.remote_cache_expiry"
+	// imageDigestPrefix separates the fingerprint an image is pinned to from its name, in the digest notation of
+	// kubernetes like images/ubuntu/20.04@sha256:<fingerprint>
+	imageDigestPrefix = "@sha256:"
+)
+
+// ErrFingerprintMismatch is returned if the image pulled for a pinned fingerprint has another fingerprint
+var ErrFingerprintMismatch = errors.New("image fingerprint mismatch")
+
+// fingerprintPattern matches a complete fingerprint of an image, which is the sha256 of it
+var fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ImagePolicy controls how LXD keeps the images LXE pulls
+type ImagePolicy struct {
+	// AutoUpdate lets LXD refresh pulled images from their source. Existing containers are not touched, but new ones
+	// may be created from a newer image under the same name than the one already running
+	AutoUpdate bool
+	// RemoteCacheExpiry is the number of days after which LXD removes cached images which weren't used. It's a server
+	// wide setting of LXD and is left untouched if zero
+	RemoteCacheExpiry int
+}
+
 // Image is here to translate the relevant data from lxd image to cri image
 type Image struct {
 	Hash    string
@@ -18,8 +44,64 @@ type Image struct {
 	Size    int64
 }
 
-// PullImage copies the given image from the remote server
-func (l *client) PullImage(name string) (string, error) {
+// ImageInfo describes a local image as CRI reports its status
+type ImageInfo struct {
+	// Fingerprint identifies the image, it's the sha256 of it
+	Fingerprint string
+	// Aliases are the local names of the image
+	Aliases []string
+	// Size is the total size of the image in bytes
+	Size int64
+	// User is the default user of the image, either a name or a uid. It's read from the image property "user" and empty
+	// if the image configures none
+	User string
+}
+
+// imagePropertyUser is the image property holding the default user
+const imagePropertyUser = "user"
+
+// UID returns the default user of the image as uid, if it's numeric
+func (i *ImageInfo) UID() (int64, bool) {
+	uid, err := strconv.ParseInt(i.User, 10, 64)
+	if err != nil || uid < 0 {
+		return 0, false
+	}
+
+	return uid, true
+}
+
+// toImageInfo converts a LXD image
+func toImageInfo(img *lxdApi.Image) *ImageInfo {
+	aliases := []string{}
+	for _, ali := range img.Aliases {
+		aliases = append(aliases, ali.Name+":latest")
+	}
+
+	return &ImageInfo{
+		Fingerprint: img.Fingerprint,
+		Aliases:     aliases,
+		Size:        img.Size,
+		User:        img.Properties[imagePropertyUser],
+	}
+}
+
+// PullImage copies the given image from the remote server and returns the info of the local copy
+func (l *client) PullImage(name string) (*ImageInfo, error) {
+	fingerprint, err := l.pullImage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := l.GetServer().GetImage(fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("image %v missing after pull: %w", fingerprint, err)
+	}
+
+	return toImageInfo(img), nil
+}
+
+// pullImage copies the given image from the remote server and returns its fingerprint
+func (l *client) pullImage(name string) (string, error) {
 	imageID, err := l.parseImage(name)
 	if err != nil {
 		return "", err
@@ -33,27 +115,127 @@ func (l *client) PullImage(name string) (string, error) {
 		return "", err
 	}
 
-	imageRef := dereferenceAlias(imgServer, imageID.Alias)
+	// a pinned image is fetched by its fingerprint, wherever the alias points to by now
+	imageRef := imageID.Fingerprint
+	if imageRef == "" {
+		imageRef = dereferenceAlias(imgServer, imageID.Alias)
+	}
 
 	image, _, err := imgServer.GetImage(imageRef)
 	if err != nil {
 		return "", err
 	}
 
+	err = checkFingerprint(imageID, image.Fingerprint)
+	if err != nil {
+		return "", err
+	}
+
+	// updating a pinned image would defeat the pinning
+	autoUpdate := l.imagePolicy.AutoUpdate && !imageID.Pinned()
+
 	args := lxd.ImageCopyArgs{
 		CopyAliases: false, // We shouldn't rely on default aliases, as aliases are unique per remote
-		AutoUpdate:  true,  // Maybe bug: currently NOT a technical requirement to know where the source is
+		AutoUpdate:  autoUpdate,
 	}
 
-	err = l.opwait.CopyImage(imgServer, *image, &args)
+	err = l.opWait().CopyImage(imgServer, *image, &args)
 	if err != nil {
 		return "", fmt.Errorf("unable to pull requested image %v from server %v, %w",
 			image, imageID.Remote, err)
 	}
 
+	if imageID.Pinned() {
+		local, _, err := l.GetServer().GetImage(imageID.Fingerprint)
+		if err != nil {
+			return "", fmt.Errorf("pinned image %v missing after pull: %w", imageID.Fingerprint, err)
+		}
+
+		err = checkFingerprint(imageID, local.Fingerprint)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = l.ensureImageAutoUpdate(image.Fingerprint, autoUpdate)
+	if err != nil {
+		return "", err
+	}
+
+	// the local alias of a tag moves with every pull, a pinned image is found by its fingerprint instead
+	if imageID.Pinned() {
+		return image.Fingerprint, nil
+	}
+
 	return image.Fingerprint, l.ensureImageAlias(imageID.Tag(), image.Fingerprint)
 }
 
+// checkFingerprint returns an error if the image is pinned to another fingerprint than got
+func checkFingerprint(imageID ImageID, got string) error {
+	if imageID.Pinned() && got != imageID.Fingerprint {
+		return fmt.Errorf("%w: %v is pinned to %v, but got %v", ErrFingerprintMismatch, imageID.Alias, imageID.Fingerprint, got)
+	}
+
+	return nil
+}
+
+// ensureImageAutoUpdate sets auto_update of the local image. Copying an image which is already present doesn't change
+// the flag, so images pulled before the policy changed are corrected here.
+func (l *client) ensureImageAutoUpdate(fingerprint string, autoUpdate bool) error {
+	image, etag, err := l.GetServer().GetImage(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if image.AutoUpdate == autoUpdate {
+		return nil
+	}
+
+	put := image.Writable()
+	put.AutoUpdate = autoUpdate
+
+	err = l.GetServer().UpdateImage(fingerprint, put, etag)
+	if err != nil {
+		return fmt.Errorf("unable to set auto update of image %v: %w", fingerprint, err)
+	}
+
+	return nil
+}
+
+// SetImagePolicy changes how pulled images are kept. Auto update applies to images pulled from now on, the cache expiry
+// is changed in the server config of LXD right away.
+func (l *client) SetImagePolicy(policy ImagePolicy) error {
+	if policy.RemoteCacheExpiry < 0 {
+		return fmt.Errorf("%w: remote cache expiry can't be negative, got %v", ErrUsage, policy.RemoteCacheExpiry)
+	}
+
+	if policy.RemoteCacheExpiry > 0 {
+		server, etag, err := l.GetServer().GetServer()
+		if err != nil {
+			return err
+		}
+
+		expiry := strconv.Itoa(policy.RemoteCacheExpiry)
+		if server.Config[cfgImagesRemoteCacheExpiry] != expiry {
+			put := server.Writable()
+			if put.Config == nil {
+				put.Config = map[string]interface{}{}
+			}
+
+			put.Config[cfgImagesRemoteCacheExpiry] = expiry
+
+			err = l.GetServer().UpdateServer(put, etag)
+			if err != nil {
+				return fmt.Errorf("unable to set %v: %w", cfgImagesRemoteCacheExpiry, err)
+			}
+		}
+	}
+
+	l.imagePolicy = policy
+
+	return nil
+}
+
 // RemoveImage will remove the given image
 func (l *client) RemoveImage(name string) error {
 	imageID, err := l.parseImage(name)
@@ -68,7 +250,7 @@ func (l *client) RemoveImage(name string) error {
 		return nil
 	}
 
-	err = l.opwait.DeleteImage(hash)
+	err = l.opWait().DeleteImage(hash)
 	if err != nil {
 		if shared.IsErrNotFound(err) {
 			return nil
@@ -80,10 +262,66 @@ func (l *client) RemoveImage(name string) error {
 	return nil
 }
 
+// PublishStopTimeout is the time in seconds a running container has to shut down before it is published
+var PublishStopTimeout = 30
+
+// PublishAsImage creates a local image from the container id, e.g. a golden image for further pods, and points alias
+// to it. An existing alias is moved to the new image. A running container is refused unless stop is set, it's then
+// stopped for the time of the publish and started again afterwards. The returned fingerprint can be used as image of
+// new containers.
+func (l *client) PublishAsImage(id, alias string, stop bool) (string, error) {
+	if alias == "" {
+		return "", fmt.Errorf("%w: publishing container %v requires an alias", ErrUsage, id)
+	}
+
+	ct, _, err := l.GetServer().GetContainer(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return "", fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return "", err
+	}
+
+	running := ct.StatusCode != lxdApi.Stopped
+	if running {
+		if !stop {
+			return "", fmt.Errorf("%w: container %v must be stopped to be published, but is %v", ErrUsage, id, ct.Status)
+		}
+
+		log.WithField("containerid", id).Info("stopping container to publish it")
+
+		err = l.opWait().StopContainer(id, PublishStopTimeout, 1)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	fingerprint, err := l.opWait().CreateImage(lxdApi.ImagesPost{
+		Source: &lxdApi.ImagesPostSource{
+			Type: "container",
+			Name: id,
+		},
+	})
+	if err == nil {
+		err = l.ensureImageAlias(alias, fingerprint)
+	}
+
+	if running {
+		err = errand.Append(err, l.opWait().StartContainer(id))
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return fingerprint, nil
+}
+
 // Create the specified image alis, update if already exist
 // from github.com/lxc/lxd/lxc/image.go:172 + changes
 func (l *client) ensureImageAlias(alias string, fingerprint string) error {
-	current, err := l.server.GetImageAliases()
+	current, err := l.GetServer().GetImageAliases()
 	if err != nil {
 		return err
 	}
@@ -98,7 +336,7 @@ func (l *client) ensureImageAlias(alias string, fingerprint string) error {
 				break
 			}
 
-			err = l.server.DeleteImageAlias(ca.Name)
+			err = l.GetServer().DeleteImageAlias(ca.Name)
 			if err != nil {
 				return fmt.Errorf("failed to delete alias for update: %v, %w", alias, err)
 			}
@@ -115,7 +353,7 @@ func (l *client) ensureImageAlias(alias string, fingerprint string) error {
 	aliasPost.Name = alias
 	aliasPost.Target = fingerprint
 
-	err = l.server.CreateImageAlias(aliasPost)
+	err = l.GetServer().CreateImageAlias(aliasPost)
 	if err != nil {
 		return fmt.Errorf("failed to create alias: %v, %w", alias, err)
 	}
@@ -127,7 +365,7 @@ func (l *client) ensureImageAlias(alias string, fingerprint string) error {
 func (l *client) ListImages(filter string) ([]Image, error) {
 	response := []Image{}
 
-	imglist, err := l.server.GetImages()
+	imglist, err := l.GetServer().GetImages()
 	if err != nil {
 		return nil, fmt.Errorf("unable to list images: %w", err)
 	}
@@ -154,6 +392,33 @@ func (l *client) ListImages(filter string) ([]Image, error) {
 
 // GetImage will fetch information about the already downloaded image identified by name
 func (l *client) GetImage(name string) (*Image, error) {
+	img, err := l.localImage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := toImageInfo(img)
+
+	return &Image{
+		Hash:    info.Fingerprint,
+		Aliases: info.Aliases,
+		Size:    info.Size,
+	}, nil
+}
+
+// ImageStatus returns the info of the already downloaded image identified by ref. If the image isn't present, the
+// error is a not found
+func (l *client) ImageStatus(ref string) (*ImageInfo, error) {
+	img, err := l.localImage(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return toImageInfo(img), nil
+}
+
+// localImage returns the already downloaded image identified by name or a not found error
+func (l *client) localImage(name string) (*lxdApi.Image, error) {
 	imageID, err := l.parseImage(name)
 	if err != nil {
 		if strings.HasSuffix(err.Error(), "doesn't exist") {
@@ -170,21 +435,17 @@ func (l *client) GetImage(name string) (*Image, error) {
 		return nil, fmt.Errorf("image %w: %s, unable to find hash", shared.NewErrNotFound(), name)
 	}
 
-	img, _, err := l.server.GetImage(hash)
+	img, _, err := l.GetServer().GetImage(hash)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get image: %v, %w", name, err)
-	}
+		// removed since the hash was resolved
+		if shared.IsErrNotFound(err) {
+			return nil, fmt.Errorf("image %w: %s", shared.NewErrNotFound(), name)
+		}
 
-	aliases := []string{}
-	for _, ali := range img.Aliases {
-		aliases = append(aliases, ali.Name+":latest")
+		return nil, fmt.Errorf("unable to get image: %v, %w", name, err)
 	}
 
-	return &Image{
-		Hash:    img.Fingerprint,
-		Aliases: aliases,
-		Size:    img.Size,
-	}, nil
+	return img, nil
 }
 
 // FSPoolUsage contains fields to describe the usage of a filesystem / storagepool
@@ -197,7 +458,7 @@ type FSPoolUsage struct {
 
 // GetFSPoolUsage returns a list of usage information about the used storage pools
 func (l *client) GetFSPoolUsage() ([]FSPoolUsage, error) {
-	pools, err := l.server.GetStoragePools()
+	pools, err := l.GetServer().GetStoragePools()
 	if err != nil {
 		return nil, err
 	}
@@ -205,7 +466,7 @@ func (l *client) GetFSPoolUsage() ([]FSPoolUsage, error) {
 	rval := []FSPoolUsage{}
 
 	for _, pool := range pools {
-		pRcs, err := l.server.GetStoragePoolResources(pool.Name)
+		pRcs, err := l.GetServer().GetStoragePoolResources(pool.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -225,6 +486,13 @@ func (l *client) GetFSPoolUsage() ([]FSPoolUsage, error) {
 type ImageID struct {
 	Remote string
 	Alias  string
+	// Fingerprint the image is pinned to, if any. The alias is informational then
+	Fingerprint string
+}
+
+// Pinned returns true if the image is identified by its fingerprint instead of an alias which may move
+func (i ImageID) Pinned() bool {
+	return i.Fingerprint != ""
 }
 
 // Tag builds from remote and alias an alias for local
@@ -236,11 +504,24 @@ func (i ImageID) Tag() string {
 // already a hash this one.
 // It it's not found second return will be false and error will be zero.
 func (i ImageID) Hash(l *client) (string, bool, error) {
-	exists, _, err := l.server.GetImageAlias(i.Tag())
+	if i.Pinned() {
+		_, _, err := l.GetServer().GetImage(i.Fingerprint)
+		if err != nil {
+			if shared.IsErrNotFound(err) {
+				return "", false, nil
+			}
+
+			return "", false, err
+		}
+
+		return i.Fingerprint, true, nil
+	}
+
+	exists, _, err := l.GetServer().GetImageAlias(i.Tag())
 	if err != nil { // nolint: nestif
 		if shared.IsErrNotFound(err) {
 			// it still might be a hash, check that
-			_, _, err = l.server.GetImage(i.Alias)
+			_, _, err = l.GetServer().GetImage(i.Alias)
 			if err != nil {
 				if shared.IsErrNotFound(err) {
 					return "", false, nil
@@ -262,6 +543,11 @@ func (i ImageID) Hash(l *client) (string, bool, error) {
 // parseImage will take an external image and split it up into
 // remote and tag
 func (l *client) parseImage(name string) (ImageID, error) {
+	name, fingerprint, err := splitImageDigest(name)
+	if err != nil {
+		return ImageID{}, err
+	}
+
 	img, err := convertDockerImageNameToLXC(name)
 	if err != nil {
 		return ImageID{}, err
@@ -272,7 +558,23 @@ func (l *client) parseImage(name string) (ImageID, error) {
 		return ImageID{}, err
 	}
 
-	return ImageID{Remote: remote, Alias: tag}, nil
+	return ImageID{Remote: remote, Alias: tag, Fingerprint: fingerprint}, nil
+}
+
+// splitImageDigest splits the fingerprint an image name is pinned to off the name. The fingerprint must be complete,
+// as a prefix could match another image in the future
+func splitImageDigest(name string) (string, string, error) {
+	i := strings.LastIndex(name, imageDigestPrefix)
+	if i < 0 {
+		return name, "", nil
+	}
+
+	fingerprint := name[i+len(imageDigestPrefix):]
+	if !fingerprintPattern.MatchString(fingerprint) {
+		return "", "", fmt.Errorf("image name %w: %s, pinned fingerprint must be 64 lowercase hex characters", ErrParse, name)
+	}
+
+	return name[:i], fingerprint, nil
 }
 
 func convertDockerImageNameToLXC(inputName string) (string, error) {
@@ -1,5 +1,105 @@
 package lxf
 
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SetImagePolicy_CacheExpiry(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(&api.Server{}, "etag", nil)
+
+	err := client.SetImagePolicy(ImagePolicy{AutoUpdate: true, RemoteCacheExpiry: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, ImagePolicy{AutoUpdate: true, RemoteCacheExpiry: 3}, client.imagePolicy)
+
+	assert.Equal(t, 1, fake.UpdateServerCallCount())
+	put, etag := fake.UpdateServerArgsForCall(0)
+	assert.Equal(t, "3", put.Config[cfgImagesRemoteCacheExpiry])
+	assert.Equal(t, "etag", etag)
+}
+
+func TestClient_SetImagePolicy_CacheExpiryUnchanged(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(&api.Server{ServerPut: api.ServerPut{Config: map[string]interface{}{cfgImagesRemoteCacheExpiry: "3"}}}, "", nil)
+
+	err := client.SetImagePolicy(ImagePolicy{RemoteCacheExpiry: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.UpdateServerCallCount())
+}
+
+func TestClient_SetImagePolicy_KeepCacheExpiry(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	err := client.SetImagePolicy(ImagePolicy{AutoUpdate: false})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetServerCallCount())
+}
+
+func TestClient_SetImagePolicy_Negative(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	client.imagePolicy.AutoUpdate = true
+
+	err := client.SetImagePolicy(ImagePolicy{RemoteCacheExpiry: -1})
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.True(t, client.imagePolicy.AutoUpdate)
+	assert.Equal(t, 0, fake.UpdateServerCallCount())
+}
+
+func TestClient_ensureImageAutoUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		policy     bool
+		image      bool
+		wantUpdate bool
+	}{
+		{"disable", false, true, true},
+		{"enable", true, false, true},
+		{"already disabled", false, false, false},
+		{"already enabled", true, true, false},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, fake := testClient()
+			fake.GetImageReturns(&api.Image{ImagePut: api.ImagePut{AutoUpdate: tt.image}}, "etag", nil)
+
+			err := client.ensureImageAutoUpdate("abc", tt.policy)
+			assert.NoError(t, err)
+
+			if !tt.wantUpdate {
+				assert.Equal(t, 0, fake.UpdateImageCallCount())
+				return
+			}
+
+			assert.Equal(t, 1, fake.UpdateImageCallCount())
+			fp, put, etag := fake.UpdateImageArgsForCall(0)
+			assert.Equal(t, "abc", fp)
+			assert.Equal(t, tt.policy, put.AutoUpdate)
+			assert.Equal(t, "etag", etag)
+		})
+	}
+}
+
 // func TestListImages(t *testing.T) {
 // 	lt := newLXFTest(t)
 // 	imgs := lt.listImages("")
@@ -96,3 +196,198 @@ package lxf
 // 			duration)
 // 	}
 // }
+
+func testPublishClient(status api.StatusCode) (*client, *lxdfakes.FakeContainerServer) {
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.StatusCode = status
+	ct.Status = status.String()
+
+	createOp := &lxdfakes.FakeOperation{}
+	createOp.GetReturns(api.Operation{Metadata: map[string]interface{}{"fingerprint": "abc"}})
+
+	fake.GetContainerReturns(ct, "", nil)
+	fake.CreateImageReturns(createOp, nil)
+	fake.UpdateContainerStateReturns(&lxdfakes.FakeOperation{}, nil)
+
+	return client, fake
+}
+
+func TestClient_PublishAsImage_Stopped(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testPublishClient(api.Stopped)
+
+	fingerprint, err := client.PublishAsImage("foo", "golden", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", fingerprint)
+
+	post, _ := fake.CreateImageArgsForCall(0)
+	assert.Equal(t, "container", post.Source.Type)
+	assert.Equal(t, "foo", post.Source.Name)
+
+	alias := fake.CreateImageAliasArgsForCall(0)
+	assert.Equal(t, "golden", alias.Name)
+	assert.Equal(t, "abc", alias.Target)
+	assert.Equal(t, 0, fake.UpdateContainerStateCallCount())
+}
+
+func TestClient_PublishAsImage_RunningRefused(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testPublishClient(api.Running)
+
+	_, err := client.PublishAsImage("foo", "golden", false)
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateImageCallCount())
+}
+
+func TestClient_PublishAsImage_RunningStopped(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testPublishClient(api.Running)
+
+	fingerprint, err := client.PublishAsImage("foo", "golden", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", fingerprint)
+	assert.Equal(t, 2, fake.UpdateContainerStateCallCount())
+
+	_, stop, _ := fake.UpdateContainerStateArgsForCall(0)
+	assert.Equal(t, "stop", stop.Action)
+
+	_, start, _ := fake.UpdateContainerStateArgsForCall(1)
+	assert.Equal(t, "start", start.Action)
+}
+
+func TestClient_PublishAsImage_ErrorRestarts(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testPublishClient(api.Running)
+	fake.CreateImageReturns(nil, errors.New("publish failed"))
+
+	_, err := client.PublishAsImage("foo", "golden", true)
+	assert.Error(t, err)
+	assert.Equal(t, 2, fake.UpdateContainerStateCallCount())
+	assert.Equal(t, 0, fake.CreateImageAliasCallCount())
+}
+
+func TestClient_PublishAsImage_NotFound(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerReturns(nil, "", shared.NewErrNotFound())
+
+	_, err := client.PublishAsImage("foo", "golden", false)
+	assert.True(t, shared.IsErrNotFound(err))
+}
+
+const testFingerprint = "2dd442a946be6cee3d3a3cfb619a936fae0ff10600511596261d045960416f54"
+
+func Test_splitImageDigest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		in              string
+		wantName        string
+		wantFingerprint string
+		wantErr         bool
+	}{
+		{"alias", "images/ubuntu/20.04", "images/ubuntu/20.04", "", false},
+		{"tag", "critest.asag.io/foobar:latest", "critest.asag.io/foobar:latest", "", false},
+		{"pinned", "images/ubuntu/20.04@sha256:" + testFingerprint, "images/ubuntu/20.04", testFingerprint, false},
+		{"pinned prefix", "images/ubuntu/20.04@sha256:2dd442a946be", "", "", true},
+		{"pinned uppercase", "images/ubuntu@sha256:" + strings.ToUpper(testFingerprint), "", "", true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			name, fingerprint, err := splitImageDigest(tt.in)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrParse))
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantFingerprint, fingerprint)
+		})
+	}
+}
+
+func Test_checkFingerprint(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, checkFingerprint(ImageID{Alias: "ubuntu"}, "abc"))
+	assert.NoError(t, checkFingerprint(ImageID{Alias: "ubuntu", Fingerprint: testFingerprint}, testFingerprint))
+	assert.True(t, errors.Is(checkFingerprint(ImageID{Alias: "ubuntu", Fingerprint: testFingerprint}, "abc"), ErrFingerprintMismatch))
+}
+
+func TestImageID_Hash_Pinned(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetImageReturns(&api.Image{Fingerprint: testFingerprint}, "", nil)
+
+	hash, found, err := ImageID{Remote: "images", Alias: "ubuntu", Fingerprint: testFingerprint}.Hash(client)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testFingerprint, hash)
+	assert.Equal(t, testFingerprint, fake.GetImageArgsForCall(0))
+	// the moving alias is not consulted
+	assert.Equal(t, 0, fake.GetImageAliasCallCount())
+}
+
+func TestImageID_Hash_PinnedMissing(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetImageReturns(nil, "", shared.NewErrNotFound())
+
+	_, found, err := ImageID{Remote: "images", Alias: "ubuntu", Fingerprint: testFingerprint}.Hash(client)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestClient_ImageStatus(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetImageReturns(&api.Image{
+		Fingerprint: testFingerprint,
+		Size:        1024,
+		Aliases:     []api.ImageAlias{{Name: "ubuntu"}},
+		ImagePut:    api.ImagePut{Properties: map[string]string{"user": "1000"}},
+	}, "", nil)
+
+	info, err := client.ImageStatus("ubuntu@sha256:" + testFingerprint)
+	assert.NoError(t, err)
+	assert.Equal(t, &ImageInfo{Fingerprint: testFingerprint, Aliases: []string{"ubuntu:latest"}, Size: 1024, User: "1000"}, info)
+
+	uid, is := info.UID()
+	assert.True(t, is)
+	assert.Equal(t, int64(1000), uid)
+}
+
+func TestClient_ImageStatus_NotFound(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetImageReturns(nil, "", shared.NewErrNotFound())
+
+	_, err := client.ImageStatus("ubuntu@sha256:" + testFingerprint)
+	assert.True(t, shared.IsErrNotFound(err))
+}
+
+func TestImageInfo_UID(t *testing.T) {
+	t.Parallel()
+
+	_, is := (&ImageInfo{}).UID()
+	assert.False(t, is)
+
+	_, is = (&ImageInfo{User: "nginx"}).UID()
+	assert.False(t, is)
+
+	uid, is := (&ImageInfo{User: "0"}).UID()
+	assert.True(t, is)
+	assert.Equal(t, int64(0), uid)
+}
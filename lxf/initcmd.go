@@ -0,0 +1,98 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// cfgInitCommand stores InitCommand, as lxc.init.cmd loses the separation of the arguments
+	cfgInitCommand = "user.init_command"
+	lxcInitCmd     = "lxc.init.cmd"
+)
+
+// validateInitCommand checks the arguments can be passed in a line of raw.lxc
+func validateInitCommand(args []string) error {
+	for _, a := range args {
+		if strings.ContainsAny(a, "\n\r") {
+			return fmt.Errorf("%w: init command must not contain line breaks: %q", ErrUsage, a)
+		}
+
+		// liblxc has no escaping within quotes, so one of them must be free to quote the argument with
+		if strings.Contains(a, "'") && strings.Contains(a, `"`) {
+			return fmt.Errorf("%w: init command argument must not contain both single and double quotes: %q", ErrUsage, a)
+		}
+	}
+
+	if len(args) > 0 && args[0] == "" {
+		return fmt.Errorf("%w: init command must not start with an empty argument", ErrUsage)
+	}
+
+	return nil
+}
+
+// formatInitCommand joins the arguments to the value of lxc.init.cmd. liblxc splits it at whitespace and honors quotes,
+// so arguments containing them are quoted. Arguments must be validated by validateInitCommand first
+func formatInitCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t'\"") {
+			if strings.Contains(a, "'") {
+				a = `"` + a + `"`
+			} else {
+				a = "'" + a + "'"
+			}
+		}
+
+		quoted = append(quoted, a)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// makeInitCommandConfig lets liblxc run args as PID 1 instead of the init of the image. Nothing is changed if args is
+// empty, so an lxc.init.cmd of RawLXC applies then
+func makeInitCommandConfig(config map[string]string, args []string) error {
+	if len(args) == 0 {
+		delete(config, cfgInitCommand)
+		return nil
+	}
+
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	config[cfgInitCommand] = string(raw)
+	setRawLXCOption(config, lxcInitCmd, formatInitCommand(args))
+
+	return nil
+}
+
+// parseInitCommand returns the InitCommand stored in config and the raw.lxc lines without the one generated for it
+func parseInitCommand(config map[string]string, rawLXC []string) ([]string, []string, error) {
+	raw, has := config[cfgInitCommand]
+	if !has {
+		return nil, rawLXC, nil
+	}
+
+	var args []string
+
+	err := json.Unmarshal([]byte(raw), &args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %v: %w", cfgInitCommand, err)
+	}
+
+	generated := lxcInitCmd + " = " + formatInitCommand(args)
+	lines := make([]string, 0, len(rawLXC))
+
+	for _, l := range rawLXC {
+		if l != generated {
+			lines = append(lines, l)
+		}
+	}
+
+	return args, lines, nil
+}
@@ -0,0 +1,79 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_formatInitCommand(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/bin/sh -c 'sleep 10 && echo done'", formatInitCommand([]string{"/bin/sh", "-c", "sleep 10 && echo done"}))
+	assert.Equal(t, `/app --name "it's" ''`, formatInitCommand([]string{"/app", "--name", "it's", ""}))
+}
+
+func Test_validateInitCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", []string{"/app", "--flag", ""}, false},
+		{"empty command", []string{"", "--flag"}, true},
+		{"line break", []string{"/app", "foo\nlxc.include = /bar"}, true},
+		{"single quotes", []string{"/app", "it's"}, false},
+		{"both quotes", []string{"/app", `it's "quoted"`}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateInitCommand(tt.args)
+			assert.False(t, (err != nil) != tt.wantErr)
+
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func Test_makeInitCommandConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	args := []string{"/bin/sh", "-c", "exec my app"}
+	config := map[string]string{cfgRawLXC: "lxc.include = /foo\nlxc.init.cmd = /sbin/init"}
+
+	err := makeInitCommandConfig(config, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "lxc.include = /foo\nlxc.init.cmd = /bin/sh -c 'exec my app'", config[cfgRawLXC])
+
+	parsed, lines, err := parseInitCommand(config, rawLXCLines(config))
+	assert.NoError(t, err)
+	assert.Equal(t, args, parsed)
+	assert.Equal(t, []string{"lxc.include = /foo"}, lines)
+}
+
+func Test_makeInitCommandConfig_ImageInit(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{cfgRawLXC: "lxc.init.cmd = /sbin/init", cfgInitCommand: `["/app"]`}
+
+	err := makeInitCommandConfig(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "lxc.init.cmd = /sbin/init", config[cfgRawLXC])
+	assert.NotContains(t, config, cfgInitCommand)
+
+	parsed, lines, err := parseInitCommand(config, rawLXCLines(config))
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+	assert.Equal(t, []string{"lxc.init.cmd = /sbin/init"}, lines)
+}
@@ -0,0 +1,146 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// cfgInstanceType records the instance type, as neither profiles nor the container API of LXD tell it
+const cfgInstanceType = "user.instance_type"
+
+// InstanceType is the kind of instance LXD runs
+type InstanceType string
+
+const (
+	// InstanceTypeContainer is a system container sharing the kernel of the node
+	InstanceTypeContainer InstanceType = "container"
+	// InstanceTypeVM is a virtual machine with its own kernel, which isolates stronger
+	InstanceTypeVM InstanceType = "virtual-machine"
+)
+
+// IsVM tells whether instances of this type are virtual machines. The empty type is a container
+func (t InstanceType) IsVM() bool {
+	return t == InstanceTypeVM
+}
+
+// unsupportedVMDevices are device types which only containers can have, as they pass through host device nodes
+var unsupportedVMDevices = map[string]bool{
+	device.CharType:  true,
+	device.BlockType: true,
+}
+
+// vmDeviceOptions are device options LXD only knows for virtual machines
+var vmDeviceOptions = []string{device.DiskOptionBootPriority}
+
+// deviceOptions drops the options of a device which instances of this type don't know, so a device can be shared by
+// containers and virtual machines
+func (t InstanceType) deviceOptions(options map[string]string) map[string]string {
+	if !t.IsVM() {
+		for _, o := range vmDeviceOptions {
+			delete(options, o)
+		}
+	}
+
+	return options
+}
+
+// validateInstanceType checks the instance type is known and for virtual machines, that the object uses only what LXD
+// supports for them. Most of the fields ending up in raw.lxc or tuning the kernel of the node don't
+func (o *LXDObject) validateInstanceType() error {
+	switch o.InstanceType {
+	case "", InstanceTypeContainer:
+		return nil
+	case InstanceTypeVM:
+	default:
+		return fmt.Errorf("%w: unknown instance type %v, must be %v or %v", ErrUsage, o.InstanceType,
+			InstanceTypeContainer, InstanceTypeVM)
+	}
+
+	for _, d := range o.Devices {
+		name, options := d.ToMap()
+		if unsupportedVMDevices[options["type"]] {
+			return fmt.Errorf("%w: device %v of type %v isn't supported by virtual machines", ErrUsage, name,
+				options["type"])
+		}
+	}
+
+	switch {
+	case len(o.RawLXC) > 0:
+		return fmt.Errorf("%w: raw lxc isn't supported by virtual machines", ErrUsage)
+	case len(o.DeviceCgroupRules) > 0:
+		return fmt.Errorf("%w: device cgroup rules aren't supported by virtual machines", ErrUsage)
+	case o.SeccompProfile != "" && o.SeccompProfile != SeccompProfileUnconfined:
+		return fmt.Errorf("%w: seccomp profiles aren't supported by virtual machines", ErrUsage)
+	case o.ShmSize != "":
+		return fmt.Errorf("%w: shm size isn't supported by virtual machines", ErrUsage)
+	case o.PidsLimit > 0:
+		return fmt.Errorf("%w: pids limit isn't supported by virtual machines", ErrUsage)
+	case o.MemorySwap != "", o.MemorySwappiness != nil:
+		return fmt.Errorf("%w: swap limits aren't supported by virtual machines", ErrUsage)
+	case o.AppArmorUnconfined:
+		return fmt.Errorf("%w: apparmor unconfined isn't supported by virtual machines", ErrUsage)
+	}
+
+	return nil
+}
+
+// validateVM checks the container uses none of the fields which are passed to liblxc and therefore only work for
+// system containers
+func (c *Container) validateVM() error {
+	switch {
+	case c.Privileged:
+		return fmt.Errorf("%w: privileged isn't supported by virtual machines", ErrUsage)
+	case len(c.Tmpfs) > 0:
+		return fmt.Errorf("%w: tmpfs mounts aren't supported by virtual machines", ErrUsage)
+	case len(c.InitCommand) > 0:
+		return fmt.Errorf("%w: init command isn't supported by virtual machines", ErrUsage)
+	case c.RunAsUser != nil, c.RunAsGroup != nil, len(c.SupplementalGroups) > 0, c.FSGroup != nil:
+		return fmt.Errorf("%w: init user and groups aren't supported by virtual machines", ErrUsage)
+	}
+
+	return nil
+}
+
+// instanceToContainer converts an instance into the representation of the container API, which both types share
+func instanceToContainer(i *api.Instance) api.Container {
+	return api.Container{
+		ContainerPut: api.ContainerPut{
+			Architecture: i.Architecture,
+			Config:       i.Config,
+			Devices:      i.Devices,
+			Ephemeral:    i.Ephemeral,
+			Profiles:     i.Profiles,
+			Stateful:     i.Stateful,
+			Description:  i.Description,
+		},
+		CreatedAt:       i.CreatedAt,
+		ExpandedConfig:  i.ExpandedConfig,
+		ExpandedDevices: i.ExpandedDevices,
+		Name:            i.Name,
+		Status:          i.Status,
+		StatusCode:      i.StatusCode,
+		LastUsedAt:      i.LastUsedAt,
+		Location:        i.Location,
+	}
+}
+
+// createVM creates the container as virtual machine, which is only possible through the instance API of LXD
+func (c *Container) createVM(put api.ContainerPut, fingerprint string) error {
+	return c.targetOpwait().CreateInstance(api.InstancesPost{
+		Name: c.ID,
+		InstancePut: api.InstancePut{
+			Architecture: put.Architecture,
+			Config:       put.Config,
+			Devices:      put.Devices,
+			Ephemeral:    put.Ephemeral,
+			Profiles:     put.Profiles,
+		},
+		Source: api.InstanceSource{
+			Fingerprint: fingerprint,
+			Type:        "image",
+		},
+		Type: api.InstanceTypeVM,
+	})
+}
@@ -0,0 +1,130 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXDObject_validateInstanceType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		obj     *LXDObject
+		wantErr bool
+	}{
+		{"default", &LXDObject{}, false},
+		{"container with char device", &LXDObject{InstanceType: InstanceTypeContainer, Devices: device.Devices{&device.Char{Path: "/dev/fuse"}}}, false},
+		{"vm", &LXDObject{InstanceType: InstanceTypeVM, Devices: device.Devices{&device.Disk{Path: "/data", Source: "/srv"}}}, false},
+		{"vm unconfined seccomp", &LXDObject{InstanceType: InstanceTypeVM, SeccompProfile: SeccompProfileUnconfined}, false},
+		{"unknown", &LXDObject{InstanceType: "jail"}, true},
+		{"vm with char device", &LXDObject{InstanceType: InstanceTypeVM, Devices: device.Devices{&device.Char{Path: "/dev/fuse"}}}, true},
+		{"vm with block device", &LXDObject{InstanceType: InstanceTypeVM, Devices: device.Devices{&device.Block{Path: "/dev/sdb"}}}, true},
+		{"vm with raw lxc", &LXDObject{InstanceType: InstanceTypeVM, RawLXC: []string{"lxc.apparmor.profile = unconfined"}}, true},
+		{"vm with seccomp", &LXDObject{InstanceType: InstanceTypeVM, SeccompProfile: SeccompProfileRuntimeDefault}, true},
+		{"vm with shm size", &LXDObject{InstanceType: InstanceTypeVM, ShmSize: "64MB"}, true},
+		{"vm with pids limit", &LXDObject{InstanceType: InstanceTypeVM, PidsLimit: 100}, true},
+		{"vm with swap", &LXDObject{InstanceType: InstanceTypeVM, MemorySwap: MemorySwapDisabled}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.obj.validateInstanceType()
+			assert.Equal(t, tt.wantErr, err != nil, err)
+
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func TestContainer_validateVM(t *testing.T) {
+	t.Parallel()
+
+	uid := int64(1000)
+
+	tests := []struct {
+		name    string
+		c       *Container
+		wantErr bool
+	}{
+		{"plain", &Container{}, false},
+		{"privileged", &Container{Privileged: true}, true},
+		{"tmpfs", &Container{Tmpfs: []TmpfsMount{{Path: "/tmp"}}}, true},
+		{"init command", &Container{InitCommand: []string{"/bin/sh"}}, true},
+		{"run as user", &Container{RunAsUser: &uid}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.c.validateVM()
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestContainer_createVM(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.CreateInstanceReturns(&lxdfakes.FakeOperation{}, nil)
+
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+
+	err := c.createVM(api.ContainerPut{Profiles: c.Profiles, Config: map[string]string{cfgInstanceType: string(InstanceTypeVM)}}, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+
+	post := fake.CreateInstanceArgsForCall(0)
+	assert.Equal(t, "foo", post.Name)
+	assert.Equal(t, api.InstanceTypeVM, post.Type)
+	assert.Equal(t, "abc", post.Source.Fingerprint)
+	assert.Equal(t, []string{"sandboxID"}, post.Profiles)
+	assert.Equal(t, string(InstanceTypeVM), post.Config[cfgInstanceType])
+}
+
+func Test_makeContainerConfig_VM(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{}
+	c.ID = "foo"
+	c.InstanceType = InstanceTypeVM
+
+	config := makeContainerConfig(c)
+	assert.NotContains(t, config, cfgSecurityPrivileged)
+	assert.Equal(t, string(InstanceTypeVM), config[cfgInstanceType])
+
+	c.InstanceType = ""
+
+	config = makeContainerConfig(c)
+	assert.Equal(t, "false", config[cfgSecurityPrivileged])
+	assert.NotContains(t, config, cfgInstanceType)
+}
+
+func TestInstanceType_deviceOptions(t *testing.T) {
+	t.Parallel()
+
+	_, options := (&device.Disk{Path: "/", Pool: "default", BootPriority: 10}).ToMap()
+	assert.Equal(t, "10", InstanceTypeVM.deviceOptions(options)[device.DiskOptionBootPriority])
+
+	_, options = (&device.Disk{Path: "/", Pool: "default", BootPriority: 10}).ToMap()
+	assert.NotContains(t, InstanceTypeContainer.deviceOptions(options), device.DiskOptionBootPriority)
+
+	_, options = (&device.Disk{Path: "/", Pool: "default", BootPriority: 10}).ToMap()
+	assert.NotContains(t, InstanceType("").deviceOptions(options), device.DiskOptionBootPriority)
+}
@@ -0,0 +1,168 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// KeepAliveInterval is how often the connection to LXD is checked while LXE is otherwise idle
+var KeepAliveInterval = 30 * time.Second
+
+// isConnectionError returns true if err means the connection to LXD was lost, as opposed to LXD answering with an
+// error
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	// a timeout means LXD is slow rather than gone, a new connection wouldn't help and the request might still complete
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
+
+// withReconnect calls f and if it fails because the connection to LXD was lost, connects again and retries f once.
+// Only use it for calls which are safe to repeat. Calls changing the state, like starting, stopping or creating a
+// container and exec, aren't wrapped, as LXD might have executed them before the connection was lost. They fail
+// instead and are repeated by kubelet, by then the connection was restored by the next call or keepAlive.
+func (l *client) withReconnect(f func(server lxd.ContainerServer) error) error {
+	server := l.GetServer()
+
+	err := f(server)
+	if !isConnectionError(err) {
+		return err
+	}
+
+	log.WithError(err).Warn("lost connection to lxd, reconnecting")
+
+	rerr := l.reconnect(server)
+	if rerr != nil {
+		log.WithError(rerr).Error("failed reconnecting to lxd")
+		return err
+	}
+
+	return f(l.GetServer())
+}
+
+// reconnect replaces the stale connection to LXD. Concurrent callers wait for a single reconnect instead of each
+// opening their own connection.
+func (l *client) reconnect(stale lxd.ContainerServer) error {
+	l.reconnecting.Lock()
+	defer l.reconnecting.Unlock()
+
+	// someone else reconnected while we were waiting
+	if l.GetServer() != stale {
+		return nil
+	}
+
+	return l.connect()
+}
+
+// checkConnection pings LXD and connects again if the connection was lost
+func (l *client) checkConnection() error {
+	return l.withReconnect(func(server lxd.ContainerServer) error {
+		_, _, err := server.GetServer()
+		return err
+	})
+}
+
+// keepAlive checks the connection every interval, so a dropped connection or event listener is already established
+// again when the next call arrives. It never returns.
+func (l *client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if l.eventsLost() {
+			log.Warn("lxd event listener disconnected, reconnecting")
+
+			err := l.reconnect(l.GetServer())
+			if err != nil {
+				log.WithError(err).Error("failed reconnecting to lxd")
+			}
+
+			continue
+		}
+
+		err := l.checkConnection()
+		if err != nil && isConnectionError(err) {
+			log.WithError(err).Error("lxd not reachable")
+		}
+	}
+}
+
+// eventsLost returns true if the event listener of the current connection got disconnected. Lifecycle events would go
+// missing otherwise, although the api calls themselves still work.
+func (l *client) eventsLost() bool {
+	l.mu.RLock()
+	done := l.eventsDone
+	l.mu.RUnlock()
+
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// eventListener is the part of lxd.EventListener the client uses
+type eventListener interface {
+	AddHandler(types []string, function func(api.Event)) (*lxd.EventTarget, error)
+	Wait() error
+	Disconnect()
+}
+
+// getEvents opens the event listener of server
+func (l *client) getEvents(server lxd.ContainerServer) (eventListener, error) {
+	if l.listen != nil {
+		return l.listen(server)
+	}
+
+	listener, err := server.GetEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// disconnectEvents disconnects listener if LXD didn't already. lxd.EventListener checks whether it's disconnected
+// without holding its lock, so it panics if the connection is lost at the same time.
+func disconnectEvents(listener eventListener) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithField("panic", r).Debug("lxd event listener was disconnected concurrently")
+		}
+	}()
+
+	listener.Disconnect()
+}
+
+// watchEvents closes done once listener got disconnected
+func watchEvents(listener eventListener, done chan<- struct{}) {
+	_ = listener.Wait()
+
+	close(done)
+}
+
+// dialServer opens a new connection to LXD
+func (l *client) dialServer(httpClient *http.Client) (lxd.ContainerServer, error) {
+	if l.dial != nil {
+		return l.dial(httpClient)
+	}
+
+	return l.conn.connectServer(httpClient)
+}
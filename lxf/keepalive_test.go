@@ -0,0 +1,258 @@
+package lxf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEventListener delivers events to its handlers until it's disconnected
+type fakeEventListener struct {
+	mu           sync.Mutex
+	handlers     []func(api.Event)
+	disconnected chan struct{}
+}
+
+func newFakeEventListener() *fakeEventListener {
+	return &fakeEventListener{disconnected: make(chan struct{})}
+}
+
+func (f *fakeEventListener) AddHandler(types []string, function func(api.Event)) (*lxd.EventTarget, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers = append(f.handlers, function)
+
+	return &lxd.EventTarget{}, nil
+}
+
+func (f *fakeEventListener) Wait() error {
+	<-f.disconnected
+	return nil
+}
+
+func (f *fakeEventListener) Disconnect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.disconnected:
+	default:
+		close(f.disconnected)
+	}
+}
+
+func (f *fakeEventListener) emit(event api.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	select {
+	case <-f.disconnected:
+		return
+	default:
+	}
+
+	for _, h := range f.handlers {
+		h(event)
+	}
+}
+
+func Test_isConnectionError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"api error", errors.New("not found"), false},
+		{"eof", &url.Error{Op: "Get", URL: "http://unix.socket/1.0", Err: io.EOF}, true},
+		{"reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"url error", &url.Error{Op: "Get", URL: "https://10.0.0.1:8443/1.0", Err: errors.New("no route to host")}, true},
+		{"timeout", &url.Error{Op: "Get", URL: "https://10.0.0.1:8443/1.0", Err: &net.DNSError{IsTimeout: true}}, false},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isConnectionError(tt.err))
+		})
+	}
+}
+
+func TestClient_GetContainer_Reconnect(t *testing.T) {
+	t.Parallel()
+
+	client, dropped := testClient()
+	dropped.GetContainerReturns(nil, "", &url.Error{Op: "Get", URL: "http://unix.socket/1.0/containers/foo", Err: io.EOF})
+
+	fresh := &lxdfakes.FakeContainerServer{}
+	fresh.GetEventsReturns(&lxd.EventListener{}, nil)
+	fresh.GetContainerReturns(basicContainer("foo", "bar"), "", nil)
+
+	client.dial = func(*http.Client) (lxd.ContainerServer, error) {
+		return fresh, nil
+	}
+
+	c, err := client.GetContainer("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", c.ID)
+	assert.Equal(t, 1, dropped.GetContainerCallCount())
+	assert.Equal(t, 1, fresh.GetContainerCallCount())
+	assert.Same(t, fresh, client.GetServer())
+}
+
+func TestClient_GetContainer_ReconnectFails(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerReturns(nil, "", &url.Error{Op: "Get", URL: "http://unix.socket/1.0/containers/foo", Err: io.EOF})
+
+	client.dial = func(*http.Client) (lxd.ContainerServer, error) {
+		return nil, syscall.ECONNREFUSED
+	}
+
+	_, err := client.GetContainer("foo")
+	assert.True(t, errors.Is(err, io.EOF))
+	assert.Equal(t, 1, fake.GetContainerCallCount())
+	assert.Same(t, fake, client.GetServer())
+}
+
+func TestClient_GetContainer_NoReconnectOnAPIError(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerReturns(nil, "", errors.New("not found"))
+
+	client.dial = func(*http.Client) (lxd.ContainerServer, error) {
+		t.Fatal("must not reconnect")
+		return nil, nil
+	}
+
+	_, err := client.GetContainer("foo")
+	assert.Error(t, err)
+	assert.Equal(t, 1, fake.GetContainerCallCount())
+}
+
+func TestClient_reconnect_AlreadyReconnected(t *testing.T) {
+	t.Parallel()
+
+	client, _ := testClient()
+
+	client.dial = func(*http.Client) (lxd.ContainerServer, error) {
+		t.Fatal("must not reconnect")
+		return nil, nil
+	}
+
+	err := client.reconnect(&lxdfakes.FakeContainerServer{})
+	assert.NoError(t, err)
+}
+
+func TestClient_reconnect_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	client, stale := testClient()
+
+	var dials int32
+
+	client.dial = func(*http.Client) (lxd.ContainerServer, error) {
+		atomic.AddInt32(&dials, 1)
+
+		fresh := &lxdfakes.FakeContainerServer{}
+		fresh.GetEventsReturns(&lxd.EventListener{}, nil)
+
+		return fresh, nil
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, client.reconnect(stale))
+			assert.NotNil(t, client.opWait())
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+	assert.NotSame(t, stale, client.GetServer())
+}
+
+func TestClient_checkConnection(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(&api.Server{}, "", nil)
+
+	err := client.checkConnection()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.GetServerCallCount())
+}
+
+func TestClient_eventsLost(t *testing.T) {
+	t.Parallel()
+
+	client, _ := testClient()
+	client.eventsDone = make(chan struct{})
+	assert.False(t, client.eventsLost())
+
+	close(client.eventsDone)
+	assert.True(t, client.eventsLost())
+}
+
+func TestClient_reconnect_DisconnectsEvents(t *testing.T) {
+	t.Parallel()
+
+	client, _ := testClient()
+
+	fresh := &lxdfakes.FakeContainerServer{}
+	fresh.GetContainerReturns(nil, "", shared.NewErrNotFound())
+
+	client.dial = func(*http.Client) (lxd.ContainerServer, error) {
+		return fresh, nil
+	}
+
+	var listeners []*fakeEventListener
+
+	client.listen = func(lxd.ContainerServer) (eventListener, error) {
+		listener := newFakeEventListener()
+		listeners = append(listeners, listener)
+
+		return listener, nil
+	}
+
+	assert.NoError(t, client.connect())
+	assert.NoError(t, client.reconnect(client.GetServer()))
+	assert.Len(t, listeners, 2)
+
+	// LXD sends every event to each listener still connected
+	event := api.Event{Type: "lifecycle", Metadata: []byte(`{"action":"container-started","source":"/1.0/containers/foo"}`)}
+	for _, listener := range listeners {
+		listener.emit(event)
+	}
+
+	// the event is only handled once
+	assert.Equal(t, 1, fresh.GetContainerCallCount())
+}
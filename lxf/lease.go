@@ -0,0 +1,263 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// ErrLeaseReclaimUnsupported is returned if leases can't be reclaimed with the connected LXD
+var ErrLeaseReclaimUnsupported = errors.New("lease reclaim unsupported")
+
+// dhcpRelease tells the dnsmasq serving the bridge to drop the lease. LXD has no API for it, the tool is part of
+// dnsmasq-utils
+var dhcpRelease = func(bridge string, ip net.IP, hwaddr string) error {
+	out, err := exec.Command("dhcp_release", bridge, ip.String(), hwaddr).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("dhcp_release: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// ReclaimLease removes the dynamic lease of ip in bridge, if no instance is associated with it anymore. The ip must be
+// recorded in the network config of sb, so only leases LXE got for its own pods are touched. The lease is released in
+// the dnsmasq of the bridge on this host, so a local LXD is required. Reclaiming a lease which doesn't exist is not an
+// error.
+func (l *client) ReclaimLease(sb *Sandbox, bridge string, ip net.IP) error {
+	if l.conn.IsRemote() {
+		return fmt.Errorf("%w: the dnsmasq of a remote LXD can't be reached", ErrLeaseReclaimUnsupported)
+	}
+
+	if ip.To4() == nil {
+		return fmt.Errorf("%w: only IPv4 leases can be reclaimed, got %v", ErrUsage, ip)
+	}
+
+	if !sb.hasIP(ip) {
+		return fmt.Errorf("%w: %v is not an ip of pod %v", ErrUsage, ip, sb.ID)
+	}
+
+	leases, err := l.GetServer().GetNetworkLeases(bridge)
+	if err != nil {
+		return err
+	}
+
+	for _, lease := range leases {
+		if !ip.Equal(net.ParseIP(lease.Address)) {
+			continue
+		}
+
+		// static leases are generated by LXD from the config of an existing instance
+		if lease.Type == "static" {
+			return fmt.Errorf("%w: lease of %v in bridge %v is static for %v", ErrUsage, ip, bridge, lease.Hostname)
+		}
+
+		owner, err := l.leaseOwner(bridge, ip, lease.Hwaddr)
+		if err != nil {
+			return err
+		}
+
+		if owner != "" {
+			return fmt.Errorf("%w: lease of %v in bridge %v is in use by %v", ErrUsage, ip, bridge, owner)
+		}
+
+		return dhcpRelease(bridge, ip, lease.Hwaddr)
+	}
+
+	return nil
+}
+
+// hasIP returns true if ip is one of the addresses recorded in the network config of the sandbox
+func (s *Sandbox) hasIP(ip net.IP) bool {
+	for _, v := range s.NetworkConfig.ModeData {
+		if ip.Equal(net.ParseIP(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// leaseOwner returns the name of the instance which has a nic in bridge either configured with ip or using hwaddr,
+// empty if there is none.
+func (l *client) leaseOwner(bridge string, ip net.IP, hwaddr string) (string, error) {
+	cts, err := l.GetServer().GetContainers()
+	if err != nil {
+		return "", err
+	}
+
+	for _, ct := range cts {
+		for name, dev := range ct.ExpandedDevices {
+			if dev["type"] != "nic" || (dev["parent"] != bridge && dev["network"] != bridge) {
+				continue
+			}
+
+			if ip.Equal(net.ParseIP(dev["ipv4.address"])) {
+				return ct.Name, nil
+			}
+
+			if hwaddr != "" && (strings.EqualFold(dev["hwaddr"], hwaddr) || strings.EqualFold(ct.Config["volatile."+name+".hwaddr"], hwaddr)) {
+				return ct.Name, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// BridgeUtilization counts the usable IPv4 addresses of bridge, which are the ones within its dhcp ranges or the whole
+// subnet if there are none, excluding the address of the bridge itself. Used are the addresses of leases within those,
+// so a monitoring can alert before new pods don't get an address anymore.
+func (l *client) BridgeUtilization(bridge string) (int, int, error) {
+	nw, _, err := l.GetServer().GetNetwork(bridge)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	bridgeIP, subnet, err := net.ParseCIDR(nw.Config["ipv4.address"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: bridge %v has no IPv4 subnet: %v", ErrUsage, bridge, err)
+	}
+
+	ranges, err := shared.ParseIPRanges(nw.Config["ipv4.dhcp.ranges"])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// without ranges the whole subnet is used
+	if len(ranges) == 0 {
+		ranges = []shared.IPRange{{}}
+	}
+
+	inRanges := func(ip net.IP) bool {
+		for _, r := range ranges {
+			if shared.CountUsableIPs(subnet, ip, ip) == 1 && r.Contains(ip) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var total int64
+	for _, r := range ranges {
+		total += shared.CountUsableIPs(subnet, r.Start, r.End)
+	}
+
+	if inRanges(bridgeIP) {
+		total--
+	}
+
+	leases, err := l.GetServer().GetNetworkLeases(bridge)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	used := 0
+	seen := make(map[string]bool)
+
+	for _, lease := range leases {
+		ip := net.ParseIP(lease.Address)
+		if ip == nil || ip.Equal(bridgeIP) || seen[ip.String()] || !inRanges(ip) {
+			continue
+		}
+
+		seen[ip.String()] = true
+		used++
+	}
+
+	return int(total), used, nil
+}
+
+// BridgeMember is a nic of an instance in a bridge
+type BridgeMember struct {
+	// ContainerID is the name of the instance
+	ContainerID string
+	// Nic is the name of the nic device
+	Nic string
+	// IPs are the configured addresses of the nic and the ones leased to its hwaddr. Empty if it has none, e.g. because
+	// the dhcp range of the bridge is exhausted
+	IPs []net.IP
+}
+
+// ContainersOnBridge lists the nics of all instances which are in bridge, sorted by instance and nic. Their addresses are
+// correlated from the static addresses of the nics and the leases of the bridge, so addresses leased twice or nics
+// without address show up.
+func (l *client) ContainersOnBridge(bridge string) ([]BridgeMember, error) {
+	leases, err := l.GetServer().GetNetworkLeases(bridge)
+	if err != nil {
+		return nil, err
+	}
+
+	cts, err := l.GetServer().GetContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	members := []BridgeMember{}
+
+	for _, ct := range cts {
+		for name, dev := range ct.ExpandedDevices {
+			if dev["type"] != "nic" || (dev["parent"] != bridge && dev["network"] != bridge) {
+				continue
+			}
+
+			hwaddr := dev["hwaddr"]
+			if hwaddr == "" {
+				hwaddr = ct.Config["volatile."+name+".hwaddr"]
+			}
+
+			members = append(members, BridgeMember{
+				ContainerID: ct.Name,
+				Nic:         name,
+				IPs:         memberIPs(dev, hwaddr, leases),
+			})
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].ContainerID != members[j].ContainerID {
+			return members[i].ContainerID < members[j].ContainerID
+		}
+
+		return members[i].Nic < members[j].Nic
+	})
+
+	return members, nil
+}
+
+// memberIPs returns the addresses configured in the nic dev and the ones of the leases of hwaddr, each only once
+func memberIPs(dev map[string]string, hwaddr string, leases []api.NetworkLease) []net.IP {
+	var ips []net.IP
+
+	add := func(ip net.IP) {
+		if ip == nil {
+			return
+		}
+
+		for _, known := range ips {
+			if known.Equal(ip) {
+				return
+			}
+		}
+
+		ips = append(ips, ip)
+	}
+
+	add(net.ParseIP(dev["ipv4.address"]))
+	add(net.ParseIP(dev["ipv6.address"]))
+
+	for _, lease := range leases {
+		if hwaddr != "" && strings.EqualFold(lease.Hwaddr, hwaddr) {
+			add(net.ParseIP(lease.Address))
+		}
+	}
+
+	return ips
+}
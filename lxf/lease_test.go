@@ -0,0 +1,226 @@
+package lxf
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLeaseSandbox returns a sandbox which got ip assigned
+func testLeaseSandbox(ip string) *Sandbox {
+	sb := &Sandbox{}
+	sb.ID = "foo"
+	sb.NetworkConfig.ModeData = map[string]string{"interface-address": ip}
+
+	return sb
+}
+
+func TestClient_ReclaimLease_Stale(t *testing.T) {
+	client, fake := testClient()
+
+	fake.GetNetworkLeasesReturns([]api.NetworkLease{
+		{Hostname: "foo", Address: "10.0.0.2", Hwaddr: "00:16:3e:00:00:02", Type: "dynamic"},
+	}, nil)
+	fake.GetContainersReturns([]api.Container{}, nil)
+
+	var released net.IP
+
+	orig := dhcpRelease
+	defer func() { dhcpRelease = orig }()
+
+	dhcpRelease = func(bridge string, ip net.IP, hwaddr string) error {
+		released = ip
+		return nil
+	}
+
+	err := client.ReclaimLease(testLeaseSandbox("10.0.0.2"), "lxebr0", net.ParseIP("10.0.0.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", released.String())
+}
+
+func TestClient_ReclaimLease_Missing(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetNetworkLeasesReturns([]api.NetworkLease{}, nil)
+
+	err := client.ReclaimLease(testLeaseSandbox("10.0.0.2"), "lxebr0", net.ParseIP("10.0.0.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetContainersCallCount())
+}
+
+func TestClient_ReclaimLease_Static(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetNetworkLeasesReturns([]api.NetworkLease{
+		{Hostname: "foo", Address: "10.0.0.2", Type: "static"},
+	}, nil)
+
+	err := client.ReclaimLease(testLeaseSandbox("10.0.0.2"), "lxebr0", net.ParseIP("10.0.0.2"))
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestClient_ReclaimLease_InUse(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetNetworkLeasesReturns([]api.NetworkLease{
+		{Hostname: "foo", Address: "10.0.0.2", Hwaddr: "00:16:3e:00:00:02", Type: "dynamic"},
+	}, nil)
+
+	ct := api.Container{Name: "bar"}
+	ct.Config = map[string]string{"volatile.eth0.hwaddr": "00:16:3E:00:00:02"}
+	ct.ExpandedDevices = map[string]map[string]string{
+		"eth0": {"type": "nic", "nictype": "bridged", "parent": "lxebr0"},
+	}
+	fake.GetContainersReturns([]api.Container{ct}, nil)
+
+	err := client.ReclaimLease(testLeaseSandbox("10.0.0.2"), "lxebr0", net.ParseIP("10.0.0.2"))
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestClient_ReclaimLease_IPv6(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	err := client.ReclaimLease(testLeaseSandbox("fd00::2"), "lxebr0", net.ParseIP("fd00::2"))
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetNetworkLeasesCallCount())
+}
+
+func TestClient_ReclaimLease_NotOfPod(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	// the lease isn't used by any instance, but LXE never assigned it to the pod
+	err := client.ReclaimLease(testLeaseSandbox("10.0.0.3"), "lxebr0", net.ParseIP("10.0.0.2"))
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetNetworkLeasesCallCount())
+}
+
+func TestClient_ReclaimLease_Remote(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	client.conn = Config{RemoteURL: "https://10.0.0.1:8443"}
+
+	err := client.ReclaimLease(testLeaseSandbox("10.0.0.2"), "lxebr0", net.ParseIP("10.0.0.2"))
+	assert.True(t, errors.Is(err, ErrLeaseReclaimUnsupported))
+	assert.Equal(t, 0, fake.GetNetworkLeasesCallCount())
+}
+
+func TestClient_BridgeUtilization(t *testing.T) {
+	t.Parallel()
+
+	leases := []api.NetworkLease{
+		{Address: "10.0.0.1"},  // bridge
+		{Address: "10.0.0.20"}, // in first range
+		{Address: "10.0.0.20"},
+		{Address: "10.0.0.55"}, // outside ranges
+		{Address: "10.0.0.201"},
+		{Address: "fd00::20"},
+	}
+
+	tests := []struct {
+		name      string
+		ranges    string
+		wantTotal int
+		wantUsed  int
+	}{
+		{"whole subnet", "", 253, 3},
+		{"ranges", "10.0.0.10-10.0.0.29,10.0.0.200-10.0.0.209", 30, 2},
+		{"range with bridge", "10.0.0.1-10.0.0.10", 9, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := testClient()
+
+			fake.GetNetworkReturns(&api.Network{NetworkPut: api.NetworkPut{Config: map[string]string{
+				"ipv4.address":     "10.0.0.1/24",
+				"ipv4.dhcp.ranges": tt.ranges,
+			}}}, "", nil)
+			fake.GetNetworkLeasesReturns(leases, nil)
+
+			total, used, err := client.BridgeUtilization("lxebr0")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTotal, total)
+			assert.Equal(t, tt.wantUsed, used)
+		})
+	}
+}
+
+func TestClient_BridgeUtilization_NoIPv4(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetNetworkReturns(&api.Network{NetworkPut: api.NetworkPut{Config: map[string]string{"ipv4.address": "none"}}}, "", nil)
+
+	_, _, err := client.BridgeUtilization("lxebr0")
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetNetworkLeasesCallCount())
+}
+
+func TestClient_ContainersOnBridge(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetNetworkLeasesReturns([]api.NetworkLease{
+		{Hostname: "foo", Address: "10.0.0.2", Hwaddr: "00:16:3E:00:00:02", Type: "dynamic"},
+		{Hostname: "foo", Address: "fd00::2", Hwaddr: "00:16:3e:00:00:02", Type: "dynamic"},
+		{Hostname: "other", Address: "10.0.0.9", Hwaddr: "00:16:3e:00:00:09", Type: "dynamic"},
+	}, nil)
+	fake.GetContainersReturns([]api.Container{
+		{
+			Name: "foo",
+			ContainerPut: api.ContainerPut{Config: map[string]string{
+				"volatile.eth0.hwaddr": "00:16:3e:00:00:02",
+			}},
+			ExpandedDevices: map[string]map[string]string{
+				"eth0": {"type": "nic", "nictype": "bridged", "parent": "lxebr0"},
+				"eth1": {"type": "nic", "nictype": "bridged", "parent": "br1"},
+				"root": {"type": "disk", "path": "/"},
+			},
+		},
+		{
+			Name: "bar",
+			ExpandedDevices: map[string]map[string]string{
+				"eth0": {"type": "nic", "network": "lxebr0", "ipv4.address": "10.0.0.3"},
+				"eth1": {"type": "nic", "network": "lxebr0"},
+			},
+		},
+	}, nil)
+
+	members, err := client.ContainersOnBridge("lxebr0")
+	assert.NoError(t, err)
+	assert.Equal(t, []BridgeMember{
+		{ContainerID: "bar", Nic: "eth0", IPs: []net.IP{net.ParseIP("10.0.0.3")}},
+		{ContainerID: "bar", Nic: "eth1"},
+		{ContainerID: "foo", Nic: "eth0", IPs: []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("fd00::2")}},
+	}, members)
+}
+
+func TestClient_ContainersOnBridge_LeasesFail(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetNetworkLeasesReturns(nil, errors.New("not found"))
+
+	_, err := client.ContainersOnBridge("lxebr0")
+	assert.Error(t, err)
+	assert.Equal(t, 0, fake.GetContainersCallCount())
+}
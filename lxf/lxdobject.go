@@ -1,13 +1,25 @@
 package lxf // import "github.com/automaticserver/lxe/lxf"
 
-import "github.com/automaticserver/lxe/lxf/device"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/automaticserver/lxe/lxf/device"
+)
+
+const (
+	cfgRawLXC     = "raw.lxc"
+	cfgConfigHash = "user.confighash"
+)
 
 // LXDObject contains common properties of containers and sandboxes without CRI influence
 type LXDObject struct {
 	// client holds the lxf.Client representing as a lxd client
 	// nolint: structcheck
 	client *client
-	// ID is a unique generated ID and is read-only
+	// ID is a unique ID created by the IDGenerator of the client and is read-only
 	ID string
 	// ETag uniquely identifies user modifiable content of this resource, prevents race conditions when saving
 	// see: https://lxd.readthedocs.io/en/latest/api-extensions/#etag
@@ -19,4 +31,138 @@ type LXDObject struct {
 	Devices device.Devices
 	// Config contains options not provided by a own property
 	Config map[string]string
+	// RawLXC are lines of the raw.lxc config which are passed to liblxc as is. Lines LXE generates from other fields are
+	// merged into it, so reading an object back also lists these
+	RawLXC []string
+	// SeccompProfile restricts the syscalls in the notation of kubernetes: runtime/default, unconfined or
+	// localhost/<path> of a policy in the format of liblxc. Containers without profile use the one of their sandbox
+	SeccompProfile string
+	// ShmSize is the size of a tmpfs mounted at /dev/shm, like 64MB or 1GiB. LXD has no disk device for a tmpfs, so it
+	// is mounted by liblxc. A container without size takes the one of its sandbox. If neither is set, /dev/shm is left
+	// to LXD and the init of the container
+	ShmSize string
+	// DeviceCgroupRules allow or deny access to device nodes, e.g. to a GPU. They are added to the raw.lxc config, so
+	// reading an object back moves matching lxc.cgroup.devices lines of RawLXC into this field. A container without
+	// rules takes the ones of its sandbox
+	DeviceCgroupRules []DeviceCgroupRule
+	// Architecture of the instance like x86_64 or an alias like arm64, which must be supported by the node. A sandbox
+	// only records it for its containers which have none set. If empty, LXD uses the architecture of the image
+	Architecture string
+	// Autostart lets LXD start the instance when it starts itself, e.g. after a reboot of the node. Instances with
+	// higher AutostartPriority start first and LXD waits AutostartDelay seconds after starting this one. Values of a
+	// sandbox apply to its containers through the profile, unset ones are left to LXD
+	Autostart         *bool
+	AutostartPriority int
+	AutostartDelay    int
+	// AppArmorUnconfined runs the instance without AppArmor profile, which is only allowed if it's privileged. A sandbox
+	// passes it on to its privileged containers
+	AppArmorUnconfined bool
+	// PidsLimit is the maximum number of processes in the instance, which protects the node from fork bombs. LXD
+	// limits each instance on its own, so the one of a sandbox applies to each of its containers and not to the pod as
+	// a whole. Zero means unlimited
+	PidsLimit int64
+	// MemorySwap enables or disables swap for the instance with "true" or "false", empty leaves it to LXD, which
+	// enables it. MemorySwappiness is the swap priority of LXD from 0 to 10, which LXD turns into the swappiness of the
+	// cgroup; nil keeps its default. Both are supported by containers only and a sandbox passes them on to its containers
+	// through the profile
+	MemorySwap       string
+	MemorySwappiness *int
+	// CPUPriority is the priority from 1 to 10 the instance gets cpu time with while the cpus are busy, zero leaves it
+	// to LXD, which uses the highest. CPUAllowance limits the cpu time, either softly by a percentage like 50% or hard
+	// by a time within a period like 25ms/100ms. See CPUPriorityFromShares and CPUAllowanceFromQuota for the kubernetes
+	// counterparts. Both are ignored for virtual machines, as LXD doesn't support them there
+	CPUPriority  int
+	CPUAllowance string
+	// HugepageLimits maps page sizes like 2MB to the bytes of hugepages of that size the instance may use. Only the
+	// listed sizes are limited, each must be supported by LXD and the node
+	HugepageLimits map[string]int64
+	// Timezone is a name of the tz database like Europe/Zurich, which is passed as TZ variable. The image must ship the
+	// tz database for it to take effect. A sandbox passes it on to its containers through the profile. If empty, the
+	// configuration of the image is left untouched
+	Timezone string
+	// InstanceType selects whether LXD runs a system container or a virtual machine, empty means a container. Virtual
+	// machines isolate stronger, but support less: no passed through device nodes and nothing which is passed to
+	// liblxc. A sandbox only records it for its containers which have none set. It can't change after creation
+	InstanceType InstanceType
+	// DeleteProtected keeps LXD from deleting the instance. A sandbox passes it on to its containers through the profile
+	// and refuses to be deleted itself. Deleting either only succeeds if ForceDelete is set, which clears the protection
+	// first
+	DeleteProtected bool
+	// ForceDelete lets Delete clear the delete protection. It's not stored, so it has to be set on each object anew, or
+	// for all objects with Config.ForceDeleteProtected
+	ForceDelete bool
+	// AppliedConfigHash is the ConfigHash stored by the last apply and is read-only
+	AppliedConfigHash string
+
+	// appliedNics are the options of the nics as last read from or saved to LXD, see changedNics
+	appliedNics map[string]map[string]string
+}
+
+// ConfigHash returns a hash of the desired state of the object, which a reconciliation loop compares against
+// AppliedConfigHash to know whether an apply is needed. It is fed by every field of LXDObject an apply can change in
+// LXD, Config without the volatile keys LXD manages by itself. Properties of the embedding Container or Sandbox don't
+// feed the hash.
+func (o *LXDObject) ConfigHash() string {
+	in := struct {
+		Devices        map[string]map[string]string
+		Config         map[string]string
+		RawLXC         []string
+		SeccompProfile string
+		ShmSize        string
+		DeviceCgroups  []string
+		Autostart      *bool
+		Priority       int
+		Delay          int
+		Unconfined     bool
+		PidsLimit      int64
+		Swap           string
+		Swappiness     *int
+		CPUPriority    int
+		CPUAllowance   string
+		Hugepages      map[string]int64
+		Timezone       string
+		Protected      bool
+	}{
+		Devices:        make(map[string]map[string]string, len(o.Devices)),
+		Config:         make(map[string]string, len(o.Config)),
+		RawLXC:         o.RawLXC,
+		SeccompProfile: o.SeccompProfile,
+		ShmSize:        o.ShmSize,
+		Autostart:      o.Autostart,
+		Priority:       o.AutostartPriority,
+		Delay:          o.AutostartDelay,
+		Unconfined:     o.AppArmorUnconfined,
+		PidsLimit:      o.PidsLimit,
+		Swap:           o.MemorySwap,
+		Swappiness:     o.MemorySwappiness,
+		CPUPriority:    o.CPUPriority,
+		CPUAllowance:   o.CPUAllowance,
+		Hugepages:      o.HugepageLimits,
+		Timezone:       o.Timezone,
+		Protected:      o.DeleteProtected,
+	}
+
+	for _, r := range o.DeviceCgroupRules {
+		in.DeviceCgroups = append(in.DeviceCgroups, r.rawLXC())
+	}
+
+	for _, d := range o.Devices {
+		name, options := d.ToMap()
+		in.Devices[name] = options
+	}
+
+	for k, v := range o.Config {
+		if k == cfgConfigHash || strings.HasPrefix(k, cfgVolatile+".") {
+			continue
+		}
+
+		in.Config[k] = v
+	}
+
+	// json encodes map keys sorted, so the output is stable. Encoding only strings can't fail
+	b, _ := json.Marshal(in)
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,69 @@
+package lxf
+
+import (
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLXDObject() *LXDObject {
+	return &LXDObject{
+		Devices: device.Devices{&device.Disk{Path: "/data", Source: "/srv/data"}},
+		Config:  map[string]string{"limits.processes": "100"},
+		RawLXC:  []string{"lxc.apparmor.profile = unconfined"},
+	}
+}
+
+func TestLXDObject_ConfigHash_Stable(t *testing.T) {
+	t.Parallel()
+
+	a := testLXDObject()
+	b := testLXDObject()
+
+	assert.Len(t, a.ConfigHash(), 64)
+	assert.Equal(t, a.ConfigHash(), b.ConfigHash())
+}
+
+func TestLXDObject_ConfigHash_IgnoresVolatileAndOwnKey(t *testing.T) {
+	t.Parallel()
+
+	a := testLXDObject()
+	b := testLXDObject()
+	b.Config["volatile.eth0.hwaddr"] = "00:16:3e:00:00:01"
+	b.Config[cfgConfigHash] = "foo"
+	b.AppliedConfigHash = "foo"
+	b.ID = "bar"
+
+	assert.Equal(t, a.ConfigHash(), b.ConfigHash())
+}
+
+func TestLXDObject_ConfigHash_Changes(t *testing.T) {
+	t.Parallel()
+
+	base := testLXDObject().ConfigHash()
+
+	tests := []struct {
+		name   string
+		modify func(o *LXDObject)
+	}{
+		{"config", func(o *LXDObject) { o.Config["limits.processes"] = "200" }},
+		{"device", func(o *LXDObject) { o.Devices[0].(*device.Disk).Readonly = true }},
+		{"device added", func(o *LXDObject) { o.Devices.Upsert(&device.Nic{Name: "eth1", NicType: "bridged", Parent: "br0"}) }},
+		{"raw lxc", func(o *LXDObject) { o.RawLXC = append(o.RawLXC, "lxc.cap.drop = sys_time") }},
+		{"autostart priority", func(o *LXDObject) { o.AutostartPriority = 10 }},
+		{"apparmor unconfined", func(o *LXDObject) { o.AppArmorUnconfined = true }},
+		{"memory swap", func(o *LXDObject) { o.MemorySwap = MemorySwapDisabled }},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := testLXDObject()
+			tt.modify(o)
+			assert.NotEqual(t, base, o.ConfigHash())
+		})
+	}
+}
@@ -1,14 +1,17 @@
 package lxf // import "github.com/automaticserver/lxe/lxf"
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/shared"
+	"github.com/dionysius/errand"
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared/api"
 	opencontainers "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
@@ -28,7 +31,17 @@ func (l *client) NewContainer(sandboxID string, additionalProfiles ...string) *C
 
 // GetContainer returns the container identified by id
 func (l *client) GetContainer(id string) (*Container, error) {
-	ct, ETag, err := l.server.GetContainer(id)
+	var (
+		ct   *api.Container
+		ETag string
+	)
+
+	err := l.withReconnect(func(server lxd.ContainerServer) error {
+		var err error
+		ct, ETag, err = server.GetContainer(id)
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -47,11 +60,33 @@ func (l *client) ListContainers() ([]*Container, error) {
 		etag string
 	)
 
-	cts, err := l.server.GetContainers()
+	var cts []api.Container
+
+	err = l.withReconnect(func(server lxd.ContainerServer) error {
+		cts, err = server.GetContainers()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// the container API of LXD lists no virtual machines
+	if l.GetServer().HasExtension("instances") {
+		var vms []api.Instance
+
+		err = l.withReconnect(func(server lxd.ContainerServer) error {
+			vms, err = server.GetInstances(api.InstanceTypeVM)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range vms {
+			cts = append(cts, instanceToContainer(&vms[i]))
+		}
+	}
+
 	var cl = []*Container{}
 
 	for _, ct := range cts {
@@ -71,6 +106,148 @@ func (l *client) ListContainers() ([]*Container, error) {
 	return cl, nil
 }
 
+// RenameContainer renames the stopped container oldID to newName and returns the new id. LXD doesn't allow renaming
+// running containers, neither can the new name be used already.
+func (l *client) RenameContainer(oldID, newName string) (string, error) {
+	_, _, err := l.GetServer().GetContainer(newName)
+	if err == nil {
+		return "", fmt.Errorf("rename container %v: %w: %v", oldID, ErrExists, newName)
+	} else if !shared.IsErrNotFound(err) {
+		return "", err
+	}
+
+	ct, _, err := l.GetServer().GetContainer(oldID)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return "", fmt.Errorf("container %w: %s", shared.NewErrNotFound(), oldID)
+		}
+
+		return "", err
+	}
+
+	if ct.StatusCode != api.Stopped {
+		return "", fmt.Errorf("%w: container %v must be stopped to be renamed, but is %v", ErrUsage, oldID, ct.Status)
+	}
+
+	err = l.opWait().RenameContainer(oldID, newName)
+	if err != nil {
+		return "", err
+	}
+
+	return newName, nil
+}
+
+// MoveStopTimeout is the time in seconds a running container has to shut down before it is moved to another member
+var MoveStopTimeout = 30
+
+// MoveContainer moves the container to targetMember of the LXD cluster. A live migration keeps the running container
+// running and requires CRIU on both members, ErrStatefulUnsupported is returned without. Otherwise a running container
+// is stopped, moved and started again.
+func (l *client) MoveContainer(id, targetMember string, live bool) error {
+	if !l.GetServer().IsClustered() {
+		return fmt.Errorf("%w: moving container %v requires a LXD cluster", ErrUsage, id)
+	}
+
+	ct, _, err := l.GetServer().GetContainer(id)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+		}
+
+		return err
+	}
+
+	if ct.Location == targetMember {
+		return nil
+	}
+
+	running := ct.StatusCode == api.Running
+	log := log.WithField("containerid", id).WithField("target", targetMember).WithField("live", live)
+
+	progress := func(op api.Operation) {
+		for key, val := range op.Metadata {
+			if strings.HasSuffix(key, "_progress") {
+				log.WithField(key, val).Info("moving container")
+			}
+		}
+	}
+
+	if live {
+		if !running {
+			return fmt.Errorf("%w: container %v must be running to be migrated live, but is %v", ErrUsage, id, ct.Status)
+		}
+
+		err = l.checkStateful(ErrLiveMigration, id)
+		if err != nil {
+			return err
+		}
+
+		err = l.opWait().MoveContainer(id, targetMember, true, progress)
+		if isCRIUError(err) {
+			return l.statefulError(ErrLiveMigration, id, err)
+		}
+
+		return err
+	}
+
+	if running {
+		log.Info("stopping container to move it")
+
+		err = l.opWait().StopContainer(id, MoveStopTimeout, 1)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = l.opWait().MoveContainer(id, targetMember, false, progress)
+	if err != nil {
+		// the container stays on its member, so at least bring it back up
+		if running {
+			return errand.Append(err, l.opWait().StartContainer(id))
+		}
+
+		return err
+	}
+
+	if running {
+		return l.opWait().StartContainer(id)
+	}
+
+	return nil
+}
+
+// WaitRunningInterval is how often WaitRunning looks up the state of the container
+var WaitRunningInterval = 100 * time.Millisecond
+
+// WaitRunning blocks until the container is running and returns the pid of its init process. It keeps waiting while
+// the container is in any other state, so it can be called right after the start was requested, and returns when ctx
+// is done.
+func (l *client) WaitRunning(ctx context.Context, id string) (int64, error) {
+	ticker := time.NewTicker(WaitRunningInterval)
+	defer ticker.Stop()
+
+	for {
+		state, _, err := l.GetServer().GetContainerState(id)
+		if err != nil {
+			if shared.IsErrNotFound(err) {
+				return 0, fmt.Errorf("container %w: %s", shared.NewErrNotFound(), id)
+			}
+
+			return 0, err
+		}
+
+		if state.StatusCode == api.Running && state.Pid > 0 {
+			return state.Pid, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("container %v is %v: %w", id, state.Status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
 // toContainer will convert an lxd container to lxf format
 func (l *client) toContainer(ct *api.Container, etag string) (*Container, error) { // nolint: gocognit
 	var err error
@@ -83,6 +260,14 @@ func (l *client) toContainer(ct *api.Container, etag string) (*Container, error)
 		}
 	}
 
+	var restartCount uint64
+	if restartCountS, is := ct.Config[cfgRestartCount]; is {
+		restartCount, err = strconv.ParseUint(restartCountS, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var privileged bool
 	if privilegedS, is := ct.Config[cfgSecurityPrivileged]; is {
 		privileged, err = strconv.ParseBool(privilegedS)
@@ -91,6 +276,48 @@ func (l *client) toContainer(ct *api.Container, etag string) (*Container, error)
 		}
 	}
 
+	var runAsUser, runAsGroup *int64
+	if userS, is := ct.Config[cfgSecurityRunAsUser]; is {
+		user, err := strconv.ParseInt(userS, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		runAsUser = &user
+	}
+
+	if groupS, is := ct.Config[cfgSecurityRunAsGroup]; is {
+		group, err := strconv.ParseInt(groupS, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		runAsGroup = &group
+	}
+
+	var groups []int64
+	if groupsS, is := ct.Config[cfgSecurityGroups]; is {
+		groups, err = parseIDs(groupsS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fsGroup *int64
+	if fsGroupS, is := ct.Config[cfgSecurityFSGroup]; is {
+		group, err := strconv.ParseInt(fsGroupS, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		fsGroup = &group
+	}
+
+	tmpfs, err := parseTmpfsConfig(ct.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	createdAt := time.Time{}.UnixNano()
 	if createdAtS, is := ct.Config[cfgCreatedAt]; is {
 		createdAt, err = strconv.ParseInt(createdAtS, 10, 64)
@@ -133,23 +360,76 @@ func (l *client) toContainer(ct *api.Container, etag string) (*Container, error)
 	c.CreatedAt = time.Unix(0, createdAt)
 	c.StartedAt = time.Unix(0, startedAt)
 	c.FinishedAt = time.Unix(0, finishedAt)
+	c.RestartCount = uint32(restartCount)
 
 	c.Environment = extractEnvVars(ct.Config)
+
+	c.parseKeepImageTemplatesConfig(ct.Config)
+	c.InstanceType = InstanceType(ct.Config[cfgInstanceType])
+
+	if c.parseTimezoneConfig(ct.Config) {
+		delete(c.Environment, "TZ")
+	}
+
 	c.Privileged = privileged
+	c.Ephemeral = ct.Ephemeral
+	c.RunAsUser = runAsUser
+	c.RunAsGroup = runAsGroup
+	c.SupplementalGroups = groups
+	c.FSGroup = fsGroup
+	c.Tmpfs = tmpfs
+	c.DeviceCgroupRules, c.RawLXC = splitDeviceCgroupRules(parseSandboxRawLXCConfig(ct.Config, rawLXCLines(ct.Config)))
+	c.AppArmorUnconfined, c.RawLXC = parseAppArmorConfig(ct.Config, c.RawLXC)
+	c.PIDNamespaceHolder, c.RawLXC = parsePIDNamespaceConfig(ct.Config, c.RawLXC)
+
+	c.InitCommand, c.RawLXC, err = parseInitCommand(ct.Config, c.RawLXC)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.parseBootConfig(ct.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.parsePidsLimitConfig(ct.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.parseDeleteProtectionConfig(ct.Config)
+
+	err = c.parseMemorySwapConfig(ct.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.parseCPUConfig(ct.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.parseHugepagesConfig(ct.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SeccompProfile = ct.Config[cfgSeccompProfile]
+	c.ShmSize = ct.Config[cfgShmSize]
+	c.Architecture = ct.Architecture
+	c.AppliedConfigHash = ct.Config[cfgConfigHash]
 	c.CloudInitUserData = ct.Config[cfgCloudInitUserData]
 	c.CloudInitMetaData = ct.Config[cfgCloudInitMetaData]
 	c.CloudInitNetworkConfig = ct.Config[cfgCloudInitNetworkConfig]
 
 	// get devices
-	for name, options := range ct.Devices {
-		d, err := device.Detect(name, options)
-		if err != nil {
-			return nil, err
-		}
-
-		c.Devices.Upsert(d)
+	c.Devices, err = detectDevices(ct.Devices)
+	if err != nil {
+		return nil, err
 	}
 
+	c.appliedNics = nicOptions(c.Devices)
+
 	c.Resources = &opencontainers.LinuxResources{}
 	c.Resources.CPU = &opencontainers.LinuxCPU{}
 
@@ -1,11 +1,14 @@
 package lxf
 
 import (
+	"context"
+	"errors"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
 	"github.com/automaticserver/lxe/shared"
 	"github.com/lxc/lxd/shared/api"
 	opencontainers "github.com/opencontainers/runtime-spec/specs-go"
@@ -54,6 +57,27 @@ func TestClient_GetContainer_Minimal(t *testing.T) {
 	assert.Equal(t, 1, fake.GetContainerCallCount())
 }
 
+func TestClient_GetContainer_UnknownDevice(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "bar")
+	ct.Devices = map[string]map[string]string{
+		"ib0":  {"type": "infiniband", "nictype": "physical"},
+		"eth0": {"type": "none"},
+	}
+
+	fake.GetContainerReturns(ct, "", nil)
+
+	s, err := client.GetContainer("foo")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, device.Devices{
+		&device.None{KeyName: "eth0"},
+		&device.Unknown{KeyName: "ib0", Options: map[string]string{"type": "infiniband", "nictype": "physical"}},
+	}, s.Devices)
+}
+
 func TestClient_GetContainer_Missing(t *testing.T) {
 	t.Parallel()
 
@@ -125,6 +149,27 @@ func TestClient_ListContainers_Missing(t *testing.T) {
 	assert.Equal(t, 1, fake.GetContainersCallCount())
 }
 
+func TestClient_ListContainers_VirtualMachines(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	vm := basicContainer("vm", "default")
+	vm.Config[cfgInstanceType] = string(InstanceTypeVM)
+
+	fake.HasExtensionReturns(true)
+	fake.GetContainersReturns([]api.Container{*basicContainer("foo", "default")}, nil)
+	fake.GetInstancesReturns([]api.Instance{{Name: vm.Name, InstancePut: api.InstancePut{Config: vm.Config, Profiles: vm.Profiles}}}, nil)
+
+	sl, err := client.ListContainers()
+	assert.NoError(t, err)
+	assert.Len(t, sl, 2)
+	assert.Equal(t, "instances", fake.HasExtensionArgsForCall(0))
+	assert.Equal(t, api.InstanceTypeVM, fake.GetInstancesArgsForCall(0))
+	assert.Equal(t, InstanceType(""), sl[0].InstanceType)
+	assert.Equal(t, InstanceTypeVM, sl[1].InstanceType)
+}
+
 func TestClient_ListContainers_NonCri(t *testing.T) {
 	t.Parallel()
 
@@ -138,6 +183,55 @@ func TestClient_ListContainers_NonCri(t *testing.T) {
 	assert.Equal(t, 1, fake.GetContainersCallCount())
 }
 
+func TestClient_RenameContainer_Stopped(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	ct := basicContainer("foo", "default")
+	ct.StatusCode = api.Stopped
+
+	fake.GetContainerReturnsOnCall(0, nil, "", shared.NewErrNotFound())
+	fake.GetContainerReturnsOnCall(1, ct, "", nil)
+	fake.RenameContainerReturns(fakeOp, nil)
+
+	id, err := client.RenameContainer("foo", "bar")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", id)
+	assert.Equal(t, 1, fake.RenameContainerCallCount())
+}
+
+func TestClient_RenameContainer_TargetExists(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetContainerReturns(basicContainer("bar", "default"), "", nil)
+
+	_, err := client.RenameContainer("foo", "bar")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrExists))
+	assert.Equal(t, 0, fake.RenameContainerCallCount())
+}
+
+func TestClient_RenameContainer_Running(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.StatusCode = api.Running
+
+	fake.GetContainerReturnsOnCall(0, nil, "", shared.NewErrNotFound())
+	fake.GetContainerReturnsOnCall(1, ct, "", nil)
+
+	_, err := client.RenameContainer("foo", "bar")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.RenameContainerCallCount())
+}
+
 func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 	t.Parallel()
 
@@ -150,6 +244,7 @@ func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 	ct := &api.Container{
 		Name: "containerName",
 		ContainerPut: api.ContainerPut{
+			Architecture: "aarch64",
 			Config: map[string]string{
 				cfgVolatileBaseImage:             "image",
 				cfgMetaName:                      "metaName",
@@ -161,8 +256,19 @@ func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 				cfgCreatedAt:                     strconv.FormatInt(now.UnixNano(), 10),
 				cfgStartedAt:                     strconv.FormatInt(past.UnixNano(), 10),
 				cfgFinishedAt:                    strconv.FormatInt(future.UnixNano(), 10),
+				cfgRestartCount:                  "3",
 				cfgEnvironmentPrefix + ".data":   "content",
 				cfgSecurityPrivileged:            "true",
+				cfgSecurityRunAsUser:             "1000",
+				cfgSecurityRunAsGroup:            "100",
+				cfgSecurityGroups:                "10,20",
+				cfgSecurityFSGroup:               "2000",
+				cfgTmpfs:                         `[{"Path":"/cache","Size":1024}]`,
+				cfgRawLXC:                        "lxc.include = /foo\nlxc.init.uid = 1000\nlxc.cgroup.devices.allow = c 195:* rwm",
+				cfgConfigHash:                    "confighash",
+				cfgSeccompProfile:                SeccompProfileUnconfined,
+				cfgSyscallsBlacklistDefault:      "false",
+				cfgShmSize:                       "64MB",
 				cfgCloudInitUserData:             "userData",
 				cfgCloudInitMetaData:             "metaData",
 				cfgCloudInitNetworkConfig:        "networkConfig",
@@ -176,7 +282,8 @@ func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 					"type": "none",
 				},
 			},
-			Profiles: []string{"profile"},
+			Profiles:  []string{"profile"},
+			Ephemeral: true,
 		},
 		StatusCode: api.Aborting,
 	}
@@ -192,6 +299,7 @@ func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 	exp.Profiles = []string{"profile"}
 	exp.Image = "image"
 	exp.Privileged = true
+	exp.Ephemeral = true
 	exp.Environment = map[string]string{"data": "content"}
 	exp.Labels = map[string]string{"alabel": "aLabel"}
 	exp.Annotations = map[string]string{"anannotation": "anAnnotation"}
@@ -200,12 +308,31 @@ func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 	exp.CreatedAt = now
 	exp.StartedAt = past
 	exp.FinishedAt = future
+	exp.RestartCount = 3
 	exp.StateName = ContainerStateExited
 	exp.LogPath = "logPath"
 	exp.CloudInitUserData = "userData"
 	exp.CloudInitMetaData = "metaData"
 	exp.CloudInitNetworkConfig = "networkConfig"
 
+	var user int64 = 1000
+	var group int64 = 100
+
+	exp.RunAsUser = &user
+	exp.RunAsGroup = &group
+	exp.SupplementalGroups = []int64{10, 20}
+
+	var fsGroup int64 = 2000
+
+	exp.FSGroup = &fsGroup
+	exp.Tmpfs = []TmpfsMount{{Path: "/cache", Size: 1024}}
+	exp.RawLXC = []string{"lxc.include = /foo", "lxc.init.uid = 1000"}
+	exp.AppliedConfigHash = "confighash"
+	exp.SeccompProfile = SeccompProfileUnconfined
+	exp.ShmSize = "64MB"
+	exp.DeviceCgroupRules = []DeviceCgroupRule{{Type: "c", Major: "195", Minor: "*", Access: "rwm"}}
+	exp.Architecture = "aarch64"
+
 	var shares uint64 = 600
 	var quota int64 = 300
 	var period uint64 = 100
@@ -228,3 +355,168 @@ func TestClient_toContainer_AllFieldsSuccessful(t *testing.T) {
 }
 
 // TODO lifecycle event handler, but first network modes need an interface
+
+func testMoveClient(status api.StatusCode) (*client, *lxdfakes.FakeContainerServer, *lxdfakes.FakeContainerServer, *lxdfakes.FakeOperation) {
+	client, fake := testClient()
+	fakeTarget := &lxdfakes.FakeContainerServer{}
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	ct := basicContainer("foo", "default")
+	ct.StatusCode = status
+	ct.Location = "node1"
+
+	fake.IsClusteredReturns(true)
+	fake.GetContainerReturns(ct, "", nil)
+	fake.UseTargetReturns(fakeTarget)
+	fake.UpdateContainerStateReturns(fakeOp, nil)
+	fakeTarget.MigrateContainerReturns(fakeOp, nil)
+
+	return client, fake, fakeTarget, fakeOp
+}
+
+func TestClient_MoveContainer_NotClustered(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	err := client.MoveContainer("foo", "node2", false)
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+}
+
+func TestClient_MoveContainer_AlreadyThere(t *testing.T) {
+	t.Parallel()
+
+	client, fake, _, _ := testMoveClient(api.Running)
+
+	err := client.MoveContainer("foo", "node1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.UseTargetCallCount())
+}
+
+func TestClient_MoveContainer_Cold(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeTarget, _ := testMoveClient(api.Running)
+
+	err := client.MoveContainer("foo", "node2", false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "node2", fake.UseTargetArgsForCall(0))
+	_, post := fakeTarget.MigrateContainerArgsForCall(0)
+	assert.False(t, post.Live)
+
+	assert.Equal(t, 2, fake.UpdateContainerStateCallCount())
+	_, stop, _ := fake.UpdateContainerStateArgsForCall(0)
+	assert.Equal(t, "stop", stop.Action)
+	_, start, _ := fake.UpdateContainerStateArgsForCall(1)
+	assert.Equal(t, "start", start.Action)
+}
+
+func TestClient_MoveContainer_ColdStopped(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeTarget, _ := testMoveClient(api.Stopped)
+
+	err := client.MoveContainer("foo", "node2", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fakeTarget.MigrateContainerCallCount())
+	assert.Equal(t, 0, fake.UpdateContainerStateCallCount())
+}
+
+func TestClient_MoveContainer_ColdFailedRestarts(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeTarget, _ := testMoveClient(api.Running)
+
+	fakeTarget.MigrateContainerReturns(nil, errors.New("no space left"))
+
+	err := client.MoveContainer("foo", "node2", false)
+	assert.Error(t, err)
+	assert.Equal(t, 2, fake.UpdateContainerStateCallCount())
+	_, start, _ := fake.UpdateContainerStateArgsForCall(1)
+	assert.Equal(t, "start", start.Action)
+}
+
+func TestClient_MoveContainer_Live(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeTarget, _ := testMoveClient(api.Running)
+
+	err := client.MoveContainer("foo", "node2", true)
+	assert.NoError(t, err)
+
+	_, post := fakeTarget.MigrateContainerArgsForCall(0)
+	assert.True(t, post.Live)
+	assert.Equal(t, 0, fake.UpdateContainerStateCallCount())
+}
+
+func TestClient_MoveContainer_LiveWithoutCRIU(t *testing.T) {
+	t.Parallel()
+
+	client, _, _, fakeOp := testMoveClient(api.Running)
+
+	fakeOp.WaitReturns(errors.New("Unable to perform container live migration. CRIU isn't installed on the source server"))
+
+	err := client.MoveContainer("foo", "node2", true)
+	assert.True(t, errors.Is(err, ErrLiveMigration))
+}
+
+func TestClient_MoveContainer_LiveStopped(t *testing.T) {
+	t.Parallel()
+
+	client, _, fakeTarget, _ := testMoveClient(api.Stopped)
+
+	err := client.MoveContainer("foo", "node2", true)
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fakeTarget.MigrateContainerCallCount())
+}
+
+func TestClient_WaitRunning_Running(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerStateReturns(&api.ContainerState{StatusCode: api.Running, Pid: 42}, "", nil)
+
+	pid, err := client.WaitRunning(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), pid)
+	assert.Equal(t, 1, fake.GetContainerStateCallCount())
+}
+
+func TestClient_WaitRunning_Starting(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerStateReturnsOnCall(0, &api.ContainerState{StatusCode: api.Stopped}, "", nil)
+	fake.GetContainerStateReturnsOnCall(1, &api.ContainerState{StatusCode: api.Running}, "", nil)
+	fake.GetContainerStateReturnsOnCall(2, &api.ContainerState{StatusCode: api.Running, Pid: 42}, "", nil)
+
+	pid, err := client.WaitRunning(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), pid)
+	assert.Equal(t, 3, fake.GetContainerStateCallCount())
+}
+
+func TestClient_WaitRunning_Timeout(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerStateReturns(&api.ContainerState{StatusCode: api.Stopped, Status: "Stopped"}, "", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.WaitRunning(ctx, "foo")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClient_WaitRunning_Missing(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerStateReturns(nil, "", shared.NewErrNotFound())
+
+	_, err := client.WaitRunning(context.Background(), "foo")
+	assert.True(t, shared.IsErrNotFound(err))
+}
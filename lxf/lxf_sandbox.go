@@ -6,8 +6,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/shared"
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared/api"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -25,7 +25,17 @@ func (l *client) NewSandbox() *Sandbox {
 
 // GetSandbox will find a sandbox by id and return it.
 func (l *client) GetSandbox(id string) (*Sandbox, error) {
-	p, ETag, err := l.server.GetProfile(id)
+	var (
+		p    *api.Profile
+		ETag string
+	)
+
+	err := l.withReconnect(func(server lxd.ContainerServer) error {
+		var err error
+		p, ETag, err = server.GetProfile(id)
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +51,14 @@ func (l *client) GetSandbox(id string) (*Sandbox, error) {
 func (l *client) ListSandboxes() ([]*Sandbox, error) {
 	var ETag string
 
-	ps, err := l.server.GetProfiles()
+	var ps []api.Profile
+
+	err := l.withReconnect(func(server lxd.ContainerServer) error {
+		var err error
+		ps, err = server.GetProfiles()
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +124,15 @@ func (l *client) toSandbox(p *api.Profile, etag string) (*Sandbox, error) {
 	s.Labels = sandboxConfigStore.StrippedPrefixMap(p.Config, cfgLabels)
 	s.Annotations = sandboxConfigStore.StrippedPrefixMap(p.Config, cfgAnnotations)
 	s.Config = sandboxConfigStore.UnreservedMap(p.Config)
+	s.DeviceCgroupRules, s.RawLXC = splitDeviceCgroupRules(rawLXCLines(p.Config))
+	s.AppArmorUnconfined, s.RawLXC = parseAppArmorConfig(p.Config, s.RawLXC)
+	s.SharedPIDNamespace, _ = strconv.ParseBool(p.Config[cfgSharedPIDNamespace])
+	s.SeccompProfile = p.Config[cfgSeccompProfile]
+	s.ShmSize = p.Config[cfgShmSize]
+	s.Architecture = p.Config[cfgArchitecture]
+	s.InstanceType = InstanceType(p.Config[cfgInstanceType])
+	s.parseTimezoneConfig(p.Config)
+	s.AppliedConfigHash = p.Config[cfgConfigHash]
 	s.State = getSandboxState(p.Config[cfgState])
 	s.CreatedAt = time.Unix(0, createdAt)
 
@@ -115,18 +141,43 @@ func (l *client) toSandbox(p *api.Profile, etag string) (*Sandbox, error) {
 		return nil, err
 	}
 
+	err = s.parseBootConfig(p.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.parsePidsLimitConfig(p.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	s.parseDeleteProtectionConfig(p.Config)
+
+	err = s.parseMemorySwapConfig(p.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.parseCPUConfig(p.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.parseHugepagesConfig(p.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	// cloud-init network config & vendor-data are write-only so not read
 
 	// get devices
-	for name, options := range p.Devices {
-		d, err := device.Detect(name, options)
-		if err != nil {
-			return nil, err
-		}
-
-		s.Devices.Upsert(d)
+	s.Devices, err = detectDevices(p.Devices)
+	if err != nil {
+		return nil, err
 	}
 
+	s.appliedNics = nicOptions(s.Devices)
+
 	// get containers using this sandbox
 	for _, selflink := range p.UsedBy {
 		name := GetContainerIDFromSelflink(selflink)
@@ -0,0 +1,58 @@
+package lxo // import "github.com/automaticserver/lxe/lxf/lxo"
+
+import (
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// CreateContainerSnapshot will create a snapshot of the container and wait till operation is done or return an error.
+// A stateful snapshot includes the runtime state of the running container.
+func (l *LXO) CreateContainerSnapshot(id, name string, stateful bool) error {
+	op, err := l.server.CreateContainerSnapshot(id, api.ContainerSnapshotsPost{Name: name, Stateful: stateful})
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+// DeleteContainerSnapshot will delete the snapshot of the container and wait till operation is done or return an error
+func (l *LXO) DeleteContainerSnapshot(id, name string) error {
+	op, err := l.server.DeleteContainerSnapshot(id, name)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+// CreateContainerBackup will create a backup of the container including its snapshots and wait till operation is done
+// or return an error
+func (l *LXO) CreateContainerBackup(id, name string) error {
+	op, err := l.server.CreateContainerBackup(id, api.ContainerBackupsPost{Name: name, ContainerOnly: false})
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+// DeleteContainerBackup will delete the backup of the container and wait till operation is done or return an error
+func (l *LXO) DeleteContainerBackup(id, name string) error {
+	op, err := l.server.DeleteContainerBackup(id, name)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+// CreateContainerFromBackup will import the backup and wait till operation is done or return an error
+func (l *LXO) CreateContainerFromBackup(args lxd.ContainerBackupArgs) error {
+	op, err := l.server.CreateContainerFromBackup(args)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
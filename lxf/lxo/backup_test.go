@@ -0,0 +1,129 @@
+package lxo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXO_CreateContainerSnapshot_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateContainerSnapshotReturns(fakeOp, nil)
+
+	err := lxo.CreateContainerSnapshot("foo", "snap", true)
+	assert.NoError(t, err)
+
+	id, post := fake.CreateContainerSnapshotArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, api.ContainerSnapshotsPost{Name: "snap", Stateful: true}, post)
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_CreateContainerSnapshot_Error(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateContainerSnapshotReturns(fakeOp, errors.New("something failed"))
+
+	err := lxo.CreateContainerSnapshot("foo", "snap", false)
+	assert.Error(t, err)
+	assert.Equal(t, 0, fakeOp.WaitCallCount())
+}
+
+func TestLXO_DeleteContainerSnapshot_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.DeleteContainerSnapshotReturns(fakeOp, nil)
+
+	err := lxo.DeleteContainerSnapshot("foo", "snap")
+	assert.NoError(t, err)
+
+	id, name := fake.DeleteContainerSnapshotArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, "snap", name)
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_CreateContainerBackup_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateContainerBackupReturns(fakeOp, nil)
+
+	err := lxo.CreateContainerBackup("foo", "backup")
+	assert.NoError(t, err)
+
+	id, post := fake.CreateContainerBackupArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, "backup", post.Name)
+	assert.False(t, post.ContainerOnly)
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_CreateContainerBackup_Error(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateContainerBackupReturns(fakeOp, errors.New("something failed"))
+
+	err := lxo.CreateContainerBackup("foo", "backup")
+	assert.Error(t, err)
+	assert.Equal(t, 0, fakeOp.WaitCallCount())
+}
+
+func TestLXO_DeleteContainerBackup_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.DeleteContainerBackupReturns(fakeOp, nil)
+
+	err := lxo.DeleteContainerBackup("foo", "backup")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_CreateContainerFromBackup_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateContainerFromBackupReturns(fakeOp, nil)
+
+	err := lxo.CreateContainerFromBackup(lxd.ContainerBackupArgs{PoolName: "default"})
+	assert.NoError(t, err)
+	assert.Equal(t, "default", fake.CreateContainerFromBackupArgsForCall(0).PoolName)
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_CreateContainerFromBackup_Error(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateContainerFromBackupReturns(fakeOp, errors.New("something failed"))
+
+	err := lxo.CreateContainerFromBackup(lxd.ContainerBackupArgs{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, fakeOp.WaitCallCount())
+}
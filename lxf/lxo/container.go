@@ -68,6 +68,16 @@ func (l *LXO) CreateContainer(container api.ContainersPost) error {
 	return op.Wait()
 }
 
+// CreateInstance will create the instance, e.g. a virtual machine, and wait till operation is done or return an error
+func (l *LXO) CreateInstance(instance api.InstancesPost) error {
+	op, err := l.server.CreateInstance(instance)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
 // UpdateContainer will create the container and wait till operation is done or
 // return an error
 func (l *LXO) UpdateContainer(id string, container api.ContainerPut, etag string) error {
@@ -79,6 +89,17 @@ func (l *LXO) UpdateContainer(id string, container api.ContainerPut, etag string
 	return op.Wait()
 }
 
+// RenameContainer will rename the container and wait till operation is done or
+// return an error
+func (l *LXO) RenameContainer(id, newName string) error {
+	op, err := l.server.RenameContainer(id, api.ContainerPost{Name: newName})
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
 // DeleteContainer will delete the container and wait till operation is done or
 // return an error
 func (l *LXO) DeleteContainer(id string) error {
@@ -89,3 +110,21 @@ func (l *LXO) DeleteContainer(id string) error {
 
 	return op.Wait()
 }
+
+// MoveContainer will move the container to the cluster member target and wait till operation is done or return an
+// error. The optional progress handler receives the updates of the operation.
+func (l *LXO) MoveContainer(id, target string, live bool, progress func(api.Operation)) error {
+	op, err := l.server.UseTarget(target).MigrateContainer(id, api.ContainerPost{Name: id, Migration: true, Live: live})
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		_, err = op.AddHandler(progress)
+		if err != nil {
+			return err
+		}
+	}
+
+	return op.Wait()
+}
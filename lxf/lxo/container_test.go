@@ -152,6 +152,22 @@ func TestLXO_CreateContainer_Error(t *testing.T) {
 	assert.Equal(t, 0, fakeOp.WaitCallCount())
 }
 
+func TestLXO_CreateInstance_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateInstanceReturns(fakeOp, nil)
+	fakeOp.WaitReturns(nil)
+
+	err := lxo.CreateInstance(api.InstancesPost{Type: api.InstanceTypeVM})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, fake.CreateInstanceCallCount())
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
 func TestLXO_UpdateContainer_Simple(t *testing.T) {
 	t.Parallel()
 
@@ -183,6 +199,40 @@ func TestLXO_UpdateContainer_Error(t *testing.T) {
 	assert.Equal(t, 0, fakeOp.WaitCallCount())
 }
 
+func TestLXO_RenameContainer_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.RenameContainerReturns(fakeOp, nil)
+	fakeOp.WaitReturns(nil)
+
+	err := lxo.RenameContainer("foo", "bar")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, fake.RenameContainerCallCount())
+	id, post := fake.RenameContainerArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, "bar", post.Name)
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_RenameContainer_Error(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.RenameContainerReturns(fakeOp, errors.New("something failed"))
+
+	err := lxo.RenameContainer("foo", "bar")
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, fake.RenameContainerCallCount())
+	assert.Equal(t, 0, fakeOp.WaitCallCount())
+}
+
 func TestLXO_DeleteContainer_Simple(t *testing.T) {
 	t.Parallel()
 
@@ -213,3 +263,40 @@ func TestLXO_DeleteContainer_Error(t *testing.T) {
 	assert.Equal(t, 1, fake.DeleteContainerCallCount())
 	assert.Equal(t, 0, fakeOp.WaitCallCount())
 }
+
+func TestLXO_MoveContainer_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeTarget := &lxdfakes.FakeContainerServer{}
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.UseTargetReturns(fakeTarget)
+	fakeTarget.MigrateContainerReturns(fakeOp, nil)
+	fakeOp.WaitReturns(nil)
+
+	err := lxo.MoveContainer("foo", "node2", true, func(api.Operation) {})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "node2", fake.UseTargetArgsForCall(0))
+	id, post := fakeTarget.MigrateContainerArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, api.ContainerPost{Name: "foo", Migration: true, Live: true}, post)
+	assert.Equal(t, 1, fakeOp.AddHandlerCallCount())
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_MoveContainer_Error(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeTarget := &lxdfakes.FakeContainerServer{}
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.UseTargetReturns(fakeTarget)
+	fakeTarget.MigrateContainerReturns(fakeOp, errors.New("something failed"))
+
+	err := lxo.MoveContainer("foo", "node2", false, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 0, fakeOp.WaitCallCount())
+}
@@ -1,10 +1,16 @@
 package lxo // import "github.com/automaticserver/lxe/lxf/lxo"
 
 import (
+	"errors"
+	"fmt"
+
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared/api"
 )
 
+// ErrNoFingerprint is returned if LXD created an image but didn't report its fingerprint
+var ErrNoFingerprint = errors.New("no fingerprint")
+
 // CopyImage copies an image from the specified server and wait till operation is done or
 // return an error
 func (l *LXO) CopyImage(source lxd.ImageServer, image api.Image, args *lxd.ImageCopyArgs) error {
@@ -28,3 +34,24 @@ func (l *LXO) DeleteImage(hash string) error {
 
 	return op.Wait()
 }
+
+// CreateImage creates an image, e.g. from a container, and waits till the operation is done. It returns the
+// fingerprint of the new image
+func (l *LXO) CreateImage(image api.ImagesPost) (string, error) {
+	op, err := l.server.CreateImage(image, nil)
+	if err != nil {
+		return "", err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint, ok := op.Get().Metadata["fingerprint"].(string)
+	if !ok || fingerprint == "" {
+		return "", fmt.Errorf("%w: operation returned no fingerprint", ErrNoFingerprint)
+	}
+
+	return fingerprint, nil
+}
@@ -72,3 +72,47 @@ func TestLXO_DeleteImage_Error(t *testing.T) {
 	assert.Equal(t, 1, fake.DeleteImageCallCount())
 	assert.Equal(t, 0, fakeOp.WaitCallCount())
 }
+
+func TestLXO_CreateImage_Simple(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateImageReturns(fakeOp, nil)
+	fakeOp.WaitReturns(nil)
+	fakeOp.GetReturns(api.Operation{Metadata: map[string]interface{}{"fingerprint": "abc"}})
+
+	fingerprint, err := lxo.CreateImage(api.ImagesPost{})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", fingerprint)
+
+	assert.Equal(t, 1, fake.CreateImageCallCount())
+	assert.Equal(t, 1, fakeOp.WaitCallCount())
+}
+
+func TestLXO_CreateImage_NoFingerprint(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateImageReturns(fakeOp, nil)
+	fakeOp.WaitReturns(nil)
+
+	_, err := lxo.CreateImage(api.ImagesPost{})
+	assert.True(t, errors.Is(err, ErrNoFingerprint))
+}
+
+func TestLXO_CreateImage_Error(t *testing.T) {
+	t.Parallel()
+
+	lxo, fake := newFakeClient()
+	fakeOp := &lxdfakes.FakeOperation{}
+
+	fake.CreateImageReturns(fakeOp, errors.New("something failed"))
+
+	_, err := lxo.CreateImage(api.ImagesPost{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, fakeOp.WaitCallCount())
+}
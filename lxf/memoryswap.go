@@ -0,0 +1,78 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	cfgLimitMemorySwap         = "limits.memory.swap"
+	cfgLimitMemorySwapPriority = "limits.memory.swap.priority"
+)
+
+const (
+	// MemorySwapEnabled lets the instance swap, which is the default of LXD
+	MemorySwapEnabled = "true"
+	// MemorySwapDisabled keeps the memory of the instance out of swap
+	MemorySwapDisabled = "false"
+)
+
+const (
+	// minSwappiness and maxSwappiness bound the swap priority of LXD, which it maps to the swappiness of the cgroup
+	minSwappiness = 0
+	maxSwappiness = 10
+)
+
+// validateMemorySwap checks swap is either enabled, disabled or unset and the swappiness is within the range of LXD. A
+// swappiness has no effect if swap is disabled, so that combination is refused
+func (o *LXDObject) validateMemorySwap() error {
+	switch o.MemorySwap {
+	case "", MemorySwapEnabled, MemorySwapDisabled:
+	default:
+		return fmt.Errorf("%w: memory swap must be %v or %v: %v", ErrUsage, MemorySwapEnabled, MemorySwapDisabled, o.MemorySwap)
+	}
+
+	if o.MemorySwappiness == nil {
+		return nil
+	}
+
+	if *o.MemorySwappiness < minSwappiness || *o.MemorySwappiness > maxSwappiness {
+		return fmt.Errorf("%w: memory swappiness must be between %d and %d: %d", ErrUsage, minSwappiness, maxSwappiness,
+			*o.MemorySwappiness)
+	}
+
+	if o.MemorySwap == MemorySwapDisabled {
+		return fmt.Errorf("%w: memory swappiness requires swap to be enabled", ErrUsage)
+	}
+
+	return nil
+}
+
+// makeMemorySwapConfig writes the swap settings to config, the unset ones are left to LXD
+func (o *LXDObject) makeMemorySwapConfig(config map[string]string) {
+	SetIfSet(&config, cfgLimitMemorySwap, o.MemorySwap)
+
+	if o.MemorySwappiness != nil {
+		config[cfgLimitMemorySwapPriority] = strconv.Itoa(*o.MemorySwappiness)
+	}
+}
+
+// parseMemorySwapConfig reads the swap settings from config
+func (o *LXDObject) parseMemorySwapConfig(config map[string]string) error {
+	o.MemorySwap = config[cfgLimitMemorySwap]
+	o.MemorySwappiness = nil
+
+	v, has := config[cfgLimitMemorySwapPriority]
+	if !has {
+		return nil
+	}
+
+	swappiness, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrParse, cfgLimitMemorySwapPriority, err)
+	}
+
+	o.MemorySwappiness = &swappiness
+
+	return nil
+}
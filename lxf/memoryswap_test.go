@@ -0,0 +1,101 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXDObject_validateMemorySwap(t *testing.T) {
+	t.Parallel()
+
+	swappiness := func(i int) *int { return &i }
+
+	tests := []struct {
+		name    string
+		obj     LXDObject
+		wantErr bool
+	}{
+		{"unset", LXDObject{}, false},
+		{"enabled", LXDObject{MemorySwap: MemorySwapEnabled}, false},
+		{"disabled", LXDObject{MemorySwap: MemorySwapDisabled}, false},
+		{"swappiness", LXDObject{MemorySwappiness: swappiness(0)}, false},
+		{"enabled with swappiness", LXDObject{MemorySwap: MemorySwapEnabled, MemorySwappiness: swappiness(10)}, false},
+		{"unknown", LXDObject{MemorySwap: "1GB"}, true},
+		{"negative swappiness", LXDObject{MemorySwappiness: swappiness(-1)}, true},
+		{"swappiness too high", LXDObject{MemorySwappiness: swappiness(11)}, true},
+		{"disabled with swappiness", LXDObject{MemorySwap: MemorySwapDisabled, MemorySwappiness: swappiness(5)}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.obj.validateMemorySwap()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrUsage))
+		})
+	}
+}
+
+func TestLXDObject_makeMemorySwapConfig_Enabled(t *testing.T) {
+	t.Parallel()
+
+	swappiness := 3
+	o := &LXDObject{MemorySwap: MemorySwapEnabled, MemorySwappiness: &swappiness}
+	config := map[string]string{}
+
+	o.makeMemorySwapConfig(config)
+	assert.Equal(t, map[string]string{cfgLimitMemorySwap: "true", cfgLimitMemorySwapPriority: "3"}, config)
+
+	r := &LXDObject{}
+	err := r.parseMemorySwapConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeMemorySwapConfig_Disabled(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{MemorySwap: MemorySwapDisabled}
+	config := map[string]string{}
+
+	o.makeMemorySwapConfig(config)
+	assert.Equal(t, map[string]string{cfgLimitMemorySwap: "false"}, config)
+
+	r := &LXDObject{}
+	err := r.parseMemorySwapConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeMemorySwapConfig_Unset(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	(&LXDObject{}).makeMemorySwapConfig(config)
+	assert.Empty(t, config)
+}
+
+func TestLXDObject_parseMemorySwapConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	err := (&LXDObject{}).parseMemorySwapConfig(map[string]string{cfgLimitMemorySwapPriority: "low"})
+	assert.True(t, errors.Is(err, ErrParse))
+}
+
+func TestContainer_Apply_SwappinessWithoutSwap(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.MemorySwap = MemorySwapDisabled
+	c.MemorySwappiness = new(int)
+
+	err := c.Apply()
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
@@ -0,0 +1,121 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// validateNicSubnets checks the static addresses of the bridged nics in devices are within the subnet of their bridge.
+// A nic with an address outside of it is created by LXD without complaint, but has no connectivity. Only bridges managed
+// by LXD are checked, the subnet of other ones isn't known
+func (l *client) validateNicSubnets(devices device.Devices) error {
+	bridges := make(map[string]*api.Network)
+
+	for _, d := range devices {
+		nic, is := d.(*device.Nic)
+		if !is || nic.NicType == device.NicTypeRouted || nic.Parent == "" {
+			continue
+		}
+
+		if nic.IPv4Address == "" && nic.IPv6Address == "" {
+			continue
+		}
+
+		bridge, has := bridges[nic.Parent]
+		if !has {
+			var err error
+
+			bridge, _, err = l.GetServer().GetNetwork(nic.Parent)
+			if err != nil && !shared.IsErrNotFound(err) {
+				return err
+			}
+
+			bridges[nic.Parent] = bridge
+		}
+
+		if bridge == nil || !bridge.Managed || bridge.Type != "bridge" {
+			continue
+		}
+
+		err := validateNicSubnet(nic, bridge, "ipv4", nic.IPv4Address)
+		if err != nil {
+			return err
+		}
+
+		err = validateNicSubnet(nic, bridge, "ipv6", nic.IPv6Address)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nicOptions returns the options of the nics in devices by their name, nil if there are none
+func nicOptions(devices device.Devices) map[string]map[string]string {
+	var nics map[string]map[string]string
+
+	for _, d := range devices {
+		if _, is := d.(*device.Nic); !is {
+			continue
+		}
+
+		if nics == nil {
+			nics = make(map[string]map[string]string)
+		}
+
+		name, options := d.ToMap()
+		nics[name] = options
+	}
+
+	return nics
+}
+
+// changedNics returns the nics of devices which are new or differ from the nic of the same name in applied. Only these
+// need to be validated by validateNicSubnets, the others are in LXD already and keep working as they are, even if the
+// subnet of their bridge changed since. This also saves looking up the bridges on every apply
+func changedNics(devices device.Devices, applied map[string]map[string]string) device.Devices {
+	var changed device.Devices
+
+	for name, options := range nicOptions(devices) {
+		if reflect.DeepEqual(options, applied[name]) {
+			continue
+		}
+
+		for _, d := range devices {
+			if n, _ := d.ToMap(); n == name {
+				changed = append(changed, d)
+			}
+		}
+	}
+
+	return changed
+}
+
+// validateNicSubnet checks address of nic is within the subnet bridge has for family, "ipv4" or "ipv6"
+func validateNicSubnet(nic *device.Nic, bridge *api.Network, family, address string) error {
+	if address == "" {
+		return nil
+	}
+
+	name, _ := nic.ToMap()
+
+	_, subnet, err := net.ParseCIDR(bridge.Config[family+".address"])
+	if err != nil {
+		return fmt.Errorf("%w: nic %v has %v address %v, but bridge %v has no %v subnet", ErrUsage, name, family, address,
+			bridge.Name, family)
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil || !subnet.Contains(ip) {
+		return fmt.Errorf("%w: %v address %v of nic %v is outside of the subnet %v of bridge %v", ErrUsage, family,
+			address, name, subnet, bridge.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testManagedBridge(name, ipv4 string) *api.Network {
+	return &api.Network{
+		Name:    name,
+		Type:    "bridge",
+		Managed: true,
+		NetworkPut: api.NetworkPut{
+			Config: map[string]string{"ipv4.address": ipv4, "ipv6.address": "none"},
+		},
+	}
+}
+
+func TestClient_validateNicSubnets(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetNetworkReturns(testManagedBridge("lxebr0", "10.0.0.1/24"), "", nil)
+
+	err := client.validateNicSubnets(device.Devices{
+		&device.Nic{Name: "eth0", NicType: "bridged", Parent: "lxebr0", IPv4Address: "10.0.0.5"},
+		&device.Nic{Name: "eth1", NicType: "bridged", Parent: "lxebr0"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.GetNetworkCallCount())
+}
+
+func TestClient_validateNicSubnets_OutsideSubnet(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetNetworkReturns(testManagedBridge("lxebr0", "10.0.0.1/24"), "", nil)
+
+	err := client.validateNicSubnets(device.Devices{
+		&device.Nic{Name: "eth0", NicType: "bridged", Parent: "lxebr0", IPv4Address: "10.0.1.5"},
+	})
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Contains(t, err.Error(), "10.0.0.0/24")
+}
+
+func TestClient_validateNicSubnets_NoSubnetOfFamily(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetNetworkReturns(testManagedBridge("lxebr0", "10.0.0.1/24"), "", nil)
+
+	err := client.validateNicSubnets(device.Devices{
+		&device.Nic{Name: "eth0", NicType: "bridged", Parent: "lxebr0", IPv6Address: "fd00::5"},
+	})
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestClient_validateNicSubnets_Skipped(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	unmanaged := testManagedBridge("br0", "10.0.0.1/24")
+	unmanaged.Managed = false
+
+	fake.GetNetworkReturnsOnCall(0, unmanaged, "", nil)
+	fake.GetNetworkReturnsOnCall(1, nil, "", shared.NewErrNotFound())
+
+	err := client.validateNicSubnets(device.Devices{
+		&device.Nic{Name: "eth0", NicType: "bridged", Parent: "br0", IPv4Address: "192.168.0.5"},
+		&device.Nic{Name: "eth1", NicType: "bridged", Parent: "missing", IPv4Address: "192.168.0.5"},
+		&device.Nic{Name: "eth2", NicType: device.NicTypeRouted, IPv4Address: "192.168.0.5"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.GetNetworkCallCount())
+}
+
+func TestContainer_Apply_NicOutsideSubnet(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetNetworkReturns(testManagedBridge("lxebr0", "10.0.0.1/24"), "", nil)
+
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.Devices = device.Devices{&device.Nic{Name: "eth0", NicType: "bridged", Parent: "lxebr0", IPv4Address: "10.0.1.5"}}
+	c.sandbox = &Sandbox{}
+
+	err := c.Apply()
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
+
+func Test_changedNics(t *testing.T) {
+	t.Parallel()
+
+	eth0 := &device.Nic{Name: "eth0", NicType: "bridged", Parent: "lxebr0", IPv4Address: "10.0.0.5"}
+	eth1 := &device.Nic{Name: "eth1", NicType: "bridged", Parent: "lxebr0", IPv4Address: "10.0.0.6"}
+	devices := device.Devices{eth0, eth1, &device.None{KeyName: "extra"}}
+
+	// on creation every nic is new
+	assert.ElementsMatch(t, device.Devices{eth0, eth1}, changedNics(devices, nil))
+
+	applied := nicOptions(devices)
+	assert.Empty(t, changedNics(devices, applied))
+
+	eth1.IPv4Address = "10.0.1.6"
+	assert.Equal(t, device.Devices{eth1}, changedNics(devices, applied))
+}
+
+func TestClient_Reconcile_NicUnchanged(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.Devices = map[string]map[string]string{
+		"eth0": {"type": "nic", "nictype": "bridged", "parent": "lxebr0", "ipv4.address": "10.0.0.5"},
+	}
+
+	fake.GetContainerReturns(ct, "etag", nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	live, err := detectDevices(ct.Devices)
+	assert.NoError(t, err)
+
+	// the bridges aren't looked up for nics which are in LXD already
+	err = client.Reconcile(&LXDObject{ID: "foo", Devices: live})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetNetworkCallCount())
+}
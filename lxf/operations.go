@@ -0,0 +1,119 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// ErrBusy is returned if a container can't be deleted, as an operation of LXD is still in flight for it
+var ErrBusy = errors.New("container busy")
+
+var (
+	// DeleteBusyTimeout bounds how long Delete waits for operations of the container still in flight
+	DeleteBusyTimeout = 30 * time.Second
+	// deleteBusyInterval is the pause before deleting a busy container is retried
+	deleteBusyInterval = 500 * time.Millisecond
+)
+
+// isBusyError detects if LXD refused a request, as another operation holds the instance. LXD only reports this in the
+// message, e.g. "Instance is busy running a start operation"
+func isBusyError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "busy")
+}
+
+// containerOperations returns the operations of LXD which are still in flight and involve the container id
+func (l *client) containerOperations(id string) ([]api.Operation, error) {
+	ops, err := l.GetServer().GetOperations()
+	if err != nil {
+		return nil, err
+	}
+
+	var inFlight []api.Operation
+
+	for _, op := range ops {
+		if op.StatusCode != api.Pending && op.StatusCode != api.Running {
+			continue
+		}
+
+		if operationInvolves(op, id) {
+			inFlight = append(inFlight, op)
+		}
+	}
+
+	return inFlight, nil
+}
+
+// operationInvolves returns true if one of the resources of op is the container id, addressed by either API
+func operationInvolves(op api.Operation, id string) bool {
+	for _, resources := range op.Resources {
+		for _, r := range resources {
+			u, err := url.Parse(r)
+			if err != nil {
+				continue
+			}
+
+			if strings.HasSuffix(u.Path, "/containers/"+id) || strings.HasSuffix(u.Path, "/instances/"+id) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// settleOperations cancels the operations in flight for container id, or waits for the ones which can't be cancelled
+// until they're done or ctx is.
+func (l *client) settleOperations(ctx context.Context, id string) error {
+	ops, err := l.containerOperations(id)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		log := log.WithField("containerid", id).WithField("operation", op.ID).WithField("description", op.Description)
+
+		if op.MayCancel {
+			log.Info("cancelling operation in flight")
+
+			err = l.GetServer().DeleteOperation(op.ID)
+			if err != nil {
+				return fmt.Errorf("%w: operation %v (%v) of container %v can't be cancelled: %v", ErrBusy, op.ID, op.Description, id, err)
+			}
+
+			continue
+		}
+
+		log.Info("waiting for operation in flight")
+
+		// LXD waits whole seconds and returns the operation on timeout too, so ctx tells whether it's done
+		_, _, err = l.GetServer().GetOperationWait(op.ID, waitSeconds(ctx))
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: operation %v (%v) of container %v can't be cancelled and is still running: %v", ErrBusy, op.ID, op.Description, id, ctx.Err())
+		}
+
+		// the operation failing is no concern of the delete
+		if err != nil {
+			log.WithError(err).Debug("operation in flight failed")
+		}
+	}
+
+	return nil
+}
+
+// waitSeconds returns the whole seconds until ctx is done, at least one. Without deadline LXD waits until the operation
+// is done
+func waitSeconds(ctx context.Context) int {
+	deadline, has := ctx.Deadline()
+	if !has {
+		return -1
+	}
+
+	return int(math.Max(1, math.Ceil(time.Until(deadline).Seconds())))
+}
@@ -0,0 +1,122 @@
+package lxf
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testOperation(id string, mayCancel bool, resources ...string) api.Operation {
+	return api.Operation{
+		ID:          id,
+		Description: "Starting container",
+		StatusCode:  api.Running,
+		MayCancel:   mayCancel,
+		Resources:   map[string][]string{"containers": resources},
+	}
+}
+
+func Test_operationInvolves(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, operationInvolves(testOperation("op", false, "/1.0/containers/foo"), "foo"))
+	assert.True(t, operationInvolves(testOperation("op", false, "/1.0/instances/foo?project=bar"), "foo"))
+	assert.False(t, operationInvolves(testOperation("op", false, "/1.0/containers/foobar"), "foo"))
+	assert.False(t, operationInvolves(testOperation("op", false), "foo"))
+}
+
+func TestContainer_DeleteContext_CancelsOperation(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.DeleteContainerReturnsOnCall(0, nil, errors.New("Instance is busy running a start operation"))
+	fake.DeleteContainerReturnsOnCall(1, &lxdfakes.FakeOperation{}, nil)
+
+	done := testOperation("done", true, "/1.0/containers/foo")
+	done.StatusCode = api.Success
+
+	fake.GetOperationsReturns([]api.Operation{
+		testOperation("other", true, "/1.0/containers/bar"),
+		done,
+		testOperation("start", true, "/1.0/containers/foo"),
+	}, nil)
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client}}
+
+	err := c.DeleteContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DeleteOperationCallCount())
+	assert.Equal(t, "start", fake.DeleteOperationArgsForCall(0))
+	assert.Equal(t, 2, fake.DeleteContainerCallCount())
+}
+
+func TestContainer_DeleteContext_WaitsForOperation(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.DeleteContainerReturnsOnCall(0, nil, errors.New("Instance is busy running a start operation"))
+	fake.DeleteContainerReturnsOnCall(1, &lxdfakes.FakeOperation{}, nil)
+	fake.GetOperationsReturns([]api.Operation{testOperation("start", false, "/1.0/containers/foo")}, nil)
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := c.DeleteContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.DeleteOperationCallCount())
+
+	id, timeout := fake.GetOperationWaitArgsForCall(0)
+	assert.Equal(t, "start", id)
+	assert.Equal(t, 10, timeout)
+}
+
+func TestContainer_DeleteContext_NotCancellable(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.DeleteContainerReturns(nil, errors.New("Instance is busy running a start operation"))
+	fake.GetOperationsReturns([]api.Operation{testOperation("start", true, "/1.0/containers/foo")}, nil)
+	fake.DeleteOperationReturns(errors.New("This operation can't be cancelled"))
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client}}
+
+	err := c.DeleteContext(context.Background())
+	assert.True(t, errors.Is(err, ErrBusy))
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+}
+
+func TestContainer_DeleteContext_StaysBusy(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.DeleteContainerReturns(nil, errors.New("Instance is busy running a start operation"))
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := c.DeleteContext(ctx)
+	assert.True(t, errors.Is(err, ErrBusy))
+}
+
+func TestContainer_DeleteContext_OtherError(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.DeleteContainerReturns(nil, errors.New("Container is running"))
+
+	c := &Container{LXDObject: LXDObject{ID: "foo", client: client}}
+
+	err := c.DeleteContext(context.Background())
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrBusy))
+	assert.Equal(t, 0, fake.GetOperationsCallCount())
+}
@@ -0,0 +1,164 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// cfgSharedPIDNamespace records on the sandbox that its containers share one PID namespace
+	cfgSharedPIDNamespace = "user.shared_pid_namespace"
+	// cfgPIDNamespaceHolder records on a container the id of the container whose PID namespace it joined
+	cfgPIDNamespaceHolder = "user.pid_namespace_holder"
+
+	lxcNamespaceSharePID  = "lxc.namespace.share.pid"
+	lxcNamespaceShareUser = "lxc.namespace.share.user"
+)
+
+// selectPIDNamespaceHolder sets PIDNamespaceHolder to the running container of the sandbox which owns the shared PID
+// namespace. If there's none, e.g. as the previous holder was an init container which finished, this container
+// becomes the holder and its init the PID 1 of the namespace. Containers which aren't holders are skipped, as their
+// namespace dies with the one of their holder anyway.
+func (c *Container) selectPIDNamespaceHolder(s *Sandbox) error {
+	c.PIDNamespaceHolder = ""
+
+	if !s.SharedPIDNamespace {
+		return nil
+	}
+
+	cl, err := s.Containers()
+	if err != nil {
+		return err
+	}
+
+	var holder *Container
+
+	for _, o := range cl {
+		if o.ID == c.ID || o.PIDNamespaceHolder != "" || o.StateName != ContainerStateRunning {
+			continue
+		}
+
+		if holder == nil || o.StartedAt.Before(holder.StartedAt) {
+			holder = o
+		}
+	}
+
+	if holder == nil {
+		return nil
+	}
+
+	// the PID namespace of an unprivileged holder belongs to its user namespace, which a privileged container can't
+	// join and vice versa
+	if holder.Privileged != c.Privileged {
+		return fmt.Errorf("%w: container %v can't share the PID namespace of container %v, as only one of them is privileged", ErrUsage, c.ID, holder.ID)
+	}
+
+	c.PIDNamespaceHolder = holder.ID
+
+	return nil
+}
+
+// joinPIDNamespace selects the holder of the PID namespace before the container is started and saves it if it changed
+func (c *Container) joinPIDNamespace() error {
+	s, err := c.Sandbox()
+	if err != nil {
+		return err
+	}
+
+	prev := c.PIDNamespaceHolder
+
+	err = c.selectPIDNamespaceHolder(s)
+	if err != nil || c.PIDNamespaceHolder == prev {
+		return err
+	}
+
+	return c.Apply()
+}
+
+// checkPIDNamespaceMembers returns an error if the container holds the shared PID namespace of the sandbox and other
+// containers which joined it are still running, as the kernel kills their processes once the holder stops
+func (c *Container) checkPIDNamespaceMembers(s *Sandbox) error {
+	if !s.SharedPIDNamespace || c.PIDNamespaceHolder != "" {
+		return nil
+	}
+
+	cl, err := s.Containers()
+	if err != nil {
+		return err
+	}
+
+	var members []string
+
+	for _, o := range cl {
+		if o.ID != c.ID && o.PIDNamespaceHolder == c.ID && o.StateName == ContainerStateRunning {
+			members = append(members, o.ID)
+		}
+	}
+
+	if len(members) > 0 {
+		return fmt.Errorf("%w: container %v holds the PID namespace of the running containers %v, which must be stopped first", ErrUsage, c.ID, members)
+	}
+
+	return nil
+}
+
+// stopPIDNamespace checks if the container can be stopped without killing the processes of others sharing its PID
+// namespace
+func (c *Container) stopPIDNamespace() error {
+	if c.PIDNamespaceHolder != "" {
+		return nil
+	}
+
+	s, err := c.Sandbox()
+	if err != nil {
+		return err
+	}
+
+	return c.checkPIDNamespaceMembers(s)
+}
+
+// makePIDNamespaceConfig lets liblxc join the namespaces of the holder on start. liblxc resolves the holder by its name.
+// An unprivileged container joins its user namespace too, otherwise it lacks the privileges to mount /proc for the
+// PID namespace
+func (c *Container) makePIDNamespaceConfig(config map[string]string) {
+	if c.PIDNamespaceHolder == "" {
+		delete(config, cfgPIDNamespaceHolder)
+		return
+	}
+
+	config[cfgPIDNamespaceHolder] = c.PIDNamespaceHolder
+	setRawLXCOption(config, lxcNamespaceSharePID, c.PIDNamespaceHolder)
+
+	if !c.Privileged {
+		setRawLXCOption(config, lxcNamespaceShareUser, c.PIDNamespaceHolder)
+	}
+}
+
+// parsePIDNamespaceConfig returns the holder recorded in config and the raw.lxc lines without the ones generated for it
+func parsePIDNamespaceConfig(config map[string]string, rawLXC []string) (string, []string) {
+	holder := config[cfgPIDNamespaceHolder]
+	if holder == "" {
+		return "", rawLXC
+	}
+
+	generated := map[string]bool{
+		lxcNamespaceSharePID + " = " + holder:  true,
+		lxcNamespaceShareUser + " = " + holder: true,
+	}
+	lines := make([]string, 0, len(rawLXC))
+
+	for _, l := range rawLXC {
+		if !generated[l] {
+			lines = append(lines, l)
+		}
+	}
+
+	return holder, lines
+}
+
+// makeSharedPIDNamespaceConfig records whether the containers of the sandbox share their PID namespace
+func (s *Sandbox) makeSharedPIDNamespaceConfig(config map[string]string) {
+	if s.SharedPIDNamespace {
+		config[cfgSharedPIDNamespace] = strconv.FormatBool(true)
+	}
+}
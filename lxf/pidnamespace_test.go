@@ -0,0 +1,150 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSharedPIDSandbox(cl ...*Container) *Sandbox {
+	s := &Sandbox{SharedPIDNamespace: true}
+	s.containers = append([]*Container{}, cl...)
+
+	return s
+}
+
+func testPIDContainer(id string, state ContainerStateName, startedAt int64, holder string) *Container {
+	c := &Container{StateName: state, StartedAt: time.Unix(startedAt, 0), PIDNamespaceHolder: holder}
+	c.ID = id
+
+	return c
+}
+
+func TestContainer_selectPIDNamespaceHolder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		sandbox *Sandbox
+		want    string
+	}{
+		{"not shared", &Sandbox{}, ""},
+		{"first container", testSharedPIDSandbox(), ""},
+		{"oldest running holder", testSharedPIDSandbox(
+			testPIDContainer("b", ContainerStateRunning, 2, ""),
+			testPIDContainer("a", ContainerStateRunning, 1, ""),
+		), "a"},
+		{"skips members", testSharedPIDSandbox(
+			testPIDContainer("a", ContainerStateRunning, 1, "b"),
+			testPIDContainer("b", ContainerStateRunning, 2, ""),
+		), "b"},
+		{"finished holder", testSharedPIDSandbox(
+			testPIDContainer("init", ContainerStateExited, 1, ""),
+		), ""},
+		{"itself", testSharedPIDSandbox(
+			testPIDContainer("foo", ContainerStateRunning, 1, ""),
+		), ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := testPIDContainer("foo", ContainerStateCreated, 0, "previous")
+
+			err := c.selectPIDNamespaceHolder(tt.sandbox)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c.PIDNamespaceHolder)
+		})
+	}
+}
+
+func TestContainer_selectPIDNamespaceHolder_MixedPrivileges(t *testing.T) {
+	t.Parallel()
+
+	holder := testPIDContainer("a", ContainerStateRunning, 1, "")
+	holder.Privileged = true
+
+	c := testPIDContainer("foo", ContainerStateCreated, 0, "")
+
+	err := c.selectPIDNamespaceHolder(testSharedPIDSandbox(holder))
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestContainer_checkPIDNamespaceMembers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		c       *Container
+		sandbox *Sandbox
+		wantErr bool
+	}{
+		{"not shared", testPIDContainer("a", ContainerStateRunning, 1, ""), &Sandbox{}, false},
+		{"running member", testPIDContainer("a", ContainerStateRunning, 1, ""), testSharedPIDSandbox(
+			testPIDContainer("a", ContainerStateRunning, 1, ""),
+			testPIDContainer("b", ContainerStateRunning, 2, "a"),
+		), true},
+		{"stopped member", testPIDContainer("a", ContainerStateRunning, 1, ""), testSharedPIDSandbox(
+			testPIDContainer("a", ContainerStateRunning, 1, ""),
+			testPIDContainer("b", ContainerStateExited, 2, "a"),
+		), false},
+		{"member itself", testPIDContainer("b", ContainerStateRunning, 2, "a"), testSharedPIDSandbox(
+			testPIDContainer("a", ContainerStateRunning, 1, ""),
+			testPIDContainer("b", ContainerStateRunning, 2, "a"),
+		), false},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.c.checkPIDNamespaceMembers(tt.sandbox)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrUsage))
+		})
+	}
+}
+
+func TestContainer_makePIDNamespaceConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	c := testPIDContainer("foo", ContainerStateCreated, 0, "a")
+	c.RawLXC = []string{"lxc.include = /foo"}
+	config := map[string]string{}
+
+	makeRawLXC(config, c.RawLXC)
+	c.makePIDNamespaceConfig(config)
+	assert.Equal(t, "lxc.include = /foo\nlxc.namespace.share.pid = a\nlxc.namespace.share.user = a", config[cfgRawLXC])
+
+	holder, lines := parsePIDNamespaceConfig(config, rawLXCLines(config))
+	assert.Equal(t, "a", holder)
+	assert.Equal(t, c.RawLXC, lines)
+}
+
+func TestContainer_makePIDNamespaceConfig_Privileged(t *testing.T) {
+	t.Parallel()
+
+	c := testPIDContainer("foo", ContainerStateCreated, 0, "a")
+	c.Privileged = true
+	config := map[string]string{}
+
+	c.makePIDNamespaceConfig(config)
+	assert.Equal(t, "lxc.namespace.share.pid = a", config[cfgRawLXC])
+}
+
+func TestContainer_makePIDNamespaceConfig_Holder(t *testing.T) {
+	t.Parallel()
+
+	c := testPIDContainer("foo", ContainerStateCreated, 0, "")
+	config := map[string]string{cfgPIDNamespaceHolder: "a"}
+
+	c.makePIDNamespaceConfig(config)
+	assert.NotContains(t, config, cfgPIDNamespaceHolder)
+	assert.NotContains(t, config, cfgRawLXC)
+}
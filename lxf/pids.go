@@ -0,0 +1,43 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const cfgLimitProcesses = "limits.processes"
+
+// validatePidsLimit checks the pids limit isn't negative
+func (o *LXDObject) validatePidsLimit() error {
+	if o.PidsLimit < 0 {
+		return fmt.Errorf("%w: pids limit must not be negative: %d", ErrUsage, o.PidsLimit)
+	}
+
+	return nil
+}
+
+// makePidsLimitConfig writes the pids limit to config, if one is set
+func (o *LXDObject) makePidsLimitConfig(config map[string]string) {
+	if o.PidsLimit > 0 {
+		config[cfgLimitProcesses] = strconv.FormatInt(o.PidsLimit, 10)
+	}
+}
+
+// parsePidsLimitConfig reads the pids limit from config
+func (o *LXDObject) parsePidsLimitConfig(config map[string]string) error {
+	o.PidsLimit = 0
+
+	v, has := config[cfgLimitProcesses]
+	if !has {
+		return nil
+	}
+
+	limit, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrParse, cfgLimitProcesses, err)
+	}
+
+	o.PidsLimit = limit
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLXDObject_validatePidsLimit(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, (&LXDObject{}).validatePidsLimit())
+	assert.NoError(t, (&LXDObject{PidsLimit: 1024}).validatePidsLimit())
+	assert.True(t, errors.Is((&LXDObject{PidsLimit: -1}).validatePidsLimit(), ErrUsage))
+}
+
+func TestLXDObject_makePidsLimitConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{PidsLimit: 1024}
+	config := map[string]string{}
+
+	o.makePidsLimitConfig(config)
+	assert.Equal(t, map[string]string{cfgLimitProcesses: "1024"}, config)
+
+	r := &LXDObject{}
+	err := r.parsePidsLimitConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makePidsLimitConfig_Unset(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	(&LXDObject{}).makePidsLimitConfig(config)
+	assert.Empty(t, config)
+}
+
+func TestLXDObject_parsePidsLimitConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	err := (&LXDObject{}).parsePidsLimitConfig(map[string]string{cfgLimitProcesses: "many"})
+	assert.True(t, errors.Is(err, ErrParse))
+}
+
+func TestContainer_Apply_NegativePidsLimit(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.PidsLimit = -1
+
+	err := c.Apply()
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
@@ -0,0 +1,58 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+
+	"github.com/automaticserver/lxe/lxf/device"
+)
+
+// ReconcileProxies makes the proxy devices of the sandbox sandboxID match desired, which must only contain proxies.
+// Missing proxies are added, changed ones replaced and the ones not desired anymore removed. The proxies publishing the
+// port mappings of a pod are devices of its sandbox profile. LXD applies a change of the profile to the running
+// containers of the sandbox right away, so removed proxies stop listening before ReconcileProxies returns
+func (l *client) ReconcileProxies(sandboxID string, desired device.Devices) error {
+	for _, d := range desired {
+		if _, is := d.(*device.Proxy); !is {
+			name, _ := d.ToMap()
+			return fmt.Errorf("%w: device %v is no proxy", ErrUsage, name)
+		}
+	}
+
+	err := desired.Validate()
+	if err != nil {
+		return err
+	}
+
+	return l.updateProfileDevices(sandboxID, func(devices map[string]map[string]string) (bool, error) {
+		live := make(map[string]map[string]string)
+
+		for name, options := range devices {
+			if options["type"] == device.ProxyType {
+				live[name] = options
+			}
+		}
+
+		current, err := detectDevices(live)
+		if err != nil {
+			return false, err
+		}
+
+		upsert, remove := desired.Diff(current)
+
+		for _, d := range upsert {
+			name, options := d.ToMap()
+			devices[name] = options
+		}
+
+		for _, d := range remove {
+			name, _ := d.ToMap()
+			delete(devices, name)
+		}
+
+		if len(upsert) > 0 || len(remove) > 0 {
+			log.WithField("sandboxid", sandboxID).WithField("added", len(upsert)).WithField("removed", len(remove)).Debug("reconciled proxies")
+		}
+
+		return len(upsert) > 0 || len(remove) > 0, nil
+	})
+}
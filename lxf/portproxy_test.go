@@ -0,0 +1,132 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func testProxy(listen, connect string) *device.Proxy {
+	l, _ := device.NewProxyEndpoint(listen)
+	c, _ := device.NewProxyEndpoint(connect)
+
+	return &device.Proxy{Listen: l, Destination: c}
+}
+
+func TestClient_ReconcileProxies(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	p := basicProfile("sandbox")
+	p.Devices = map[string]map[string]string{
+		"eth0":                 {"type": "none"},
+		"proxy-tcp:0.0.0.0:80": {"type": "proxy", "listen": "tcp:0.0.0.0:80", "connect": "tcp:127.0.0.1:80"},
+		"proxy-tcp:0.0.0.0:81": {"type": "proxy", "listen": "tcp:0.0.0.0:81", "connect": "tcp:127.0.0.1:81"},
+		"proxy-tcp:0.0.0.0:82": {"type": "proxy", "listen": "tcp:0.0.0.0:82", "connect": "tcp:127.0.0.1:82"},
+	}
+
+	fake.GetProfileReturns(p, "etag", nil)
+
+	err := client.ReconcileProxies("sandbox", device.Devices{
+		// unchanged, changed and added
+		testProxy("tcp:0.0.0.0:80", "tcp:127.0.0.1:80"),
+		testProxy("tcp:0.0.0.0:81", "tcp:127.0.0.1:8081"),
+		testProxy("udp:0.0.0.0:53", "udp:127.0.0.1:53"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.UpdateProfileCallCount())
+
+	name, put, etag := fake.UpdateProfileArgsForCall(0)
+	assert.Equal(t, "sandbox", name)
+	assert.Equal(t, "etag", etag)
+	assert.Equal(t, map[string]map[string]string{
+		"eth0":                 {"type": "none"},
+		"proxy-tcp:0.0.0.0:80": {"type": "proxy", "listen": "tcp:0.0.0.0:80", "connect": "tcp:127.0.0.1:80"},
+		"proxy-tcp:0.0.0.0:81": {"type": "proxy", "listen": "tcp:0.0.0.0:81", "connect": "tcp:127.0.0.1:8081"},
+		"proxy-udp:0.0.0.0:53": {"type": "proxy", "listen": "udp:0.0.0.0:53", "connect": "udp:127.0.0.1:53"},
+	}, put.Devices)
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestClient_ReconcileProxies_OfSandbox(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetProfileReturns(basicProfile("sandbox"), "def", nil)
+
+	// the sandbox gets its proxies like RunPodSandbox does
+	proxies := device.Devices{
+		testProxy("tcp:0.0.0.0:8080", "tcp:127.0.0.1:80"),
+		testProxy("udp:0.0.0.0:5353", "udp:127.0.0.1:53"),
+	}
+
+	s := client.NewSandbox()
+	s.ID = "sandbox"
+	s.ETag = "abc"
+
+	for _, proxy := range proxies {
+		s.Devices.Upsert(proxy)
+	}
+
+	err := s.Apply()
+	assert.NoError(t, err)
+
+	_, applied, _ := fake.UpdateProfileArgsForCall(0)
+	p := basicProfile("sandbox")
+	p.ProfilePut = applied
+	fake.GetProfileReturns(p, "def", nil)
+
+	// the tcp mapping is gone
+	err = client.ReconcileProxies("sandbox", device.Devices{testProxy("udp:0.0.0.0:5353", "udp:127.0.0.1:53")})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.UpdateProfileCallCount())
+
+	_, put, _ := fake.UpdateProfileArgsForCall(1)
+	assert.Equal(t, map[string]map[string]string{
+		"eth0":                   {"type": "none"},
+		"proxy-udp:0.0.0.0:5353": {"type": "proxy", "listen": "udp:0.0.0.0:5353", "connect": "udp:127.0.0.1:53"},
+	}, put.Devices)
+}
+
+func TestClient_ReconcileProxies_Unchanged(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	p := basicProfile("sandbox")
+	p.Devices = map[string]map[string]string{
+		"proxy-tcp:0.0.0.0:80": {"type": "proxy", "listen": "tcp:0.0.0.0:80", "connect": "tcp:127.0.0.1:80"},
+	}
+
+	fake.GetProfileReturns(p, "etag", nil)
+
+	err := client.ReconcileProxies("sandbox", device.Devices{testProxy("tcp:0.0.0.0:80", "tcp:127.0.0.1:80")})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.UpdateProfileCallCount())
+}
+
+func TestClient_ReconcileProxies_NoProxy(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	err := client.ReconcileProxies("sandbox", device.Devices{&device.Disk{Path: "/data", Source: "/srv"}})
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetProfileCallCount())
+}
+
+func TestClient_ReconcileProxies_ETagConflict(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetProfileReturns(basicProfile("sandbox"), "etag", nil)
+	fake.UpdateProfileReturns(errors.New("ETag doesn't match"))
+
+	err := client.ReconcileProxies("sandbox", device.Devices{testProxy("tcp:0.0.0.0:80", "tcp:127.0.0.1:80")})
+	assert.True(t, errors.Is(err, ErrETagConflict))
+}
@@ -0,0 +1,46 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import "strings"
+
+const (
+	// cfgSandboxRawLXC records the raw.lxc lines a container took from its sandbox, so they are told apart from its own
+	cfgSandboxRawLXC = "user.sandbox_raw_lxc"
+)
+
+// makeSandboxRawLXCConfig merges the raw.lxc lines of sandbox in front of the ones of the container. The raw.lxc of a
+// container replaces the one of its profiles instead of adding to it, so the lines of the sandbox would be lost
+// otherwise. Lines of the container come later, so they win for keys which can only be set once
+func makeSandboxRawLXCConfig(config map[string]string, sandbox *Sandbox, lines []string) {
+	var inherited []string
+	if sandbox != nil {
+		inherited = sandbox.rawLXCWithDeviceCgroupRules()
+	}
+
+	makeRawLXC(config, append(append([]string{}, inherited...), lines...))
+
+	if len(inherited) == 0 {
+		delete(config, cfgSandboxRawLXC)
+		return
+	}
+
+	config[cfgSandboxRawLXC] = strings.Join(inherited, "\n")
+}
+
+// parseSandboxRawLXCConfig returns the raw.lxc lines without the ones taken from the sandbox
+func parseSandboxRawLXCConfig(config map[string]string, rawLXC []string) []string {
+	inherited := make(map[string]bool)
+
+	for _, l := range strings.Split(config[cfgSandboxRawLXC], "\n") {
+		inherited[strings.TrimSpace(l)] = true
+	}
+
+	lines := make([]string, 0, len(rawLXC))
+
+	for _, l := range rawLXC {
+		if !inherited[strings.TrimSpace(l)] {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,124 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/shared"
+)
+
+// Reconcile makes the live container desired.ID match the devices and config of desired. Devices of a type LXE doesn't
+// know are managed externally and neither added, changed nor removed, even if desired lists them. Of the config only
+// keys of desired are added or changed, other keys are left as is, since LXD and the image add their own. Reserved keys
+// and raw.lxc are left to Container.Apply. The update is saved under the ETag of the live container; if it changed in
+// the meantime ErrETagConflict is returned and the reconcile has to be repeated.
+func (l *client) Reconcile(desired *LXDObject) error {
+	if desired.ID == "" {
+		return fmt.Errorf("%w: reconcile requires the id of the container", ErrUsage)
+	}
+
+	err := desired.Devices.Validate()
+	if err != nil {
+		return err
+	}
+
+	ct, etag, err := l.GetServer().GetContainer(desired.ID)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), desired.ID)
+		}
+
+		return err
+	}
+
+	live, err := detectDevices(ct.Devices)
+	if err != nil {
+		return err
+	}
+
+	err = l.validateNicSubnets(changedNics(desired.Devices, nicOptions(live)))
+	if err != nil {
+		return err
+	}
+
+	external := make(map[string]bool)
+	managed := device.Devices{}
+
+	for _, d := range live {
+		name, _ := d.ToMap()
+		if _, is := d.(*device.Unknown); is {
+			external[name] = true
+			continue
+		}
+
+		managed = append(managed, d)
+	}
+
+	want := device.Devices{}
+
+	for _, d := range desired.Devices {
+		name, _ := d.ToMap()
+		if _, is := d.(*device.Unknown); is || external[name] {
+			log.WithField("containerid", desired.ID).WithField("device", name).Debug("not reconciling externally managed device")
+			continue
+		}
+
+		want = append(want, d)
+	}
+
+	upsert, remove := want.Diff(managed)
+	changed := len(upsert) > 0 || len(remove) > 0
+
+	put := ct.Writable()
+	if put.Devices == nil {
+		put.Devices = make(map[string]map[string]string)
+	}
+
+	if put.Config == nil {
+		put.Config = make(map[string]string)
+	}
+
+	for _, d := range upsert {
+		name, options := d.ToMap()
+		put.Devices[name] = options
+	}
+
+	for _, d := range remove {
+		name, _ := d.ToMap()
+		delete(put.Devices, name)
+	}
+
+	for key, val := range desired.Config {
+		switch {
+		case containerConfigStore.IsReserved(key):
+			log.Warnf("config key '%v' is reserved and can't be used", key)
+		case put.Config[key] != val:
+			put.Config[key] = val
+			changed = true
+		}
+	}
+
+	if hash := desired.ConfigHash(); put.Config[cfgConfigHash] != hash {
+		put.Config[cfgConfigHash] = hash
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	err = l.opWait().UpdateContainer(desired.ID, put, etag)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return fmt.Errorf("container %w: %s", shared.NewErrNotFound(), desired.ID)
+		} else if shared.IsErrETagMismatch(err) {
+			return fmt.Errorf("reconcile container %v: %w", desired.ID, ErrETagConflict)
+		}
+
+		return err
+	}
+
+	desired.AppliedConfigHash = put.Config[cfgConfigHash]
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReconcileClient() (*client, *lxdfakes.FakeContainerServer) {
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "default")
+	ct.Config["limits.kernel.nofile"] = "100"
+	ct.Config["volatile.eth0.hwaddr"] = "00:16:3e:00:00:01"
+	ct.Devices = map[string]map[string]string{
+		"data":  {"type": "disk", "path": "/data", "source": "/srv/old"},
+		"extra": {"type": "none"},
+		"gpu":   {"type": "gpu", "id": "0"},
+	}
+
+	fake.GetContainerReturns(ct, "etag", nil)
+	fake.UpdateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	return client, fake
+}
+
+func TestClient_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testReconcileClient()
+
+	desired := &LXDObject{ID: "foo", Config: map[string]string{"limits.kernel.nofile": "200"}}
+	desired.Devices = device.Devices{
+		&device.Disk{KeyName: "data", Path: "/data", Source: "/srv/new"},
+		&device.Disk{KeyName: "cache", Path: "/cache", Source: "/srv/cache"},
+	}
+
+	err := client.Reconcile(desired)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.UpdateContainerCallCount())
+
+	id, put, etag := fake.UpdateContainerArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, "etag", etag)
+
+	assert.Equal(t, "/srv/new", put.Devices["data"]["source"])
+	assert.Equal(t, "/srv/cache", put.Devices["cache"]["source"])
+	assert.NotContains(t, put.Devices, "extra")
+	// the device of unknown type is managed externally
+	assert.Equal(t, map[string]string{"type": "gpu", "id": "0"}, put.Devices["gpu"])
+
+	assert.Equal(t, "200", put.Config["limits.kernel.nofile"])
+	assert.Equal(t, "00:16:3e:00:00:01", put.Config["volatile.eth0.hwaddr"])
+	assert.Equal(t, desired.ConfigHash(), put.Config[cfgConfigHash])
+	assert.Equal(t, desired.ConfigHash(), desired.AppliedConfigHash)
+}
+
+func TestClient_Reconcile_ExternalDeviceUntouched(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testReconcileClient()
+
+	desired := &LXDObject{ID: "foo"}
+	desired.Devices = device.Devices{
+		&device.Disk{KeyName: "data", Path: "/data", Source: "/srv/old"},
+		&device.None{KeyName: "extra"},
+		&device.Disk{KeyName: "gpu", Path: "/gpu"},
+	}
+
+	err := client.Reconcile(desired)
+	assert.NoError(t, err)
+
+	_, put, _ := fake.UpdateContainerArgsForCall(0)
+	assert.Equal(t, map[string]string{"type": "gpu", "id": "0"}, put.Devices["gpu"])
+}
+
+func TestClient_Reconcile_Unchanged(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testReconcileClient()
+
+	desired := &LXDObject{ID: "foo", Config: map[string]string{"limits.kernel.nofile": "100"}}
+	desired.Devices = device.Devices{
+		&device.Disk{KeyName: "data", Path: "/data", Source: "/srv/old"},
+		&device.None{KeyName: "extra"},
+	}
+
+	ct, _, _ := fake.GetContainer("foo")
+	ct.Config[cfgConfigHash] = desired.ConfigHash()
+
+	err := client.Reconcile(desired)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestClient_Reconcile_ReservedConfig(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testReconcileClient()
+
+	desired := &LXDObject{ID: "foo", Config: map[string]string{cfgSecurityPrivileged: "true"}}
+
+	err := client.Reconcile(desired)
+	assert.NoError(t, err)
+
+	_, put, _ := fake.UpdateContainerArgsForCall(0)
+	assert.NotContains(t, put.Config, cfgSecurityPrivileged)
+}
+
+func TestClient_Reconcile_ETagConflict(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testReconcileClient()
+	fake.UpdateContainerReturns(nil, errors.New("ETag doesn't match"))
+
+	err := client.Reconcile(&LXDObject{ID: "foo"})
+	assert.True(t, errors.Is(err, ErrETagConflict))
+}
+
+func TestClient_Reconcile_Missing(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetContainerReturns(nil, "", shared.NewErrNotFound())
+
+	err := client.Reconcile(&LXDObject{ID: "foo"})
+	assert.True(t, shared.IsErrNotFound(err))
+	assert.Equal(t, 0, fake.UpdateContainerCallCount())
+}
+
+func TestClient_Reconcile_WithoutID(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	err := client.Reconcile(&LXDObject{})
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.GetContainerCallCount())
+}
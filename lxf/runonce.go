@@ -0,0 +1,197 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+const (
+	// runOnceName is passed to the IDGenerator for the instances of RunOnce
+	runOnceName = "run-once"
+)
+
+// bufferCloser collects the output of a command, closing it has no effect
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+// Close implements io.Closer
+func (b *bufferCloser) Close() error {
+	return nil
+}
+
+// validateRunOnce checks the fields of obj RunOnce applies to the instance
+func (l *client) validateRunOnce(obj *LXDObject, image string, cmd []string) error {
+	switch {
+	case image == "":
+		return fmt.Errorf("%w: run once requires an image", ErrUsage)
+	case len(cmd) == 0:
+		return fmt.Errorf("%w: run once requires a command", ErrUsage)
+	}
+
+	err := obj.Devices.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateRawLXC(obj.RawLXC)
+	if err != nil {
+		return err
+	}
+
+	err = validateDeviceCgroupRules(obj.DeviceCgroupRules)
+	if err != nil {
+		return err
+	}
+
+	err = validateSeccompProfile(obj.SeccompProfile)
+	if err != nil {
+		return err
+	}
+
+	err = obj.validatePidsLimit()
+	if err != nil {
+		return err
+	}
+
+	err = obj.validateMemorySwap()
+	if err != nil {
+		return err
+	}
+
+	err = obj.validateCPU()
+	if err != nil {
+		return err
+	}
+
+	err = obj.validateHugepages()
+	if err != nil {
+		return err
+	}
+
+	return validateTimezone(obj.Timezone)
+}
+
+// makeRunOnceConfig returns the instance config of obj. The CRI fields aren't written, so the instance is no container
+// of LXE and isn't listed as such
+func makeRunOnceConfig(obj *LXDObject) (map[string]string, error) {
+	config := make(map[string]string, len(obj.Config))
+
+	for k, v := range obj.Config {
+		config[k] = v
+	}
+
+	makeRawLXC(config, obj.rawLXCWithDeviceCgroupRules())
+	obj.makePidsLimitConfig(config)
+	obj.makeMemorySwapConfig(config)
+	obj.makeCPUConfig(config)
+	obj.makeHugepagesConfig(config)
+	obj.makeTimezoneConfig(config)
+
+	err := makeSeccompConfig(config, obj.SeccompProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// RunOnce creates an ephemeral instance from image with the devices and config of obj, starts it, runs cmd in it to
+// completion and removes the instance again. It returns the output and exit code of cmd. The instance gets the default
+// profile of LXD only, it's not part of any sandbox
+func (l *client) RunOnce(obj *LXDObject, image string, cmd []string) (stdout, stderr []byte, exitCode int, err error) {
+	err = l.validateRunOnce(obj, image, cmd)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	imageID, err := l.parseImage(image)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	hash, found, err := imageID.Hash(l)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if !found {
+		return nil, nil, 0, fmt.Errorf("image %w on local remote: %s", shared.NewErrNotFound(), image)
+	}
+
+	config, err := makeRunOnceConfig(obj)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	devices := make(map[string]map[string]string)
+
+	// the instance is always a container
+	for _, d := range obj.Devices {
+		name, options := d.ToMap()
+		devices[name] = InstanceTypeContainer.deviceOptions(options)
+	}
+
+	id, err := l.newID(runOnceName)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	err = l.opWait().CreateContainer(api.ContainersPost{
+		Name: id,
+		ContainerPut: api.ContainerPut{
+			Architecture: obj.Architecture,
+			Config:       config,
+			Devices:      devices,
+			// LXD deletes the instance as soon as it stops, even if LXE is gone in the meantime
+			Ephemeral: true,
+		},
+		Source: api.ContainerSource{
+			Fingerprint: hash,
+			Type:        "image",
+		},
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	defer func() {
+		cerr := l.cleanupRunOnce(id)
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	err = l.opWait().StartContainer(id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	out, errOut := &bufferCloser{}, &bufferCloser{}
+
+	code, err := l.Exec(id, cmd, nil, out, errOut, false, false, 0, nil)
+	if err != nil {
+		return out.Bytes(), errOut.Bytes(), int(code), err
+	}
+
+	return out.Bytes(), errOut.Bytes(), int(code), nil
+}
+
+// cleanupRunOnce stops the instance immediately, which lets LXD delete it as it's ephemeral. The instance is deleted
+// explicitly if it's still there, e.g. as it never started
+func (l *client) cleanupRunOnce(id string) error {
+	err := l.opWait().StopContainer(id, 0, 0)
+	if err != nil && !shared.IsErrNotFound(err) {
+		log.WithError(err).WithField("containerid", id).Warn("unable to stop run once instance")
+	}
+
+	err = l.opWait().DeleteContainer(id)
+	if err != nil && !shared.IsErrNotFound(err) {
+		return err
+	}
+
+	return nil
+}
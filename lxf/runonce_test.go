@@ -0,0 +1,111 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRunOnceClient(exitCode int32) (*client, *lxdfakes.FakeContainerServer) {
+	client, fake := testClient()
+
+	fake.GetImageAliasReturns(&api.ImageAliasesEntry{ImageAliasesEntryPut: api.ImageAliasesEntryPut{Target: "abc"}}, "", nil)
+	fake.CreateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.UpdateContainerStateReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.DeleteContainerReturns(nil, shared.NewErrNotFound())
+
+	execOp := &lxdfakes.FakeOperation{}
+	execOp.GetReturns(api.Operation{Metadata: map[string]interface{}{"return": float64(exitCode)}})
+
+	fake.ExecContainerCalls(func(id string, req api.ContainerExecPost, args *lxd.ContainerExecArgs) (lxd.Operation, error) {
+		_, _ = args.Stdout.Write([]byte("out"))
+		_, _ = args.Stderr.Write([]byte("err"))
+
+		go sendDataDone(args, 0)
+
+		return execOp, nil
+	})
+
+	return client, fake
+}
+
+func TestClient_RunOnce(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testRunOnceClient(3)
+
+	obj := &LXDObject{Config: map[string]string{"limits.memory": "64MB"}, PidsLimit: 100}
+
+	stdout, stderr, exitCode, err := client.RunOnce(obj, "busybox", []string{"true"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("out"), stdout)
+	assert.Equal(t, []byte("err"), stderr)
+	assert.Equal(t, 3, exitCode)
+
+	post := fake.CreateContainerArgsForCall(0)
+	assert.True(t, post.Ephemeral)
+	assert.Equal(t, "abc", post.Source.Fingerprint)
+	assert.Equal(t, "64MB", post.Config["limits.memory"])
+	assert.Equal(t, "100", post.Config[cfgLimitProcesses])
+	assert.NotContains(t, post.Config, cfgIsCRI)
+
+	id, req, _ := fake.ExecContainerArgsForCall(0)
+	assert.Equal(t, post.Name, id)
+	assert.Equal(t, []string{"true"}, req.Command)
+
+	// started and stopped again
+	_, start, _ := fake.UpdateContainerStateArgsForCall(0)
+	assert.Equal(t, "start", start.Action)
+	_, stop, _ := fake.UpdateContainerStateArgsForCall(1)
+	assert.Equal(t, "stop", stop.Action)
+	assert.True(t, stop.Force)
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+}
+
+func TestClient_RunOnce_StartFails(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testRunOnceClient(0)
+
+	startOp := &lxdfakes.FakeOperation{}
+	startOp.WaitReturns(errors.New("start failed"))
+	fake.UpdateContainerStateReturnsOnCall(0, startOp, nil)
+	fake.DeleteContainerReturns(&lxdfakes.FakeOperation{}, nil)
+
+	_, _, _, err := client.RunOnce(&LXDObject{}, "busybox", []string{"true"})
+	assert.EqualError(t, err, "start failed")
+	assert.Equal(t, 0, fake.ExecContainerCallCount())
+	assert.Equal(t, 1, fake.DeleteContainerCallCount())
+}
+
+func TestClient_RunOnce_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		obj   *LXDObject
+		image string
+		cmd   []string
+	}{
+		{"missing image", &LXDObject{}, "", []string{"true"}},
+		{"missing command", &LXDObject{}, "busybox", nil},
+		{"negative pids limit", &LXDObject{PidsLimit: -1}, "busybox", []string{"true"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := testRunOnceClient(0)
+
+			_, _, _, err := client.RunOnce(tt.obj, tt.image, tt.cmd)
+			assert.True(t, errors.Is(err, ErrUsage))
+			assert.Equal(t, 0, fake.CreateContainerCallCount())
+		})
+	}
+}
@@ -1,18 +1,15 @@
 package lxf // import "github.com/automaticserver/lxe/lxf"
 
 import (
-	"crypto/md5" // nolint: gosec
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/network/cloudinit"
 	"github.com/automaticserver/lxe/shared"
 	"github.com/ghodss/yaml"
 	"github.com/lxc/lxd/shared/api"
-	"k8s.io/apimachinery/pkg/util/uuid"
 )
 
 const (
@@ -42,11 +39,33 @@ var (
 			cfgCloudInitNetworkConfig,
 			cfgCloudInitVendorData,
 			cfgNetworkConfigModeData,
+			cfgRawLXC,
+			cfgConfigHash,
+			cfgSeccompProfile,
+			cfgRawSeccomp,
+			cfgSyscallsBlacklistDefault,
+			cfgShmSize,
+			cfgArchitecture,
+			cfgInstanceType,
+			cfgBootAutostart,
+			cfgBootAutostartPriority,
+			cfgBootAutostartDelay,
+			cfgLimitProcesses,
+			cfgLimitMemorySwap,
+			cfgLimitMemorySwapPriority,
+			cfgLimitCPUPriority,
+			cfgLimitCPUAllowance,
+			cfgSecurityProtectionDelete,
+			cfgTimezone,
+			cfgEnvironmentTZ,
+			cfgAppArmorUnconfined,
+			cfgSharedPIDNamespace,
 		}, reservedConfigCRI...,
 		)...,
 	).WithReservedPrefixes(
 		append([]string{
 			cfgNetworkConfig,
+			cfgLimitHugepagesPrefix,
 		}, reservedConfigPrefixesCRI...,
 		)...,
 	)
@@ -74,6 +93,13 @@ type Sandbox struct {
 	LogDirectory string
 	// CloudInitNetworkConfigEntries to set
 	CloudInitNetworkConfigEntries []cloudinit.NetworkConfigEntryPhysical
+	// SharedPIDNamespace lets the containers of the sandbox see and signal each others processes. As a sandbox has no
+	// process of its own, the first running container holds the namespace and its init is PID 1 reaping the orphans of
+	// all containers, see Container.PIDNamespaceHolder. Its init must therefore reap children, which an InitCommand
+	// doesn't necessarily do. If the holder stops, the kernel kills the processes of the other containers too, so
+	// stopping the holder is refused as long as containers which joined it are running. Containers with an isolated
+	// idmap can't share the namespace
+	SharedPIDNamespace bool
 
 	// sandbox is the parent sandbox of this container
 	containers []*Container
@@ -200,15 +226,98 @@ func (s *Sandbox) Apply() error {
 	}
 
 	// Always stop inheriting default eth0 device
-	s.Devices.Upsert(&device.None{
-		KeyName: lxdInitDefaultNicName,
-	})
+	s.Devices.Mask(lxdInitDefaultNicName)
 
-	err := s.apply()
+	err := s.Devices.Validate()
 	if err != nil {
 		return err
 	}
 
+	err = validateRawLXC(s.RawLXC)
+	if err != nil {
+		return err
+	}
+
+	err = s.validateBoot()
+	if err != nil {
+		return err
+	}
+
+	err = s.validatePidsLimit()
+	if err != nil {
+		return err
+	}
+
+	err = s.validateMemorySwap()
+	if err != nil {
+		return err
+	}
+
+	err = s.validateCPU()
+	if err != nil {
+		return err
+	}
+
+	err = s.validateHugepages()
+	if err != nil {
+		return err
+	}
+
+	err = validateTimezone(s.Timezone)
+	if err != nil {
+		return err
+	}
+
+	err = s.validateInstanceType()
+	if err != nil {
+		return err
+	}
+
+	privileged, _ := strconv.ParseBool(s.Config[cfgSecurityPrivileged])
+
+	err = s.validateAppArmor(privileged)
+	if err != nil {
+		return err
+	}
+
+	if s.AppArmorUnconfined {
+		log.WithField("sandboxid", s.ID).WithField("podname", s.Metadata.Name).WithField("namespace", s.Metadata.Namespace).Warn("pod runs unconfined by AppArmor")
+	}
+
+	err = validateSeccompProfile(s.SeccompProfile)
+	if err != nil {
+		return err
+	}
+
+	_, err = shmMount(s.ShmSize)
+	if err != nil {
+		return err
+	}
+
+	err = validateDeviceCgroupRules(s.DeviceCgroupRules)
+	if err != nil {
+		return err
+	}
+
+	if s.Architecture != "" {
+		s.Architecture, err = s.client.validateArchitecture(s.Architecture)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.client.validateNicSubnets(changedNics(s.Devices, s.appliedNics))
+	if err != nil {
+		return err
+	}
+
+	err = s.apply()
+	if err != nil {
+		return err
+	}
+
+	s.appliedNics = nicOptions(s.Devices)
+
 	return s.refresh()
 }
 
@@ -219,18 +328,21 @@ func (s *Sandbox) Stop() error {
 	return s.apply()
 }
 
-// Delete will delete the given sandbox, returns nil when sandbox is already deleted
+// Delete will delete the given sandbox, returns nil when sandbox is already deleted. A delete protected sandbox is only
+// deleted if ForceDelete or Config.ForceDeleteProtected of the client is set. The config disks of the sandbox are
+// removed as well
 func (s *Sandbox) Delete() error {
-	err := s.client.server.DeleteProfile(s.ID)
-	if err != nil {
-		if shared.IsErrNotFound(err) {
-			return nil
-		}
+	if s.DeleteProtected && !s.forceDelete() {
+		return fmt.Errorf("%w: sandbox %v, it can only be deleted with force", ErrDeleteProtected, s.ID)
+	}
 
+	err := s.client.GetServer().DeleteProfile(s.ID)
+	if err != nil && !shared.IsErrNotFound(err) {
 		return err
 	}
 
-	return nil
+	// the containers are gone with the profile, so their config disks aren't mounted anymore
+	return s.client.removeConfigDisks(s.ID)
 }
 
 // apply saves the changes to LXD
@@ -275,6 +387,33 @@ func (s *Sandbox) apply() error {
 		}
 	}
 
+	makeRawLXC(config, s.rawLXCWithDeviceCgroupRules())
+	s.makeSharedPIDNamespaceConfig(config)
+
+	err = makeSeccompConfig(config, s.SeccompProfile)
+	if err != nil {
+		return err
+	}
+
+	// only recorded, the containers of the sandbox mount it
+	SetIfSet(&config, cfgShmSize, s.ShmSize)
+	SetIfSet(&config, cfgArchitecture, s.Architecture)
+	SetIfSet(&config, cfgInstanceType, string(s.InstanceType))
+
+	// not applied through the profile, as it would make unprivileged containers of the sandbox unconfined too
+	if s.AppArmorUnconfined {
+		config[cfgAppArmorUnconfined] = strconv.FormatBool(true)
+	}
+
+	// the containers of the sandbox inherit these through the profile
+	s.makeBootConfig(config)
+	s.makePidsLimitConfig(config)
+	s.makeMemorySwapConfig(config)
+	s.makeCPUConfig(config)
+	s.makeDeleteProtectionConfig(config)
+	s.makeHugepagesConfig(config)
+	s.makeTimezoneConfig(config)
+
 	// write cloud-init network config
 	data := cloudinit.NetworkConfig{
 		Version: 1,
@@ -315,9 +454,11 @@ manage_etc_hosts: true
 
 	for _, d := range s.Devices {
 		name, options := d.ToMap()
-		devices[name] = options
+		devices[name] = s.InstanceType.deviceOptions(options)
 	}
 
+	configHash := s.ConfigHash()
+	config[cfgConfigHash] = configHash
 	config[cfgSchema] = SchemaVersionProfile
 	profile := api.ProfilePut{
 		Config:  config,
@@ -325,32 +466,47 @@ manage_etc_hosts: true
 	}
 
 	if s.ID == "" { // profile has to be created
-		s.ID = s.CreateID()
+		id, err := s.CreateID()
+		if err != nil {
+			return err
+		}
+
+		s.ID = id
 
-		return s.client.server.CreateProfile(api.ProfilesPost{
+		err = s.client.GetServer().CreateProfile(api.ProfilesPost{
 			Name:       s.ID,
 			ProfilePut: profile,
 		})
+		if err != nil {
+			return err
+		}
+
+		s.AppliedConfigHash = configHash
+
+		return nil
 	}
 	// else profile has to be updated
 	if s.ETag == "" {
 		return fmt.Errorf("update profile not allowed: %w", ErrMissingETag)
 	}
 
-	err = s.client.server.UpdateProfile(s.ID, profile, s.ETag)
+	err = s.client.GetServer().UpdateProfile(s.ID, profile, s.ETag)
 	if err != nil {
 		if shared.IsErrNotFound(err) {
 			return fmt.Errorf("sandbox %w: %s", shared.NewErrNotFound(), s.ID)
+		} else if shared.IsErrETagMismatch(err) {
+			return fmt.Errorf("update profile %v: %w", s.ID, ErrETagConflict)
 		}
 
 		return err
 	}
 
+	s.AppliedConfigHash = configHash
+
 	return nil
 }
 
-// CreateID creates a unique profile id
-func (s *Sandbox) CreateID() string {
-	bin := md5.Sum([]byte(uuid.NewUUID())) // nolint: gosec
-	return string(s.Metadata.Name[0]) + b32lowerEncoder.EncodeToString(bin[:])[:15]
+// CreateID creates a unique profile id using the id generator of the client
+func (s *Sandbox) CreateID() (string, error) {
+	return s.client.newID(s.Metadata.Name)
 }
@@ -1,5 +1,94 @@
 package lxf
 
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandbox_Apply_ETagConflict(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.UpdateProfileReturns(errors.New("ETag doesn't match: abc vs def"))
+
+	s := client.NewSandbox()
+	s.ID = "foo"
+	s.ETag = "abc"
+
+	err := s.Apply()
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrETagConflict))
+	assert.Equal(t, 1, fake.UpdateProfileCallCount())
+	assert.Equal(t, 0, fake.GetProfileCallCount())
+}
+
+func TestSandbox_Apply_PreservesUnknownDevices(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	p := basicProfile("foo")
+	p.Devices = map[string]map[string]string{
+		"gpu0": {"type": "pci", "address": "0000:01:00.0"},
+	}
+
+	fake.GetProfileReturns(p, "etag", nil)
+
+	s, err := client.GetSandbox("foo")
+	assert.NoError(t, err)
+
+	err = s.Apply()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, fake.UpdateProfileCallCount())
+	_, put, etag := fake.UpdateProfileArgsForCall(0)
+	assert.Equal(t, "etag", etag)
+	assert.Equal(t, map[string]string{"type": "pci", "address": "0000:01:00.0"}, put.Devices["gpu0"])
+}
+
+func TestSandbox_Apply_MasksDefaultNic(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetProfileReturns(basicProfile("foo"), "def", nil)
+
+	s := client.NewSandbox()
+	s.ID = "foo"
+	s.ETag = "abc"
+
+	err := s.Apply()
+	assert.NoError(t, err)
+
+	_, put, _ := fake.UpdateProfileArgsForCall(0)
+	assert.Equal(t, map[string]string{"type": "none"}, put.Devices["eth0"])
+}
+
+func TestSandbox_Apply_AppArmorUnconfinedOnlyRecorded(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetProfileReturns(basicProfile("foo"), "def", nil)
+
+	s := client.NewSandbox()
+	s.ID = "foo"
+	s.ETag = "abc"
+	s.Config[cfgSecurityPrivileged] = "true"
+	s.AppArmorUnconfined = true
+
+	err := s.Apply()
+	assert.NoError(t, err)
+
+	// the profile applies to all containers, the privileged ones add the line themselves
+	_, put, _ := fake.UpdateProfileArgsForCall(0)
+	assert.Equal(t, "true", put.Config[cfgAppArmorUnconfined])
+	assert.NotContains(t, put.Config[cfgRawLXC], lxcAppArmorProfile)
+}
+
 // func TestCreateSandbox(t *testing.T) {
 // 	lt := newLXFTest(t)
 
@@ -69,7 +69,7 @@ func IsSchemaCurrent(i interface{}) bool {
 
 // Ensure applies all migration steps from detected schema to current schema
 func (m *MigrationWorkspace) Ensure() error { // nolint: gocognit
-	profiles, err := m.lxf.server.GetProfiles()
+	profiles, err := m.lxf.GetServer().GetProfiles()
 	if err != nil {
 		return err
 	}
@@ -104,7 +104,7 @@ func (m *MigrationWorkspace) Ensure() error { // nolint: gocognit
 		if counter > 0 {
 			anyChanges = true
 
-			err = m.lxf.server.UpdateProfile(p.Name, p.Writable(), "")
+			err = m.lxf.GetServer().UpdateProfile(p.Name, p.Writable(), "")
 			if err != nil {
 				return err
 			}
@@ -113,7 +113,7 @@ func (m *MigrationWorkspace) Ensure() error { // nolint: gocognit
 
 	var etag string
 
-	containers, err := m.lxf.server.GetContainers()
+	containers, err := m.lxf.GetServer().GetContainers()
 	if err != nil {
 		return err
 	}
@@ -154,7 +154,7 @@ func (m *MigrationWorkspace) Ensure() error { // nolint: gocognit
 		if counter > 0 {
 			anyChanges = true
 
-			err := m.lxf.opwait.UpdateContainer(c.Name, c.Writable(), etag)
+			err := m.lxf.opWait().UpdateContainer(c.Name, c.Writable(), etag)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,114 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// Seccomp profiles in the notation of kubernetes
+const (
+	// SeccompProfileRuntimeDefault applies the default policy of LXD, same as if no profile is set
+	SeccompProfileRuntimeDefault = "runtime/default"
+	// SeccompProfileDockerDefault is the deprecated name of SeccompProfileRuntimeDefault
+	SeccompProfileDockerDefault = "docker/default"
+	// SeccompProfileUnconfined doesn't deny any syscall by default
+	SeccompProfileUnconfined = "unconfined"
+	// SeccompProfileLocalhostPrefix is followed by the absolute path of a policy on the node
+	SeccompProfileLocalhostPrefix = "localhost/"
+)
+
+const (
+	cfgSeccompProfile           = "user.security.seccomp_profile"
+	cfgRawSeccomp               = "raw.seccomp"
+	cfgSyscallsBlacklistDefault = "security.syscalls.blacklist_default"
+)
+
+// seccompPolicyPath returns the path of a localhost profile, and false if profile isn't one
+func seccompPolicyPath(profile string) (string, bool) {
+	if !strings.HasPrefix(profile, SeccompProfileLocalhostPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(profile, SeccompProfileLocalhostPrefix), true
+}
+
+// validateSeccompProfile checks the profile is known. The policy of a localhost profile must exist on the node
+func validateSeccompProfile(profile string) error {
+	switch profile {
+	case "", SeccompProfileRuntimeDefault, SeccompProfileDockerDefault, SeccompProfileUnconfined:
+		return nil
+	}
+
+	p, is := seccompPolicyPath(profile)
+	if !is {
+		return fmt.Errorf("%w: unknown seccomp profile: %v", ErrUsage, profile)
+	}
+
+	if !path.IsAbs(p) {
+		return fmt.Errorf("%w: seccomp policy path must be absolute: %v", ErrUsage, p)
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return fmt.Errorf("%w: seccomp policy: %v", ErrUsage, err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: seccomp policy is not a file: %v", ErrUsage, p)
+	}
+
+	return nil
+}
+
+// readSeccompPolicy reads the policy at p, which must be in the format of liblxc. Profiles in the json format of
+// docker can't be used by LXD.
+func readSeccompPolicy(p string) (string, error) {
+	policy, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+
+	// the first line of a liblxc policy is its version
+	version, _ := bufio.NewReader(bytes.NewReader(policy)).ReadString('\n')
+
+	switch strings.TrimSpace(version) {
+	case "1", "2":
+		return string(policy), nil
+	default:
+		return "", fmt.Errorf("%w: seccomp policy %v is not in the format of liblxc", ErrUsage, p)
+	}
+}
+
+// makeSeccompConfig sets the LXD options for the seccomp profile. Without profile nothing is set, so a container
+// inherits the profile of its sandbox.
+func makeSeccompConfig(config map[string]string, profile string) error {
+	if profile == "" {
+		return nil
+	}
+
+	config[cfgSeccompProfile] = profile
+
+	switch profile {
+	case SeccompProfileRuntimeDefault, SeccompProfileDockerDefault:
+		// set explicitly, so a relaxed profile of the sandbox doesn't apply
+		config[cfgSyscallsBlacklistDefault] = "true"
+	case SeccompProfileUnconfined:
+		config[cfgSyscallsBlacklistDefault] = "false"
+	default:
+		p, _ := seccompPolicyPath(profile)
+
+		policy, err := readSeccompPolicy(p)
+		if err != nil {
+			return err
+		}
+
+		config[cfgRawSeccomp] = policy
+	}
+
+	return nil
+}
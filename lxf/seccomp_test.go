@@ -0,0 +1,122 @@
+package lxf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSeccompPolicy = "2\nblacklist\nreject_force_umount\n[all]\nkexec_load errno 1\n"
+
+func testSeccompDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "lxe-seccomp")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	err = ioutil.WriteFile(filepath.Join(dir, "lxc.policy"), []byte(testSeccompPolicy), 0644)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "docker.json"), []byte(`{"defaultAction": "SCMP_ACT_ERRNO"}`), 0644)
+	assert.NoError(t, err)
+
+	return dir
+}
+
+func Test_validateSeccompProfile(t *testing.T) {
+	t.Parallel()
+
+	dir := testSeccompDir(t)
+
+	tests := []struct {
+		name    string
+		profile string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"runtime default", SeccompProfileRuntimeDefault, false},
+		{"docker default", SeccompProfileDockerDefault, false},
+		{"unconfined", SeccompProfileUnconfined, false},
+		{"localhost", SeccompProfileLocalhostPrefix + filepath.Join(dir, "lxc.policy"), false},
+		{"localhost missing", SeccompProfileLocalhostPrefix + filepath.Join(dir, "missing.policy"), true},
+		{"localhost directory", SeccompProfileLocalhostPrefix + dir, true},
+		{"localhost relative", SeccompProfileLocalhostPrefix + "lxc.policy", true},
+		{"unknown", "foo", true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateSeccompProfile(tt.profile)
+			assert.False(t, (err != nil) != tt.wantErr)
+
+			if err != nil {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func Test_makeSeccompConfig_None(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	err := makeSeccompConfig(config, "")
+	assert.NoError(t, err)
+	assert.Empty(t, config)
+}
+
+func Test_makeSeccompConfig_RuntimeDefault(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	err := makeSeccompConfig(config, SeccompProfileRuntimeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		cfgSeccompProfile:           SeccompProfileRuntimeDefault,
+		cfgSyscallsBlacklistDefault: "true",
+	}, config)
+}
+
+func Test_makeSeccompConfig_Unconfined(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{}
+
+	err := makeSeccompConfig(config, SeccompProfileUnconfined)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		cfgSeccompProfile:           SeccompProfileUnconfined,
+		cfgSyscallsBlacklistDefault: "false",
+	}, config)
+}
+
+func Test_makeSeccompConfig_Localhost(t *testing.T) {
+	t.Parallel()
+
+	profile := SeccompProfileLocalhostPrefix + filepath.Join(testSeccompDir(t), "lxc.policy")
+	config := map[string]string{}
+
+	err := makeSeccompConfig(config, profile)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		cfgSeccompProfile: profile,
+		cfgRawSeccomp:     testSeccompPolicy,
+	}, config)
+}
+
+func Test_makeSeccompConfig_LocalhostDockerFormat(t *testing.T) {
+	t.Parallel()
+
+	profile := SeccompProfileLocalhostPrefix + filepath.Join(testSeccompDir(t), "docker.json")
+
+	err := makeSeccompConfig(map[string]string{}, profile)
+	assert.True(t, errors.Is(err, ErrUsage))
+}
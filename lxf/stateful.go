@@ -0,0 +1,131 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// ErrStatefulUnsupported is returned by the operations saving or restoring the runtime state of a container, like
+// Checkpoint or a live MoveContainer, if LXD can't do so as CRIU is missing. The error of the operation itself, like
+// ErrCheckpoint, is matched as well
+var ErrStatefulUnsupported = errors.New("stateful operations unsupported")
+
+// statefulDriver is the driver of LXD which saves the runtime state of containers with CRIU
+const statefulDriver = "lxc"
+
+// Settings of Config.CRIU. LXD doesn't report whether CRIU is installed, so CRIUAuto assumes it is until a stateful
+// operation fails for lack of it. CRIUInstalled and CRIUMissing state it explicitly
+const (
+	CRIUAuto      = "auto"
+	CRIUInstalled = "installed"
+	CRIUMissing   = "missing"
+)
+
+// criuMissingTTL is how long a stateful operation which failed as CRIU is missing keeps further ones from being issued
+// with CRIUAuto. CRIU may be installed meanwhile, so it's tried again afterwards
+var criuMissingTTL = 10 * time.Minute
+
+// statefulUnsupportedError is ErrStatefulUnsupported for a failed operation, which is matched too
+type statefulUnsupportedError struct {
+	// op is the error of the operation, like ErrCheckpoint
+	op error
+	id string
+	// reason is why LXD can't save the state
+	reason string
+}
+
+func (e *statefulUnsupportedError) Error() string {
+	return fmt.Sprintf("%v: %v: container %v: %v", ErrStatefulUnsupported, e.op, e.id, e.reason)
+}
+
+// Is matches ErrStatefulUnsupported and the error of the operation
+func (e *statefulUnsupportedError) Is(target error) bool {
+	return target == ErrStatefulUnsupported || target == e.op
+}
+
+// isCRIUMissing returns true if err of LXD tells CRIU isn't installed, e.g. "Unable to create a stateful snapshot. CRIU
+// isn't installed" or the lookup of its binary failing. Other CRIU errors mean the state of that container couldn't be
+// handled
+func isCRIUMissing(err error) bool {
+	msg := strings.ToLower(err.Error())
+
+	return strings.Contains(msg, "criu isn't installed") || strings.Contains(msg, `"criu": executable file not found`)
+}
+
+// SupportsStatefulOps tells whether LXD can save and restore the runtime state of containers, which Checkpoint,
+// RestoreCheckpoint and a live MoveContainer require. LXD needs its lxc driver for that and CRIU, which it doesn't
+// report in its environment. So unless Config.CRIU states it, CRIU is only known to be missing once a stateful operation
+// failed for lack of it, which is remembered for a while. Callers can probe this once instead of issuing stateful
+// requests which would fail.
+func (l *client) SupportsStatefulOps() (bool, error) {
+	if l.criuMissing() {
+		return false, nil
+	}
+
+	var server *api.Server
+
+	err := l.withReconnect(func(s lxd.ContainerServer) error {
+		var err error
+		server, _, err = s.GetServer()
+
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, driver := range strings.Split(server.Environment.Driver, "|") {
+		if strings.TrimSpace(driver) == statefulDriver {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// criuMissing returns whether CRIU is configured missing or, with CRIUAuto, a stateful operation recently failed as it
+// is
+func (l *client) criuMissing() bool {
+	switch l.conn.CRIU {
+	case CRIUInstalled:
+		return false
+	case CRIUMissing:
+		return true
+	}
+
+	l.statefulMu.Lock()
+	defer l.statefulMu.Unlock()
+
+	return !l.criuMissingAt.IsZero() && time.Since(l.criuMissingAt) < criuMissingTTL
+}
+
+// checkStateful returns ErrStatefulUnsupported with op if CRIU is known to be missing, so the operation on the
+// container id isn't issued
+func (l *client) checkStateful(op error, id string) error {
+	if !l.criuMissing() {
+		return nil
+	}
+
+	return &statefulUnsupportedError{op: op, id: id, reason: "CRIU is missing"}
+}
+
+// statefulError wraps err of the stateful operation op on the container id. If CRIU is missing, ErrStatefulUnsupported
+// is returned and with CRIUAuto remembered for criuMissingTTL. Other errors of CRIU only return op
+func (l *client) statefulError(op error, id string, err error) error {
+	if isCRIUMissing(err) {
+		if l.conn.CRIU == "" || l.conn.CRIU == CRIUAuto {
+			l.statefulMu.Lock()
+			l.criuMissingAt = time.Now()
+			l.statefulMu.Unlock()
+		}
+
+		return &statefulUnsupportedError{op: op, id: id, reason: err.Error()}
+	}
+
+	return fmt.Errorf("%w: container %v: %v", op, id, err)
+}
@@ -0,0 +1,164 @@
+package lxf
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServerDriver(driver string) *api.Server {
+	return &api.Server{
+		Environment: api.ServerEnvironment{Driver: driver},
+	}
+}
+
+func TestClient_SupportsStatefulOps(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(testServerDriver("lxc | qemu"), "", nil)
+
+	ok, err := client.SupportsStatefulOps()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestClient_SupportsStatefulOps_NoLXC(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(testServerDriver("qemu"), "", nil)
+
+	ok, err := client.SupportsStatefulOps()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestClient_SupportsStatefulOps_ServerError(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(nil, "", errors.New("server error"))
+
+	_, err := client.SupportsStatefulOps()
+	assert.Error(t, err)
+}
+
+func TestClient_Checkpoint_CRIUMissingRemembered(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeOp := testCheckpointClient(api.Running)
+	fake.GetServerReturns(testServerDriver("lxc"), "", nil)
+	fakeOp.WaitReturns(errors.New("Unable to create a stateful snapshot. CRIU isn't installed"))
+
+	err := client.Checkpoint("foo", filepath.Join(testCheckpointDir(t), "foo.tar.gz"))
+	assert.True(t, errors.Is(err, ErrStatefulUnsupported))
+	assert.True(t, errors.Is(err, ErrCheckpoint))
+
+	ok, err := client.SupportsStatefulOps()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, fake.GetServerCallCount())
+
+	// further stateful operations aren't issued anymore
+	err = client.RestoreCheckpoint("foo", filepath.Join(testCheckpointDir(t), "foo.tar.gz"))
+	assert.True(t, errors.Is(err, ErrStatefulUnsupported))
+	assert.Equal(t, 0, fake.CreateContainerFromBackupCallCount())
+}
+
+func TestClient_Checkpoint_CRIUFailed(t *testing.T) {
+	t.Parallel()
+
+	client, _, fakeOp := testCheckpointClient(api.Running)
+	fakeOp.WaitReturns(errors.New("Failed to run: criu dump: exit status 1"))
+
+	err := client.Checkpoint("foo", filepath.Join(testCheckpointDir(t), "foo.tar.gz"))
+	assert.True(t, errors.Is(err, ErrCheckpoint))
+	assert.False(t, errors.Is(err, ErrStatefulUnsupported))
+	assert.False(t, client.criuMissing())
+}
+
+func TestClient_MoveContainer_LiveWithoutCRIU_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	client, _, _, fakeOp := testMoveClient(api.Running)
+	fakeOp.WaitReturns(errors.New("Unable to perform container live migration. CRIU isn't installed on the source server"))
+
+	err := client.MoveContainer("foo", "node2", true)
+	assert.True(t, errors.Is(err, ErrStatefulUnsupported))
+	assert.True(t, errors.Is(err, ErrLiveMigration))
+	assert.True(t, client.criuMissing())
+}
+
+func TestClient_Checkpoint_CRIUMissingExpires(t *testing.T) {
+	t.Parallel()
+
+	client, fake, fakeOp := testCheckpointClient(api.Running)
+	fake.GetServerReturns(testServerDriver("lxc"), "", nil)
+	fakeOp.WaitReturns(errors.New("Unable to create a stateful snapshot. CRIU isn't installed"))
+
+	err := client.Checkpoint("foo", filepath.Join(testCheckpointDir(t), "foo.tar.gz"))
+	assert.True(t, errors.Is(err, ErrStatefulUnsupported))
+	assert.True(t, client.criuMissing())
+
+	// CRIU may have been installed meanwhile
+	client.criuMissingAt = time.Now().Add(-criuMissingTTL)
+
+	ok, err := client.SupportsStatefulOps()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestClient_SupportsStatefulOps_CRIUConfigured(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerReturns(testServerDriver("lxc"), "", nil)
+
+	client.conn.CRIU = CRIUMissing
+	ok, err := client.SupportsStatefulOps()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, fake.GetServerCallCount())
+
+	err = client.checkStateful(ErrCheckpoint, "foo")
+	assert.True(t, errors.Is(err, ErrStatefulUnsupported))
+
+	client.conn.CRIU = CRIUInstalled
+	err = client.statefulError(ErrCheckpoint, "foo", errors.New("Unable to create a stateful snapshot. CRIU isn't installed"))
+	assert.True(t, errors.Is(err, ErrStatefulUnsupported))
+
+	// an explicit setting isn't overridden by a failed operation
+	ok, err = client.SupportsStatefulOps()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func Test_isCRIUMissing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"Unable to create a stateful snapshot. CRIU isn't installed", true},
+		{"Unable to perform container live migration. CRIU isn't installed on the source server", true},
+		{`exec: "criu": executable file not found in $PATH`, true},
+		{"Failed to run: criu dump: exit status 1", false},
+		{"criu restore: container not found", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.msg, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isCRIUMissing(errors.New(tt.msg)))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/automaticserver/lxe/lxf/lxo"
+	"github.com/automaticserver/lxe/shared"
+)
+
+// clusterGroupPrefix marks a target as cluster group instead of a member, like LXD does
+const clusterGroupPrefix = "@"
+
+// ErrClusterGroupsUnsupported is returned if a cluster group is targeted, but LXD doesn't know cluster groups yet
+var ErrClusterGroupsUnsupported = errors.New("cluster groups unsupported")
+
+// validateTarget checks target is a member or, prefixed with @, a cluster group of the LXD cluster. An empty target
+// lets LXD select the member
+func (l *client) validateTarget(target string) error {
+	if target == "" {
+		return nil
+	}
+
+	if !l.GetServer().IsClustered() {
+		return fmt.Errorf("%w: target %v requires a LXD cluster", ErrUsage, target)
+	}
+
+	if !strings.HasPrefix(target, clusterGroupPrefix) {
+		_, _, err := l.GetServer().GetClusterMember(target)
+		if err != nil && shared.IsErrNotFound(err) {
+			return fmt.Errorf("%w: cluster member %v doesn't exist", ErrUsage, target)
+		}
+
+		return err
+	}
+
+	group := strings.TrimPrefix(target, clusterGroupPrefix)
+	if group == "" {
+		return fmt.Errorf("%w: target %v lacks the name of the cluster group", ErrUsage, target)
+	}
+
+	if !l.GetServer().HasExtension("clustering_groups") {
+		return fmt.Errorf("%w: LXD can't place instances in cluster group %v, it requires LXD 4.19 or newer", ErrClusterGroupsUnsupported, group)
+	}
+
+	// the client of LXD has no call for cluster groups yet
+	_, _, err := l.GetServer().RawQuery("GET", "/1.0/cluster/groups/"+url.PathEscape(group), nil, "")
+	if err != nil && shared.IsErrNotFound(err) {
+		return fmt.Errorf("%w: cluster group %v doesn't exist", ErrUsage, group)
+	}
+
+	return err
+}
+
+// targetOpwait returns the operation waiter which creates the container on its target. With a cluster group as target,
+// LXD selects the member within the group, e.g. by its placement scriptlet
+func (c *Container) targetOpwait() *lxo.LXO {
+	if c.Target == "" {
+		return c.client.opWait()
+	}
+
+	return lxo.NewClient(c.client.GetServer().UseTarget(c.Target))
+}
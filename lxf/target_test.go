@@ -0,0 +1,97 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_validateTarget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		target    string
+		clustered bool
+		groups    bool
+		queryErr  error
+		memberErr error
+		wantErr   error
+	}{
+		{"none", "", false, false, nil, nil, nil},
+		{"member", "node1", true, false, nil, nil, nil},
+		{"group", "@gpu", true, true, nil, nil, nil},
+		{"not clustered", "node1", false, false, nil, nil, ErrUsage},
+		{"unknown member", "node9", true, false, nil, shared.NewErrNotFound(), ErrUsage},
+		{"groups unsupported", "@gpu", true, false, nil, nil, ErrClusterGroupsUnsupported},
+		{"unknown group", "@gpu", true, true, shared.NewErrNotFound(), nil, ErrUsage},
+		{"group without name", "@", true, true, nil, nil, ErrUsage},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, fake := testClient()
+			fake.IsClusteredReturns(tt.clustered)
+			fake.HasExtensionReturns(tt.groups)
+			fake.RawQueryReturns(&api.Response{}, "", tt.queryErr)
+			fake.GetClusterMemberReturns(&api.ClusterMember{}, "", tt.memberErr)
+
+			err := client.validateTarget(tt.target)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestContainer_targetOpwait(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	targeted := &lxdfakes.FakeContainerServer{}
+	targeted.CreateContainerReturns(&lxdfakes.FakeOperation{}, nil)
+	fake.UseTargetReturns(targeted)
+
+	c := client.NewContainer("sandboxID")
+	assert.Equal(t, client.opwait, c.targetOpwait())
+
+	c.Target = "@gpu"
+	err := c.targetOpwait().CreateContainer(api.ContainersPost{Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "@gpu", fake.UseTargetArgsForCall(0))
+	assert.Equal(t, 1, targeted.CreateContainerCallCount())
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
+
+func TestContainer_Apply_UnknownClusterGroup(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.IsClusteredReturns(true)
+	fake.HasExtensionReturns(true)
+	fake.RawQueryReturns(nil, "", shared.NewErrNotFound())
+
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.Target = "@gpu"
+	c.sandbox = &Sandbox{}
+
+	err := c.Apply()
+	assert.True(t, errors.Is(err, ErrUsage))
+
+	_, path, _, _ := fake.RawQueryArgsForCall(0)
+	assert.Equal(t, "/1.0/cluster/groups/gpu", path)
+	assert.Equal(t, 0, fake.UseTargetCallCount())
+}
@@ -0,0 +1,117 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/automaticserver/lxe/shared"
+)
+
+const (
+	cfgKeepImageTemplates = "user.keep_image_templates"
+)
+
+// managedTemplateTargets are the files LXE manages itself through cloud-init if the sandbox sets a hostname. Images
+// usually ship templates for them which LXD renders with the container name
+var managedTemplateTargets = []string{"/etc/hostname", "/etc/hosts"}
+
+// Template is a metadata template of an instance, which LXD renders into the file at Path on the triggers in When,
+// e.g. create, copy or start
+type Template struct {
+	// Path of the rendered file inside the instance
+	Path string
+	// File is the name of the template file
+	File string
+	// When lists the triggers on which the template is rendered
+	When []string
+	// CreateOnly renders the template only if Path doesn't exist yet
+	CreateOnly bool
+}
+
+// Templates returns the metadata templates of the container, which it got from its image, sorted by their path
+func (c *Container) Templates() ([]Template, error) {
+	meta, _, err := c.client.GetServer().GetContainerMetadata(c.ID)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return nil, shared.NewErrNotFound()
+		}
+
+		return nil, err
+	}
+
+	templates := make([]Template, 0, len(meta.Templates))
+
+	for path, t := range meta.Templates {
+		templates = append(templates, Template{
+			Path:       path,
+			File:       t.Template,
+			When:       t.When,
+			CreateOnly: t.CreateOnly,
+		})
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Path < templates[j].Path
+	})
+
+	return templates, nil
+}
+
+// managesEtcHosts tells whether cloud-init of the container writes /etc/hostname and /etc/hosts, see Sandbox.apply
+func (c *Container) managesEtcHosts() (bool, error) {
+	if c.KeepImageTemplates {
+		return false, nil
+	}
+
+	sb, err := c.Sandbox()
+	if err != nil {
+		return false, err
+	}
+
+	return sb.Hostname != "", nil
+}
+
+// disableManagedTemplates removes the templates of managedTemplateTargets from the metadata of the container. LXD
+// renders the templates triggered by create only on the first start, so removing them right after creation keeps them
+// from ever overwriting what cloud-init writes. The template files themselves are left in place
+func (c *Container) disableManagedTemplates() error {
+	manages, err := c.managesEtcHosts()
+	if err != nil || !manages {
+		return err
+	}
+
+	meta, etag, err := c.client.GetServer().GetContainerMetadata(c.ID)
+	if err != nil {
+		return err
+	}
+
+	removed := false
+
+	for _, path := range managedTemplateTargets {
+		if _, has := meta.Templates[path]; has {
+			delete(meta.Templates, path)
+
+			removed = true
+		}
+	}
+
+	if !removed {
+		return nil
+	}
+
+	log.WithField("containerid", c.ID).Debug("disabled image templates of hostname and hosts")
+
+	return c.client.GetServer().SetContainerMetadata(c.ID, *meta, etag)
+}
+
+// makeKeepImageTemplatesConfig records whether the templates of the image are kept untouched
+func (c *Container) makeKeepImageTemplatesConfig(config map[string]string) {
+	if c.KeepImageTemplates {
+		config[cfgKeepImageTemplates] = strconv.FormatBool(true)
+	}
+}
+
+// parseKeepImageTemplatesConfig reads whether the templates of the image are kept untouched
+func (c *Container) parseKeepImageTemplatesConfig(config map[string]string) {
+	c.KeepImageTemplates = config[cfgKeepImageTemplates] == strconv.FormatBool(true)
+}
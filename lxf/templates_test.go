@@ -0,0 +1,121 @@
+package lxf
+
+import (
+	"testing"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testImageMetadata() *api.ImageMetadata {
+	return &api.ImageMetadata{
+		Templates: map[string]*api.ImageMetadataTemplate{
+			"/etc/hostname":              {When: []string{"create", "copy"}, Template: "hostname.tpl"},
+			"/etc/hosts":                 {When: []string{"create", "copy"}, Template: "hosts.tpl"},
+			"/etc/init/console.override": {When: []string{"create"}, CreateOnly: true, Template: "upstart-override.tpl"},
+		},
+	}
+}
+
+func TestContainer_Templates(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+
+	fake.GetContainerMetadataReturns(testImageMetadata(), "etag", nil)
+
+	templates, err := c.Templates()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", fake.GetContainerMetadataArgsForCall(0))
+	assert.Equal(t, []Template{
+		{Path: "/etc/hostname", File: "hostname.tpl", When: []string{"create", "copy"}},
+		{Path: "/etc/hosts", File: "hosts.tpl", When: []string{"create", "copy"}},
+		{Path: "/etc/init/console.override", File: "upstart-override.tpl", When: []string{"create"}, CreateOnly: true},
+	}, templates)
+}
+
+func TestContainer_disableManagedTemplates(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+	c.sandbox = &Sandbox{Hostname: "myhost"}
+
+	fake.GetContainerMetadataReturns(testImageMetadata(), "etag", nil)
+
+	err := c.disableManagedTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.SetContainerMetadataCallCount())
+
+	id, meta, etag := fake.SetContainerMetadataArgsForCall(0)
+	assert.Equal(t, "foo", id)
+	assert.Equal(t, "etag", etag)
+	assert.Len(t, meta.Templates, 1)
+	assert.Contains(t, meta.Templates, "/etc/init/console.override")
+}
+
+func TestContainer_disableManagedTemplates_NoneShipped(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.ID = "foo"
+	c.sandbox = &Sandbox{Hostname: "myhost"}
+
+	fake.GetContainerMetadataReturns(&api.ImageMetadata{}, "etag", nil)
+
+	err := c.disableManagedTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.SetContainerMetadataCallCount())
+}
+
+func TestContainer_disableManagedTemplates_Untouched(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		hostname string
+		keep     bool
+	}{
+		{"no hostname", "", false},
+		{"kept", "myhost", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := testClient()
+			c := client.NewContainer("sandboxID")
+			c.ID = "foo"
+			c.KeepImageTemplates = tt.keep
+			c.sandbox = &Sandbox{Hostname: tt.hostname}
+
+			err := c.disableManagedTemplates()
+			assert.NoError(t, err)
+			assert.Equal(t, 0, fake.GetContainerMetadataCallCount())
+			assert.Equal(t, 0, fake.SetContainerMetadataCallCount())
+		})
+	}
+}
+
+func TestContainer_KeepImageTemplatesConfig(t *testing.T) {
+	t.Parallel()
+
+	c := &Container{KeepImageTemplates: true}
+	config := map[string]string{}
+	c.makeKeepImageTemplatesConfig(config)
+	assert.Equal(t, map[string]string{cfgKeepImageTemplates: "true"}, config)
+
+	r := &Container{}
+	r.parseKeepImageTemplatesConfig(config)
+	assert.True(t, r.KeepImageTemplates)
+
+	c.KeepImageTemplates = false
+	config = map[string]string{}
+	c.makeKeepImageTemplatesConfig(config)
+	assert.Empty(t, config)
+}
@@ -0,0 +1,50 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	cfgTimezone = "user.timezone"
+	// cfgEnvironmentTZ is the variable the C library reads the timezone from, overriding /etc/localtime of the image
+	cfgEnvironmentTZ = cfgEnvironmentPrefix + ".TZ"
+)
+
+// validateTimezone checks tz is a name of the tz database like Europe/Zurich. Local is refused as it refers to the
+// timezone of LXE and not to one the instance can find
+func validateTimezone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+
+	if tz == "Local" {
+		return fmt.Errorf("%w: timezone must be a name of the tz database: %v", ErrUsage, tz)
+	}
+
+	_, err := time.LoadLocation(tz)
+	if err != nil {
+		return fmt.Errorf("%w: unknown timezone %v: %v", ErrUsage, tz, err)
+	}
+
+	return nil
+}
+
+// makeTimezoneConfig sets the TZ variable of the instance, if a timezone is set. The timezone is recorded too, so it
+// can be told apart from a TZ variable set as any other
+func (o *LXDObject) makeTimezoneConfig(config map[string]string) {
+	if o.Timezone == "" {
+		return
+	}
+
+	config[cfgTimezone] = o.Timezone
+	config[cfgEnvironmentTZ] = o.Timezone
+}
+
+// parseTimezoneConfig reads the timezone from config. It returns true if the TZ variable was set for it, so the caller
+// doesn't list it as a variable on its own
+func (o *LXDObject) parseTimezoneConfig(config map[string]string) bool {
+	o.Timezone = config[cfgTimezone]
+
+	return o.Timezone != "" && config[cfgEnvironmentTZ] == o.Timezone
+}
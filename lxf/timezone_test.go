@@ -0,0 +1,94 @@
+package lxf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateTimezone(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tz      string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"utc", "UTC", false},
+		{"zone", "Europe/Zurich", false},
+		{"local", "Local", true},
+		{"unknown", "Mars/Olympus_Mons", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateTimezone(tt.tz)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrUsage))
+		})
+	}
+}
+
+func TestLXDObject_makeTimezoneConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	o := &LXDObject{Timezone: "Europe/Zurich"}
+	config := map[string]string{}
+
+	o.makeTimezoneConfig(config)
+	assert.Equal(t, map[string]string{cfgTimezone: "Europe/Zurich", "environment.TZ": "Europe/Zurich"}, config)
+
+	r := &LXDObject{}
+	assert.True(t, r.parseTimezoneConfig(config))
+	assert.Equal(t, o, r)
+}
+
+func TestLXDObject_makeTimezoneConfig_Unset(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{"environment.TZ": "UTC"}
+
+	(&LXDObject{}).makeTimezoneConfig(config)
+	assert.Equal(t, map[string]string{"environment.TZ": "UTC"}, config)
+
+	r := &LXDObject{}
+	assert.False(t, r.parseTimezoneConfig(config))
+	assert.Empty(t, r.Timezone)
+}
+
+func TestClient_GetContainer_Timezone(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	ct := basicContainer("foo", "bar")
+	ct.Config[cfgTimezone] = "Europe/Zurich"
+	ct.Config["environment.TZ"] = "Europe/Zurich"
+	ct.Config["environment.LANG"] = "C.UTF-8"
+	fake.GetContainerReturns(ct, "", nil)
+
+	c, err := client.GetContainer("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Zurich", c.Timezone)
+	assert.Equal(t, map[string]string{"LANG": "C.UTF-8"}, c.Environment)
+}
+
+func TestContainer_Apply_TimezoneConflict(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	c := client.NewContainer("sandboxID")
+	c.Metadata.Name = "foo"
+	c.Image = "busybox"
+	c.Timezone = "Europe/Zurich"
+	c.Environment["TZ"] = "UTC"
+
+	err := c.Apply()
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.Equal(t, 0, fake.CreateContainerCallCount())
+}
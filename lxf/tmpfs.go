@@ -0,0 +1,189 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/shared/units"
+)
+
+const (
+	cfgTmpfs   = "user.tmpfs"
+	cfgShmSize = "user.shm_size"
+	// lxcMountEntry is the raw.lxc option used to mount a tmpfs
+	lxcMountEntry = "lxc.mount.entry"
+	// shmPath is where the tmpfs of ShmSize is mounted
+	shmPath = "/dev/shm"
+)
+
+// TmpfsMount is a memory backed filesystem mounted into the container. LXD has no disk device for this, so it is
+// mounted by liblxc when the container starts. Nothing is persisted on the host, the content is gone when the
+// container stops and the mount is removed together with the container.
+type TmpfsMount struct {
+	// Path inside the container, it is created if missing
+	Path string
+	// Size in bytes the tmpfs is limited to. If zero, the tmpfs default of half of the memory is used. The memory used
+	// is accounted to the container
+	Size int64
+}
+
+// mountEntry returns the value of the lxc mount entry. The target of a mount entry is relative to the rootfs
+func (m TmpfsMount) mountEntry() string {
+	options := "rw,nosuid,nodev,create=dir"
+	if m.Size > 0 {
+		options += ",size=" + strconv.FormatInt(m.Size, 10)
+	}
+
+	return fmt.Sprintf("tmpfs %s tmpfs %s 0 0", strings.TrimPrefix(path.Clean(m.Path), "/"), options)
+}
+
+// isTmpfsMountEntry returns true for raw.lxc lines generated by mountEntry
+func isTmpfsMountEntry(key, value string) bool {
+	return key == lxcMountEntry && strings.HasPrefix(value, "tmpfs ")
+}
+
+// nodeMemory returns the total memory in bytes of the LXD node the container is created on. It's reported by LXD, as
+// LXE may run on another host. The node of a cluster group target isn't known yet, so 0 is returned for it
+func (l *client) nodeMemory(target string) (int64, error) {
+	if strings.HasPrefix(target, clusterGroupPrefix) {
+		return 0, nil
+	}
+
+	server := l.GetServer()
+	if target != "" {
+		server = server.UseTarget(target)
+	}
+
+	resources, err := server.GetServerResources()
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(resources.Memory.Total), nil
+}
+
+// validateTmpfs checks the paths of the mounts are unique and absolute and their sizes fit into the memory of the node.
+// The sizes aren't checked if memory is 0
+func validateTmpfs(mounts []TmpfsMount, memory int64) error {
+	paths := make(map[string]bool)
+
+	for _, m := range mounts {
+		p := path.Clean(m.Path)
+
+		switch {
+		case !path.IsAbs(p) || p == "/":
+			return fmt.Errorf("%w: tmpfs path must be absolute and not the root: %v", ErrUsage, m.Path)
+		case strings.ContainsAny(p, " \t\n"):
+			return fmt.Errorf("%w: tmpfs path must not contain whitespace: %v", ErrUsage, m.Path)
+		case paths[p]:
+			return fmt.Errorf("%w: tmpfs path is used multiple times: %v", ErrUsage, m.Path)
+		case m.Size < 0:
+			return fmt.Errorf("%w: tmpfs size must not be negative: %v", ErrUsage, m.Size)
+		case memory > 0 && m.Size > memory:
+			return fmt.Errorf("%w: tmpfs size %v of %v exceeds the node memory of %v", ErrUsage, m.Size, m.Path, memory)
+		}
+
+		paths[p] = true
+	}
+
+	return nil
+}
+
+// tmpfsMounts returns the tmpfs mounts of the container including the one of ShmSize
+func (c *Container) tmpfsMounts() ([]TmpfsMount, error) {
+	shm, err := shmMount(c.ShmSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if shm == nil {
+		return c.Tmpfs, nil
+	}
+
+	return append(append([]TmpfsMount{}, c.Tmpfs...), *shm), nil
+}
+
+// validateTmpfsMemory checks the sizes of the tmpfs mounts of the container fit into the memory of its node
+func (c *Container) validateTmpfsMemory() error {
+	mounts, err := c.tmpfsMounts()
+	if err != nil || len(mounts) == 0 {
+		return err
+	}
+
+	memory, err := c.client.nodeMemory(c.Target)
+	if err != nil {
+		return err
+	}
+
+	return validateTmpfs(mounts, memory)
+}
+
+// makeTmpfsConfig stores the mounts in config and replaces the mount entries in raw.lxc
+func makeTmpfsConfig(config map[string]string, mounts []TmpfsMount) error {
+	filterRawLXC(config, isTmpfsMountEntry)
+
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(mounts)
+	if err != nil {
+		return err
+	}
+
+	config[cfgTmpfs] = string(raw)
+
+	for _, m := range mounts {
+		appendRawLXC(config, lxcMountEntry+" = "+m.mountEntry())
+	}
+
+	return nil
+}
+
+// parseShmSize returns the bytes of a shm size like 64MB or 1GiB
+func parseShmSize(size string) (int64, error) {
+	b, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid shm size %v: %v", ErrUsage, size, err)
+	}
+
+	if b <= 0 {
+		return 0, fmt.Errorf("%w: shm size must be positive: %v", ErrUsage, size)
+	}
+
+	return b, nil
+}
+
+// shmMount returns the tmpfs mount of size at /dev/shm, or nil if size is empty
+func shmMount(size string) (*TmpfsMount, error) {
+	if size == "" {
+		return nil, nil
+	}
+
+	b, err := parseShmSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TmpfsMount{Path: shmPath, Size: b}, nil
+}
+
+// parseTmpfsConfig loads the mounts stored by makeTmpfsConfig
+func parseTmpfsConfig(config map[string]string) ([]TmpfsMount, error) {
+	raw, has := config[cfgTmpfs]
+	if !has {
+		return nil, nil
+	}
+
+	var mounts []TmpfsMount
+
+	err := json.Unmarshal([]byte(raw), &mounts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v: %w", cfgTmpfs, err)
+	}
+
+	return mounts, nil
+}
@@ -0,0 +1,168 @@
+package lxf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/lxdfakes"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmpfsMount_mountEntry(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "tmpfs cache tmpfs rw,nosuid,nodev,create=dir,size=1048576 0 0", TmpfsMount{Path: "/cache/", Size: 1048576}.mountEntry())
+	assert.Equal(t, "tmpfs var/tmp tmpfs rw,nosuid,nodev,create=dir 0 0", TmpfsMount{Path: "/var/tmp"}.mountEntry())
+}
+
+func Test_validateTmpfs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mounts  []TmpfsMount
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", []TmpfsMount{{Path: "/cache", Size: 512}, {Path: "/tmp"}}, false},
+		{"whole memory", []TmpfsMount{{Path: "/cache", Size: 1024}}, false},
+		{"exceeds memory", []TmpfsMount{{Path: "/cache", Size: 1025}}, true},
+		{"negative size", []TmpfsMount{{Path: "/cache", Size: -1}}, true},
+		{"relative", []TmpfsMount{{Path: "cache"}}, true},
+		{"root", []TmpfsMount{{Path: "/"}}, true},
+		{"whitespace", []TmpfsMount{{Path: "/my cache"}}, true},
+		{"duplicate", []TmpfsMount{{Path: "/cache"}, {Path: "/cache/"}}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateTmpfs(tt.mounts, 1024)
+			assert.False(t, (err != nil) != tt.wantErr)
+
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
+
+func Test_makeTmpfsConfig_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	mounts := []TmpfsMount{{Path: "/cache", Size: 1048576}}
+	config := map[string]string{cfgRawLXC: "lxc.include = /foo\nlxc.mount.entry = tmpfs old tmpfs rw 0 0"}
+
+	err := makeTmpfsConfig(config, mounts)
+	assert.NoError(t, err)
+	assert.Equal(t, "lxc.include = /foo\nlxc.mount.entry = tmpfs cache tmpfs rw,nosuid,nodev,create=dir,size=1048576 0 0", config[cfgRawLXC])
+
+	parsed, err := parseTmpfsConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, mounts, parsed)
+
+	// applying again doesn't accumulate entries
+	err = makeTmpfsConfig(config, mounts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(config[cfgRawLXC], lxcMountEntry))
+}
+
+func Test_makeTmpfsConfig_Removed(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{cfgRawLXC: "lxc.mount.entry = tmpfs cache tmpfs rw 0 0"}
+
+	err := makeTmpfsConfig(config, nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, config, cfgRawLXC)
+	assert.NotContains(t, config, cfgTmpfs)
+}
+
+func Test_validateTmpfs_MemoryUnknown(t *testing.T) {
+	t.Parallel()
+
+	err := validateTmpfs([]TmpfsMount{{Path: "/cache", Size: 1 << 40}}, 0)
+	assert.NoError(t, err)
+}
+
+func TestClient_nodeMemory(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerResourcesReturns(&api.Resources{Memory: api.ResourcesMemory{Total: 8 << 30}}, nil)
+
+	memory, err := client.nodeMemory("")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8<<30), memory)
+	assert.Equal(t, 0, fake.UseTargetCallCount())
+}
+
+func TestClient_nodeMemory_Target(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	member := &lxdfakes.FakeContainerServer{}
+	member.GetServerResourcesReturns(&api.Resources{Memory: api.ResourcesMemory{Total: 4 << 30}}, nil)
+	fake.UseTargetReturns(member)
+
+	memory, err := client.nodeMemory("node2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4<<30), memory)
+	assert.Equal(t, "node2", fake.UseTargetArgsForCall(0))
+	assert.Equal(t, 0, fake.GetServerResourcesCallCount())
+
+	// the member of a cluster group isn't known yet
+	memory, err = client.nodeMemory("@gpu")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), memory)
+}
+
+func TestClient_nodeMemory_Error(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+	fake.GetServerResourcesReturns(nil, errors.New("resources unavailable"))
+
+	_, err := client.nodeMemory("")
+	assert.Error(t, err)
+}
+
+func Test_parseShmSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"bytes", "1024", 1024, false},
+		{"megabytes", "64MB", 64000000, false},
+		{"gibibytes", "1GiB", 1073741824, false},
+		{"zero", "0", 0, true},
+		{"negative", "-1MB", 0, true},
+		{"unknown unit", "64XB", 0, true},
+		{"garbage", "much", 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseShmSize(tt.size)
+			assert.False(t, (err != nil) != tt.wantErr)
+			assert.Equal(t, tt.want, got)
+
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrUsage))
+			}
+		})
+	}
+}
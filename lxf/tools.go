@@ -1,3 +1,4 @@
+//go:build tools
 // +build tools
 
 package lxf // import "github.com/automaticserver/lxe/lxf"
@@ -2,10 +2,24 @@ package lxf // import "github.com/automaticserver/lxe/lxf"
 
 import (
 	"encoding/base32"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/automaticserver/lxe/lxf/device"
 )
 
 var (
 	b32lowerEncoder = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567")
+
+	// rawLXCForbidden are the raw.lxc keys lxd refuses
+	rawLXCForbidden = map[string]bool{
+		"lxc.logfile":    true,
+		"lxc.log.file":   true,
+		"lxc.syslog":     true,
+		"lxc.log.syslog": true,
+		"lxc.ephemeral":  true,
+	}
 )
 
 // SetIfSet sets a key in a map[string]string with the value, if the value is not empty
@@ -26,3 +40,158 @@ func AppendIfSet(s *map[string]string, key, value string) {
 		}
 	}
 }
+
+// rawLXCLines returns the non-empty lines of the raw.lxc config of s
+func rawLXCLines(s map[string]string) []string {
+	var lines []string
+
+	for _, l := range strings.Split(s[cfgRawLXC], "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines
+}
+
+// makeRawLXC writes the lines into the raw.lxc config of s, dropping repeated lines
+func makeRawLXC(s map[string]string, lines []string) {
+	delete(s, cfgRawLXC)
+
+	seen := make(map[string]bool)
+
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if l == "" || seen[l] {
+			continue
+		}
+
+		seen[l] = true
+
+		appendRawLXC(s, l)
+	}
+}
+
+// validateRawLXC checks the lines are single comments or "key = value" options of liblxc. Options lxd refuses to
+// pass through are rejected early
+func validateRawLXC(lines []string) error {
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+
+		switch {
+		case l == "" || strings.HasPrefix(l, "#"):
+			continue
+		case strings.Contains(l, "\n"):
+			return fmt.Errorf("%w: raw.lxc line must not contain a newline: %q", ErrUsage, l)
+		case !strings.Contains(l, "="):
+			return fmt.Errorf("%w: raw.lxc line must be of form 'key = value': %q", ErrUsage, l)
+		}
+
+		key := strings.TrimSpace(l[:strings.Index(l, "=")])
+
+		switch {
+		case !strings.HasPrefix(key, "lxc."):
+			return fmt.Errorf("%w: raw.lxc key must start with 'lxc.': %q", ErrUsage, key)
+		case rawLXCForbidden[key] || strings.HasPrefix(key, "lxc.prlimit."):
+			return fmt.Errorf("%w: raw.lxc key is managed by lxd: %q", ErrUsage, key)
+		}
+	}
+
+	return nil
+}
+
+// setRawLXCOption sets the lxc option key in the raw.lxc config of s. An existing line for this key is replaced, so
+// applying the same option repeatedly doesn't accumulate lines
+func setRawLXCOption(s map[string]string, key, value string) {
+	filterRawLXC(s, func(k, _ string) bool { return k == key })
+	appendRawLXC(s, key+" = "+value)
+}
+
+// appendRawLXC adds the lines to the raw.lxc config of s
+func appendRawLXC(s map[string]string, lines ...string) {
+	for _, l := range lines {
+		AppendIfSet(&s, cfgRawLXC, l)
+	}
+}
+
+// filterRawLXC removes all lines from the raw.lxc config of s for which drop returns true
+func filterRawLXC(s map[string]string, drop func(key, value string) bool) {
+	var lines []string
+
+	for _, l := range strings.Split(s[cfgRawLXC], "\n") {
+		if l == "" {
+			continue
+		}
+
+		if i := strings.Index(l, "="); i >= 0 && drop(strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])) {
+			continue
+		}
+
+		lines = append(lines, l)
+	}
+
+	if len(lines) == 0 {
+		delete(s, cfgRawLXC)
+		return
+	}
+
+	s[cfgRawLXC] = strings.Join(lines, "\n")
+}
+
+// formatIDs joins uids or gids with commas
+func formatIDs(ids []int64) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.FormatInt(id, 10))
+	}
+
+	return strings.Join(s, ",")
+}
+
+// parseIDs parses a comma separated list of uids or gids
+func parseIDs(s string) ([]int64, error) {
+	var ids []int64
+
+	for _, f := range strings.Split(s, ",") {
+		if f == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// detectDevices loads the devices of a lxd device map. Devices of unknown type are kept as device.Unknown, so they
+// are written back unchanged when applying.
+func detectDevices(raw map[string]map[string]string) (device.Devices, error) {
+	var devices device.Devices
+
+	for name, options := range raw {
+		d, err := device.Detect(name, options)
+		if err != nil {
+			if !device.IsUnknownType(err) {
+				return nil, err
+			}
+
+			log.WithField("device", name).WithField("type", options["type"]).Debug("keeping device of unknown type verbatim")
+
+			d = &device.Unknown{}
+
+			err = d.FromMap(name, options)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		devices.Upsert(d)
+	}
+
+	return devices, nil
+}
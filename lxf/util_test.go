@@ -0,0 +1,72 @@
+package lxf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setRawLXCOption(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   string
+	}{
+		{"empty", map[string]string{}, "lxc.init.uid = 1000"},
+		{"append", map[string]string{cfgRawLXC: "lxc.include = /foo"}, "lxc.include = /foo\nlxc.init.uid = 1000"},
+		{"replace", map[string]string{cfgRawLXC: "lxc.init.uid=0\nlxc.include = /foo"}, "lxc.include = /foo\nlxc.init.uid = 1000"},
+		{"idempotent", map[string]string{cfgRawLXC: "lxc.init.uid = 1000"}, "lxc.init.uid = 1000"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			setRawLXCOption(tt.config, "lxc.init.uid", "1000")
+			assert.Equal(t, tt.want, tt.config[cfgRawLXC])
+		})
+	}
+}
+
+func Test_makeRawLXC(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]string{cfgRawLXC: "lxc.include = /old"}
+
+	makeRawLXC(config, []string{"lxc.include = /foo", "", " lxc.include = /foo", "lxc.apparmor.profile = unconfined"})
+	assert.Equal(t, "lxc.include = /foo\nlxc.apparmor.profile = unconfined", config[cfgRawLXC])
+	assert.Equal(t, []string{"lxc.include = /foo", "lxc.apparmor.profile = unconfined"}, rawLXCLines(config))
+
+	makeRawLXC(config, nil)
+	assert.NotContains(t, config, cfgRawLXC)
+}
+
+func Test_validateRawLXC(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		lines   []string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"options", []string{"lxc.include = /foo", "lxc.hook.pre-start=/bin/true"}, false},
+		{"comment", []string{"# some comment"}, false},
+		{"newline", []string{"lxc.include = /foo\nlxc.include = /bar"}, true},
+		{"no value", []string{"lxc.include"}, true},
+		{"not lxc", []string{"foo = bar"}, true},
+		{"forbidden", []string{"lxc.log.file = /tmp/log"}, true},
+		{"prlimit", []string{"lxc.prlimit.nofile = 1024"}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRawLXC(tt.lines)
+			assert.False(t, (err != nil) != tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package lxf // import "github.com/automaticserver/lxe/lxf"
+
+import (
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/shared"
+)
+
+const (
+	volumeTypeCustom = "custom"
+)
+
+// blockPoolDrivers are the storage pool drivers which store a custom volume as block device holding a regular
+// filesystem. Such a filesystem must only be mounted read-write once: lvm volumes could be activated on another cluster
+// member and ceph rbd volumes on any host using the same cluster, without either knowing about the other. Volumes of
+// the other drivers (dir, btrfs, zfs, cephfs) are plain directories or shared filesystems and can be written to by
+// many containers at the same time.
+var blockPoolDrivers = map[string]bool{
+	"lvm":  true,
+	"ceph": true,
+}
+
+// shareDisks prepares the disks attaching a custom volume for being used by multiple containers. Host paths can always
+// be bind mounted into several containers. A custom volume which is already attached to another container is shared
+// read-write if the driver of its pool allows it, otherwise the disk is attached read-only.
+func (c *Container) shareDisks() error {
+	for _, d := range c.Devices {
+		disk, is := d.(*device.Disk)
+		if !is || disk.Pool == "" || disk.Source == "" || disk.Path == "/" || disk.Readonly {
+			continue
+		}
+
+		inUse, err := c.client.volumeUsedByOthers(disk.Pool, disk.Source, c.ID)
+		if err != nil {
+			return err
+		}
+
+		if !inUse {
+			continue
+		}
+
+		pool, _, err := c.client.GetServer().GetStoragePool(disk.Pool)
+		if err != nil {
+			return err
+		}
+
+		if blockPoolDrivers[pool.Driver] {
+			log.WithField("pool", disk.Pool).WithField("volume", disk.Source).WithField("driver", pool.Driver).
+				Warn("volume is already attached read-write to another container, attaching read-only")
+
+			disk.Readonly = true
+		}
+	}
+
+	return nil
+}
+
+// volumeUsedByOthers returns true if a container other than except uses the custom volume. A volume which doesn't
+// exist yet isn't used by anyone.
+func (l *client) volumeUsedByOthers(pool, volume, except string) (bool, error) {
+	vol, _, err := l.GetServer().GetStoragePoolVolume(pool, volumeTypeCustom, volume)
+	if err != nil {
+		if shared.IsErrNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	for _, selflink := range vol.UsedBy {
+		if id := GetContainerIDFromSelflink(selflink); id != "" && id != except {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
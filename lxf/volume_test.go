@@ -0,0 +1,78 @@
+package lxf
+
+import (
+	"testing"
+
+	"github.com/automaticserver/lxe/lxf/device"
+	"github.com/automaticserver/lxe/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainer_shareDisks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		usedBy       []string
+		driver       string
+		wantReadonly bool
+	}{
+		{"unused", nil, "lvm", false},
+		{"used by itself", []string{"/1.0/containers/foo"}, "lvm", false},
+		{"shared dir", []string{"/1.0/containers/bar"}, "dir", false},
+		{"shared zfs", []string{"/1.0/containers/bar"}, "zfs", false},
+		{"shared lvm", []string{"/1.0/containers/bar"}, "lvm", true},
+		{"shared ceph", []string{"/1.0/containers/foo", "/1.0/containers/bar"}, "ceph", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := testClient()
+
+			fake.GetStoragePoolVolumeReturns(&api.StorageVolume{UsedBy: tt.usedBy}, "", nil)
+			fake.GetStoragePoolReturns(&api.StoragePool{Driver: tt.driver}, "", nil)
+
+			disk := &device.Disk{Path: "/data", Pool: "default", Source: "vol"}
+			c := &Container{}
+			c.client = client
+			c.ID = "foo"
+			c.Devices = device.Devices{disk}
+
+			err := c.shareDisks()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantReadonly, disk.Readonly)
+
+			pool, volType, name := fake.GetStoragePoolVolumeArgsForCall(0)
+			assert.Equal(t, []string{"default", volumeTypeCustom, "vol"}, []string{pool, volType, name})
+		})
+	}
+}
+
+func TestContainer_shareDisks_HostPathEmptyDir(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	c := &Container{}
+	c.client = client
+	c.Devices = device.Devices{&device.Disk{Path: "/cache", Source: "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/cache"}}
+
+	err := c.shareDisks()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetStoragePoolVolumeCallCount())
+}
+
+func TestClient_volumeUsedByOthers_Missing(t *testing.T) {
+	t.Parallel()
+
+	client, fake := testClient()
+
+	fake.GetStoragePoolVolumeReturns(nil, "", shared.NewErrNotFound())
+
+	used, err := client.volumeUsedByOthers("default", "vol", "foo")
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
@@ -0,0 +1,45 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"os"
+)
+
+// FreezeAllocation stops or resumes allocating IPs of bridge, e.g. while its ranges are reconfigured in a maintenance
+// window. While frozen, finding or reserving an IP for a new pod fails with ErrAllocationFrozen. Existing pods keep
+// their IPs and releasing them still works. An allocation in progress finishes before FreezeAllocation returns
+func (p *lxdBridgePlugin) FreezeAllocation(bridge string, frozen bool) {
+	c := p.bridgeLeases(bridge)
+	c.Lock()
+	defer c.Unlock()
+
+	if c.frozen != frozen {
+		log.WithField("bridge", bridge).WithField("frozen", frozen).Info("ip allocation of bridge changed")
+	}
+
+	c.frozen = frozen
+}
+
+// AllocationFrozen returns whether allocating IPs of bridge is frozen, either by FreezeAllocation or by the FreezeFile
+func (p *lxdBridgePlugin) AllocationFrozen(bridge string) bool {
+	c := p.bridgeLeases(bridge)
+	c.Lock()
+	defer c.Unlock()
+
+	return p.frozen(c)
+}
+
+// frozen returns whether the bridge of the locked cache c may not allocate IPs. The FreezeFile is looked up on every
+// allocation, so creating and removing it takes effect on the next pod without restarting the plugin
+func (p *lxdBridgePlugin) frozen(c *leaseCache) bool {
+	if c.frozen {
+		return true
+	}
+
+	if p.conf.FreezeFile == "" {
+		return false
+	}
+
+	_, err := os.Stat(p.conf.FreezeFile)
+
+	return err == nil
+}
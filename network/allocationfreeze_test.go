@@ -0,0 +1,98 @@
+package network
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	lxdApi "github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lxdBridgePlugin_FreezeAllocation(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{"ipv4.address": "192.168.224.1/24"},
+		},
+	}, "", nil)
+
+	plugin.FreezeAllocation(testLXDBridge, true)
+	assert.True(t, plugin.AllocationFrozen(testLXDBridge))
+	assert.False(t, plugin.AllocationFrozen("other"))
+
+	_, err := plugin.findFreeIP(nil)
+	assert.True(t, errors.Is(err, ErrAllocationFrozen))
+
+	err = plugin.reserveIP(net.ParseIP("192.168.224.10"))
+	assert.True(t, errors.Is(err, ErrAllocationFrozen))
+
+	plugin.FreezeAllocation(testLXDBridge, false)
+	assert.False(t, plugin.AllocationFrozen(testLXDBridge))
+
+	ip, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, ip)
+}
+
+func Test_lxdBridgePlugin_FreezeAllocation_KeepsReleasing(t *testing.T) {
+	t.Parallel()
+
+	plugin, _ := testLXDBridgePlugin()
+
+	err := plugin.reservationStore().Reserve(Reservation{Bridge: testLXDBridge, IP: net.ParseIP("192.168.224.10"), At: time.Now()})
+	assert.NoError(t, err)
+
+	plugin.FreezeAllocation(testLXDBridge, true)
+	plugin.releaseIP(testLXDBridge, net.ParseIP("192.168.224.10"))
+
+	reservations, err := plugin.reservationStore().List()
+	assert.NoError(t, err)
+	assert.Empty(t, reservations)
+}
+
+func Test_lxdBridgePlugin_FreezeAllocation_FreezeFile(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{"ipv4.address": "192.168.224.1/24"},
+		},
+	}, "", nil)
+
+	dir, err := ioutil.TempDir("", "lxe-freeze")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	plugin.conf.FreezeFile = filepath.Join(dir, "freeze")
+	assert.False(t, plugin.AllocationFrozen(testLXDBridge))
+
+	err = ioutil.WriteFile(plugin.conf.FreezeFile, nil, 0644)
+	assert.NoError(t, err)
+	assert.True(t, plugin.AllocationFrozen(testLXDBridge))
+
+	_, err = plugin.findFreeIP(nil)
+	assert.True(t, errors.Is(err, ErrAllocationFrozen))
+
+	err = os.Remove(plugin.conf.FreezeFile)
+	assert.NoError(t, err)
+	assert.False(t, plugin.AllocationFrozen(testLXDBridge))
+
+	ip, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, ip)
+}
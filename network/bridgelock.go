@@ -0,0 +1,96 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFileMode is the mode lock files are created with, other allocators usually run as root too
+const lockFileMode = 0600
+
+// lockFilePath returns the lock file of bridge in dir
+func lockFilePath(dir, bridge string) string {
+	return filepath.Join(dir, bridge+".lock")
+}
+
+// flockBridge takes an exclusive flock on the lock file of bridge in dir, blocking until it's free. The lock is bound to
+// the returned file and released by closing it, or by the kernel if the process dies
+func flockBridge(dir, bridge string) (*os.File, error) {
+	f, err := os.OpenFile(lockFilePath(dir, bridge), os.O_CREATE|os.O_RDWR, lockFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Flock(int(f.Fd()), unix.LOCK_EX)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock bridge %v: %w", bridge, err)
+	}
+
+	return f, nil
+}
+
+// validateLockDir creates the lock directory if missing and checks a lock can be taken in it, so taking the lock later
+// doesn't fail for lack of permissions
+func validateLockDir(dir, bridge string) error {
+	err := os.MkdirAll(dir, 0755) // nolint: gomnd
+	if err != nil {
+		return err
+	}
+
+	f, err := flockBridge(dir, bridge)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// lockBridge serializes allocations of IPs in bridge and returns its lease cache. Within the process, the lock of the
+// cache is held. If a LockDir is configured, an flock on the lock file of the bridge is taken in addition, which
+// serializes with other processes taking the same. The returned unlock releases both, in reverse order
+func (p *lxdBridgePlugin) lockBridge(bridge string) (*leaseCache, func(), error) {
+	c := p.bridgeLeases(bridge)
+	c.Lock()
+
+	if p.conf.LockDir == "" {
+		return c, c.Unlock, nil
+	}
+
+	f, err := flockBridge(p.conf.LockDir, bridge)
+	if err != nil {
+		c.Unlock()
+		return nil, nil, err
+	}
+
+	return c, func() {
+		f.Close()
+		c.Unlock()
+	}, nil
+}
+
+// LockBridge blocks until no IP of bridge is allocated by the plugin and keeps it from allocating more until unlock is
+// called. This lets custom allocators reserve addresses without racing the plugin. The lock always serializes within
+// this process. Across processes it only does if the plugin got a LockDir: other processes then have to take an
+// exclusive flock(2) on the file <LockDir>/<bridge>.lock for as long as they allocate. As the plugin can't know what got
+// reserved meanwhile, unlock drops the cached leases, so the next allocation fetches them from LXD again. If the lock
+// file can't be locked, the lock holds within the process only
+func (p *lxdBridgePlugin) LockBridge(bridge string) (unlock func()) {
+	c, unlockAll, err := p.lockBridge(bridge)
+	if err != nil {
+		log.WithError(err).WithField("bridge", bridge).Error("unable to lock bridge across processes")
+
+		c = p.bridgeLeases(bridge)
+		c.Lock()
+		unlockAll = c.Unlock
+	}
+
+	return func() {
+		c.leases = nil
+
+		unlockAll()
+	}
+}
@@ -0,0 +1,111 @@
+package network
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lockedWithin reports whether lock returns within d
+func lockedWithin(lock func(), d time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		lock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func Test_lxdBridgePlugin_LockBridge(t *testing.T) {
+	t.Parallel()
+
+	plugin, _ := testLXDBridgePlugin()
+
+	unlock := plugin.LockBridge(testLXDBridge)
+
+	acquired := make(chan func(), 1)
+	assert.False(t, lockedWithin(func() { acquired <- plugin.LockBridge(testLXDBridge) }, 50*time.Millisecond))
+
+	// other bridges aren't affected
+	assert.True(t, lockedWithin(func() { plugin.LockBridge("other")() }, time.Second))
+
+	unlock()
+
+	select {
+	case unlock = <-acquired:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal("lock wasn't acquired after unlock")
+	}
+}
+
+func Test_lxdBridgePlugin_LockBridge_InvalidatesLeases(t *testing.T) {
+	t.Parallel()
+
+	plugin, _ := testLXDBridgePlugin()
+
+	c := plugin.bridgeLeases(testLXDBridge)
+	c.leases = []net.IP{net.ParseIP("10.0.0.2")}
+
+	plugin.LockBridge(testLXDBridge)()
+	assert.Nil(t, c.leases)
+}
+
+func Test_lxdBridgePlugin_lockBridge_AcrossProcesses(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "bridgelock")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	plugin, _ := testLXDBridgePlugin()
+	plugin.conf.LockDir = dir
+
+	// a lock on another open file of the same path conflicts like one of another process
+	other, err := flockBridge(dir, testLXDBridge)
+	assert.NoError(t, err)
+
+	unlocked := make(chan func(), 1)
+	assert.False(t, lockedWithin(func() {
+		_, unlock, err := plugin.lockBridge(testLXDBridge)
+		assert.NoError(t, err)
+		unlocked <- unlock
+	}, 50*time.Millisecond))
+
+	other.Close()
+
+	select {
+	case unlock := <-unlocked:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal("lock wasn't acquired after the other process released it")
+	}
+}
+
+func Test_validateLockDir(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "bridgelock")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	lockDir := filepath.Join(dir, "locks")
+
+	err = validateLockDir(lockDir, testLXDBridge)
+	assert.NoError(t, err)
+	assert.FileExists(t, lockFilePath(lockDir, testLXDBridge))
+}
@@ -1,12 +1,17 @@
 package network
 
 import (
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/automaticserver/lxe/network/libcnifake"
+	"github.com/automaticserver/lxe/shared"
 	"github.com/containernetworking/cni/libcni"
 	types020 "github.com/containernetworking/cni/pkg/types/020"
 	"github.com/containernetworking/cni/pkg/types/current"
@@ -16,17 +21,18 @@ import (
 var (
 	// verify interface satisfaction
 	_ Plugin           = &cniPlugin{}
+	_ GarbageCollector = &cniPlugin{}
 	_ PodNetwork       = &cniPodNetwork{}
 	_ ContainerNetwork = &cniContainerNetwork{}
 )
 
-func fakeCNIFiles(t *testing.T) (string, string, string, string) {
+func fakeCNIFiles(t testing.TB) (string, string, string, string) {
 	tmpDir, err := ioutil.TempDir("", "cni")
 	assert.NoError(t, err)
 
 	binPath := filepath.Join(tmpDir, DefaultCNIbinPath)
 	confPath := filepath.Join(tmpDir, DefaultCNIconfPath)
-	netnsPath := filepath.Join(tmpDir, defaultCNInetnsPath)
+	netnsPath := filepath.Join(tmpDir, DefaultCNInetnsPath)
 
 	err = os.MkdirAll(confPath, 0700)
 	assert.NoError(t, err)
@@ -69,9 +75,11 @@ func TestConfCNI_setDefaults(t *testing.T) {
 	assert.NotEmpty(t, conf.BinPath)
 	assert.NotEmpty(t, conf.ConfPath)
 	assert.NotEmpty(t, conf.NetnsPath)
+	// attachments made before the cache dir was configurable must stay reachable
+	assert.Equal(t, libcni.CacheDir, conf.CacheDir)
 }
 
-func testCNIPlugin(t *testing.T) (*cniPlugin, *libcnifake.FakeCNI, string) {
+func testCNIPlugin(t testing.TB) (*cniPlugin, *libcnifake.FakeCNI, string) {
 	fake := &libcnifake.FakeCNI{}
 	tmpDir, binPath, confPath, netnsPath := fakeCNIFiles(t)
 
@@ -82,6 +90,19 @@ func testCNIPlugin(t *testing.T) (*cniPlugin, *libcnifake.FakeCNI, string) {
 			ConfPath:  confPath,
 			NetnsPath: netnsPath,
 		},
+		// a plain file stands in for the pinned namespace
+		newNetns: func(path string) error {
+			return ioutil.WriteFile(path, nil, 0600)
+		},
+		removeNetns: func(path string) error {
+			err := os.Remove(path)
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		},
+		netnsInode: netnsInode,
 	}, fake, tmpDir
 }
 
@@ -100,6 +121,107 @@ func Test_cniPlugin_PodNetwork_Simple(t *testing.T) {
 	assert.NotNil(t, tPodNet.runtimeConf)
 }
 
+func Test_cniPlugin_PodNetwork_InterfaceAnnotation(t *testing.T) {
+	t.Parallel()
+
+	plugin, _, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	podNet, err := plugin.PodNetwork("foo", map[string]string{AnnotationInterface: "net0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "net0", podNet.(*cniPodNetwork).runtimeConf.IfName)
+}
+
+func Test_interfaceName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{"default", nil, shared.DefaultInterface, false},
+		{"pod", map[string]string{AnnotationInterface: "net0"}, "net0", false},
+		{"network", map[string]string{AnnotationInterface: "net0", AnnotationInterface + ".lo": "lo0"}, "lo0", false},
+		{"other network", map[string]string{AnnotationInterface + ".other": "net1"}, shared.DefaultInterface, false},
+		{"empty", map[string]string{AnnotationInterface: ""}, "", true},
+		{"too long", map[string]string{AnnotationInterface: "averylonginterface"}, "", true},
+		{"slash", map[string]string{AnnotationInterface: "net/0"}, "", true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interfaceName("lo", tt.annotations)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_cniPlugin_PodNetwork_CachedNetList(t *testing.T) {
+	t.Parallel()
+
+	plugin, _, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	first, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	second, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	other, err := plugin.PodNetwork("bar", nil)
+	assert.NoError(t, err)
+
+	// the config doesn't depend on the pod
+	assert.Same(t, first.(*cniPodNetwork).netList, second.(*cniPodNetwork).netList)
+	assert.Same(t, first.(*cniPodNetwork).netList, other.(*cniPodNetwork).netList)
+}
+
+func Test_cniPlugin_PodNetwork_ConfChanged(t *testing.T) {
+	t.Parallel()
+
+	plugin, _, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	first, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(plugin.conf.ConfPath, "10-lo.conf"), []byte(`
+	{
+		"cniVersion": "0.4.0",
+		"name": "lo-reloaded",
+		"type": "loopback"
+	}`), 0600)
+	assert.NoError(t, err)
+
+	second, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	assert.NotSame(t, first.(*cniPodNetwork).netList, second.(*cniPodNetwork).netList)
+	assert.Equal(t, "lo-reloaded", second.(*cniPodNetwork).netList.Name)
+}
+
+func Benchmark_cniPlugin_PodNetwork(b *testing.B) {
+	plugin, _, tmpDir := testCNIPlugin(b)
+	defer os.RemoveAll(tmpDir)
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = plugin.PodNetwork("foo", nil)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			plugin.netLists.netList = nil
+			_, _ = plugin.PodNetwork("foo", nil)
+		}
+	})
+}
+
 func Test_cniPlugin_UpdateRuntimeConfig(t *testing.T) {
 	t.Parallel()
 
@@ -122,7 +244,7 @@ func Test_cniPlugin_getCNIRuntimeConf(t *testing.T) {
 	assert.Equal(t, &libcni.RuntimeConf{
 		ContainerID: "foo",
 		NetNS:       "",
-		IfName:      DefaultInterface,
+		IfName:      shared.DefaultInterface,
 		Args:        [][2]string{},
 	}, conf)
 }
@@ -289,20 +411,316 @@ func testCNIContNet(t *testing.T) (*cniContainerNetwork, *libcnifake.FakeCNI, st
 	}, fake, tmpDir
 }
 
-func Test_cniContainerNetwork_WhenStarted(t *testing.T) {
+func Test_cniPodNetwork_WhenStarted(t *testing.T) {
 	t.Parallel()
 
-	contNet, fake, tmpDir := testCNIContNet(t)
+	podNet, fake, tmpDir := testCNIPodNet(t)
 	defer os.RemoveAll(tmpDir)
 
 	fake.AddNetworkListReturns(&current.Result{CNIVersion: "4.0", IPs: []*current.IPConfig{}}, nil)
 
-	res, err := contNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Pid: 6})
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Privileged: true})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, fake.AddNetworkListCallCount())
-	assert.NotEmpty(t, res.Data)
 	assert.Empty(t, res.Nics)
 	assert.Empty(t, res.NetworkConfigEntries)
+	assert.True(t, res.AddressPending)
+
+	// the network is attached to the namespace pinned for the pod
+	netns := filepath.Join(podNet.plugin.conf.NetnsPath, "foo")
+	assert.Equal(t, netns, res.Data["netns"])
+	assert.FileExists(t, netns)
+
+	_, _, argRuntimeConf := fake.AddNetworkListArgsForCall(0)
+	assert.Equal(t, netns, argRuntimeConf.NetNS)
+}
+
+func Test_cniPodNetwork_WhenStarted_Unprivileged(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake, tmpDir := testCNIPodNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	// a namespace of the host user namespace gives root of the containers no capabilities, the first started container
+	// holds the network instead
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}})
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+	assert.Equal(t, 0, fake.AddNetworkListCallCount())
+	assert.NoFileExists(t, filepath.Join(podNet.plugin.conf.NetnsPath, "foo"))
+}
+
+func Test_cniPodNetwork_WhenStarted_Addresses(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake, tmpDir := testCNIPodNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	result, err := current.NewResult([]byte(`{"cniVersion":"0.4.0","interfaces":[{"name":"eth1"}],"ips":[{"version":"6","interface":0,"address":"fd00::64/64"},{"version":"4","interface":0,"address":"10.22.0.64/16"}]}`))
+	assert.NoError(t, err)
+
+	fake.AddNetworkListReturns(result, nil)
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Privileged: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "eth1", res.Interface)
+	assert.Equal(t, "10.22.0.64", res.IPv4.String())
+	assert.Equal(t, "fd00::64", res.IPv6.String())
+	assert.False(t, res.AddressPending)
+}
+
+func Test_cniPodNetwork_WhenStarted_SetupFails(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake, tmpDir := testCNIPodNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	fake.AddNetworkListReturns(nil, errors.New("no more addresses"))
+
+	_, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Privileged: true})
+	assert.Error(t, err)
+	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+
+	// kubelet retries with a new pod, the namespace of this one must not be left behind
+	assert.NoFileExists(t, filepath.Join(podNet.plugin.conf.NetnsPath, "foo"))
+}
+
+func Test_cniPodNetwork_WhenStarted_StoresNetList(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "4.0", IPs: []*current.IPConfig{}}, nil)
+
+	podNet, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Privileged: true})
+	assert.NoError(t, err)
+
+	netList, err := libcni.ConfListFromBytes([]byte(res.Data["netlist"]))
+	assert.NoError(t, err)
+	assert.Equal(t, "lo", netList.Name)
+}
+
+func Test_cniPodNetwork_WhenStopped(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake, tmpDir := testCNIPodNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "4.0", IPs: []*current.IPConfig{}}, nil)
+	fake.DelNetworkListReturns(nil)
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Privileged: true})
+	assert.NoError(t, err)
+
+	err = podNet.WhenStopped(ctx, &Properties{Data: res.Data})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+
+	// the plugins clean up within the namespace before it's released
+	_, _, argRuntimeConf := fake.DelNetworkListArgsForCall(0)
+	assert.Equal(t, res.Data["netns"], argRuntimeConf.NetNS)
+	assert.NoFileExists(t, res.Data["netns"])
+
+	// deleting the stopped pod tears down again, which must succeed
+	err = podNet.WhenDeleted(ctx, &Properties{Data: res.Data})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.DelNetworkListCallCount())
+}
+
+func Test_cniPodNetwork_WhenDeleted_StoredNetList(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake, tmpDir := testCNIPodNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	fake.DelNetworkListReturns(nil)
+
+	err := podNet.WhenDeleted(ctx, &Properties{Data: map[string]string{
+		"netlist": `{"cniVersion":"0.4.0","name":"attached","plugins":[{"type":"loopback"}]}`,
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+
+	_, argNetList, _ := fake.DelNetworkListArgsForCall(0)
+	assert.Equal(t, "attached", argNetList.Name)
+}
+
+func Test_cniContainerNetwork_WhenCreated_JoinsPodNetns(t *testing.T) {
+	t.Parallel()
+
+	contNet, _, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	netns := filepath.Join(tmpDir, "net")
+
+	res, err := contNet.WhenCreated(ctx, &Properties{Data: map[string]string{"netns": netns}})
+	assert.NoError(t, err)
+	assert.Equal(t, netns, res.JoinNetns)
+}
+
+func Test_cniContainerNetwork_WhenCreated_PodNotStarted(t *testing.T) {
+	t.Parallel()
+
+	contNet, _, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	// the container becomes the holder of the pod network once started
+	res, err := contNet.WhenCreated(ctx, &Properties{})
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+}
+
+func Test_cniContainerNetwork_WhenCreated_JoinsHolder(t *testing.T) {
+	t.Parallel()
+
+	contNet, _, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	netns := filepath.Join(tmpDir, "net")
+	err := ioutil.WriteFile(netns, nil, 0600)
+	assert.NoError(t, err)
+
+	inode, err := netnsInode(netns)
+	assert.NoError(t, err)
+
+	res, err := contNet.WhenCreated(ctx, &Properties{Data: map[string]string{
+		"netns":              netns,
+		"netns-holder":       "baz",
+		"netns-holder-inode": strconv.FormatUint(inode, 10),
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, netns, res.JoinNetns)
+}
+
+func Test_cniContainerNetwork_WhenCreated_HolderPidReused(t *testing.T) {
+	t.Parallel()
+
+	contNet, _, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	netns := filepath.Join(tmpDir, "net")
+	err := ioutil.WriteFile(netns, nil, 0600)
+	assert.NoError(t, err)
+
+	inode, err := netnsInode(netns)
+	assert.NoError(t, err)
+
+	// another process got the pid of the holder, its namespace is a different one
+	res, err := contNet.WhenCreated(ctx, &Properties{Data: map[string]string{
+		"netns":              netns,
+		"netns-holder":       "baz",
+		"netns-holder-inode": strconv.FormatUint(inode+1, 10),
+	}})
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+}
+
+func Test_cniContainerNetwork_WhenCreated_HolderGone(t *testing.T) {
+	t.Parallel()
+
+	contNet, _, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	res, err := contNet.WhenCreated(ctx, &Properties{Data: map[string]string{
+		"netns":        filepath.Join(tmpDir, "gone"),
+		"netns-holder": "baz",
+	}})
+	assert.NoError(t, err)
+	assert.Nil(t, res)
+}
+
+func Test_cniContainerNetwork_WhenStarted_PreUpgradeSandbox(t *testing.T) {
+	t.Parallel()
+
+	contNet, fake, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	contNet.pod.plugin.netnsInode = func(path string) (uint64, error) { return 4026532, nil }
+
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "0.4.0", IPs: []*current.IPConfig{}}, nil)
+
+	// sandboxes of earlier versions have no namespace of the pod, the first started container holds the network
+	data := map[string]string{"netlist": `{"cniVersion":"0.4.0","name":"attached","plugins":[{"type":"loopback"}]}`}
+
+	cres, err := contNet.WhenCreated(ctx, &Properties{Data: data})
+	assert.NoError(t, err)
+	assert.Nil(t, cres)
+
+	res, err := contNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{Data: data}, Pid: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.AddNetworkListCallCount())
+	assert.Equal(t, "bar", res.Data["netns-holder"])
+	assert.Equal(t, "4026532", res.Data["netns-holder-inode"])
+	assert.Equal(t, "/proc/7/ns/net", res.Data["netns"])
+	assert.Equal(t, 0, fake.DelNetworkListCallCount())
+
+	_, _, argRuntimeConf := fake.AddNetworkListArgsForCall(0)
+	assert.Equal(t, "/proc/7/ns/net", argRuntimeConf.NetNS)
+}
+
+func Test_cniContainerNetwork_WhenStarted_HolderRestarted(t *testing.T) {
+	t.Parallel()
+
+	contNet, fake, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	contNet.pod.plugin.netnsInode = func(path string) (uint64, error) {
+		if path == "/proc/9/ns/net" {
+			return 4026533, nil
+		}
+
+		return 0, os.ErrNotExist
+	}
+
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "0.4.0", IPs: []*current.IPConfig{}}, nil)
+	fake.DelNetworkListReturns(nil)
+
+	// the holder was restarted, its earlier namespace is gone
+	data := map[string]string{
+		"netns":              "/proc/7/ns/net",
+		"netns-holder":       "bar",
+		"netns-holder-inode": "4026532",
+		"netlist":            `{"cniVersion":"0.4.0","name":"attached","plugins":[{"type":"loopback"}]}`,
+	}
+
+	res, err := contNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{Data: data}, Pid: 9})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+	assert.Equal(t, 1, fake.AddNetworkListCallCount())
+	assert.Equal(t, "bar", res.Data["netns-holder"])
+	assert.Equal(t, "4026533", res.Data["netns-holder-inode"])
+	assert.Equal(t, "/proc/9/ns/net", res.Data["netns"])
+
+	_, _, argRuntimeConf := fake.AddNetworkListArgsForCall(0)
+	assert.Equal(t, "/proc/9/ns/net", argRuntimeConf.NetNS)
+
+	// started again in the same namespace, the holder keeps its network
+	data = res.Data
+
+	_, err = contNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{Data: data}, Pid: 9})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+	assert.Equal(t, 1, fake.AddNetworkListCallCount())
+}
+
+func Test_cniContainerNetwork_WhenStarted(t *testing.T) {
+	t.Parallel()
+
+	contNet, fake, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	res, err := contNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{Data: map[string]string{
+		"netns":  filepath.Join(tmpDir, "net"),
+		"result": `{"cniVersion":"0.4.0","ips":[{"version":"4","address":"10.22.0.64/16"}]}`,
+	}}, Pid: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.AddNetworkListCallCount())
+	assert.Nil(t, res.Data)
+	assert.Equal(t, "10.22.0.64", res.IPv4.String())
 }
 
 func Test_cniContainerNetwork_WhenDeleted(t *testing.T) {
@@ -311,9 +729,93 @@ func Test_cniContainerNetwork_WhenDeleted(t *testing.T) {
 	contNet, fake, tmpDir := testCNIContNet(t)
 	defer os.RemoveAll(tmpDir)
 
+	// the pod network outlives its containers
+	err := contNet.WhenDeleted(ctx, &Properties{Data: map[string]string{"netns": filepath.Join(tmpDir, "net")}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.DelNetworkListCallCount())
+}
+
+func Test_cniContainerNetwork_WhenDeleted_Holder(t *testing.T) {
+	t.Parallel()
+
+	contNet, fake, tmpDir := testCNIContNet(t)
+	defer os.RemoveAll(tmpDir)
+
 	fake.DelNetworkListReturns(nil)
 
-	err := contNet.WhenDeleted(ctx, &Properties{})
+	err := contNet.WhenDeleted(ctx, &Properties{Data: map[string]string{"netns": "/proc/7/ns/net", "netns-holder": "baz"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.DelNetworkListCallCount())
+
+	err = contNet.WhenDeleted(ctx, &Properties{Data: map[string]string{"netns": "/proc/7/ns/net", "netns-holder": "bar"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+}
+
+func Test_cniPlugin_GC(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	plugin.conf.CacheDir = filepath.Join(tmpDir, "cache")
+	resultsDir := filepath.Join(plugin.conf.CacheDir, "results")
+
+	err := os.MkdirAll(resultsDir, 0700)
+	assert.NoError(t, err)
+
+	config := []byte(`{"cniVersion":"0.4.0","name":"lo","plugins":[{"type":"loopback"}]}`)
+
+	// the last id is how containerd names its sandboxes, which share the cache dir
+	for _, id := range []string{"valid", "orphan", strings.Repeat("0a", 32)} {
+		raw, err := json.Marshal(&cachedAttachment{Kind: "cniCacheV1", ContainerID: id, Config: config, IfName: "eth0", NetworkName: "lo"})
+		assert.NoError(t, err)
+
+		err = ioutil.WriteFile(filepath.Join(resultsDir, "lo-"+id+"-eth0"), raw, 0600)
+		assert.NoError(t, err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(resultsDir, "garbage"), []byte("foo"), 0600)
+	assert.NoError(t, err)
+
+	err = plugin.GC(ctx, []string{"valid"})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, fake.DelNetworkListCallCount())
+
+	_, argNetList, argRuntimeConf := fake.DelNetworkListArgsForCall(0)
+	assert.Equal(t, "lo", argNetList.Name)
+	assert.Equal(t, "orphan", argRuntimeConf.ContainerID)
+	assert.Equal(t, "eth0", argRuntimeConf.IfName)
+}
+
+func Test_cniPlugin_GC_Netns(t *testing.T) {
+	t.Parallel()
+
+	plugin, _, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	plugin.conf.CacheDir = filepath.Join(tmpDir, "missing")
+
+	for _, id := range []string{"valid", "orphan"} {
+		err := plugin.newNetns(filepath.Join(plugin.conf.NetnsPath, id))
+		assert.NoError(t, err)
+	}
+
+	err := plugin.GC(ctx, []string{"valid"})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(plugin.conf.NetnsPath, "valid"))
+	assert.NoFileExists(t, filepath.Join(plugin.conf.NetnsPath, "orphan"))
+}
+
+func Test_cniPlugin_GC_NoCache(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	plugin.conf.CacheDir = filepath.Join(tmpDir, "missing")
+
+	err := plugin.GC(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.DelNetworkListCallCount())
 }
@@ -0,0 +1,156 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+var (
+	// pluginNotFound matches the error of libcni if the binary of a plugin isn't in the bin path
+	pluginNotFound = regexp.MustCompile(`failed to find plugin "([^"]+)"`)
+	// ipamMessages are parts of the errors ipam plugins return if they can't assign an address
+	ipamMessages = []string{"no IP addresses available", "no free IP", "range is full", "ipam"}
+	// netnsMessages are parts of the errors plugins return if they can't enter the network namespace
+	netnsMessages = []string{"netns", "network namespace", "/proc/"}
+)
+
+// cniError holds the fields all errors of a cni plugin have in common
+type cniError struct {
+	// Network is the name of the network config list
+	Network string
+	// Plugin is the type of the plugin which failed, the best guess if libcni doesn't tell
+	Plugin string
+	// Err is the error returned by libcni
+	Err error
+}
+
+func (e *cniError) describe(what string) string {
+	return fmt.Sprintf("%s of plugin %s in network %s: %v", what, e.Plugin, e.Network, e.Err)
+}
+
+func (e *cniError) Unwrap() error {
+	return e.Err
+}
+
+// PluginError is returned if a plugin of the network can't be found or fails for a reason not covered by IPAMError or
+// NetnsError. Retrying won't help usually.
+type PluginError struct{ cniError }
+
+func (e *PluginError) Error() string {
+	return e.describe("cni plugin error")
+}
+
+// IPAMError is returned if the ipam plugin can't assign an address, e.g. its range is exhausted. Retrying later might
+// succeed once other pods released their addresses.
+type IPAMError struct{ cniError }
+
+func (e *IPAMError) Error() string {
+	return e.describe("cni ipam error")
+}
+
+// NetnsError is returned if the network namespace of the pod can't be entered, e.g. as it was released in the meantime.
+// Retrying with a new namespace might succeed.
+type NetnsError struct{ cniError }
+
+func (e *NetnsError) Error() string {
+	return e.describe("cni netns error")
+}
+
+// classifyCNIError wraps err returned by libcni for netList into one of PluginError, IPAMError or NetnsError. The
+// netns is the path of the namespace which was passed to the plugins, if any.
+func classifyCNIError(netList *libcni.NetworkConfigList, netns string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	ce := cniError{Err: err}
+	if netList != nil {
+		ce.Network = netList.Name
+	}
+
+	msg := err.Error()
+
+	if m := pluginNotFound.FindStringSubmatch(msg); m != nil {
+		ce.Plugin = m[1]
+		return &PluginError{ce}
+	}
+
+	var pathErr *os.PathError
+	if netns != "" && errors.As(err, &pathErr) && pathErr.Path == netns {
+		ce.Plugin = guessPlugin(netList, msg, false)
+		return &NetnsError{ce}
+	}
+
+	var typesErr *types.Error
+	if errors.As(err, &typesErr) {
+		msg = typesErr.Msg + " " + typesErr.Details
+	}
+
+	switch {
+	case containsAny(msg, netnsMessages):
+		ce.Plugin = guessPlugin(netList, msg, false)
+		return &NetnsError{ce}
+	case (typesErr != nil && typesErr.Code == types.ErrTryAgainLater) || containsAny(msg, ipamMessages):
+		ce.Plugin = guessPlugin(netList, msg, true)
+		return &IPAMError{ce}
+	}
+
+	ce.Plugin = guessPlugin(netList, msg, false)
+
+	return &PluginError{ce}
+}
+
+// guessPlugin returns the type of the plugin in netList which is mentioned in msg. Otherwise it's the first ipam
+// plugin if ipam is set, or the first plugin of the list
+func guessPlugin(netList *libcni.NetworkConfigList, msg string, ipam bool) string {
+	if netList == nil || len(netList.Plugins) == 0 {
+		return ""
+	}
+
+	for _, p := range netList.Plugins {
+		if p.Network == nil {
+			continue
+		}
+
+		if strings.Contains(msg, p.Network.Type) || (p.Network.IPAM.Type != "" && strings.Contains(msg, p.Network.IPAM.Type)) {
+			if ipam && p.Network.IPAM.Type != "" {
+				return p.Network.IPAM.Type
+			}
+
+			return p.Network.Type
+		}
+	}
+
+	if ipam {
+		for _, p := range netList.Plugins {
+			if p.Network != nil && p.Network.IPAM.Type != "" {
+				return p.Network.IPAM.Type
+			}
+		}
+	}
+
+	if netList.Plugins[0].Network == nil {
+		return ""
+	}
+
+	return netList.Plugins[0].Network.Type
+}
+
+// containsAny returns true if s contains any of the parts, ignoring the case
+func containsAny(s string, parts []string) bool {
+	s = strings.ToLower(s)
+
+	for _, p := range parts {
+		if strings.Contains(s, strings.ToLower(p)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCNIErrorNetList(t *testing.T) *libcni.NetworkConfigList {
+	netList, err := libcni.ConfListFromBytes([]byte(`{"cniVersion":"0.4.0","name":"mynet","plugins":[` +
+		`{"type":"bridge","ipam":{"type":"host-local"}},{"type":"portmap"}]}`))
+	assert.NoError(t, err)
+
+	return netList
+}
+
+func Test_classifyCNIError(t *testing.T) {
+	t.Parallel()
+
+	netList := testCNIErrorNetList(t)
+	netns := "/proc/5/ns/net"
+
+	tests := []struct {
+		name       string
+		err        error
+		wantPlugin string
+		target     interface{}
+	}{
+		{"plugin not found", errors.New(`failed to find plugin "portmap" in path [/opt/cni/bin]`), "portmap", &PluginError{}},
+		{"ipam exhausted", &types.Error{Code: types.ErrInternal, Msg: "failed to allocate for range 0: no IP addresses available in range set: 10.22.0.1-10.22.0.3"}, "host-local", &IPAMError{}},
+		{"try again later", &types.Error{Code: types.ErrTryAgainLater, Msg: "busy"}, "host-local", &IPAMError{}},
+		{"netns message", &types.Error{Code: types.ErrInternal, Msg: "failed to open netns \"/proc/5/ns/net\": no such file"}, "bridge", &NetnsError{}},
+		{"netns path", &os.PathError{Op: "open", Path: netns, Err: syscall.ENOENT}, "bridge", &NetnsError{}},
+		{"other", &types.Error{Code: types.ErrInvalidNetworkConfig, Msg: "portmap: invalid config"}, "portmap", &PluginError{}},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := classifyCNIError(netList, netns, tt.err)
+			assert.IsType(t, tt.target, err)
+			assert.True(t, errors.Is(err, tt.err))
+
+			var ce interface{ Unwrap() error }
+			assert.True(t, errors.As(err, &ce))
+
+			switch e := err.(type) {
+			case *PluginError:
+				assert.Equal(t, tt.wantPlugin, e.Plugin)
+				assert.Equal(t, "mynet", e.Network)
+			case *IPAMError:
+				assert.Equal(t, tt.wantPlugin, e.Plugin)
+				assert.Equal(t, "mynet", e.Network)
+			case *NetnsError:
+				assert.Equal(t, tt.wantPlugin, e.Plugin)
+				assert.Equal(t, "mynet", e.Network)
+			}
+
+			assert.Contains(t, err.Error(), tt.wantPlugin)
+		})
+	}
+}
+
+func Test_classifyCNIError_Nil(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, classifyCNIError(nil, "", nil))
+}
+
+func Test_cniPodNetwork_setup_IPAMError(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake, tmpDir := testCNIPodNet(t)
+	defer os.RemoveAll(tmpDir)
+
+	podNet.netList = testCNIErrorNetList(t)
+	fake.AddNetworkListReturns(nil, &types.Error{Code: types.ErrInternal, Msg: "no IP addresses available in range set"})
+
+	_, err := podNet.setup(ctx, "/proc/5/ns/net")
+
+	var ipamErr *IPAMError
+	assert.True(t, errors.As(err, &ipamErr))
+	assert.Equal(t, "host-local", ipamErr.Plugin)
+}
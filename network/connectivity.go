@@ -0,0 +1,88 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// AnnotationConnectivityCheck on a pod set to "true" pings the gateway from within the network namespace of the pod
+// right after the network got attached. The outcome doesn't fail the start but is reported by Status, so a NAT or
+// firewall silently dropping the traffic of pods shows up at pod start. Only supported by the cni plugin, as the bridge
+// plugin leaves requesting the address to dhcp within the pod.
+const AnnotationConnectivityCheck = "lxe.io/connectivity-check"
+
+const (
+	// dataConnectivityGateway is the address the check pinged
+	dataConnectivityGateway = "connectivity-gateway"
+	// dataConnectivityError is empty if the gateway answered, otherwise why the check failed
+	dataConnectivityError = "connectivity-error"
+)
+
+var (
+	ErrUnreachable = errors.New("gateway unreachable")
+	ErrNoGateway   = errors.New("no gateway")
+)
+
+// ConnectivityCheck is the outcome of pinging the gateway from within the pod
+type ConnectivityCheck struct {
+	// Gateway which was pinged, nil if none was known
+	Gateway net.IP
+	// Err is nil if the gateway answered
+	Err error
+}
+
+// connectivityCheckEnabled reports if the pod asks for the check by its annotations
+func connectivityCheckEnabled(annotations map[string]string) bool {
+	enabled, _ := strconv.ParseBool(annotations[AnnotationConnectivityCheck])
+	return enabled
+}
+
+// pingFromNetns sends a few echo requests to ip from within the network namespace netns
+func pingFromNetns(ctx context.Context, netns string, ip net.IP) error {
+	out, err := exec.CommandContext(ctx, "nsenter", "--net="+netns, "ping", "-n", "-c", "1", "-w", "3", ip.String()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v: %s", ErrUnreachable, ip, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// checkConnectivity pings gateway from within netns using ping and records the outcome in data
+func checkConnectivity(ctx context.Context, ping func(ctx context.Context, netns string, ip net.IP) error, netns string, gateway net.IP, data map[string]string) {
+	var err error
+
+	switch {
+	case gateway == nil:
+		err = ErrNoGateway
+	case ping == nil:
+		err = fmt.Errorf("%w: no ping command", ErrNotImplemented)
+	default:
+		data[dataConnectivityGateway] = gateway.String()
+		err = ping(ctx, netns, gateway)
+	}
+
+	data[dataConnectivityError] = ""
+	if err != nil {
+		data[dataConnectivityError] = err.Error()
+	}
+}
+
+// recordedConnectivity returns the outcome of the check recorded in data or nil if no check ran
+func recordedConnectivity(data map[string]string) *ConnectivityCheck {
+	msg, has := data[dataConnectivityError]
+	if !has {
+		return nil
+	}
+
+	check := &ConnectivityCheck{Gateway: net.ParseIP(data[dataConnectivityGateway])}
+	if msg != "" {
+		check.Err = errors.New(msg)
+	}
+
+	return check
+}
@@ -0,0 +1,111 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_connectivityCheckEnabled(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, connectivityCheckEnabled(nil))
+	assert.False(t, connectivityCheckEnabled(map[string]string{AnnotationConnectivityCheck: "no"}))
+	assert.True(t, connectivityCheckEnabled(map[string]string{AnnotationConnectivityCheck: "true"}))
+}
+
+func Test_checkConnectivity(t *testing.T) {
+	t.Parallel()
+
+	gateway := net.ParseIP("10.22.0.1")
+	reachable := func(_ context.Context, _ string, _ net.IP) error { return nil }
+	unreachable := func(_ context.Context, _ string, ip net.IP) error { return ErrUnreachable }
+
+	tests := []struct {
+		name    string
+		ping    func(ctx context.Context, netns string, ip net.IP) error
+		gateway net.IP
+		want    error
+	}{
+		{"reachable", reachable, gateway, nil},
+		{"unreachable", unreachable, gateway, ErrUnreachable},
+		{"no gateway", reachable, nil, ErrNoGateway},
+		{"no ping command", nil, gateway, ErrNotImplemented},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := map[string]string{}
+			checkConnectivity(ctx, tt.ping, "/proc/5/ns/net", tt.gateway, data)
+
+			check := recordedConnectivity(data)
+			assert.NotNil(t, check)
+
+			if tt.want == nil {
+				assert.NoError(t, check.Err)
+				assert.Equal(t, tt.gateway.String(), check.Gateway.String())
+			} else {
+				assert.Contains(t, check.Err.Error(), tt.want.Error())
+			}
+		})
+	}
+}
+
+func Test_recordedConnectivity_NoCheck(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, recordedConnectivity(map[string]string{}))
+}
+
+func Test_resultGateway(t *testing.T) {
+	t.Parallel()
+
+	gateway := net.ParseIP("10.22.0.1")
+
+	assert.Nil(t, resultGateway(nil))
+	assert.Nil(t, resultGateway(&current.Result{IPs: []*current.IPConfig{{Address: net.IPNet{IP: net.ParseIP("10.22.0.5")}}}}))
+	assert.Equal(t, gateway, resultGateway(types.Result(&current.Result{IPs: []*current.IPConfig{{Gateway: gateway}}})))
+}
+
+func Test_cniPodNetwork_WhenStarted_ConnectivityCheck(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	gateway := net.ParseIP("10.22.0.1")
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "0.4.0", IPs: []*current.IPConfig{{
+		Address: net.IPNet{IP: net.ParseIP("10.22.0.5"), Mask: net.CIDRMask(24, 32)},
+		Gateway: gateway,
+	}}}, nil)
+
+	var pinged string
+
+	plugin.pingCmd = func(_ context.Context, netns string, ip net.IP) error {
+		pinged = netns + " " + ip.String()
+		return errors.New("100% packet loss")
+	}
+
+	podNet, err := plugin.PodNetwork("foo", map[string]string{AnnotationConnectivityCheck: "true"})
+	assert.NoError(t, err)
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}, Privileged: true})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(plugin.conf.NetnsPath, "foo")+" 10.22.0.1", pinged)
+
+	status, err := podNet.Status(ctx, &PropertiesRunning{Properties: Properties{Data: res.Data}})
+	assert.NoError(t, err)
+	assert.Equal(t, gateway.String(), status.Connectivity.Gateway.String())
+	assert.EqualError(t, status.Connectivity.Err, "100% packet loss")
+}
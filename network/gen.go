@@ -1,3 +1,8 @@
 package network // import "github.com/automaticserver/lxe/network"
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o ./libcnifake/cni.go github.com/containernetworking/cni/libcni.CNI
+
+// networkfakes
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Plugin
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . PodNetwork
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . ContainerNetwork
@@ -1,11 +1,19 @@
 package network // import "github.com/automaticserver/lxe/network"
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os/exec"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/network/cloudinit"
@@ -16,11 +24,47 @@ import (
 )
 
 const (
-	DefaultLXDBridge = "lxebr0"
+	DefaultLXDBridge     = "lxebr0"
+	DefaultLeaseCacheTTL = 5 * time.Second
+
+	// AnnotationIP on a pod requests this IP from the bridge instead of a randomly found one
+	AnnotationIP = "lxe.io/ip"
+	// AnnotationPreferredIP on a pod is tried first when finding a free IP, e.g. the IP a recreated pod had before. Unlike
+	// AnnotationIP another IP is chosen if it isn't free
+	AnnotationPreferredIP = "lxe.io/preferred-ip"
+	// AnnotationAdditionalBridges on a pod is a comma separated list of existing LXD bridges the pod gets another nic
+	// in, e.g. for a management network. The nics are named eth1, eth2 and so on and get an IP of their bridge
+	AnnotationAdditionalBridges = "lxe.io/additional-bridges"
+
+	// dataAdditionalBridge and dataAdditionalAddress prefix the pod data recording bridge and IP of each additional nic,
+	// followed by the name of the interface
+	dataAdditionalBridge  = "additional-bridge."
+	dataAdditionalAddress = "additional-address."
+
+	// conflictRetries is how many other IPs are tried if the found IP is already in use
+	conflictRetries = 3
+
+	// Firewall drivers LXD uses to set up NAT for managed bridges
+	FirewallXtables  = "xtables"
+	FirewallNftables = "nftables"
 )
 
 var (
-	ErrNotBridge = errors.New("not a bridge")
+	ErrNotBridge         = errors.New("not a bridge")
+	ErrAddressConflict   = errors.New("address conflict")
+	ErrAddressOutOfRange = errors.New("address out of range")
+	ErrInvalidDNSConfig  = errors.New("invalid dns config")
+	ErrInvalidGateway    = errors.New("invalid gateway")
+	ErrDNSDisabled       = errors.New("dns disabled")
+	// ErrAllocationFrozen is returned while no IPs of a bridge are allocated, see FreezeAllocation and
+	// ConfLXDBridge.FreezeFile. It's temporary, so creating the pod can be retried later
+	ErrAllocationFrozen = errors.New("ip allocation frozen")
+
+	// domainLabel is a single label of a domain name according to RFC 1123
+	domainLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+	// procIPTablesNames lists the loaded legacy iptables tables, if any
+	procIPTablesNames = "/proc/net/ip_tables_names"
 )
 
 // ConfLXDBridge are configuration options for the LXDBridge plugin. All properties are optional and get a default value
@@ -29,12 +73,139 @@ type ConfLXDBridge struct {
 	Cidr       string
 	Nat        bool
 	CreateOnly bool
+	// LeaseCacheTTL is how long the leases of the bridge are reused for finding free IPs
+	LeaseCacheTTL time.Duration
+	// DisableDHCP turns off the DHCP server of the bridge. Pods then get neither an IP found by the plugin nor a dhcp
+	// config, as the addresses are expected to be assigned by other means, e.g. statically
+	DisableDHCP bool
+	// ConflictCheck probes a found IP before it is used and selects another one if something on the bridge answers.
+	// This catches hosts on the same segment which LXD has no lease of
+	ConflictCheck bool
+	// DNS keeps the DNS server of the bridge enabled. It is disabled by default, as the DNS of Kubernetes is always set
+	// by mounting a resolv.conf into the containers
+	DNS bool
+	// DNSDomain is the domain of the bridge and DNSSearch the search domains handed out by dhcp, both require DNS
+	DNSDomain string
+	DNSSearch []string
+	// Gateway is handed out by dhcp instead of the address of the bridge. It must be a usable address of Cidr, unless
+	// GatewayOffSubnet is set. An off-subnet gateway must be reachable by the pods by other means, e.g. an onlink route
+	Gateway          net.IP
+	GatewayOffSubnet bool
+	// LockDir holds a lock file per bridge, which is locked with flock(2) while IPs are allocated. Other processes
+	// allocating in the same bridge can take it to serialize with the plugin. If empty, allocations are only serialized
+	// within the process, see LockBridge
+	LockDir string
+	// DHCPPool limits the addresses handed out by dhcp and found for pods to a subrange of Cidr, leaving the rest of
+	// the subnet for other hosts. The whole subnet is used if it's unset
+	DHCPPool shared.IPRange
+	// ReservationStore keeps the IPs allocated for pods until LXD knows them. If nil, they're kept in memory
+	ReservationStore ReservationStore
+	// FreezeFile freezes the IP allocation of all bridges while it exists, see FreezeAllocation. Operators can create it
+	// for a maintenance window and remove it afterwards. Unset by default
+	FreezeFile string
 }
 
 func (c *ConfLXDBridge) setDefaults() {
 	if c.LXDBridge == "" {
 		c.LXDBridge = DefaultLXDBridge
 	}
+
+	if c.LeaseCacheTTL == 0 {
+		c.LeaseCacheTTL = DefaultLeaseCacheTTL
+	}
+}
+
+// validateDNS checks the domains are valid and only set if the DNS is enabled, as they would have no effect otherwise
+func (c *ConfLXDBridge) validateDNS() error {
+	if !c.DNS && (c.DNSDomain != "" || len(c.DNSSearch) > 0) {
+		return fmt.Errorf("%w: dns domain and search domains require the dns of bridge %v to be enabled", ErrInvalidDNSConfig, c.LXDBridge)
+	}
+
+	for _, domain := range append([]string{c.DNSDomain}, c.DNSSearch...) {
+		if domain != "" && !isDomainName(domain) {
+			return fmt.Errorf("%w: %q is not a valid domain name", ErrInvalidDNSConfig, domain)
+		}
+	}
+
+	return nil
+}
+
+// validateGateway checks the gateway is a usable IPv4 address of the cidr of the bridge, unless it's explicitly off
+// the subnet
+func (c *ConfLXDBridge) validateGateway() error {
+	if c.Gateway == nil {
+		return nil
+	}
+
+	if c.Gateway.To4() == nil {
+		return fmt.Errorf("%w: %v is not an IPv4 address", ErrInvalidGateway, c.Gateway)
+	}
+
+	if c.GatewayOffSubnet {
+		return nil
+	}
+
+	if c.Cidr == "" {
+		return fmt.Errorf("%w: %v can't be checked without the cidr of bridge %v, either set one or flag the gateway as off-subnet", ErrInvalidGateway, c.Gateway, c.LXDBridge)
+	}
+
+	_, subnet, err := net.ParseCIDR(c.Cidr)
+	if err != nil {
+		return err
+	}
+
+	if !isFreeIP(subnet, nil, nil, nil, c.Gateway) {
+		return fmt.Errorf("%w: %v is not a usable address of %v, flag it as off-subnet if intended", ErrInvalidGateway, c.Gateway, c.Cidr)
+	}
+
+	return nil
+}
+
+// validateDHCPPool checks the pool is a non-empty range of usable addresses of the cidr of the bridge
+func (c *ConfLXDBridge) validateDHCPPool() error {
+	if c.DHCPPool.Start == nil && c.DHCPPool.End == nil {
+		return nil
+	}
+
+	start, end := c.DHCPPool.Start, c.DHCPPool.End
+	if start == nil || end == nil || (start.To4() == nil) != (end.To4() == nil) {
+		return fmt.Errorf("%w: dhcp pool %v-%v must have a start and end of the same address family", shared.ErrInvalidRange, start, end)
+	}
+
+	if bytes.Compare(start.To16(), end.To16()) > 0 {
+		return fmt.Errorf("%w: dhcp pool %v-%v is empty", shared.ErrInvalidRange, start, end)
+	}
+
+	if c.Cidr == "" {
+		return fmt.Errorf("%w: dhcp pool %v-%v can't be checked without the cidr of bridge %v", shared.ErrInvalidRange, start, end, c.LXDBridge)
+	}
+
+	_, subnet, err := net.ParseCIDR(c.Cidr)
+	if err != nil {
+		return err
+	}
+
+	if !isFreeIP(subnet, nil, nil, nil, start) || !isFreeIP(subnet, nil, nil, nil, end) {
+		return fmt.Errorf("%w: dhcp pool %v-%v is not within the usable addresses of %v", shared.ErrInvalidRange, start, end, c.Cidr)
+	}
+
+	return nil
+}
+
+// isDomainName returns true if name consists of valid labels and isn't too long. A trailing dot is allowed
+func isDomainName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 { // nolint: gomnd
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if !domainLabel.MatchString(label) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // lxdBridgePlugin manages the pod networks using LXDBridge
@@ -42,6 +213,50 @@ type lxdBridgePlugin struct {
 	noopPlugin // every method not implemented is noop
 	server     lxd.ContainerServer
 	conf       ConfLXDBridge
+	// leases are cached per bridge, LXDBridge and the additional bridges of pods have one each
+	leasesMu sync.Mutex
+	leases   map[string]*leaseCache
+	// inUse reports if an address answers on the bridge, only called if the conflict check is enabled
+	inUse func(ip net.IP) bool
+	// ipCmd runs iproute2 to set up the routing table of pods
+	ipCmd func(ctx context.Context, args ...string) error
+}
+
+// leaseCache holds the leases of the bridge for a short time, so a burst of pod creations doesn't query LXD for every
+// allocation. Allocated IPs are reserved in the ReservationStore till their pod is removed, as LXD doesn't know about
+// them until a container of the pod is created. Its lock serializes the allocations in the bridge.
+type leaseCache struct {
+	sync.Mutex
+	fetchedAt time.Time
+	leases    []net.IP
+	// frozen keeps new IPs from being allocated, see FreezeAllocation
+	frozen bool
+}
+
+// bridgeLeases returns the lease cache of bridge
+func (p *lxdBridgePlugin) bridgeLeases(bridge string) *leaseCache {
+	p.leasesMu.Lock()
+	defer p.leasesMu.Unlock()
+
+	if p.leases == nil {
+		p.leases = make(map[string]*leaseCache)
+	}
+
+	c, has := p.leases[bridge]
+	if !has {
+		c = &leaseCache{}
+		p.leases[bridge] = c
+	}
+
+	return c
+}
+
+// invalidate forces the next lookup to fetch the leases from LXD
+func (c *leaseCache) invalidate() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.leases = nil
 }
 
 // InitPluginLXDBridge instantiates the LXDBridge plugin using the provided config
@@ -51,6 +266,15 @@ func InitPluginLXDBridge(server lxd.ContainerServer, conf ConfLXDBridge) (*lxdBr
 	p := &lxdBridgePlugin{
 		server: server,
 		conf:   conf,
+		inUse:  pingAddress,
+		ipCmd:  runIPCommand,
+	}
+
+	if conf.LockDir != "" {
+		err := validateLockDir(conf.LockDir, conf.LXDBridge)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	err := p.ensureBridge()
@@ -58,6 +282,11 @@ func InitPluginLXDBridge(server lxd.ContainerServer, conf ConfLXDBridge) (*lxdBr
 		return nil, err
 	}
 
+	err = p.reconcileReservations()
+	if err != nil {
+		return nil, fmt.Errorf("reconcile ip reservations: %w", err)
+	}
+
 	return p, nil
 }
 
@@ -80,45 +309,111 @@ func (p *lxdBridgePlugin) UpdateRuntimeConfig(conf *rtApi.RuntimeConfig) error {
 	return nil
 }
 
-// EnsureBridge ensures the bridge exists with the defined options. Cidr is an expected ipv4 cidr or can be empty to
-// automatically assign a cidr
+// EnsureBridge ensures the bridge exists with the defined options. Cidr is an IPv4 or IPv6 cidr or can be empty to
+// automatically assign an IPv4 cidr
 func (p *lxdBridgePlugin) ensureBridge() error {
-	var address string
-	if p.conf.Cidr == "" {
-		address = "auto"
-	} else {
+	p.bridgeLeases(p.conf.LXDBridge).invalidate()
+
+	err := p.conf.validateDNS()
+	if err != nil {
+		return err
+	}
+
+	err = p.conf.validateGateway()
+	if err != nil {
+		return err
+	}
+
+	err = p.conf.validateDHCPPool()
+	if err != nil {
+		return err
+	}
+
+	// the family of the cidr is the one pods get their IP from, the other one is disabled. An automatic cidr is IPv4
+	family, other := "ipv4", "ipv6"
+	address := "auto"
+
+	if p.conf.Cidr != "" {
+		_, subnet, err := net.ParseCIDR(p.conf.Cidr)
+		if err != nil {
+			return err
+		}
+
+		if subnet.IP.To4() == nil {
+			family, other = other, family
+		}
+
 		// Always use first address in range for the bridge
-		_, net, err := net.ParseCIDR(p.conf.Cidr)
+		ip := make(net.IP, len(subnet.IP))
+		copy(ip, subnet.IP)
+		ip[len(ip)-1]++
+		address = (&net.IPNet{IP: ip, Mask: subnet.Mask}).String()
+	}
+
+	if p.conf.Nat {
+		err := p.checkFirewall()
 		if err != nil {
 			return err
 		}
-		net.IP[3]++
-		address = net.String()
 	}
 
 	put := api.NetworkPut{
 		Description: "managed by LXE, default bridge",
 		Config: map[string]string{
-			"ipv4.address": address,
-			"ipv4.dhcp":    strconv.FormatBool(true),
-			"ipv4.nat":     strconv.FormatBool(p.conf.Nat),
-			"ipv6.address": "none",
+			family + ".address": address,
+			family + ".dhcp":    strconv.FormatBool(!p.conf.DisableDHCP),
+			family + ".nat":     strconv.FormatBool(p.conf.Nat),
+			other + ".address":  "none",
 			// We don't need to receive a DNS in DHCP, Kubernetes' DNS is always set by requesting a mount for resolv.conf.
 			// This disables dns in dnsmasq (option -p: https://linux.die.net/man/8/dnsmasq)
 			"raw.dnsmasq": `port=0`,
 		},
 	}
 
+	// the address of a pod is leased by dhcp like for IPv4, SLAAC would let it pick one by itself
+	if family == "ipv6" {
+		put.Config["ipv6.dhcp.stateful"] = "true"
+	}
+
+	if p.conf.DNS {
+		put.Config["raw.dnsmasq"] = ""
+		put.Config["dns.domain"] = p.conf.DNSDomain
+		put.Config["dns.search"] = strings.Join(p.conf.DNSSearch, ",")
+	}
+
+	if p.conf.DHCPPool.Start != nil {
+		put.Config[family+".dhcp.ranges"] = p.conf.DHCPPool.Start.String() + "-" + p.conf.DHCPPool.End.String()
+	}
+
+	if p.conf.Gateway != nil {
+		// dhcp option 3 is the router
+		put.Config["raw.dnsmasq"] = strings.TrimPrefix(put.Config["raw.dnsmasq"]+"\ndhcp-option=3,"+p.conf.Gateway.String(), "\n")
+	}
+
 	network, ETag, err := p.server.GetNetwork(p.conf.LXDBridge)
-	if err != nil {
-		if shared.IsErrNotFound(err) {
-			return p.server.CreateNetwork(api.NetworksPost{
-				Name:       p.conf.LXDBridge,
-				Type:       "bridge",
-				NetworkPut: put,
-			})
+	if err != nil && shared.IsErrNotFound(err) {
+		err = p.server.CreateNetwork(api.NetworksPost{
+			Name:       p.conf.LXDBridge,
+			Type:       "bridge",
+			NetworkPut: put,
+		})
+		if err == nil {
+			return nil
+		}
+
+		// another LXE might have created it in the meantime, e.g. while nodes start up. LXD doesn't report this with a
+		// distinct status, so it's looked up again and then verified and updated like any existing bridge
+		createErr := err
+
+		network, ETag, err = p.server.GetNetwork(p.conf.LXDBridge)
+		if err != nil {
+			return createErr
 		}
 
+		log.WithField("bridge", p.conf.LXDBridge).Debug("bridge got created concurrently")
+	}
+
+	if err != nil {
 		return err
 	} else if network.Type != "bridge" {
 		return fmt.Errorf("%w: %v, but is %v", ErrNotBridge, p.conf.LXDBridge, network.Type)
@@ -137,38 +432,353 @@ func (p *lxdBridgePlugin) ensureBridge() error {
 	return p.server.UpdateNetwork(p.conf.LXDBridge, network.Writable(), ETag)
 }
 
+// checkFirewall warns if the firewall driver LXD detected might not provide NAT for the bridge. LXD only reports the
+// driver it uses, so mixing it with legacy iptables rules of other tools on the host can only be warned about. Older
+// LXD doesn't report a driver at all, which isn't an error either.
+func (p *lxdBridgePlugin) checkFirewall() error {
+	server, _, err := p.server.GetServer()
+	if err != nil {
+		return err
+	}
+
+	driver := server.Environment.Firewall
+	log := log.WithField("bridge", p.conf.LXDBridge)
+
+	switch driver {
+	case FirewallXtables:
+	case FirewallNftables:
+		// LXD prefers nftables if no legacy rules were present when it started, but rules added later by other tools
+		// (e.g. a FORWARD policy set to DROP) still apply to the traffic of the bridge
+		if hasLegacyIPTables() {
+			log.Warnf("LXD uses the %v firewall driver, but legacy iptables tables are loaded on the host. These rules might drop the NAT traffic of the pods", driver)
+		}
+	case "":
+		log.Warn("LXD doesn't report its firewall driver, NAT of the bridge can't be checked")
+	default:
+		log.Warnf("LXD reports the unknown firewall driver '%v', NAT of the bridge can't be checked", driver)
+	}
+
+	return nil
+}
+
+// hasLegacyIPTables reports if any legacy iptables table is loaded on the host
+func hasLegacyIPTables() bool {
+	names, err := ioutil.ReadFile(procIPTablesNames)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(names)) != ""
+}
+
+// BridgeDNS returns the address of bridge as DNS server of its pods. It fails with ErrDNSDisabled if the dnsmasq of the
+// bridge doesn't serve DNS, e.g. because the plugin disabled it.
+func (p *lxdBridgePlugin) BridgeDNS(bridge string) (net.IP, error) {
+	network, _, err := p.server.GetNetwork(bridge)
+	if err != nil {
+		return nil, err
+	}
+
+	if network.Type != "bridge" {
+		return nil, fmt.Errorf("%w: %v, but is %v", ErrNotBridge, bridge, network.Type)
+	}
+
+	if !dnsEnabled(network) {
+		return nil, fmt.Errorf("%w in bridge %v", ErrDNSDisabled, bridge)
+	}
+
+	ip, _, err := net.ParseCIDR(network.Config[bridgeFamily(network)+".address"])
+	if err != nil {
+		return nil, fmt.Errorf("bridge %v has no usable address: %w", bridge, err)
+	}
+
+	return ip, nil
+}
+
+// dnsEnabled reports if the dnsmasq of the bridge answers DNS queries. It's disabled by listening on port 0, as set by
+// ensureBridge, or by setting the dns mode to none
+func dnsEnabled(network *api.Network) bool {
+	if network.Config["dns.mode"] == "none" {
+		return false
+	}
+
+	for _, l := range strings.Split(network.Config["raw.dnsmasq"], "\n") {
+		if strings.ReplaceAll(strings.TrimSpace(l), " ", "") == "port=0" {
+			return false
+		}
+	}
+
+	return true
+}
+
 var ErrNotImplemented = errors.New("not implemented")
 
-// findFreeIP generates a IP within the range of the provided lxd managed bridge which does
-// not exist in the current leases
-func (p *lxdBridgePlugin) findFreeIP() (net.IP, error) {
-	network, _, err := p.server.GetNetwork(p.conf.LXDBridge)
+// findFreeIP generates a IP within the range of the bridge of the plugin, see findFreeIPInBridge
+func (p *lxdBridgePlugin) findFreeIP(preferred net.IP) (net.IP, error) {
+	return p.findFreeIPInBridge(p.conf.LXDBridge, preferred)
+}
+
+// findFreeIPInBridge generates a IP within the range of the provided lxd managed bridge which does
+// not exist in the current leases. The IPv4 range is preferred, IPv6 is used if the bridge has no IPv4 address. If the
+// bridge has dhcp ranges, only addresses within them are found. The preferred IP is returned if it is free, it may be nil.
+func (p *lxdBridgePlugin) findFreeIPInBridge(bridge string, preferred net.IP) (net.IP, error) {
+	network, _, err := p.server.GetNetwork(bridge)
 	if err != nil {
 		return nil, err
-	} else if network.Config["ipv4.dhcp.ranges"] != "" {
-		// actually we can now using FindFreeIP(), but not good enough, as this field can yield multiple ranges
-		return nil, fmt.Errorf("%w to find an IP with explicitly set ip ranges `ipv4.dhcp.ranges` in bridge %v", ErrNotImplemented, p.conf.LXDBridge)
 	}
 
-	rawLeases, err := p.server.GetNetworkLeases(p.conf.LXDBridge)
+	if network.Type != "bridge" {
+		return nil, fmt.Errorf("%w: %v, but is %v", ErrNotBridge, bridge, network.Type)
+	}
+
+	family := bridgeFamily(network)
+
+	ranges, err := shared.ParseIPRanges(network.Config[family+".dhcp.ranges"])
+	if err != nil {
+		return nil, fmt.Errorf("bridge %v: %w", bridge, err)
+	}
+
+	bridgeIP, bridgeNet, err := net.ParseCIDR(network.Config[family+".address"])
 	if err != nil {
 		return nil, err
 	}
 
-	leases := []net.IP{}
+	// IPs found in use stay reserved while trying, so no try selects them again. No pod got them, so they're released
+	// once the allocation is done
+	var conflicts []net.IP
+
+	defer func() {
+		for _, ip := range conflicts {
+			p.releaseIP(bridge, ip)
+		}
+	}()
+
+	for try := 0; ; try++ {
+		ip, err := p.allocateIP(bridge, bridgeNet, bridgeIP, ranges, preferred)
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.conf.ConflictCheck || p.inUse == nil || !p.inUse(ip) {
+			return ip, nil
+		}
+
+		conflicts = append(conflicts, ip)
+
+		log.WithField("bridge", bridge).WithField("ip", ip.String()).Warn("found IP is already in use on the bridge")
+
+		if try >= conflictRetries {
+			return nil, fmt.Errorf("%w: no unused IP found in bridge %v after %v tries", ErrAddressConflict, bridge, try+1)
+		}
+	}
+}
+
+// reserveIP reserves the requested IP, if it belongs to the subnet of the bridge and is neither leased nor reserved
+// already. Unlike found IPs it may be outside of the dhcp ranges of the bridge.
+func (p *lxdBridgePlugin) reserveIP(ip net.IP) error {
+	network, _, err := p.server.GetNetwork(p.conf.LXDBridge)
+	if err != nil {
+		return err
+	}
+
+	bridgeIP, bridgeNet, err := net.ParseCIDR(network.Config[bridgeFamily(network)+".address"])
+	if err != nil {
+		return err
+	}
+
+	networkIP, broadcastIP, _, _ := shared.SubnetRange(bridgeNet, nil, nil)
+
+	if !bridgeNet.Contains(ip) || ip.Equal(networkIP) || ip.Equal(broadcastIP) {
+		return fmt.Errorf("%w: %v is not a usable address of bridge %v with subnet %v", ErrAddressOutOfRange, ip, p.conf.LXDBridge, bridgeNet)
+	}
+
+	if ip.Equal(bridgeIP) {
+		return fmt.Errorf("%w: %v is the address of bridge %v", ErrAddressConflict, ip, p.conf.LXDBridge)
+	}
+
+	c, unlock, err := p.lockBridge(p.conf.LXDBridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if p.frozen(c) {
+		return fmt.Errorf("%w: can't reserve %v in bridge %v", ErrAllocationFrozen, ip, p.conf.LXDBridge)
+	}
+
+	leases, err := p.cachedLeases(p.conf.LXDBridge, c)
+	if err != nil {
+		return err
+	}
+
+	for _, lease := range leases {
+		if lease.Equal(ip) {
+			return fmt.Errorf("%w: %v is already leased or reserved in bridge %v", ErrAddressConflict, ip, p.conf.LXDBridge)
+		}
+	}
+
+	if p.conf.ConflictCheck && p.inUse != nil && p.inUse(ip) {
+		return fmt.Errorf("%w: %v answers on bridge %v", ErrAddressConflict, ip, p.conf.LXDBridge)
+	}
+
+	return p.reservationStore().Reserve(Reservation{Bridge: p.conf.LXDBridge, IP: ip, At: time.Now()})
+}
 
-	for _, rawIP := range rawLeases {
-		leases = append(leases, net.ParseIP(rawIP.Address))
+// allocateIP selects an IP within the ranges which is neither leased nor reserved and reserves it. The preferred IP is
+// selected if it fulfills the same
+func (p *lxdBridgePlugin) allocateIP(bridge string, bridgeNet *net.IPNet, bridgeIP net.IP, ranges []shared.IPRange, preferred net.IP) (net.IP, error) {
+	// hold the cache until the found ip is reserved, so concurrent allocations can't select the same one
+	c, unlock, err := p.lockBridge(bridge)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if p.frozen(c) {
+		return nil, fmt.Errorf("%w: bridge %v", ErrAllocationFrozen, bridge)
 	}
 
-	bridgeIP, bridgeNet, err := net.ParseCIDR(network.Config["ipv4.address"])
+	leases, err := p.cachedLeases(bridge, c)
 	if err != nil {
 		return nil, err
 	}
 
 	leases = append(leases, bridgeIP) // also exclude bridge ip
 
-	return FindFreeIP(bridgeNet, leases, nil, nil), nil
+	ip, err := findFreeIPInRanges(bridgeNet, leases, ranges, preferred)
+	if err != nil {
+		return nil, fmt.Errorf("bridge %v: %w", bridge, err)
+	}
+
+	err = p.reservationStore().Reserve(Reservation{Bridge: bridge, IP: ip, At: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	return ip, nil
+}
+
+// findFreeIPInRanges returns preferred if it's free within one of the ranges, otherwise a free IP of the first range which
+// has one left. Without ranges the whole subnet is used
+func findFreeIPInRanges(subnet *net.IPNet, leases []net.IP, ranges []shared.IPRange, preferred net.IP) (net.IP, error) {
+	if len(ranges) == 0 {
+		ranges = []shared.IPRange{{}}
+	}
+
+	for _, r := range ranges {
+		if isFreeIP(subnet, leases, r.Start, r.End, preferred) {
+			return preferred, nil
+		}
+	}
+
+	var err error
+
+	for _, r := range ranges {
+		var ip net.IP
+
+		ip, err = FindFreeIP(subnet, leases, r.Start, r.End, DefaultFindFreeIPAttempts)
+		if err == nil {
+			return ip, nil
+		}
+	}
+
+	return nil, err
+}
+
+// pingAddress sends a single echo request to the ip, any answer means the address is in use
+func pingAddress(ip net.IP) bool {
+	return exec.Command("ping", "-n", "-c", "1", "-W", "1", ip.String()).Run() == nil
+}
+
+// bridgeFamily returns the config prefix of the address family pods get their IP from, which is "ipv4" unless the
+// bridge is IPv6 only
+func bridgeFamily(network *api.Network) string {
+	hasAddress := func(key string) bool {
+		return network.Config[key] != "" && network.Config[key] != "none"
+	}
+
+	if !hasAddress("ipv4.address") && hasAddress("ipv6.address") {
+		return "ipv6"
+	}
+
+	return "ipv4"
+}
+
+// cachedLeases returns the leases of bridge from its cache c including the reserved IPs. The leases are only
+// fetched from LXD if the cache is older than the configured TTL. Caller must hold the lock of the cache.
+func (p *lxdBridgePlugin) cachedLeases(bridge string, c *leaseCache) ([]net.IP, error) {
+	now := time.Now()
+
+	if c.leases == nil || now.Sub(c.fetchedAt) >= p.conf.LeaseCacheTTL {
+		rawLeases, err := p.server.GetNetworkLeases(bridge)
+
+		switch {
+		case err == nil:
+			c.leases = []net.IP{}
+			for _, rawIP := range rawLeases {
+				c.leases = append(c.leases, net.ParseIP(rawIP.Address))
+			}
+		case isLeasesUnsupported(err):
+			log.WithError(err).WithField("bridge", bridge).Debug("leases not supported, using addresses of instance configs")
+
+			c.leases, err = p.instanceAddresses(bridge)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+
+		c.fetchedAt = now
+	}
+
+	reserved, err := p.reservedIPs(bridge)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]net.IP{}, c.leases...), reserved...), nil
+}
+
+// isLeasesUnsupported detects if the LXD server or the bridge is unable to report leases
+func isLeasesUnsupported(err error) bool {
+	return shared.IsErrNotFound(err) || strings.Contains(err.Error(), `"network_leases"`)
+}
+
+// instanceAddresses returns the static addresses of all nics attached to bridge, which are configured on the
+// instances directly or inherited from their profiles. It replaces the leases if LXD can't report them.
+func (p *lxdBridgePlugin) instanceAddresses(bridge string) ([]net.IP, error) {
+	cts, err := p.server.GetContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []net.IP{}
+
+	for _, ct := range cts {
+		ips = append(ips, nicAddresses(ct.ExpandedDevices, bridge)...)
+	}
+
+	return ips, nil
+}
+
+// nicAddresses returns the static addresses of the nics in devices which are attached to bridge
+func nicAddresses(devices map[string]map[string]string, bridge string) []net.IP {
+	var ips []net.IP
+
+	for _, dev := range devices {
+		if dev["type"] != device.NicType || (dev["parent"] != bridge && dev["network"] != bridge) {
+			continue
+		}
+
+		for _, key := range []string{"ipv4.address", "ipv6.address"} {
+			if ip := net.ParseIP(dev[key]); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips
 }
 
 // lxdBridgePodNetwork is a pod network environment context
@@ -206,8 +816,35 @@ func (s *lxdBridgePodNetwork) Status(ctx context.Context, prop *PropertiesRunnin
 
 // WhenCreated is called when the pod is created.
 func (s *lxdBridgePodNetwork) WhenCreated(ctx context.Context, prop *Properties) (*Result, error) {
+	if table, has := s.annotations[AnnotationRoutingTable]; has {
+		err := validateRoutingTable(table)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %v of pod %v: %w", AnnotationRoutingTable, s.podID, err)
+		}
+	}
+
+	bridges, err := s.additionalBridges()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.plugin.conf.DisableDHCP {
+		// without dhcp there are no leases to find a free IP from and nothing in the pod would request an address
+		r := &Result{
+			Nics: []device.Nic{{
+				Name:    shared.DefaultInterface,
+				NicType: "bridged",
+				Parent:  s.plugin.conf.LXDBridge,
+			}},
+			Interface:      shared.DefaultInterface,
+			AddressPending: true,
+		}
+
+		return r, s.addAdditionalNics(r, bridges)
+	}
+
 	// default is to use the predefined lxd bridge managed by lxe
-	randIP, err := s.plugin.findFreeIP()
+	podIP, err := s.ip()
 	if err != nil {
 		return nil, err
 	}
@@ -216,30 +853,227 @@ func (s *lxdBridgePodNetwork) WhenCreated(ctx context.Context, prop *Properties)
 	// TODO: Remove, I think we don't/shouldn't need that anymore
 	r.Data = map[string]string{
 		// 	"bridge":            s.plugin.conf.LXDBridge,
-		"interface-address": randIP.String(), // except this for IP return shortcut in Status
+		"interface-address": podIP.String(), // except this for IP return shortcut in Status
 		// 	"physical-type":     "dhcp",
 	}
-	r.Nics = []device.Nic{
-		{
-			Name:        DefaultInterface,
-			NicType:     "bridged",
-			Parent:      s.plugin.conf.LXDBridge,
-			IPv4Address: randIP.String(),
-		},
+	nic := device.Nic{
+		Name:    shared.DefaultInterface,
+		NicType: "bridged",
+		Parent:  s.plugin.conf.LXDBridge,
 	}
-	r.NetworkConfigEntries = []cloudinit.NetworkConfigEntryPhysical{
-		{
-			NetworkConfigEntry: cloudinit.NetworkConfigEntry{
-				Type: "physical",
-			},
-			Name: DefaultInterface,
-			Subnets: []cloudinit.NetworkConfigEntryPhysicalSubnet{
-				{
-					Type: "dhcp",
-				},
+	subnetType := "dhcp"
+
+	if podIP.To4() != nil {
+		nic.IPv4Address = podIP.String()
+	} else {
+		nic.IPv6Address = podIP.String()
+		subnetType = "dhcp6"
+	}
+
+	r.Nics = []device.Nic{nic}
+	r.Interface = shared.DefaultInterface
+	r.setAddresses([]net.IP{podIP})
+	r.NetworkConfigEntries = []cloudinit.NetworkConfigEntryPhysical{dhcpNetworkConfigEntry(shared.DefaultInterface, subnetType)}
+
+	err = s.addAdditionalNics(r, bridges)
+	if err != nil {
+		s.plugin.releaseIP(s.plugin.conf.LXDBridge, podIP)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// dhcpNetworkConfigEntry returns the cloud-init config of an interface which requests its address by dhcp
+func dhcpNetworkConfigEntry(name, subnetType string) cloudinit.NetworkConfigEntryPhysical {
+	return cloudinit.NetworkConfigEntryPhysical{
+		NetworkConfigEntry: cloudinit.NetworkConfigEntry{
+			Type: "physical",
+		},
+		Name: name,
+		Subnets: []cloudinit.NetworkConfigEntryPhysicalSubnet{
+			{
+				Type: subnetType,
 			},
 		},
 	}
+}
+
+// additionalBridges returns the bridges of the pod annotation, which must be distinct and not the bridge of the plugin
+func (s *lxdBridgePodNetwork) additionalBridges() ([]string, error) {
+	var bridges []string
+
+	seen := map[string]bool{s.plugin.conf.LXDBridge: true}
+
+	for _, bridge := range strings.Split(s.annotations[AnnotationAdditionalBridges], ",") {
+		bridge = strings.TrimSpace(bridge)
+		if bridge == "" {
+			continue
+		}
+
+		if seen[bridge] {
+			return nil, fmt.Errorf("annotation %v of pod %v: %w: bridge %v is used more than once", AnnotationAdditionalBridges, s.podID, ErrAddressConflict, bridge)
+		}
+
+		seen[bridge] = true
+		bridges = append(bridges, bridge)
+	}
+
+	return bridges, nil
+}
+
+// addAdditionalNics adds a nic with a free IP of each bridge to r. The nics are named after the default interface, so
+// the first one is eth1. If an IP can't be found, the IPs already found for the other bridges are released again
+func (s *lxdBridgePodNetwork) addAdditionalNics(r *Result, bridges []string) error {
+	if len(bridges) > 0 && r.Data == nil {
+		r.Data = make(map[string]string)
+	}
+
+	for i, bridge := range bridges {
+		name := fmt.Sprintf("eth%d", i+1)
+
+		ip, err := s.plugin.findFreeIPInBridge(bridge, nil)
+		if err != nil {
+			s.releaseAdditional(r.Data)
+			return fmt.Errorf("additional bridge %v of pod %v: %w", bridge, s.podID, err)
+		}
+
+		nic := device.Nic{
+			Name:    name,
+			NicType: "bridged",
+			Parent:  bridge,
+		}
+		subnetType := "dhcp"
+
+		if ip.To4() != nil {
+			nic.IPv4Address = ip.String()
+		} else {
+			nic.IPv6Address = ip.String()
+			subnetType = "dhcp6"
+		}
+
+		r.Nics = append(r.Nics, nic)
+		r.NetworkConfigEntries = append(r.NetworkConfigEntries, dhcpNetworkConfigEntry(name, subnetType))
+		r.Data[dataAdditionalBridge+name] = bridge
+		r.Data[dataAdditionalAddress+name] = ip.String()
+	}
+
+	return nil
+}
+
+// releaseAdditional releases the IPs of the additional nics recorded in data
+func (s *lxdBridgePodNetwork) releaseAdditional(data map[string]string) {
+	for _, a := range AdditionalBridgeAddresses(data) {
+		s.plugin.releaseIP(a.Bridge, a.IP)
+	}
+}
+
+// BridgeAddress is an IP allocated in a bridge
+type BridgeAddress struct {
+	Bridge string
+	IP     net.IP
+}
+
+// AdditionalBridgeAddresses returns the IPs of the nics on additional bridges recorded in the data of a pod network,
+// see AnnotationAdditionalBridges. They're ordered by interface name
+func AdditionalBridgeAddresses(data map[string]string) []BridgeAddress {
+	var names []string
+
+	for key := range data {
+		if strings.HasPrefix(key, dataAdditionalBridge) {
+			names = append(names, strings.TrimPrefix(key, dataAdditionalBridge))
+		}
+	}
+
+	sort.Strings(names)
+
+	addresses := make([]BridgeAddress, 0, len(names))
+
+	for _, name := range names {
+		ip := net.ParseIP(data[dataAdditionalAddress+name])
+		if ip == nil {
+			continue
+		}
+
+		addresses = append(addresses, BridgeAddress{Bridge: data[dataAdditionalBridge+name], IP: ip})
+	}
+
+	return addresses
+}
+
+// WhenDeleted releases the IPs of all nics of the pod, so they can be found again right away. The routing of the pod
+// is removed as well.
+func (s *lxdBridgePodNetwork) WhenDeleted(ctx context.Context, prop *Properties) error {
+	var err error
+
+	if ip := net.ParseIP(prop.Data["interface-address"]); ip != nil {
+		s.plugin.releaseIP(s.plugin.conf.LXDBridge, ip)
+
+		if table, has := s.annotations[AnnotationRoutingTable]; has {
+			err = s.plugin.delPolicyRoute(ctx, s.plugin.conf.LXDBridge, ip, table)
+		}
+	}
+
+	s.releaseAdditional(prop.Data)
+
+	return err
+}
+
+// ip returns the IP requested by the pod annotation if it's available, otherwise a free IP is found
+func (s *lxdBridgePodNetwork) ip() (net.IP, error) {
+	raw, has := s.annotations[AnnotationIP]
+	if !has {
+		return s.plugin.findFreeIP(s.preferredIP())
+	}
+
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return nil, fmt.Errorf("annotation %v of pod %v: %w", AnnotationIP, s.podID, &net.ParseError{Type: "IP address", Text: raw})
+	}
+
+	err := s.plugin.reserveIP(ip)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %v of pod %v: %w", AnnotationIP, s.podID, err)
+	}
+
+	return ip, nil
+}
+
+// preferredIP returns the IP of the pod annotation, invalid values are ignored as it's only a preference
+func (s *lxdBridgePodNetwork) preferredIP() net.IP {
+	raw, has := s.annotations[AnnotationPreferredIP]
+	if !has {
+		return nil
+	}
+
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		log.WithField("podid", s.podID).WithField("ip", raw).Warnf("ignoring invalid annotation %v", AnnotationPreferredIP)
+	}
+
+	return ip
+}
+
+// WhenStarted is called when the pod is started. The address is already known since it was chosen on creation
+func (s *lxdBridgePodNetwork) WhenStarted(ctx context.Context, prop *PropertiesRunning) (*Result, error) {
+	if prop.Data["interface-address"] == "" {
+		return &Result{AddressPending: true}, nil
+	}
+
+	status, err := s.Status(ctx, prop)
+	if err != nil {
+		return nil, err
+	}
+
+	if table, has := s.annotations[AnnotationRoutingTable]; has {
+		err = s.plugin.addPolicyRoute(ctx, s.plugin.conf.LXDBridge, status.IPs[0], table)
+		if err != nil {
+			return nil, fmt.Errorf("routing table %v of pod %v: %w", table, s.podID, err)
+		}
+	}
+
+	r := &Result{Interface: shared.DefaultInterface}
+	r.setAddresses(status.IPs)
 
 	return r, nil
 }
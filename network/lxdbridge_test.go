@@ -1,8 +1,13 @@
 package network
 
 import (
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/lxf/lxdfakes"
 	"github.com/automaticserver/lxe/shared"
 	lxd "github.com/lxc/lxd/client"
@@ -28,6 +33,14 @@ func testLXDClient() (lxd.ContainerServer, *lxdfakes.FakeContainerServer) {
 	return fake, fake
 }
 
+func testLXDServer(firewall string) *lxdApi.Server {
+	return &lxdApi.Server{
+		Environment: lxdApi.ServerEnvironment{
+			Firewall: firewall,
+		},
+	}
+}
+
 func TestInitPluginLXDBridge_DefaultsAndCreate(t *testing.T) {
 	t.Parallel()
 
@@ -56,6 +69,7 @@ func TestInitPluginLXDBridge_DefinedAndUpdate(t *testing.T) {
 	cidr := "192.168.224.0/24"
 	cidrExp := "192.168.224.1/24"
 
+	fake.GetServerReturns(testLXDServer(FirewallNftables), "", nil)
 	fake.GetNetworkReturns(&lxdApi.Network{
 		Type: "bridge",
 		NetworkPut: lxdApi.NetworkPut{
@@ -162,6 +176,40 @@ func Test_lxdBridgePlugin_ensureBridge_CorrectIPRangeBridgeIP(t *testing.T) {
 	assert.Equal(t, cidrExp, args.Config["ipv4.address"])
 }
 
+func Test_lxdBridgePlugin_ensureBridge_IPv6Only(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.Cidr = "fd42:1:2:3::/64"
+	plugin.conf.Nat = true
+	plugin.conf.DHCPPool = shared.IPRange{Start: net.ParseIP("fd42:1:2:3::100"), End: net.ParseIP("fd42:1:2:3::200")}
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+	fake.GetServerReturns(testLXDServer("nftables"), "", nil)
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.CreateNetworkCallCount())
+
+	args := fake.CreateNetworkArgsForCall(0)
+	assert.Equal(t, "fd42:1:2:3::1/64", args.Config["ipv6.address"])
+	assert.Equal(t, "true", args.Config["ipv6.dhcp"])
+	assert.Equal(t, "true", args.Config["ipv6.dhcp.stateful"])
+	assert.Equal(t, "true", args.Config["ipv6.nat"])
+	assert.Equal(t, "fd42:1:2:3::100-fd42:1:2:3::200", args.Config["ipv6.dhcp.ranges"])
+	assert.Equal(t, "none", args.Config["ipv4.address"])
+	assert.NotContains(t, args.Config, "ipv4.dhcp")
+	assert.NotContains(t, args.Config, "ipv4.nat")
+
+	// pods get their ip from the created bridge
+	fake.GetNetworkReturns(&lxdApi.Network{Type: "bridge", Name: testLXDBridge, NetworkPut: lxdApi.NetworkPut{Config: args.Config}}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{}, nil)
+
+	ip, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.True(t, shared.IPRange{Start: net.ParseIP("fd42:1:2:3::100"), End: net.ParseIP("fd42:1:2:3::200")}.Contains(ip), ip)
+}
+
 func Test_lxdBridgePlugin_ensureBridge_CorrectIPRangeAuto(t *testing.T) {
 	t.Parallel()
 
@@ -179,6 +227,340 @@ func Test_lxdBridgePlugin_ensureBridge_CorrectIPRangeAuto(t *testing.T) {
 	assert.Equal(t, "auto", args.Config["ipv4.address"])
 }
 
+func Test_lxdBridgePlugin_ensureBridge_CreatedConcurrently(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	// the bridge is missing when looked up, but another LXE creates it before this one does
+	fake.GetNetworkReturnsOnCall(0, nil, "", shared.NewErrNotFound())
+	fake.CreateNetworkReturns(errors.New("The network already exists"))
+	fake.GetNetworkReturnsOnCall(1, &lxdApi.Network{Type: "bridge", Name: testLXDBridge, NetworkPut: lxdApi.NetworkPut{Config: map[string]string{}}}, "etag", nil)
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.CreateNetworkCallCount())
+	assert.Equal(t, 2, fake.GetNetworkCallCount())
+	assert.Equal(t, 1, fake.UpdateNetworkCallCount())
+
+	name, put, etag := fake.UpdateNetworkArgsForCall(0)
+	assert.Equal(t, testLXDBridge, name)
+	assert.Equal(t, "etag", etag)
+	assert.Equal(t, "auto", put.Config["ipv4.address"])
+}
+
+func Test_lxdBridgePlugin_ensureBridge_CreateFails(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+	fake.CreateNetworkReturns(errors.New("permission denied"))
+
+	err := plugin.ensureBridge()
+	assert.EqualError(t, err, "permission denied")
+	assert.Equal(t, 2, fake.GetNetworkCallCount())
+}
+
+func Test_lxdBridgePlugin_ensureBridge_DNS(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.DNS = true
+	plugin.conf.DNSDomain = "lxe.local"
+	plugin.conf.DNSSearch = []string{"lxe.local", "svc.cluster.local"}
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+
+	args := fake.CreateNetworkArgsForCall(0)
+	assert.Equal(t, "", args.Config["raw.dnsmasq"])
+	assert.Equal(t, "lxe.local", args.Config["dns.domain"])
+	assert.Equal(t, "lxe.local,svc.cluster.local", args.Config["dns.search"])
+}
+
+func Test_lxdBridgePlugin_ensureBridge_Gateway(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.Cidr = "192.168.224.0/24"
+	plugin.conf.Gateway = net.ParseIP("192.168.224.254")
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+
+	args := fake.CreateNetworkArgsForCall(0)
+	assert.Equal(t, "192.168.224.1/24", args.Config["ipv4.address"])
+	assert.Equal(t, "port=0\ndhcp-option=3,192.168.224.254", args.Config["raw.dnsmasq"])
+}
+
+func Test_lxdBridgePlugin_ensureBridge_GatewayWithDNS(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.DNS = true
+	plugin.conf.Gateway = net.ParseIP("10.0.0.1")
+	plugin.conf.GatewayOffSubnet = true
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+
+	args := fake.CreateNetworkArgsForCall(0)
+	assert.Equal(t, "dhcp-option=3,10.0.0.1", args.Config["raw.dnsmasq"])
+}
+
+func TestConfLXDBridge_validateGateway(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		conf    ConfLXDBridge
+		wantErr bool
+	}{
+		{"none", ConfLXDBridge{}, false},
+		{"within subnet", ConfLXDBridge{Cidr: "192.168.224.0/24", Gateway: net.ParseIP("192.168.224.254")}, false},
+		{"off subnet flagged", ConfLXDBridge{Cidr: "192.168.224.0/24", Gateway: net.ParseIP("10.0.0.1"), GatewayOffSubnet: true}, false},
+		{"auto cidr flagged", ConfLXDBridge{Gateway: net.ParseIP("10.0.0.1"), GatewayOffSubnet: true}, false},
+		{"off subnet", ConfLXDBridge{Cidr: "192.168.224.0/24", Gateway: net.ParseIP("10.0.0.1")}, true},
+		{"broadcast", ConfLXDBridge{Cidr: "192.168.224.0/24", Gateway: net.ParseIP("192.168.224.255")}, true},
+		{"auto cidr", ConfLXDBridge{Gateway: net.ParseIP("10.0.0.1")}, true},
+		{"ipv6", ConfLXDBridge{Gateway: net.ParseIP("fd00::1"), GatewayOffSubnet: true}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conf.validateGateway()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidGateway))
+		})
+	}
+}
+
+func Test_lxdBridgePlugin_ensureBridge_DHCPPool(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.Cidr = "192.168.224.0/24"
+	plugin.conf.DHCPPool = shared.IPRange{Start: net.ParseIP("192.168.224.100"), End: net.ParseIP("192.168.224.200")}
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+
+	args := fake.CreateNetworkArgsForCall(0)
+	assert.Equal(t, "192.168.224.100-192.168.224.200", args.Config["ipv4.dhcp.ranges"])
+}
+
+func TestConfLXDBridge_validateDHCPPool(t *testing.T) {
+	t.Parallel()
+
+	pool := func(start, end string) shared.IPRange {
+		return shared.IPRange{Start: net.ParseIP(start), End: net.ParseIP(end)}
+	}
+
+	tests := []struct {
+		name    string
+		conf    ConfLXDBridge
+		wantErr bool
+	}{
+		{"none", ConfLXDBridge{}, false},
+		{"within subnet", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: pool("192.168.224.100", "192.168.224.200")}, false},
+		{"single address", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: pool("192.168.224.100", "192.168.224.100")}, false},
+		{"empty", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: pool("192.168.224.200", "192.168.224.100")}, true},
+		{"start only", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: shared.IPRange{Start: net.ParseIP("192.168.224.100")}}, true},
+		{"network address", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: pool("192.168.224.0", "192.168.224.200")}, true},
+		{"off subnet", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: pool("192.168.224.100", "192.168.225.10")}, true},
+		{"auto cidr", ConfLXDBridge{DHCPPool: pool("192.168.224.100", "192.168.224.200")}, true},
+		{"ipv6", ConfLXDBridge{Cidr: "192.168.224.0/24", DHCPPool: pool("fd00::1", "fd00::10")}, true},
+		{"ipv6 subnet", ConfLXDBridge{Cidr: "fd00::/64", DHCPPool: pool("fd00::100", "fd00::200")}, false},
+		{"mixed families", ConfLXDBridge{Cidr: "fd00::/64", DHCPPool: pool("192.168.224.100", "fd00::200")}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conf.validateDHCPPool()
+			assert.Equal(t, tt.wantErr, errors.Is(err, shared.ErrInvalidRange))
+		})
+	}
+}
+
+func Test_lxdBridgePlugin_BridgeDNS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  map[string]string
+		want    net.IP
+		wantErr error
+	}{
+		{"enabled", map[string]string{"ipv4.address": "10.0.0.1/24", "raw.dnsmasq": ""}, net.ParseIP("10.0.0.1"), nil},
+		{"enabled ipv6", map[string]string{"ipv4.address": "none", "ipv6.address": "fd00::1/64"}, net.ParseIP("fd00::1"), nil},
+		{"disabled by plugin", map[string]string{"ipv4.address": "10.0.0.1/24", "raw.dnsmasq": "port=0\ndhcp-option=3,10.0.0.254"}, nil, ErrDNSDisabled},
+		{"dns mode none", map[string]string{"ipv4.address": "10.0.0.1/24", "dns.mode": "none"}, nil, ErrDNSDisabled},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			plugin, fake := testLXDBridgePlugin()
+			fake.GetNetworkReturns(&lxdApi.Network{Type: "bridge", NetworkPut: lxdApi.NetworkPut{Config: tt.config}}, "", nil)
+
+			ip, err := plugin.BridgeDNS(testLXDBridge)
+			assert.True(t, errors.Is(err, tt.wantErr))
+			assert.True(t, tt.want.Equal(ip))
+			assert.Equal(t, testLXDBridge, fake.GetNetworkArgsForCall(0))
+		})
+	}
+}
+
+func Test_lxdBridgePlugin_BridgeDNS_NotBridge(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	fake.GetNetworkReturns(&lxdApi.Network{Type: "macvlan"}, "", nil)
+
+	_, err := plugin.BridgeDNS(testLXDBridge)
+	assert.True(t, errors.Is(err, ErrNotBridge))
+}
+
+func TestConfLXDBridge_validateDNS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		conf    ConfLXDBridge
+		wantErr bool
+	}{
+		{"disabled", ConfLXDBridge{}, false},
+		{"enabled without domains", ConfLXDBridge{DNS: true}, false},
+		{"domains", ConfLXDBridge{DNS: true, DNSDomain: "lxe.local.", DNSSearch: []string{"a-b.example.com"}}, false},
+		{"domain without dns", ConfLXDBridge{DNSDomain: "lxe.local"}, true},
+		{"search without dns", ConfLXDBridge{DNSSearch: []string{"lxe.local"}}, true},
+		{"invalid domain", ConfLXDBridge{DNS: true, DNSDomain: "-lxe.local"}, true},
+		{"invalid search", ConfLXDBridge{DNS: true, DNSSearch: []string{"lxe..local"}}, true},
+		{"label too long", ConfLXDBridge{DNS: true, DNSDomain: strings.Repeat("a", 64) + ".local"}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conf.validateDNS()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidDNSConfig))
+		})
+	}
+}
+
+func Test_lxdBridgePlugin_ensureBridge_DHCP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		disable bool
+		want    string
+	}{
+		{"enabled", false, "true"},
+		{"disabled", true, "false"},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, fake := testLXDBridgePlugin()
+			plugin.conf.DisableDHCP = tt.disable
+
+			fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+			err := plugin.ensureBridge()
+			assert.NoError(t, err)
+
+			args := fake.CreateNetworkArgsForCall(0)
+			assert.Equal(t, tt.want, args.Config["ipv4.dhcp"])
+		})
+	}
+}
+
+func Test_lxdBridgePlugin_ensureBridge_NatFirewallSupported(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.Nat = true
+
+	fake.GetServerReturns(testLXDServer(FirewallXtables), "", nil)
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.GetServerCallCount())
+	assert.Equal(t, 1, fake.CreateNetworkCallCount())
+}
+
+func Test_lxdBridgePlugin_ensureBridge_NatFirewallUnknown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		firewall string
+	}{
+		{"not reported", ""},
+		{"unknown", "ebtables"},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, fake := testLXDBridgePlugin()
+			plugin.conf.Nat = true
+
+			fake.GetServerReturns(testLXDServer(tt.firewall), "", nil)
+			fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+			// only warned about, older LXD doesn't report the driver
+			err := plugin.ensureBridge()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, fake.CreateNetworkCallCount())
+		})
+	}
+}
+
+func Test_lxdBridgePlugin_ensureBridge_NatFirewallError(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.Nat = true
+
+	fake.GetServerReturns(nil, "", errors.New("connection lost"))
+
+	err := plugin.ensureBridge()
+	assert.Error(t, err)
+	assert.Empty(t, fake.CreateNetworkCallCount())
+}
+
+func Test_lxdBridgePlugin_ensureBridge_NoNatSkipsFirewall(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(nil, "", shared.NewErrNotFound())
+
+	err := plugin.ensureBridge()
+	assert.NoError(t, err)
+	assert.Empty(t, fake.GetServerCallCount())
+}
+
 func Test_lxdBridgePlugin_findFreeIP_Simple(t *testing.T) {
 	t.Parallel()
 
@@ -196,7 +578,7 @@ func Test_lxdBridgePlugin_findFreeIP_Simple(t *testing.T) {
 	}, "", nil)
 	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{}, nil)
 
-	ip, err := plugin.findFreeIP()
+	ip, err := plugin.findFreeIP(nil)
 	assert.NoError(t, err)
 	assert.Equal(t, "192.168.224.2", ip.String())
 }
@@ -223,29 +605,297 @@ func Test_lxdBridgePlugin_findFreeIP_WithLeases(t *testing.T) {
 		{Address: "192.168.224.5"},
 	}, nil)
 
-	ip, err := plugin.findFreeIP()
+	ip, err := plugin.findFreeIP(nil)
 	assert.NoError(t, err)
 	assert.Equal(t, "192.168.224.6", ip.String())
 }
 
-func Test_lxdBridgePlugin_findFreeIP_NoRangeSupportYet(t *testing.T) {
+func Test_lxdBridgePlugin_findFreeIP_Preferred(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		preferred     string
+		wantPreferred bool
+	}{
+		{"free", "192.168.224.5", true},
+		{"leased", "192.168.224.2", false},
+		{"bridge", "192.168.224.1", false},
+		{"broadcast", "192.168.224.7", false},
+		{"other subnet", "10.0.0.5", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, fake := testLXDBridgePlugin()
+
+			fake.GetNetworkReturns(&lxdApi.Network{
+				Type: "bridge",
+				Name: testLXDBridge,
+				NetworkPut: lxdApi.NetworkPut{
+					Config: map[string]string{
+						"ipv4.address": "192.168.224.1/29",
+					},
+				},
+			}, "", nil)
+			fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{
+				{Address: "192.168.224.2"},
+				{Address: "192.168.224.3"},
+				{Address: "192.168.224.4"},
+			}, nil)
+
+			ip, err := plugin.findFreeIP(net.ParseIP(tt.preferred))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPreferred, ip.String() == tt.preferred)
+			assert.Contains(t, []string{"192.168.224.5", "192.168.224.6"}, ip.String())
+		})
+	}
+}
+
+func Test_lxdBridgePodNetwork_preferredIP(t *testing.T) {
+	t.Parallel()
+
+	s := &lxdBridgePodNetwork{annotations: map[string]string{AnnotationPreferredIP: "10.0.0.5"}}
+	assert.Equal(t, "10.0.0.5", s.preferredIP().String())
+
+	s.annotations[AnnotationPreferredIP] = "foo"
+	assert.Nil(t, s.preferredIP())
+
+	s.annotations = nil
+	assert.Nil(t, s.preferredIP())
+}
+
+func Test_lxdBridgePlugin_findFreeIP_Ranges(t *testing.T) {
 	t.Parallel()
 
 	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.LeaseCacheTTL = time.Minute
 
 	fake.GetNetworkReturns(&lxdApi.Network{
 		Type: "bridge",
 		Name: testLXDBridge,
 		NetworkPut: lxdApi.NetworkPut{
 			Config: map[string]string{
-				"ipv4.address":     "192.168.224.1/29",
-				"ipv4.dhcp.ranges": "192.168.224.2-192.168.224.3,192.168.224.4-192.168.224.6",
+				"ipv4.address":     "192.168.224.1/28",
+				"ipv4.dhcp.ranges": "192.168.224.4-192.168.224.5,192.168.224.10-192.168.224.10",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{
+		{Address: "192.168.224.4"},
+		{Address: "192.168.224.5"},
+	}, nil)
+
+	// the first range is exhausted and the preferred ip is outside of the ranges
+	ip, err := plugin.findFreeIP(net.ParseIP("192.168.224.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.10", ip.String())
+
+	_, err = plugin.findFreeIP(nil)
+	assert.True(t, errors.Is(err, ErrNoFreeIP))
+}
+
+func Test_lxdBridgePlugin_findFreeIP_PreferredInRange(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address":     "192.168.224.1/24",
+				"ipv4.dhcp.ranges": "192.168.224.100-192.168.224.200",
+			},
+		},
+	}, "", nil)
+
+	ip, err := plugin.findFreeIP(net.ParseIP("192.168.224.150"))
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.150", ip.String())
+}
+
+func Test_lxdBridgePlugin_findFreeIP_IPv6Only(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "none",
+				"ipv6.address": "fd42:1:2:3::1/125",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{
+		{Address: "fd42:1:2:3::2"},
+		{Address: "fd42:1:2:3::3"},
+		{Address: "fd42:1:2:3::4"},
+		{Address: "fd42:1:2:3::5"},
+	}, nil)
+
+	ip, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "fd42:1:2:3::6", ip.String())
+}
+
+func Test_lxdBridgePlugin_findFreeIP_CachedLeases(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.LeaseCacheTTL = time.Minute
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/29",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{
+		{Address: "192.168.224.2"},
+		{Address: "192.168.224.3"},
+		{Address: "192.168.224.4"},
+	}, nil)
+
+	ip1, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	ip2, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, fake.GetNetworkLeasesCallCount())
+	assert.ElementsMatch(t, []string{"192.168.224.5", "192.168.224.6"}, []string{ip1.String(), ip2.String()})
+}
+
+func Test_lxdBridgePlugin_findFreeIP_LeasesUnsupported(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/29",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns(nil, errors.New(`The server is missing the required "network_leases" API extension`))
+
+	nic := func(parent, ip string) lxdApi.Container {
+		return lxdApi.Container{ExpandedDevices: map[string]map[string]string{
+			"eth0": {"type": "nic", "nictype": "bridged", "parent": parent, "ipv4.address": ip},
+		}}
+	}
+
+	fake.GetContainersReturns([]lxdApi.Container{
+		nic(testLXDBridge, "192.168.224.2"),
+		nic(testLXDBridge, "192.168.224.3"),
+		nic(testLXDBridge, "192.168.224.4"),
+		nic(testLXDBridge, "192.168.224.5"),
+		nic("otherbr0", "192.168.224.6"),
+	}, nil)
+
+	ip, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.GetContainersCallCount())
+	assert.Equal(t, "192.168.224.6", ip.String())
+}
+
+func Test_lxdBridgePlugin_findFreeIP_LeasesError(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/29",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns(nil, errors.New("connection refused"))
+
+	_, err := plugin.findFreeIP(nil)
+	assert.Error(t, err)
+	assert.Equal(t, 0, fake.GetContainersCallCount())
+}
+
+func Test_lxdBridgePlugin_findFreeIP_Conflict(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.LeaseCacheTTL = time.Minute
+	plugin.conf.ConflictCheck = true
+
+	probed := []string{}
+	plugin.inUse = func(ip net.IP) bool {
+		probed = append(probed, ip.String())
+		return len(probed) == 1
+	}
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/29",
 			},
 		},
 	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{}, nil)
+
+	ip, err := plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.Len(t, probed, 2)
+	assert.NotEqual(t, probed[0], ip.String())
+	assert.Equal(t, probed[1], ip.String())
+
+	// the conflicting ip is released again, only the found one stays reserved
+	reserved, err := plugin.reservedIPs(testLXDBridge)
+	assert.NoError(t, err)
+	assert.Len(t, reserved, 1)
+	assert.True(t, ip.Equal(reserved[0]))
+}
+
+func Test_lxdBridgePlugin_findFreeIP_ConflictExhausted(t *testing.T) {
+	t.Parallel()
 
-	_, err := plugin.findFreeIP()
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.LeaseCacheTTL = time.Minute
+	plugin.conf.ConflictCheck = true
+	plugin.inUse = func(ip net.IP) bool { return true }
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/28",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{}, nil)
+
+	ip, err := plugin.findFreeIP(nil)
 	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAddressConflict))
+	assert.Nil(t, ip)
+
+	reserved, err := plugin.reservedIPs(testLXDBridge)
+	assert.NoError(t, err)
+	assert.Empty(t, reserved)
 }
 
 func testLXDBridgePodNetwork() (*lxdBridgePodNetwork, *lxdfakes.FakeContainerServer) {
@@ -320,4 +970,187 @@ func Test_lxdBridgePodNetwork_WhenCreated_Simple(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, res.Data["interface-address"])
 	assert.NotEmpty(t, res.Nics[0].IPv4Address)
+	assert.Equal(t, shared.DefaultInterface, res.Interface)
+	assert.Equal(t, res.Nics[0].IPv4Address, res.IPv4.String())
+	assert.Nil(t, res.IPv6)
+	assert.False(t, res.AddressPending)
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_AnnotationIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		ip      string
+		wantErr error
+	}{
+		{"free", "192.168.224.5", nil},
+		{"leased", "192.168.224.2", ErrAddressConflict},
+		{"bridge", "192.168.224.1", ErrAddressConflict},
+		{"broadcast", "192.168.224.7", ErrAddressOutOfRange},
+		{"outside", "10.0.0.5", ErrAddressOutOfRange},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			podNet, fake := testLXDBridgePodNetwork()
+			podNet.annotations = map[string]string{AnnotationIP: tt.ip}
+
+			fake.GetNetworkReturns(&lxdApi.Network{
+				Type: "bridge",
+				Name: testLXDBridge,
+				NetworkPut: lxdApi.NetworkPut{
+					Config: map[string]string{
+						"ipv4.address": "192.168.224.1/29",
+					},
+				},
+			}, "", nil)
+			fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{{Address: "192.168.224.2"}}, nil)
+
+			res, err := podNet.WhenCreated(ctx, &Properties{})
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ip, res.Nics[0].IPv4Address)
+		})
+	}
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_AnnotationInvalid(t *testing.T) {
+	t.Parallel()
+
+	podNet, _ := testLXDBridgePodNetwork()
+	podNet.annotations = map[string]string{AnnotationIP: "foo"}
+
+	_, err := podNet.WhenCreated(ctx, &Properties{})
+	assert.Error(t, err)
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_DHCPDisabled(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake := testLXDBridgePodNetwork()
+	podNet.plugin.conf.DisableDHCP = true
+
+	res, err := podNet.WhenCreated(ctx, &Properties{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetNetworkLeasesCallCount())
+	assert.Empty(t, res.Nics[0].IPv4Address)
+	assert.Empty(t, res.NetworkConfigEntries)
+	assert.True(t, res.AddressPending)
+}
+
+// testMultiBridgeNetworks lets the fake return a /30 network for the bridge of the plugin and for mgmtbr0
+func testMultiBridgeNetworks(fake *lxdfakes.FakeContainerServer) {
+	fake.GetNetworkStub = func(name string) (*lxdApi.Network, string, error) {
+		address := map[string]string{testLXDBridge: "192.168.224.1/30", "mgmtbr0": "10.10.0.1/30"}[name]
+		if address == "" {
+			return nil, "", shared.NewErrNotFound()
+		}
+
+		return &lxdApi.Network{
+			Type: "bridge",
+			Name: name,
+			NetworkPut: lxdApi.NetworkPut{
+				Config: map[string]string{
+					"ipv4.address": address,
+				},
+			},
+		}, "", nil
+	}
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{}, nil)
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_AdditionalBridges(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake := testLXDBridgePodNetwork()
+	podNet.plugin.conf.LeaseCacheTTL = time.Minute
+	podNet.annotations = map[string]string{AnnotationAdditionalBridges: "mgmtbr0"}
+	testMultiBridgeNetworks(fake)
+
+	res, err := podNet.WhenCreated(ctx, &Properties{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Nics, 2)
+	assert.Equal(t, "192.168.224.2", res.Nics[0].IPv4Address)
+	assert.Equal(t, device.Nic{Name: "eth1", NicType: "bridged", Parent: "mgmtbr0", IPv4Address: "10.10.0.2"}, res.Nics[1])
+	assert.Len(t, res.NetworkConfigEntries, 2)
+	assert.Equal(t, "eth1", res.NetworkConfigEntries[1].Name)
+	assert.Equal(t, "192.168.224.2", res.IPv4.String())
+	assert.Equal(t, []BridgeAddress{{Bridge: "mgmtbr0", IP: net.ParseIP("10.10.0.2")}}, AdditionalBridgeAddresses(res.Data))
+
+	// both IPs stay allocated until the pod is deleted
+	_, err = podNet.plugin.findFreeIPInBridge("mgmtbr0", nil)
+	assert.True(t, errors.Is(err, ErrNoFreeIP))
+
+	err = podNet.WhenDeleted(ctx, &Properties{Data: res.Data})
+	assert.NoError(t, err)
+
+	ip, err := podNet.plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.2", ip.String())
+
+	ip, err = podNet.plugin.findFreeIPInBridge("mgmtbr0", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.10.0.2", ip.String())
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_AdditionalBridgeMissing(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake := testLXDBridgePodNetwork()
+	podNet.plugin.conf.LeaseCacheTTL = time.Minute
+	podNet.annotations = map[string]string{AnnotationAdditionalBridges: "mgmtbr0,missingbr0"}
+	testMultiBridgeNetworks(fake)
+
+	_, err := podNet.WhenCreated(ctx, &Properties{})
+	assert.Error(t, err)
+
+	// the IPs found before the failure are released again
+	ip, err := podNet.plugin.findFreeIP(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.2", ip.String())
+
+	ip, err = podNet.plugin.findFreeIPInBridge("mgmtbr0", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.10.0.2", ip.String())
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_AdditionalBridgeTwice(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake := testLXDBridgePodNetwork()
+	podNet.annotations = map[string]string{AnnotationAdditionalBridges: "mgmtbr0, " + testLXDBridge}
+	testMultiBridgeNetworks(fake)
+
+	_, err := podNet.WhenCreated(ctx, &Properties{})
+	assert.True(t, errors.Is(err, ErrAddressConflict))
+	assert.Equal(t, 0, fake.GetNetworkCallCount())
+}
+
+func Test_lxdBridgePodNetwork_WhenStarted_Simple(t *testing.T) {
+	t.Parallel()
+
+	podNet, _ := testLXDBridgePodNetwork()
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{Data: map[string]string{"interface-address": "192.168.224.2"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, shared.DefaultInterface, res.Interface)
+	assert.Equal(t, "192.168.224.2", res.IPv4.String())
+	assert.False(t, res.AddressPending)
+}
+
+func Test_lxdBridgePodNetwork_WhenStarted_NoData(t *testing.T) {
+	t.Parallel()
+
+	podNet, _ := testLXDBridgePodNetwork()
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{})
+	assert.NoError(t, err)
+	assert.Nil(t, res.IPv4)
+	assert.True(t, res.AddressPending)
 }
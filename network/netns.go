@@ -0,0 +1,166 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// AnnotationNetnsPath on a pod attaches its cni network to the existing network namespace at this path, which all
+// containers of the pod join. The namespace is managed externally, e.g. by a sidecar, so LXE neither creates nor
+// removes it. It must be a bind mount within one of the dirs the operator allowed, and the pod must be privileged.
+const AnnotationNetnsPath = "lxe.io/netns-path"
+
+var (
+	ErrInvalidNetns = errors.New("invalid network namespace")
+	// ErrNetnsNotAllowed is returned if a pod requests a network namespace outside of the allowed dirs
+	ErrNetnsNotAllowed = errors.New("network namespace not allowed")
+)
+
+// explicitNetns returns the network namespace requested by the annotations, if any, after validating it is within one
+// of allowedDirs. The path is returned with its symlinks resolved
+func explicitNetns(annotations map[string]string, allowedDirs []string) (string, error) {
+	path, has := annotations[AnnotationNetnsPath]
+	if !has {
+		return "", nil
+	}
+
+	resolved, err := validateNetnsPath(path, allowedDirs)
+	if err != nil {
+		return "", fmt.Errorf("annotation %v: %w", AnnotationNetnsPath, err)
+	}
+
+	return resolved, nil
+}
+
+// validateNetnsPath checks path is a network namespace bind mounted within one of allowedDirs and returns it with its
+// symlinks resolved, so a link can't point outside of the allowed dirs. The namespaces in /proc are the ones of
+// processes, e.g. of the host, so they're never allowed
+func validateNetnsPath(path string, allowedDirs []string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: path must be absolute: %q", ErrInvalidNetns, path)
+	}
+
+	if withinDir(path, "/proc") {
+		return "", fmt.Errorf("%w: %v is the namespace of a process", ErrNetnsNotAllowed, path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v: %v", ErrInvalidNetns, path, err)
+	}
+
+	if withinDir(resolved, "/proc") {
+		return "", fmt.Errorf("%w: %v is the namespace of a process", ErrNetnsNotAllowed, path)
+	}
+
+	allowed := false
+
+	for _, dir := range allowedDirs {
+		if d, err := filepath.EvalSymlinks(dir); err == nil && withinDir(resolved, d) {
+			allowed = true
+			break
+		}
+	}
+
+	if !allowed {
+		return "", fmt.Errorf("%w: %v is outside of the allowed dirs %v", ErrNetnsNotAllowed, path, allowedDirs)
+	}
+
+	var st unix.Statfs_t
+
+	err = unix.Statfs(resolved, &st)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v: %v", ErrInvalidNetns, path, err)
+	}
+
+	if st.Type != unix.NSFS_MAGIC {
+		return "", fmt.Errorf("%w: %v is no bind mounted namespace", ErrInvalidNetns, path)
+	}
+
+	return resolved, nil
+}
+
+// withinDir returns true if path is below dir
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// netnsExists returns true if the namespace file at path still exists
+func netnsExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// netnsInode returns the inode of the namespace file at path, which identifies the namespace as long as it exists
+func netnsInode(path string) (uint64, error) {
+	var st unix.Stat_t
+
+	err := unix.Stat(path, &st)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v: %v", ErrInvalidNetns, path, err)
+	}
+
+	return st.Ino, nil
+}
+
+// pinNetns creates a new network namespace and keeps it without any process in it by bind mounting it to path, like
+// ip netns add does. The namespace is owned by the user namespace of the host, so only privileged pods get one.
+func pinNetns(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return err
+	}
+
+	f.Close()
+
+	errc := make(chan error, 1)
+
+	go func() {
+		// the thread is left in the new namespace, so it isn't unlocked. The runtime terminates it with the goroutine
+		runtime.LockOSThread()
+
+		err := unix.Unshare(unix.CLONE_NEWNET)
+		if err == nil {
+			err = unix.Mount(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()), path, "none", unix.MS_BIND, "")
+		}
+
+		errc <- err
+	}()
+
+	err = <-errc
+	if err != nil {
+		_ = unpinNetns(path)
+		return fmt.Errorf("%w: pin %v: %v", ErrInvalidNetns, path, err)
+	}
+
+	return nil
+}
+
+// unpinNetns releases the namespace pinned to path by pinNetns and removes the file. The namespace is gone once no
+// process is in it anymore. Releasing one which doesn't exist isn't an error
+func unpinNetns(path string) error {
+	err := unix.Unmount(path, unix.MNT_DETACH)
+	if err != nil && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.ENOENT) {
+		return fmt.Errorf("unmount %v: %w", path, err)
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
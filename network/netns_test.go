@@ -0,0 +1,133 @@
+package network
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateNetnsPath(t *testing.T) {
+	t.Parallel()
+
+	allowed, err := ioutil.TempDir("", "netns")
+	assert.NoError(t, err)
+
+	// the parallel subtests only run after the test returned
+	t.Cleanup(func() { os.RemoveAll(allowed) })
+
+	outside, err := ioutil.TempFile("", "netns")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { os.Remove(outside.Name()) })
+
+	file := filepath.Join(allowed, "file")
+	err = ioutil.WriteFile(file, nil, 0600)
+	assert.NoError(t, err)
+
+	link := filepath.Join(allowed, "link")
+	err = os.Symlink(outside.Name(), link)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		path    string
+		dirs    []string
+		wantErr error
+	}{
+		{"relative", "proc/self/ns/net", []string{allowed}, ErrInvalidNetns},
+		{"process namespace", "/proc/1/ns/net", []string{allowed}, ErrNetnsNotAllowed},
+		{"process namespace allowed dir", "/proc/self/ns/net", []string{"/proc"}, ErrNetnsNotAllowed},
+		{"no allowed dirs", file, nil, ErrNetnsNotAllowed},
+		{"outside allowed dirs", outside.Name(), []string{allowed}, ErrNetnsNotAllowed},
+		{"link outside allowed dirs", link, []string{allowed}, ErrNetnsNotAllowed},
+		{"allowed dir itself", allowed, []string{allowed}, ErrNetnsNotAllowed},
+		{"missing", filepath.Join(allowed, "missing"), []string{allowed}, ErrInvalidNetns},
+		{"regular file", file, []string{allowed}, ErrInvalidNetns},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := validateNetnsPath(tt.path, tt.dirs)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+}
+
+func Test_explicitNetns(t *testing.T) {
+	t.Parallel()
+
+	path, err := explicitNetns(nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, path)
+
+	_, err = explicitNetns(map[string]string{AnnotationNetnsPath: "/proc/1/ns/net"}, []string{"/run/netns"})
+	assert.True(t, errors.Is(err, ErrNetnsNotAllowed))
+
+	_, err = explicitNetns(map[string]string{AnnotationNetnsPath: "relative"}, []string{"/run/netns"})
+	assert.True(t, errors.Is(err, ErrInvalidNetns))
+}
+
+func Test_cniPlugin_PodNetwork_NetnsNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	plugin, _, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	_, err := plugin.PodNetwork("foo", map[string]string{AnnotationNetnsPath: "/proc/1/ns/net"})
+	assert.True(t, errors.Is(err, ErrNetnsNotAllowed))
+}
+
+func Test_cniPodNetwork_ExplicitNetns(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "0.4.0", IPs: []*current.IPConfig{}}, nil)
+
+	// only a bind mount would pass the validation, which requires root to create
+	netns := filepath.Join(tmpDir, "external")
+	err := ioutil.WriteFile(netns, nil, 0600)
+	assert.NoError(t, err)
+
+	plugin.newNetns = func(path string) error {
+		return errors.New("must not pin a namespace")
+	}
+
+	podNet, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	podNet.(*cniPodNetwork).netnsPath = netns
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{}})
+	assert.NoError(t, err)
+	assert.Equal(t, netns, res.Data["netns"])
+
+	_, _, argRuntimeConf := fake.AddNetworkListArgsForCall(0)
+	assert.Equal(t, netns, argRuntimeConf.NetNS)
+
+	contNet, err := podNet.ContainerNetwork("bar", nil)
+	assert.NoError(t, err)
+
+	cres, err := contNet.WhenCreated(ctx, &Properties{Data: res.Data})
+	assert.NoError(t, err)
+	assert.Equal(t, netns, cres.JoinNetns)
+
+	plugin.removeNetns = func(path string) error {
+		return errors.New("must not release the namespace")
+	}
+
+	err = podNet.WhenDeleted(ctx, &Properties{Data: res.Data})
+	assert.NoError(t, err)
+
+	_, _, argRuntimeConf = fake.DelNetworkListArgsForCall(0)
+	assert.Equal(t, netns, argRuntimeConf.NetNS)
+}
@@ -6,12 +6,12 @@ import (
 
 	"github.com/automaticserver/lxe/lxf/device"
 	"github.com/automaticserver/lxe/network/cloudinit"
+	"github.com/sirupsen/logrus"
 	rtApi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
-const (
-	// DefaultInterface for containers is always eth0
-	DefaultInterface = "eth0"
+var (
+	log = logrus.StandardLogger().WithContext(context.TODO())
 )
 
 // NetworkPlugin is the interface for lxe network plugins
@@ -24,6 +24,34 @@ type Plugin interface {
 	UpdateRuntimeConfig(conf *rtApi.RuntimeConfig) error
 }
 
+// GarbageCollector is implemented by plugins which can clean up the networks of pods that don't exist anymore
+type GarbageCollector interface {
+	// GC removes the networks of all pods not contained in validPodIDs
+	GC(ctx context.Context, validPodIDs []string) error
+}
+
+// BridgeLocker is implemented by plugins allocating IPs of bridges themselves, so custom allocators can serialize with
+// them
+type BridgeLocker interface {
+	// LockBridge blocks until the plugin allocates no IP of bridge and keeps it from doing so until unlock is called
+	LockBridge(bridge string) (unlock func())
+}
+
+// AllocationFreezer is implemented by plugins allocating IPs of bridges themselves, so the allocation can be paused for
+// maintenance
+type AllocationFreezer interface {
+	// FreezeAllocation stops allocating IPs of bridge if frozen, otherwise resumes it
+	FreezeAllocation(bridge string, frozen bool)
+	// AllocationFrozen returns whether allocating IPs of bridge is stopped
+	AllocationFrozen(bridge string) bool
+}
+
+// DNSProvider is implemented by plugins whose networks can serve DNS to the pods, e.g. for generating their resolv.conf
+type DNSProvider interface {
+	// BridgeDNS returns the DNS server of the pods in bridge or an error if it serves no DNS
+	BridgeDNS(bridge string) (net.IP, error)
+}
+
 // PodNetwork is the interface for a pod network environment.
 type PodNetwork interface {
 	// ContainerNetwork enters a container network environment context
@@ -60,6 +88,8 @@ type ContainerNetwork interface {
 type Properties struct {
 	// Arbitrary Data are provided if a previous call on this PodNetwork returned them
 	Data map[string]string
+	// PortMappings of the pod to publish, only passed to plugins which are a PortMapper when the network is set up
+	PortMappings []PortMapping
 }
 
 // PropertiesRunning contains additionally running info
@@ -67,6 +97,8 @@ type PropertiesRunning struct {
 	Properties
 	// Pid of the resource. This value is set for calls where the applicable resource is running
 	Pid int64
+	// Privileged is true if the resource runs within the user namespace of the host
+	Privileged bool
 }
 
 // Result contains additionally info which can only be set on creation
@@ -77,10 +109,43 @@ type Result struct {
 	Nics []device.Nic
 	// NetworkConfigEntries of cloudinit to be set. Keep in mind cloudinit runs only when the container starts
 	NetworkConfigEntries []cloudinit.NetworkConfigEntryPhysical
+	// Interface is the name of the interface the addresses below are assigned to
+	Interface string
+	// IPv4 is the primary IPv4 address, if any
+	IPv4 net.IP
+	// IPv6 is the primary IPv6 address, if any
+	IPv6 net.IP
+	// JoinNetns is the network namespace the container has to share instead of getting its own one. Only set for
+	// container networks, if the pod network is attached to a network namespace of the pod or another container holds it
+	JoinNetns string
+	// AddressPending is set if no address is known yet at the time of the call, e.g. if a delegated IPAM hasn't reported
+	// one. Both addresses are nil then and the caller has to ask Status later on
+	AddressPending bool
+}
+
+// setAddresses sets the first address of each family as the primary address and marks the result as pending if there
+// is none
+func (r *Result) setAddresses(ips []net.IP) {
+	for _, ip := range ips {
+		switch {
+		case ip.To4() != nil:
+			if r.IPv4 == nil {
+				r.IPv4 = ip
+			}
+		case ip.To16() != nil:
+			if r.IPv6 == nil {
+				r.IPv6 = ip
+			}
+		}
+	}
+
+	r.AddressPending = r.IPv4 == nil && r.IPv6 == nil
 }
 
 // Contains Status and addresses of that pod network
 type Status struct {
 	// The IP of the pod network
 	IPs []net.IP
+	// Connectivity is the outcome of the check requested by AnnotationConnectivityCheck, nil if none ran
+	Connectivity *ConnectivityCheck
 }
@@ -0,0 +1,116 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/containernetworking/cni/libcni"
+)
+
+const (
+	// Protocols of port mappings, as the portmap plugin names them
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolSCTP = "sctp"
+
+	// capabilityPortMappings is the capability of cni plugins which publish host ports, usually the portmap plugin
+	capabilityPortMappings = "portMappings"
+	// dataPortMappings records the port mappings the network was set up with, as the teardown needs the same ones
+	dataPortMappings = "port-mappings"
+)
+
+var (
+	ErrPortMappingUnsupported = errors.New("port mapping unsupported")
+)
+
+// PortMapping publishes a port of the pod on the host
+type PortMapping struct {
+	HostPort      int32  `json:"hostPort"`
+	ContainerPort int32  `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"hostIP,omitempty"`
+}
+
+// PortMapper is implemented by plugins which can publish the ports of pods on the host themselves
+type PortMapper interface {
+	// MapsPorts reports if the port mappings passed in the properties are published
+	MapsPorts() bool
+}
+
+// MapsPorts reports if a plugin of the current network config has the portMappings capability
+func (p *cniPlugin) MapsPorts() bool {
+	netList, _, err := p.getCNINetworkConfig()
+	if err != nil {
+		return false
+	}
+
+	return hasCapability(netList, capabilityPortMappings)
+}
+
+// hasCapability reports if a plugin of netList announces the capability
+func hasCapability(netList *libcni.NetworkConfigList, capability string) bool {
+	for _, plugin := range netList.Plugins {
+		if plugin.Network.Capabilities[capability] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validatePortMappings checks the protocols of the mappings are known
+func validatePortMappings(mappings []PortMapping) error {
+	for _, m := range mappings {
+		switch m.Protocol {
+		case ProtocolTCP, ProtocolUDP, ProtocolSCTP:
+		default:
+			return fmt.Errorf("%w: unknown protocol %q of host port %d", ErrPortMappingUnsupported, m.Protocol, m.HostPort)
+		}
+	}
+
+	return nil
+}
+
+// withPortMappings passes the mappings to the plugins of the network as capability argument. It fails if mappings are
+// requested but no plugin is able to publish them, instead of silently dropping them
+func (s *cniPodNetwork) withPortMappings(mappings []PortMapping) error {
+	if len(mappings) == 0 {
+		delete(s.runtimeConf.CapabilityArgs, capabilityPortMappings)
+		return nil
+	}
+
+	err := validatePortMappings(mappings)
+	if err != nil {
+		return err
+	}
+
+	if !hasCapability(s.netList, capabilityPortMappings) {
+		return fmt.Errorf("%w: no plugin of network %v has the %v capability, e.g. add the portmap plugin", ErrPortMappingUnsupported, s.netList.Name, capabilityPortMappings)
+	}
+
+	if s.runtimeConf.CapabilityArgs == nil {
+		s.runtimeConf.CapabilityArgs = make(map[string]interface{})
+	}
+
+	s.runtimeConf.CapabilityArgs[capabilityPortMappings] = mappings
+
+	return nil
+}
+
+// recordedPortMappings returns the port mappings a network was set up with according to its data
+func recordedPortMappings(data map[string]string) ([]PortMapping, error) {
+	raw := data[dataPortMappings]
+	if raw == "" {
+		return nil, nil
+	}
+
+	var mappings []PortMapping
+
+	err := json.Unmarshal([]byte(raw), &mappings)
+	if err != nil {
+		return nil, fmt.Errorf("recorded %v: %w", dataPortMappings, err)
+	}
+
+	return mappings, nil
+}
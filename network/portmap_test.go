@@ -0,0 +1,118 @@
+package network
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// verify interface satisfaction
+	_ PortMapper = &cniPlugin{}
+)
+
+func portMapNetList(t *testing.T) *libcni.NetworkConfigList {
+	netList, err := libcni.ConfListFromBytes([]byte(`{"cniVersion":"0.4.0","name":"pods","plugins":[
+		{"type":"bridge"},
+		{"type":"portmap","capabilities":{"portMappings":true}}
+	]}`))
+	assert.NoError(t, err)
+
+	return netList
+}
+
+func Test_validatePortMappings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   []PortMapping
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"all protocols", []PortMapping{{8080, 80, ProtocolTCP, ""}, {5353, 53, ProtocolUDP, ""}, {3868, 3868, ProtocolSCTP, ""}}, false},
+		{"unknown protocol", []PortMapping{{8080, 80, "icmp", ""}}, true},
+		{"uppercase protocol", []PortMapping{{8080, 80, "TCP", ""}}, true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePortMappings(tt.input)
+			assert.False(t, (err != nil) != tt.wantErr)
+		})
+	}
+}
+
+func Test_cniPodNetwork_withPortMappings(t *testing.T) {
+	t.Parallel()
+
+	s := &cniPodNetwork{netList: portMapNetList(t), runtimeConf: &libcni.RuntimeConf{}}
+	mappings := []PortMapping{{5353, 53, ProtocolUDP, "10.0.0.1"}}
+
+	err := s.withPortMappings(mappings)
+	assert.NoError(t, err)
+	assert.Equal(t, mappings, s.runtimeConf.CapabilityArgs[capabilityPortMappings])
+
+	err = s.withPortMappings(nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, s.runtimeConf.CapabilityArgs, capabilityPortMappings)
+}
+
+func Test_cniPodNetwork_withPortMappings_NoCapability(t *testing.T) {
+	t.Parallel()
+
+	netList, err := libcni.ConfListFromBytes([]byte(`{"cniVersion":"0.4.0","name":"pods","plugins":[{"type":"bridge"}]}`))
+	assert.NoError(t, err)
+
+	s := &cniPodNetwork{netList: netList, runtimeConf: &libcni.RuntimeConf{}}
+
+	err = s.withPortMappings([]PortMapping{{8080, 80, ProtocolTCP, ""}})
+	assert.True(t, errors.Is(err, ErrPortMappingUnsupported))
+	assert.Empty(t, s.runtimeConf.CapabilityArgs)
+}
+
+func Test_recordedPortMappings(t *testing.T) {
+	t.Parallel()
+
+	mappings, err := recordedPortMappings(map[string]string{})
+	assert.NoError(t, err)
+	assert.Empty(t, mappings)
+
+	mappings, err = recordedPortMappings(map[string]string{dataPortMappings: `[{"hostPort":3868,"containerPort":3868,"protocol":"sctp"}]`})
+	assert.NoError(t, err)
+	assert.Equal(t, []PortMapping{{3868, 3868, ProtocolSCTP, ""}}, mappings)
+
+	_, err = recordedPortMappings(map[string]string{dataPortMappings: `{`})
+	assert.Error(t, err)
+}
+
+func Test_cniPodNetwork_WhenStarted_PortMappings(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake, tmpDir := testCNIPlugin(t)
+	defer os.RemoveAll(tmpDir)
+
+	fake.AddNetworkListReturns(&current.Result{CNIVersion: "4.0", IPs: []*current.IPConfig{}}, nil)
+
+	podNet, err := plugin.PodNetwork("foo", nil)
+	assert.NoError(t, err)
+
+	podNet.(*cniPodNetwork).netList = portMapNetList(t)
+
+	mappings := []PortMapping{{5353, 53, ProtocolUDP, ""}}
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{PortMappings: mappings}, Privileged: true})
+	assert.NoError(t, err)
+
+	_, _, argRuntimeConf := fake.AddNetworkListArgsForCall(0)
+	assert.Equal(t, mappings, argRuntimeConf.CapabilityArgs[capabilityPortMappings])
+
+	recorded, err := recordedPortMappings(res.Data)
+	assert.NoError(t, err)
+	assert.Equal(t, mappings, recorded)
+}
@@ -0,0 +1,204 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Reservation is an IP allocated in a bridge for a pod, which LXD doesn't know about yet
+type Reservation struct {
+	Bridge string
+	IP     net.IP
+	// At is when the IP was allocated
+	At time.Time
+}
+
+// ReservationStore keeps the IPs allocated by the lxdbridge plugin until LXD reports them as leases. The default keeps
+// them in memory, so they're lost on restart. A store persisting them, e.g. in a file or a key value store shared by
+// multiple instances of LXE, keeps a restarted or failed over LXE from handing out an IP twice. The plugin serializes
+// the calls per bridge, a store shared with other processes must be safe for concurrent use by them
+type ReservationStore interface {
+	// Reserve records r and returns ErrAddressConflict if its IP is already reserved in the bridge
+	Reserve(r Reservation) error
+	// Release removes the reservation of ip in bridge, releasing one which doesn't exist isn't an error
+	Release(bridge string, ip net.IP) error
+	// List returns the reservations of all bridges
+	List() ([]Reservation, error)
+}
+
+// memoryReservationStore is the default ReservationStore, which keeps the reservations of the process only
+type memoryReservationStore struct {
+	sync.Mutex
+	// reservations maps a bridge to the time its IPs got reserved at
+	reservations map[string]map[string]time.Time
+}
+
+func newMemoryReservationStore() *memoryReservationStore {
+	return &memoryReservationStore{reservations: make(map[string]map[string]time.Time)}
+}
+
+// Reserve records r
+func (s *memoryReservationStore) Reserve(r Reservation) error {
+	s.Lock()
+	defer s.Unlock()
+
+	ips, has := s.reservations[r.Bridge]
+	if !has {
+		ips = make(map[string]time.Time)
+		s.reservations[r.Bridge] = ips
+	}
+
+	if _, has := ips[r.IP.String()]; has {
+		return fmt.Errorf("%w: %v is already reserved in bridge %v", ErrAddressConflict, r.IP, r.Bridge)
+	}
+
+	ips[r.IP.String()] = r.At
+
+	return nil
+}
+
+// Release removes the reservation of ip in bridge
+func (s *memoryReservationStore) Release(bridge string, ip net.IP) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.reservations[bridge], ip.String())
+
+	return nil
+}
+
+// List returns the reservations of all bridges
+func (s *memoryReservationStore) List() ([]Reservation, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var list []Reservation
+
+	for bridge, ips := range s.reservations {
+		for rawIP, at := range ips {
+			list = append(list, Reservation{Bridge: bridge, IP: net.ParseIP(rawIP), At: at})
+		}
+	}
+
+	return list, nil
+}
+
+// reservationStore returns the store of the plugin, which is kept in memory unless configured
+func (p *lxdBridgePlugin) reservationStore() ReservationStore {
+	p.leasesMu.Lock()
+	defer p.leasesMu.Unlock()
+
+	if p.conf.ReservationStore == nil {
+		p.conf.ReservationStore = newMemoryReservationStore()
+	}
+
+	return p.conf.ReservationStore
+}
+
+// reservedIPs returns the IPs reserved in bridge. A reservation is kept till its pod is removed, as LXD only reports a
+// lease of the IP once a container of the pod exists and stops to once they are all gone, while the pod keeps its IP.
+// Caller must hold the lock of the lease cache of bridge
+func (p *lxdBridgePlugin) reservedIPs(bridge string) ([]net.IP, error) {
+	reservations, err := p.reservationStore().List()
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+
+	for _, r := range reservations {
+		if r.Bridge == bridge {
+			ips = append(ips, r.IP)
+		}
+	}
+
+	return ips, nil
+}
+
+// releaseIP releases the reservation of ip in bridge once its pod is removed, so it can be found again
+func (p *lxdBridgePlugin) releaseIP(bridge string, ip net.IP) {
+	c := p.bridgeLeases(bridge)
+	c.Lock()
+	defer c.Unlock()
+
+	err := p.reservationStore().Release(bridge, ip)
+	if err != nil {
+		log.WithError(err).WithField("bridge", bridge).WithField("ip", ip.String()).Warn("unable to release reserved IP")
+	}
+}
+
+// reconcileReservations releases the reservations left by pods which don't exist anymore, e.g. as LXE stopped while
+// creating or removing them. A reservation is kept if LXD has a lease
+// of its IP or a nic of an instance or profile is configured with it. It's called on start, before the plugin allocates
+// any IP, as the reservation of a pod being created isn't configured on a nic yet
+func (p *lxdBridgePlugin) reconcileReservations() error {
+	store := p.reservationStore()
+
+	reservations, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]map[string]bool)
+
+	for _, r := range reservations {
+		ips, has := used[r.Bridge]
+		if !has {
+			ips, err = p.usedAddresses(r.Bridge)
+			if err != nil {
+				return err
+			}
+
+			used[r.Bridge] = ips
+		}
+
+		if ips[r.IP.String()] {
+			continue
+		}
+
+		err = store.Release(r.Bridge, r.IP)
+		if err != nil {
+			return err
+		}
+
+		log.WithField("bridge", r.Bridge).WithField("ip", r.IP.String()).Info("released reservation of IP not used by any pod")
+	}
+
+	return nil
+}
+
+// usedAddresses returns the leases of bridge and the addresses of the nics in it of all instances and profiles
+func (p *lxdBridgePlugin) usedAddresses(bridge string) (map[string]bool, error) {
+	used := make(map[string]bool)
+
+	leases, err := p.server.GetNetworkLeases(bridge)
+	if err != nil && !isLeasesUnsupported(err) {
+		return nil, err
+	}
+
+	for _, lease := range leases {
+		used[net.ParseIP(lease.Address).String()] = true
+	}
+
+	ips, err := p.instanceAddresses(bridge)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := p.server.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		ips = append(ips, nicAddresses(profile.Devices, bridge)...)
+	}
+
+	for _, ip := range ips {
+		used[ip.String()] = true
+	}
+
+	return used, nil
+}
@@ -0,0 +1,180 @@
+package network
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	lxdApi "github.com/lxc/lxd/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingReservationStore refuses every reservation
+type failingReservationStore struct {
+	*memoryReservationStore
+}
+
+func (s failingReservationStore) Reserve(r Reservation) error {
+	return errors.New("store unavailable")
+}
+
+func Test_memoryReservationStore(t *testing.T) {
+	t.Parallel()
+
+	s := newMemoryReservationStore()
+	at := time.Now()
+
+	err := s.Reserve(Reservation{Bridge: "br0", IP: net.ParseIP("10.0.0.2"), At: at})
+	assert.NoError(t, err)
+
+	err = s.Reserve(Reservation{Bridge: "br1", IP: net.ParseIP("10.0.0.2"), At: at})
+	assert.NoError(t, err)
+
+	err = s.Reserve(Reservation{Bridge: "br0", IP: net.ParseIP("10.0.0.2"), At: at})
+	assert.True(t, errors.Is(err, ErrAddressConflict))
+
+	err = s.Release("br1", net.ParseIP("10.0.0.2"))
+	assert.NoError(t, err)
+
+	// releasing twice isn't an error
+	err = s.Release("br1", net.ParseIP("10.0.0.2"))
+	assert.NoError(t, err)
+
+	list, err := s.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "br0", list[0].Bridge)
+	assert.Equal(t, "10.0.0.2", list[0].IP.String())
+	assert.True(t, at.Equal(list[0].At))
+}
+
+func Test_lxdBridgePlugin_findFreeIP_ReservationStore(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.LeaseCacheTTL = time.Minute
+	store := newMemoryReservationStore()
+	plugin.conf.ReservationStore = store
+
+	// reserved by an LXE before it restarted
+	err := store.Reserve(Reservation{Bridge: testLXDBridge, IP: net.ParseIP("192.168.224.2"), At: time.Now()})
+	assert.NoError(t, err)
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/29",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{
+		{Address: "192.168.224.3"},
+		{Address: "192.168.224.4"},
+		{Address: "192.168.224.5"},
+	}, nil)
+
+	ip, err := plugin.findFreeIP(net.ParseIP("192.168.224.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.6", ip.String())
+
+	list, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func Test_lxdBridgePlugin_findFreeIP_ReservationStoreFails(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	plugin.conf.ReservationStore = failingReservationStore{newMemoryReservationStore()}
+
+	fake.GetNetworkReturns(&lxdApi.Network{
+		Type: "bridge",
+		Name: testLXDBridge,
+		NetworkPut: lxdApi.NetworkPut{
+			Config: map[string]string{
+				"ipv4.address": "192.168.224.1/29",
+			},
+		},
+	}, "", nil)
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{}, nil)
+
+	_, err := plugin.findFreeIP(nil)
+	assert.Error(t, err)
+}
+
+func Test_lxdBridgePlugin_reservedIPs_Kept(t *testing.T) {
+	t.Parallel()
+
+	plugin, _ := testLXDBridgePlugin()
+	plugin.conf.LeaseCacheTTL = time.Minute
+	now := time.Now()
+
+	store := plugin.reservationStore()
+	assert.NoError(t, store.Reserve(Reservation{Bridge: testLXDBridge, IP: net.ParseIP("192.168.224.2"), At: now.Add(-2 * time.Hour)}))
+	assert.NoError(t, store.Reserve(Reservation{Bridge: "other", IP: net.ParseIP("10.0.0.2"), At: now}))
+
+	// a pod without container has no lease, its reservation must not expire
+	ips, err := plugin.reservedIPs(testLXDBridge)
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.168.224.2")}, ips)
+
+	list, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func Test_lxdBridgePlugin_reconcileReservations(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+	store := plugin.reservationStore()
+	now := time.Now()
+
+	for _, ip := range []string{"192.168.224.2", "192.168.224.3", "192.168.224.4", "192.168.224.5"} {
+		assert.NoError(t, store.Reserve(Reservation{Bridge: testLXDBridge, IP: net.ParseIP(ip), At: now}))
+	}
+
+	fake.GetNetworkLeasesReturns([]lxdApi.NetworkLease{{Address: "192.168.224.2"}}, nil)
+	fake.GetContainersReturns([]lxdApi.Container{{
+		ExpandedDevices: map[string]map[string]string{
+			"eth0": {"type": "nic", "parent": testLXDBridge, "ipv4.address": "192.168.224.3"},
+		},
+	}}, nil)
+	// a pod without containers yet only has its nic in its profile
+	fake.GetProfilesReturns([]lxdApi.Profile{{
+		ProfilePut: lxdApi.ProfilePut{
+			Devices: map[string]map[string]string{
+				"eth0": {"type": "nic", "network": testLXDBridge, "ipv4.address": "192.168.224.4"},
+				"eth1": {"type": "nic", "parent": "other", "ipv4.address": "192.168.224.5"},
+			},
+		},
+	}}, nil)
+
+	err := plugin.reconcileReservations()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.GetNetworkLeasesCallCount())
+
+	list, err := store.List()
+	assert.NoError(t, err)
+
+	var ips []string
+	for _, r := range list {
+		ips = append(ips, r.IP.String())
+	}
+
+	assert.ElementsMatch(t, []string{"192.168.224.2", "192.168.224.3", "192.168.224.4"}, ips)
+}
+
+func Test_lxdBridgePlugin_reconcileReservations_Empty(t *testing.T) {
+	t.Parallel()
+
+	plugin, fake := testLXDBridgePlugin()
+
+	err := plugin.reconcileReservations()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.GetNetworkLeasesCallCount())
+}
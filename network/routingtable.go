@@ -0,0 +1,99 @@
+package network // import "github.com/automaticserver/lxe/network"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dionysius/errand"
+)
+
+// AnnotationRoutingTable on a pod routes its traffic by the given routing table of the host, e.g. to egress via a
+// specific uplink. The table is a number or a name of /etc/iproute2/rt_tables. Only supported by the bridge plugin, CNI
+// configurations can chain a plugin for this instead.
+const AnnotationRoutingTable = "lxe.io/routing-table"
+
+var (
+	ErrInvalidRoutingTable = errors.New("invalid routing table")
+
+	// routingTableName is a name of a routing table as accepted by iproute2
+	routingTableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*$`)
+	// reservedRoutingTables can't be used by pods, as they're used by the host itself
+	reservedRoutingTables = map[string]bool{"0": true, "253": true, "254": true, "255": true, "unspec": true, "default": true, "main": true, "local": true}
+)
+
+// validateRoutingTable checks table is a usable number or name of a routing table
+func validateRoutingTable(table string) error {
+	if reservedRoutingTables[table] {
+		return fmt.Errorf("%w: %v is reserved", ErrInvalidRoutingTable, table)
+	}
+
+	if _, err := strconv.ParseUint(table, 10, 32); err == nil {
+		return nil
+	}
+
+	if !routingTableName.MatchString(table) {
+		return fmt.Errorf("%w: %q is neither a number nor a name", ErrInvalidRoutingTable, table)
+	}
+
+	return nil
+}
+
+// runIPCommand runs iproute2 with the given arguments
+func runIPCommand(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "ip", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ip %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// hostRouteArgs returns the family option of iproute2 and ip as host route
+func hostRouteArgs(ip net.IP) (string, string) {
+	if ip.To4() != nil {
+		return "-4", ip.String() + "/32"
+	}
+
+	return "-6", ip.String() + "/128"
+}
+
+// addPolicyRoute installs the route to ip via bridge in table and a rule which lets all traffic from ip use the table
+func (p *lxdBridgePlugin) addPolicyRoute(ctx context.Context, bridge string, ip net.IP, table string) error {
+	if p.ipCmd == nil {
+		return fmt.Errorf("%w: no ip command to set up table %v", ErrNotImplemented, table)
+	}
+
+	family, host := hostRouteArgs(ip)
+
+	err := p.ipCmd(ctx, family, "route", "replace", host, "dev", bridge, "table", table)
+	if err != nil {
+		return err
+	}
+
+	// rules aren't unique, so a rule left from a previous start of the pod is removed first
+	_ = p.ipCmd(ctx, family, "rule", "del", "from", host, "table", table)
+
+	return p.ipCmd(ctx, family, "rule", "add", "from", host, "table", table)
+}
+
+// delPolicyRoute removes the rule and the route of addPolicyRoute as good as possible
+func (p *lxdBridgePlugin) delPolicyRoute(ctx context.Context, bridge string, ip net.IP, table string) error {
+	if p.ipCmd == nil {
+		return nil
+	}
+
+	family, host := hostRouteArgs(ip)
+
+	var errs error
+
+	errs = errand.Append(errs, p.ipCmd(ctx, family, "rule", "del", "from", host, "table", table))
+	errs = errand.Append(errs, p.ipCmd(ctx, family, "route", "del", host, "dev", bridge, "table", table))
+
+	return errs
+}
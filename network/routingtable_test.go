@@ -0,0 +1,93 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateRoutingTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		table   string
+		wantErr bool
+	}{
+		{"number", "100", false},
+		{"name", "uplink2", false},
+		{"main", "main", true},
+		{"local number", "255", true},
+		{"unspec", "0", true},
+		{"too large", "4294967296", true},
+		{"invalid name", "up link", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateRoutingTable(tt.table)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidRoutingTable))
+		})
+	}
+}
+
+// testIPCommands records the commands of the plugin
+func testIPCommands(p *lxdBridgePlugin) *[]string {
+	cmds := &[]string{}
+	p.ipCmd = func(ctx context.Context, args ...string) error {
+		*cmds = append(*cmds, strings.Join(args, " "))
+		return nil
+	}
+
+	return cmds
+}
+
+func Test_lxdBridgePodNetwork_WhenStarted_RoutingTable(t *testing.T) {
+	t.Parallel()
+
+	podNet, _ := testLXDBridgePodNetwork()
+	podNet.annotations = map[string]string{AnnotationRoutingTable: "100"}
+	cmds := testIPCommands(podNet.plugin)
+
+	res, err := podNet.WhenStarted(ctx, &PropertiesRunning{Properties: Properties{Data: map[string]string{"interface-address": "192.168.224.5"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.5", res.IPv4.String())
+	assert.Equal(t, []string{
+		"-4 route replace 192.168.224.5/32 dev " + testLXDBridge + " table 100",
+		"-4 rule del from 192.168.224.5/32 table 100",
+		"-4 rule add from 192.168.224.5/32 table 100",
+	}, *cmds)
+}
+
+func Test_lxdBridgePodNetwork_WhenDeleted_RoutingTable(t *testing.T) {
+	t.Parallel()
+
+	podNet, _ := testLXDBridgePodNetwork()
+	podNet.annotations = map[string]string{AnnotationRoutingTable: "uplink2"}
+	cmds := testIPCommands(podNet.plugin)
+
+	err := podNet.WhenDeleted(ctx, &Properties{Data: map[string]string{"interface-address": "fd42::5"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-6 rule del from fd42::5/128 table uplink2",
+		"-6 route del fd42::5/128 dev " + testLXDBridge + " table uplink2",
+	}, *cmds)
+}
+
+func Test_lxdBridgePodNetwork_WhenCreated_InvalidRoutingTable(t *testing.T) {
+	t.Parallel()
+
+	podNet, fake := testLXDBridgePodNetwork()
+	podNet.annotations = map[string]string{AnnotationRoutingTable: "main"}
+
+	_, err := podNet.WhenCreated(ctx, &Properties{})
+	assert.True(t, errors.Is(err, ErrInvalidRoutingTable))
+	assert.Equal(t, 0, fake.GetNetworkCallCount())
+}
@@ -2,63 +2,125 @@ package network // import "github.com/automaticserver/lxe/network"
 
 import (
 	"bytes"
-	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
 	"math/rand"
 	"net"
+
+	"github.com/automaticserver/lxe/shared"
+)
+
+var (
+	// ErrNoFreeIP is returned when FindFreeIP didn't find an address which isn't reserved
+	ErrNoFreeIP = errors.New("no free ip")
 )
 
+// DefaultFindFreeIPAttempts is a sensible maxAttempts for FindFreeIP. Ranges up to this size are searched completely.
+const DefaultFindFreeIPAttempts = 1024
+
 // FindFreeIP tries to find an available IP address within given subnet, respecting reserved addresses in leases and
-// must be between the start and end address. Network and broadcast IP are also reserved and automatically added to
-// leases. If start or end is nil their closest available address from the subnet is selected.
-func FindFreeIP(subnet *net.IPNet, leases []net.IP, start, end net.IP) net.IP {
-	// put non-usable addresses also to leases, so they can't be selected
-	networkIP := subnet.IP
-	broadcastIP := make(net.IP, 4)
-
-	for i := range broadcastIP {
-		broadcastIP[i] = subnet.IP[i] | ^subnet.Mask[i]
+// must be between the start and end address. Network and broadcast IP are never selected. If start or end is nil
+// their closest available address from the subnet is selected. The subnet can be of either address family, for IPv6
+// the address is chosen randomly within the whole range, so collisions are unlikely even in huge ranges.
+//
+// Ranges with at most maxAttempts addresses are searched completely, so ErrNoFreeIP reliably means they're exhausted.
+// Larger ranges are probed randomly at most maxAttempts times, where ErrNoFreeIP means the caller should retry later.
+func FindFreeIP(subnet *net.IPNet, leases []net.IP, start, end net.IP, maxAttempts int) (net.IP, error) {
+	// network and broadcast address are outside of the usable range, so only leases need to be reserved
+	start, end = shared.UsableRange(subnet, start, end)
+
+	reserved := make(map[string]bool, len(leases))
+	for _, lease := range leases {
+		reserved[lease.String()] = true
 	}
 
-	leases = append(leases, networkIP, broadcastIP)
+	usable := shared.CountUsableIPs(subnet, start, end)
+	if usable <= int64(maxAttempts) {
+		var free []net.IP
 
-	// defaults for start and end to usable addresses if not explicitly defined
-	if start == nil {
-		start = net.IPv4(networkIP[0], networkIP[1], networkIP[2], networkIP[3]+1)
-	}
+		for ip := start; usable > 0 && bytes.Compare(ip, end) <= 0; ip = shared.NextIP(ip) {
+			if !reserved[ip.String()] {
+				free = append(free, ip)
+			}
+		}
 
-	if end == nil {
-		end = net.IPv4(broadcastIP[0], broadcastIP[1], broadcastIP[2], broadcastIP[3]-1)
+		if len(free) == 0 {
+			return nil, fmt.Errorf("%w: all %d addresses between %v and %v of %v are reserved", ErrNoFreeIP, usable, start, end, subnet)
+		}
+
+		return free[rand.Intn(len(free))], nil
 	}
 
-	// Until a usable IP is found...
-	// TODO: detect if there's never a possible address and return nil?
-	var ip net.IP
-OUTER:
-	for {
-		// randomly select an ip address within the specified subnet
-		trialB := make([]byte, 4)
-		binary.LittleEndian.PutUint32(trialB, rand.Uint32())
-		for i, v := range trialB {
-			trialB[i] = subnet.IP[i] + (v &^ subnet.Mask[i])
+	// randomly select an ip address within the range until one isn't reserved
+	first := new(big.Int).SetBytes(start)
+	span := new(big.Int).Sub(new(big.Int).SetBytes(end), first)
+	span.Add(span, big.NewInt(1))
+
+	for try := 0; try < maxAttempts; try++ {
+		random := make([]byte, len(start))
+		rand.Read(random) // nolint: gosec
+
+		offset := new(big.Int).SetBytes(random)
+		offset.Mod(offset, span)
+		offset.Add(offset, first)
+
+		trial := make(net.IP, len(start))
+		b := offset.Bytes()
+		copy(trial[len(trial)-len(b):], b)
+
+		if !reserved[trial.String()] {
+			return trial, nil
 		}
-		trial := net.IPv4(trialB[0], trialB[1], trialB[2], trialB[3])
+	}
 
-		// not allowed if outside explicitly defined range
-		if bytes.Compare(trial, start) < 0 || bytes.Compare(trial, end) > 0 {
-			continue
+	return nil, fmt.Errorf("%w: no free address between %v and %v of %v after %d attempts", ErrNoFreeIP, start, end, subnet, maxAttempts)
+}
+
+// isFreeIP returns true if FindFreeIP could select ip with the same arguments
+func isFreeIP(subnet *net.IPNet, leases []net.IP, start, end net.IP, ip net.IP) bool {
+	if ip == nil || !subnet.Contains(ip) {
+		return false
+	}
+
+	_, _, start, end = shared.SubnetRange(subnet, start, end)
+	if !(shared.IPRange{Start: start, End: end}).Contains(ip) {
+		return false
+	}
+
+	for _, lease := range leases {
+		if ip.Equal(lease) {
+			return false
 		}
+	}
 
-		// not allowed if already exists in current leases
-		for _, lease := range leases {
-			if trial.Equal(lease) {
-				continue OUTER
-			}
+	return true
+}
+
+// NextFreeIP returns the lowest address within given subnet, which is not reserved in leases and is between the start
+// and end address. The same addresses are reserved as in FindFreeIP and start and end have the same defaults. Returns nil
+// if there's no free address left.
+func NextFreeIP(subnet *net.IPNet, leases []net.IP, start, end net.IP) net.IP {
+	networkIP, broadcastIP, start, end := shared.SubnetRange(subnet, start, end)
+
+	reserved := map[string]bool{
+		networkIP.String():   true,
+		broadcastIP.String(): true,
+	}
+	for _, lease := range leases {
+		reserved[lease.String()] = true
+	}
+
+	for ip := start; bytes.Compare(ip, end) <= 0; ip = shared.NextIP(ip) {
+		if !reserved[ip.String()] {
+			return ip
 		}
 
-		// IP is fine :)
-		ip = trial
-		break
+		// wrapped around after the highest possible address
+		if ip.Equal(broadcastIP) {
+			break
+		}
 	}
 
-	return ip
+	return nil
 }
@@ -1,9 +1,11 @@
 package network
 
 import (
+	"errors"
 	"net"
 	"testing"
 
+	"github.com/automaticserver/lxe/shared"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,7 +16,8 @@ func TestFindFreeIP_CanFind(t *testing.T) {
 	assert.NoError(t, err)
 
 	for i := 0; i < 20; i++ {
-		ip := FindFreeIP(ipNet, nil, nil, nil)
+		ip, err := FindFreeIP(ipNet, nil, nil, nil, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
 		assert.NotNil(t, ip)
 	}
 }
@@ -28,7 +31,8 @@ func TestFindFreeIP_ExcludesLeases(t *testing.T) {
 	leases := []net.IP{net.ParseIP("192.168.224.1")}
 
 	for i := 0; i < 10; i++ {
-		ip := FindFreeIP(ipNet, leases, nil, nil)
+		ip, err := FindFreeIP(ipNet, leases, nil, nil, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
 		assert.Equal(t, "192.168.224.2", ip.String())
 	}
 }
@@ -43,9 +47,215 @@ func TestFindFreeIP_RespectRange(t *testing.T) {
 	end := start
 
 	for i := 0; i < 10; i++ {
-		ip := FindFreeIP(ipNet, nil, start, end)
+		ip, err := FindFreeIP(ipNet, nil, start, end, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
 		assert.Equal(t, start.String(), ip.String())
 	}
 }
 
+func TestFindFreeIP_IPv6(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("fd42:1:2:3::/64")
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		ip, err := FindFreeIP(ipNet, nil, nil, nil, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
+		assert.Len(t, ip, net.IPv6len)
+		assert.True(t, ipNet.Contains(ip))
+		assert.False(t, ip.Equal(ipNet.IP))
+	}
+}
+
+func TestFindFreeIP_IPv6ExcludesLeases(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("fd42:1:2:3::/126")
+	assert.NoError(t, err)
+
+	leases := []net.IP{net.ParseIP("fd42:1:2:3::1")}
+
+	for i := 0; i < 10; i++ {
+		ip, err := FindFreeIP(ipNet, leases, nil, nil, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
+		assert.Equal(t, "fd42:1:2:3::2", ip.String())
+	}
+}
+
+func TestFindFreeIP_MultipleOctets(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/23")
+	assert.NoError(t, err)
+
+	start := net.ParseIP("192.168.224.255")
+	end := net.ParseIP("192.168.225.0")
+	found := map[string]bool{}
+
+	for i := 0; i < 100; i++ {
+		ip, err := FindFreeIP(ipNet, nil, start, end, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
+		found[ip.String()] = true
+	}
+
+	assert.Equal(t, map[string]bool{"192.168.224.255": true, "192.168.225.0": true}, found)
+}
+
+func TestFindFreeIP_MultipleOctetsWholeSubnet(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/22")
+	assert.NoError(t, err)
+
+	// randomly probed, as the subnet has more usable addresses than attempts
+	octets := map[byte]bool{}
+
+	for i := 0; i < 1000; i++ {
+		ip, err := FindFreeIP(ipNet, nil, nil, nil, 100)
+		assert.NoError(t, err)
+		assert.True(t, ipNet.Contains(ip), ip)
+		assert.False(t, ip.Equal(net.ParseIP("192.168.224.0")))
+		assert.False(t, ip.Equal(net.ParseIP("192.168.227.255")))
+
+		octets[ip.To4()[2]] = true
+	}
+
+	assert.Equal(t, map[byte]bool{224: true, 225: true, 226: true, 227: true}, octets)
+
+	// searched completely, only the addresses without lease remain across the octets
+	free := map[string]bool{"192.168.224.1": true, "192.168.225.0": true, "192.168.225.255": true, "192.168.227.254": true}
+
+	var leases []net.IP
+
+	for ip := net.ParseIP("192.168.224.1").To4(); !ip.Equal(net.ParseIP("192.168.227.255")); ip = shared.NextIP(ip) {
+		if !free[ip.String()] {
+			leases = append(leases, ip)
+		}
+	}
+
+	found := map[string]bool{}
+
+	for i := 0; i < 200; i++ {
+		ip, err := FindFreeIP(ipNet, leases, nil, nil, DefaultFindFreeIPAttempts)
+		assert.NoError(t, err)
+
+		found[ip.String()] = true
+	}
+
+	assert.Equal(t, free, found)
+}
+
+func TestFindFreeIP_SmallRangeExhausted(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/30")
+	assert.NoError(t, err)
+
+	leases := []net.IP{net.ParseIP("192.168.224.1"), net.ParseIP("192.168.224.2")}
+
+	ip, err := FindFreeIP(ipNet, leases, nil, nil, DefaultFindFreeIPAttempts)
+	assert.True(t, errors.Is(err, ErrNoFreeIP))
+	assert.Nil(t, ip)
+}
+
+func TestFindFreeIP_SmallRangeSearchedCompletely(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/24")
+	assert.NoError(t, err)
+
+	// only the last address is free, which a few random probes would likely miss
+	var leases []net.IP
+	for i := 1; i < 254; i++ {
+		leases = append(leases, net.IPv4(192, 168, 224, byte(i)))
+	}
+
+	ip, err := FindFreeIP(ipNet, leases, nil, nil, 254)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.224.254", ip.String())
+}
+
+func TestFindFreeIP_BoundedAttempts(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/28")
+	assert.NoError(t, err)
+
+	var leases []net.IP
+	for i := 1; i < 15; i++ {
+		leases = append(leases, net.IPv4(192, 168, 224, byte(i)))
+	}
+
+	ip, err := FindFreeIP(ipNet, leases, nil, nil, 4)
+	assert.True(t, errors.Is(err, ErrNoFreeIP))
+	assert.Contains(t, err.Error(), "after 4 attempts")
+	assert.Nil(t, ip)
+}
+
+func TestFindFreeIP_IPv6Bounded(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("fd42:1:2:3::/64")
+	assert.NoError(t, err)
+
+	ip, err := FindFreeIP(ipNet, nil, nil, nil, 1)
+	assert.NoError(t, err)
+	assert.True(t, ipNet.Contains(ip))
+}
+
+func TestNextFreeIP_Lowest(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/29")
+	assert.NoError(t, err)
+
+	leases := []net.IP{net.ParseIP("192.168.224.1"), net.ParseIP("192.168.224.3")}
+
+	ip := NextFreeIP(ipNet, leases, nil, nil)
+	assert.Equal(t, "192.168.224.2", ip.String())
+}
+
+func TestNextFreeIP_RespectRange(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/24")
+	assert.NoError(t, err)
+
+	ip := NextFreeIP(ipNet, nil, net.ParseIP("192.168.224.100"), net.ParseIP("192.168.224.200"))
+	assert.Equal(t, "192.168.224.100", ip.String())
+}
+
+func TestNextFreeIP_ExcludesNetworkAndBroadcast(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/30")
+	assert.NoError(t, err)
+
+	ip := NextFreeIP(ipNet, []net.IP{net.ParseIP("192.168.224.1")}, net.ParseIP("192.168.224.0"), net.ParseIP("192.168.224.3"))
+	assert.Equal(t, "192.168.224.2", ip.String())
+}
+
+func TestNextFreeIP_Full(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("192.168.224.0/30")
+	assert.NoError(t, err)
+
+	leases := []net.IP{net.ParseIP("192.168.224.1"), net.ParseIP("192.168.224.2")}
+
+	ip := NextFreeIP(ipNet, leases, nil, nil)
+	assert.Nil(t, ip)
+}
+
+func TestNextFreeIP_IPv6(t *testing.T) {
+	t.Parallel()
+
+	_, ipNet, err := net.ParseCIDR("fd00::/64")
+	assert.NoError(t, err)
+
+	ip := NextFreeIP(ipNet, []net.IP{net.ParseIP("fd00::1")}, nil, nil)
+	assert.Equal(t, "fd00::2", ip.String())
+}
+
 // TODO: Timeout or inability to find a valid ip to return an error
@@ -2,6 +2,7 @@ package shared // import "github.com/automaticserver/lxe/shared"
 
 import (
 	"errors"
+	"strings"
 )
 
 // ExitCodeUnspecified is used for unspecified and unrecoverable errors
@@ -32,3 +33,11 @@ func IsErrNotFound(err error) bool {
 func NewErrNotFound() error {
 	return errLXDNotFound
 }
+
+// LXDETagMismatch is the beginning of the error string a LXD request returns, when the provided ETag is outdated
+const LXDETagMismatch = "ETag doesn't match"
+
+// IsErrETagMismatch reports if the LXD api rejected a request because the provided ETag is outdated
+func IsErrETagMismatch(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), LXDETagMismatch)
+}
@@ -0,0 +1,166 @@
+package shared // import "github.com/automaticserver/lxe/shared"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+)
+
+const (
+	// DefaultInterface for containers is always eth0
+	DefaultInterface = "eth0"
+)
+
+var (
+	ErrInvalidRange = errors.New("invalid ip range")
+)
+
+// IPRange is an inclusive range of addresses
+type IPRange struct {
+	Start net.IP
+	End   net.IP
+}
+
+// Contains returns true if ip is within the range. An unset start or end doesn't limit the range in that direction
+func (r IPRange) Contains(ip net.IP) bool {
+	ip = ip.To16()
+
+	return (r.Start == nil || bytes.Compare(ip, r.Start.To16()) >= 0) && (r.End == nil || bytes.Compare(ip, r.End.To16()) <= 0)
+}
+
+// ParseIPRanges parses a comma separated list of ranges in the format of lxd's `dhcp.ranges` bridge config, e.g.
+// "10.0.0.10-10.0.0.100,10.0.0.200-10.0.0.250".
+func ParseIPRanges(s string) ([]IPRange, error) {
+	var ranges []IPRange
+
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		parts := strings.SplitN(r, "-", 2) // nolint: gomnd
+		if len(parts) != 2 {               // nolint: gomnd
+			return nil, fmt.Errorf("%w: %q is not of form start-end", ErrInvalidRange, r)
+		}
+
+		start, end := net.ParseIP(strings.TrimSpace(parts[0])), net.ParseIP(strings.TrimSpace(parts[1]))
+		if start == nil || end == nil || (start.To4() == nil) != (end.To4() == nil) || bytes.Compare(start.To16(), end.To16()) > 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRange, r)
+		}
+
+		ranges = append(ranges, IPRange{Start: start, End: end})
+	}
+
+	return ranges, nil
+}
+
+// CountUsableIPs returns how many addresses are usable between start and end within subnet, which excludes the
+// network and broadcast address. The
+// count saturates at math.MaxInt64, which only large IPv6 subnets reach.
+func CountUsableIPs(subnet *net.IPNet, start, end net.IP) int64 {
+	start, end = UsableRange(subnet, start, end)
+	if bytes.Compare(start, end) > 0 {
+		return 0
+	}
+
+	count := new(big.Int).Sub(new(big.Int).SetBytes(end), new(big.Int).SetBytes(start))
+	count.Add(count, big.NewInt(1))
+
+	if !count.IsInt64() {
+		return math.MaxInt64
+	}
+
+	return count.Int64()
+}
+
+// UsableRange limits the range from start to end to the usable addresses of subnet. The returned start is greater
+// than the returned end if no address is left.
+func UsableRange(subnet *net.IPNet, start, end net.IP) (net.IP, net.IP) {
+	networkIP, broadcastIP, start, end := SubnetRange(subnet, start, end)
+
+	if first := NextIP(networkIP); bytes.Compare(start, first) < 0 || !subnet.Contains(start) {
+		start = first
+	}
+
+	if last := PrevIP(broadcastIP); bytes.Compare(end, last) > 0 || !subnet.Contains(end) {
+		end = last
+	}
+
+	return start, end
+}
+
+// SubnetRange returns the network and broadcast address of subnet and the range to select addresses from. If start or
+// end is nil the closest usable address of the subnet is used instead. All returned addresses have the same length.
+func SubnetRange(subnet *net.IPNet, start, end net.IP) (net.IP, net.IP, net.IP, net.IP) {
+	networkIP, mask := normalizeSubnet(subnet)
+	size := len(networkIP)
+
+	broadcastIP := make(net.IP, size)
+	for i := range broadcastIP {
+		broadcastIP[i] = networkIP[i] | ^mask[i]
+	}
+
+	if start == nil {
+		start = NextIP(networkIP)
+	}
+
+	if end == nil {
+		end = PrevIP(broadcastIP)
+	}
+
+	return networkIP, broadcastIP, toLen(start, size), toLen(end, size)
+}
+
+// normalizeSubnet returns the network address and mask of subnet in the same length, which is 4 bytes for IPv4 and 16
+// bytes for IPv6
+func normalizeSubnet(subnet *net.IPNet) (net.IP, net.IPMask) {
+	if len(subnet.Mask) == net.IPv4len {
+		return toLen(subnet.IP, net.IPv4len), subnet.Mask
+	}
+
+	return toLen(subnet.IP, net.IPv6len), subnet.Mask
+}
+
+// toLen converts ip into its representation with size bytes
+func toLen(ip net.IP, size int) net.IP {
+	if size == net.IPv4len {
+		return ip.To4()
+	}
+
+	return ip.To16()
+}
+
+// NextIP returns the address following ip, carrying over into the higher bytes
+func NextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	return next
+}
+
+// PrevIP returns the address preceding ip, borrowing from the higher bytes
+func PrevIP(ip net.IP) net.IP {
+	prev := make(net.IP, len(ip))
+	copy(prev, ip)
+
+	for i := len(prev) - 1; i >= 0; i-- {
+		prev[i]--
+		if prev[i] != 0xff {
+			break
+		}
+	}
+
+	return prev
+}
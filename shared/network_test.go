@@ -0,0 +1,128 @@
+package shared
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"192.168.224.0", "192.168.224.1"},
+		{"192.168.224.255", "192.168.225.0"},
+		{"10.0.255.255", "10.1.0.0"},
+		{"fd42::ffff", "fd42::1:0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, NextIP(net.ParseIP(tt.input)).String())
+		})
+	}
+}
+
+func TestPrevIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"192.168.224.255", "192.168.224.254"},
+		{"192.168.225.0", "192.168.224.255"},
+		{"10.1.0.0", "10.0.255.255"},
+		{"fd42::1:0", "fd42::ffff"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, PrevIP(net.ParseIP(tt.input)).String())
+		})
+	}
+}
+
+func TestParseIPRanges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []IPRange
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"single", "10.0.0.10-10.0.0.20", []IPRange{{net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.20")}}, false},
+		{"multiple", "10.0.0.10-10.0.0.20, 10.0.0.30-10.0.0.30", []IPRange{
+			{net.ParseIP("10.0.0.10"), net.ParseIP("10.0.0.20")},
+			{net.ParseIP("10.0.0.30"), net.ParseIP("10.0.0.30")},
+		}, false},
+		{"ipv6", "fd00::10-fd00::20", []IPRange{{net.ParseIP("fd00::10"), net.ParseIP("fd00::20")}}, false},
+		{"no end", "10.0.0.10", nil, true},
+		{"invalid", "10.0.0.10-foo", nil, true},
+		{"reversed", "10.0.0.20-10.0.0.10", nil, true},
+		{"mixed families", "10.0.0.10-fd00::20", nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIPRanges(tt.input)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidRange))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCountUsableIPs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		subnet     string
+		start, end string
+		want       int64
+	}{
+		{"whole ipv4 subnet", "10.0.0.0/24", "", "", 254},
+		{"smallest ipv4 subnet", "10.0.0.0/30", "", "", 2},
+		{"range", "10.0.0.0/24", "10.0.0.10", "10.0.0.19", 10},
+		{"range beyond subnet", "10.0.0.0/24", "10.0.0.250", "10.0.1.10", 5},
+		{"empty range", "10.0.0.0/24", "10.0.0.20", "10.0.0.10", 0},
+		{"ipv6 range", "fd00::/64", "fd00::1", "fd00::ff", 255},
+		{"huge ipv6 subnet", "fd00::/64", "", "", math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		tt := tt // pin!
+
+		t.Run(tt.name, func(t *testing.T) {
+			_, subnet, err := net.ParseCIDR(tt.subnet)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.want, CountUsableIPs(subnet, net.ParseIP(tt.start), net.ParseIP(tt.end)))
+		})
+	}
+}
+
+func TestIPRange_Contains(t *testing.T) {
+	t.Parallel()
+
+	r := IPRange{Start: net.ParseIP("10.0.0.10"), End: net.ParseIP("10.0.0.20")}
+	assert.True(t, r.Contains(net.ParseIP("10.0.0.10")))
+	assert.True(t, r.Contains(net.ParseIP("10.0.0.20").To4()))
+	assert.False(t, r.Contains(net.ParseIP("10.0.0.21")))
+	assert.False(t, r.Contains(net.ParseIP("10.0.0.9")))
+	assert.True(t, IPRange{}.Contains(net.ParseIP("10.0.0.9")))
+}